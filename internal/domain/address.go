@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var ErrAddressNotFound = errors.New("address not found")
+
+// AddressKind tells what an address is used for at checkout
+type AddressKind string // @name AddressKind
+
+const (
+	AddressShipping AddressKind = "shipping"
+	AddressBilling  AddressKind = "billing"
+)
+
+// Address defines model for an entry of a user's address book. Addresses
+// are deleted outright, orders keep their own copy.
+type Address struct {
+	ID                uuid.UUID `gorm:"type:uuid;primaryKey" db:"id" json:"id"`
+	UserID            uuid.UUID `gorm:"type:uuid;index" db:"user_id" json:"user_id"`
+	Label             *string   `db:"label" json:"label,omitempty"`
+	FullName          string    `gorm:"not null" db:"full_name" json:"full_name"`
+	Line1             string    `gorm:"not null" db:"line1" json:"line1"`
+	Line2             *string   `db:"line2" json:"line2,omitempty"`
+	City              string    `gorm:"not null" db:"city" json:"city"`
+	Region            *string   `db:"region" json:"region,omitempty"`
+	PostalCode        string    `db:"postal_code" json:"postal_code"`
+	Country           string    `gorm:"type:char(2);not null" db:"country" json:"country"`
+	Phone             *string   `db:"phone" json:"phone,omitempty"`
+	IsDefaultShipping bool      `gorm:"default:false" db:"is_default_shipping" json:"is_default_shipping"`
+	IsDefaultBilling  bool      `gorm:"default:false" db:"is_default_billing" json:"is_default_billing"`
+	CreatedAt         time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time `db:"updated_at" json:"updated_at"`
+} // @name Address
+
+// Snapshot copies the parts of the address printed on an order
+func (a Address) Snapshot() *OrderAddress {
+	return &OrderAddress{
+		FullName:   a.FullName,
+		Line1:      a.Line1,
+		Line2:      a.Line2,
+		City:       a.City,
+		Region:     a.Region,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+		Phone:      a.Phone,
+	}
+}
+
+// OrderAddress is an address as it was when the order was placed, so
+// editing the address book does not rewrite order history
+type OrderAddress struct {
+	FullName   string  `json:"full_name"`
+	Line1      string  `json:"line1"`
+	Line2      *string `json:"line2,omitempty"`
+	City       string  `json:"city"`
+	Region     *string `json:"region,omitempty"`
+	PostalCode string  `json:"postal_code"`
+	Country    string  `json:"country"`
+	Phone      *string `json:"phone,omitempty"`
+} // @name OrderAddress
+
+// AddressInput is used for creating or replacing an address. The postal
+// code is checked against the format of the country.
+type AddressInput struct {
+	Label             *string `json:"label" binding:"omitempty,max=50"`
+	FullName          string  `json:"full_name" binding:"required,max=255"`
+	Line1             string  `json:"line1" binding:"required,max=255"`
+	Line2             *string `json:"line2" binding:"omitempty,max=255"`
+	City              string  `json:"city" binding:"required,max=100"`
+	Region            *string `json:"region" binding:"omitempty,max=100"`
+	PostalCode        string  `json:"postal_code" binding:"max=20"`
+	Country           string  `json:"country" binding:"required,iso3166_1_alpha2"`
+	Phone             *string `json:"phone" binding:"omitempty,e164"`
+	IsDefaultShipping bool    `json:"is_default_shipping"`
+	IsDefaultBilling  bool    `json:"is_default_billing"`
+} // @name AddressInput
+
+type AddressRepository interface {
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]Address, error)
+	// FindByID only finds addresses of userID.
+	FindByID(ctx context.Context, userID, id uuid.UUID) (Address, error)
+	FindDefault(ctx context.Context, userID uuid.UUID, kind AddressKind) (Address, error)
+	Create(ctx context.Context, address *Address) error
+	Update(ctx context.Context, address *Address) error
+	// ClearDefault unsets the default of the kind on all of the user's addresses.
+	ClearDefault(ctx context.Context, userID uuid.UUID, kind AddressKind) error
+	// PromoteDefault makes the user's oldest address the default of the kind.
+	PromoteDefault(ctx context.Context, userID uuid.UUID, kind AddressKind) error
+	Delete(ctx context.Context, userID, id uuid.UUID) (bool, error)
+	DeleteByUser(ctx context.Context, userID uuid.UUID) error
+}
+
+type AddressService interface {
+	List(ctx context.Context, userID uuid.UUID) ([]Address, error)
+	Get(ctx context.Context, userID, id uuid.UUID) (Address, error)
+	Create(ctx context.Context, userID uuid.UUID, in AddressInput) (Address, error)
+	Update(ctx context.Context, userID, id uuid.UUID, in AddressInput) (Address, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+}
+
+type AddressController interface {
+	RegisterRoutes(r *gin.Engine)
+}
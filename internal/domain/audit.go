@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditAction string // @name AuditAction
+
+const (
+	AuditUserInvited         AuditAction = "user.invited"
+	AuditInvitationAccepted  AuditAction = "user.invitation_accepted"
+	AuditRoleChanged         AuditAction = "user.role_changed"
+	AuditUserSuspended       AuditAction = "user.suspended"
+	AuditUserReactivated     AuditAction = "user.reactivated"
+	AuditPasswordResetForced AuditAction = "user.password_reset_forced"
+	AuditDeletionScheduled   AuditAction = "user.deletion_scheduled"
+	AuditDeletionCancelled   AuditAction = "user.deletion_cancelled"
+	AuditUserDeleted         AuditAction = "user.deleted"
+	AuditIdentityLinked      AuditAction = "user.identity_linked"
+	AuditAPIKeyCreated       AuditAction = "api_key.created"
+	AuditAPIKeyRevoked       AuditAction = "api_key.revoked"
+	AuditImpersonationStart  AuditAction = "user.impersonation_started"
+	AuditImpersonatedRequest AuditAction = "user.impersonated_request"
+)
+
+// AuditLog defines model for a record of a privileged action.
+// Records are append-only.
+type AuditLog struct {
+	ID           uuid.UUID         `gorm:"type:uuid;primaryKey" db:"id" json:"id"`
+	ActorID      uuid.UUID         `gorm:"type:uuid;index" db:"actor_id" json:"actor_id"`
+	Action       AuditAction       `gorm:"not null" db:"action" json:"action"`
+	TargetUserID *uuid.UUID        `gorm:"type:uuid;index" db:"target_user_id" json:"target_user_id,omitempty"`
+	Details      map[string]string `gorm:"type:jsonb;serializer:json" db:"details" json:"details,omitempty"`
+	CreatedAt    time.Time         `db:"created_at" json:"created_at"`
+} // @name AuditLog
+
+type AuditRepository interface {
+	Create(ctx context.Context, log *AuditLog) error
+}
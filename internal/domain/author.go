@@ -22,7 +22,7 @@ type AuthorInput struct {
 type AuthorRepository interface {
 	FindByID(ctx context.Context, id uuid.UUID) (Author, error)
 	FindByName(ctx context.Context, name string) (Author, error)
-	List(ctx context.Context, limit, offset int) ([]Author, error)
+	List(ctx context.Context, q QueryOptions) (Page[Author], error)
 	Create(ctx context.Context, author *Author) error
 	Update(ctx context.Context, author *Author) error
 	Delete(ctx context.Context, id uuid.UUID) error
@@ -30,7 +30,7 @@ type AuthorRepository interface {
 
 type AuthorService interface {
 	FindByID(ctx context.Context, id uuid.UUID) (*Author, error)
-	List(ctx context.Context, limit, offset int) ([]Author, error)
+	List(ctx context.Context, q QueryOptions) (Page[Author], error)
 	Create(ctx context.Context, input AuthorInput) (*Author, error)
 	Update(ctx context.Context, id uuid.UUID, input AuthorInput) (*Author, error)
 	Delete(ctx context.Context, id uuid.UUID) error
@@ -13,6 +13,9 @@ const (
 	SortByPrice     = "price"
 	SortByName      = "name"
 	SortByStock     = "available_stock"
+	// SortByRelevance ranks search matches, best first by default. Without
+	// a search it falls back to the newest books.
+	SortByRelevance = "relevance"
 )
 
 // Book defines model for Book
@@ -64,7 +67,7 @@ type BookInput struct {
 }
 
 type BookFilter struct {
-	Search       *string // name / author / isbn
+	Search       *string // title, description, author, publisher, categories or exact isbn
 	MinPrice     *float64
 	MaxPrice     *float64
 	IsActive     *bool
@@ -73,20 +76,90 @@ type BookFilter struct {
 	PublisherIDs []uuid.UUID
 	CategoryIDs  []uuid.UUID
 	MinStock     *int
+	// PriceBands are the boundaries of the price facet, DefaultPriceBands
+	// when empty
+	PriceBands []float64
 }
 
+// DefaultPriceBands split prices into under 10, 10 to 20, 20 to 50,
+// 50 to 100 and 100 or more
+var DefaultPriceBands = []float64{10, 20, 50, 100}
+
+// MaxPriceBands caps the boundaries a filter may ask for
+const MaxPriceBands = 20
+
+// FacetCount is the number of matching books with a filter value
+type FacetCount struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Count int64     `json:"count"`
+} // @name FacetCount
+
+// PriceBucket counts matching books priced from Min, inclusive, up to Max.
+// The first bucket has no Min and the last no Max.
+type PriceBucket struct {
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int64    `json:"count"`
+} // @name PriceBucket
+
+// BookFacets counts the matches per filter value. Each facet applies every
+// criterion of the filter except its own, so selecting a category still
+// shows the counts of the other categories.
+type BookFacets struct {
+	Categories []FacetCount  `json:"categories"`
+	Authors    []FacetCount  `json:"authors"`
+	Publishers []FacetCount  `json:"publishers"`
+	Prices     []PriceBucket `json:"prices"`
+	InStock    int64         `json:"in_stock"`
+} // @name BookFacets
+
+// BookHighlight holds HTML-escaped snippets of a search match with the
+// matched words wrapped in <mark> tags
+type BookHighlight struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+} // @name BookHighlight
+
+// BookHit is a book found by a search, highlighted when a text search
+// was made
+type BookHit struct {
+	Book
+	Highlight *BookHighlight `json:"highlight,omitempty"`
+} // @name BookHit
+
 type BookSearchResult struct {
-	Items  []Book `json:"items"`
-	Total  int64  `json:"total"`
-	Limit  uint64 `json:"limit"`
-	Offset uint64 `json:"offset"`
+	Items  []BookHit   `json:"items"`
+	Total  int64       `json:"total"`
+	Limit  uint64      `json:"limit"`
+	Offset uint64      `json:"offset"`
+	Facets *BookFacets `json:"facets,omitempty"`
+	PageCursors
 }
 
+// Suggestion is an autocomplete match. ID is the book, author or category
+// matched; ISBN suggestions also carry the title of their book.
+type Suggestion struct {
+	ID    uuid.UUID `json:"id"`
+	Text  string    `json:"text"`
+	Title string    `json:"title,omitempty"`
+} // @name Suggestion
+
+// BookSuggestions are the best matches of a partial query per kind, only
+// from active books. Authors and categories are listed when they have one.
+type BookSuggestions struct {
+	Titles     []Suggestion `json:"titles"`
+	Authors    []Suggestion `json:"authors"`
+	Categories []Suggestion `json:"categories"`
+	ISBNs      []Suggestion `json:"isbns"`
+} // @name BookSuggestions
+
 type BookRepository interface {
 	Create(ctx context.Context, book *Book) error
 	FindByID(ctx context.Context, id uuid.UUID) (*Book, error)
-	List(ctx context.Context, limit, offset int) ([]Book, error)
-	FilterByCriteria(ctx context.Context, filter BookFilter, pagination QueryOptions) ([]Book, int64, error)
+	List(ctx context.Context, q QueryOptions) (Page[Book], error)
+	FilterByCriteria(ctx context.Context, filter BookFilter, pagination QueryOptions) (Page[BookHit], int64, *BookFacets, error)
+	Suggest(ctx context.Context, query string, limit int) (*BookSuggestions, error)
 	Update(ctx context.Context, book *Book) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -94,8 +167,9 @@ type BookRepository interface {
 type BookService interface {
 	CreateBook(ctx context.Context, input BookInput) (*Book, error)
 	GetBook(ctx context.Context, id uuid.UUID) (*Book, error)
-	ListBooks(ctx context.Context, limit, offset int) ([]Book, error)
+	ListBooks(ctx context.Context, q QueryOptions) (Page[Book], error)
 	FilterByCriteria(ctx context.Context, filter BookFilter, q QueryOptions) (*BookSearchResult, error)
+	Suggest(ctx context.Context, query string) (*BookSuggestions, error)
 	UpdateBook(ctx context.Context, id uuid.UUID, input BookInput) (*Book, error)
 	DeleteBook(ctx context.Context, id uuid.UUID) error
 }
@@ -65,6 +65,8 @@ type CartRepository interface {
 	UpsertCartItem(ctx context.Context, cartID uuid.UUID, bookID uuid.UUID, count int, unitPrice float64) error
 	RemoveCartItem(ctx context.Context, cartID uuid.UUID, bookID uuid.UUID) error
 	ClearCart(ctx context.Context, cartID uuid.UUID) error
+	// ClearUserCart empties the user's cart without creating one
+	ClearUserCart(ctx context.Context, userID uuid.UUID) error
 }
 
 type CartService interface {
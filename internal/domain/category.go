@@ -25,7 +25,7 @@ type CategoryInput struct {
 type CategoryRepository interface {
 	FindByID(ctx context.Context, id uuid.UUID) (Category, error)
 	FindByName(ctx context.Context, name string) (Category, error)
-	List(ctx context.Context, limit, offset int) ([]Category, error)
+	List(ctx context.Context, q QueryOptions) (Page[Category], error)
 	Create(ctx context.Context, category *Category) error
 	Update(ctx context.Context, category *Category) error
 	Delete(ctx context.Context, id uuid.UUID) error
@@ -33,7 +33,7 @@ type CategoryRepository interface {
 
 type CategoryService interface {
 	FindByID(ctx context.Context, id uuid.UUID) (*Category, error)
-	List(ctx context.Context, limit, offset int) ([]Category, error)
+	List(ctx context.Context, q QueryOptions) (Page[Category], error)
 	Create(ctx context.Context, input CategoryInput) (*Category, error)
 	Update(ctx context.Context, id uuid.UUID, input CategoryInput) (*Category, error)
 	Delete(ctx context.Context, id uuid.UUID) error
@@ -7,6 +7,10 @@ const (
 	UserRoleAdmin UserRole = "ADMIN"
 )
 
+func (r UserRole) IsValid() bool {
+	return r == UserRoleUser || r == UserRoleAdmin
+}
+
 type PaymentStatus string // @name PaymentStatus
 
 const (
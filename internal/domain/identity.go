@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnknownOIDCProvider is returned for providers missing from the configuration
+var ErrUnknownOIDCProvider = errors.New("unknown login provider")
+
+// UserIdentity defines model for an account at an external OpenID Connect
+// provider that was linked to a user
+type UserIdentity struct {
+	ID       uuid.UUID `gorm:"type:uuid;primaryKey" db:"id" json:"id"`
+	UserID   uuid.UUID `gorm:"type:uuid;index" db:"user_id" json:"user_id"`
+	Provider string    `gorm:"not null" db:"provider" json:"provider"`
+	Subject  string    `gorm:"not null" db:"subject" json:"-"`
+	Email    string    `db:"email" json:"email"`
+	BaseEntity
+} // @name UserIdentity
+
+// OIDCLoginState defines model for an authorization request waiting for its
+// callback. Only the hash of the state parameter is stored.
+type OIDCLoginState struct {
+	StateHash    string    `gorm:"primaryKey" db:"state_hash" json:"-"`
+	Provider     string    `gorm:"not null" db:"provider" json:"provider"`
+	CodeVerifier string    `gorm:"not null" db:"code_verifier" json:"-"`
+	Nonce        string    `gorm:"not null" db:"nonce" json:"-"`
+	ExpiresAt    time.Time `db:"expires_at" json:"expires_at"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// ExternalIdentity holds the verified ID token claims of a provider login
+type ExternalIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// OIDCProvider runs the authorization code flow with PKCE against one provider
+type OIDCProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	// Exchange redeems the code and verifies the returned ID token, including its nonce.
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (ExternalIdentity, error)
+}
+
+// OIDCCallbackInput carries the query of the provider's redirect
+type OIDCCallbackInput struct {
+	Code             string `form:"code"`
+	State            string `form:"state" binding:"required"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+}
+
+type IdentityRepository interface {
+	FindByProviderSubject(ctx context.Context, provider, subject string) (*UserIdentity, error)
+	Create(ctx context.Context, identity *UserIdentity) error
+	DeleteByUser(ctx context.Context, userID uuid.UUID) error
+	CreateLoginState(ctx context.Context, state *OIDCLoginState) error
+	// ConsumeLoginState removes the state so a callback can only be used once.
+	// Expired states are not returned.
+	ConsumeLoginState(ctx context.Context, stateHash string) (*OIDCLoginState, error)
+}
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenUseImpersonation marks access tokens an admin obtained to act as a
+// customer. The act claim holds the admin's ID.
+const TokenUseImpersonation = "impersonation"
+
+// ImpersonationInput is used by admins to explain why they act as a user
+type ImpersonationInput struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+} // @name ImpersonationInput
+
+// ImpersonationToken is a short-lived access token without refresh token
+type ImpersonationToken struct {
+	AccessToken    string    `json:"access_token"`
+	TokenType      string    `json:"token_type"`
+	ExpiresIn      int64     `json:"expires_in"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	UserID         uuid.UUID `json:"user_id"`
+	ImpersonatorID uuid.UUID `json:"impersonator_id"`
+} // @name ImpersonationToken
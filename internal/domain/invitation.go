@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invitation defines model for an admin-issued invite to join with a role.
+// Only the hash of the token is stored; the raw token is emailed.
+type Invitation struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" db:"id" json:"id"`
+	Email      string     `gorm:"not null" db:"email" json:"email"`
+	Role       UserRole   `gorm:"type:user_role;not null" db:"role" json:"role"`
+	TokenHash  string     `gorm:"uniqueIndex;not null" db:"token_hash" json:"-"`
+	InvitedBy  uuid.UUID  `gorm:"type:uuid" db:"invited_by" json:"invited_by"`
+	ExpiresAt  time.Time  `db:"expires_at" json:"expires_at"`
+	AcceptedAt *time.Time `db:"accepted_at" json:"accepted_at,omitempty"`
+	BaseEntity
+} // @name Invitation
+
+// InvitationInput is used by admins to invite a user
+type InvitationInput struct {
+	Email string   `json:"email" binding:"required,email"`
+	Role  UserRole `json:"role" binding:"required"`
+} // @name InvitationInput
+
+// AcceptInvitationInput is used by the invitee to create their account
+type AcceptInvitationInput struct {
+	Token     string `json:"token" binding:"required"`
+	FirstName string `json:"first_name" binding:"required,min=3"`
+	LastName  string `json:"last_name" binding:"required"`
+	Mobile    string `json:"mobile" binding:"required,e164"`
+	Password  string `json:"password" binding:"required,min=6"`
+} // @name AcceptInvitationInput
+
+// RoleInput is used by admins to change a user's role
+type RoleInput struct {
+	Role UserRole `json:"role" binding:"required"`
+} // @name RoleInput
+
+type InvitationRepository interface {
+	Create(ctx context.Context, invitation *Invitation) error
+	FindByTokenHash(ctx context.Context, tokenHash string) (*Invitation, error)
+	// MarkAccepted reports false when the invitation was already accepted.
+	MarkAccepted(ctx context.Context, id uuid.UUID) (bool, error)
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LoginAttempt defines model for the failed attempts recorded against
+// a key, such as an account ("user:<id>") or a client IP ("ip:<addr>").
+type LoginAttempt struct {
+	Key           string     `gorm:"primaryKey" db:"key" json:"key"`
+	Failures      int        `gorm:"not null;default:0" db:"failures" json:"failures"`
+	LastFailureAt time.Time  `db:"last_failure_at" json:"last_failure_at"`
+	LockedUntil   *time.Time `db:"locked_until" json:"locked_until,omitempty"`
+	BaseEntity
+}
+
+// TooManyAttemptsError is returned while a key is locked out
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return fmt.Sprintf("too many failed attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+type LoginAttemptRepository interface {
+	// Find returns nil when nothing was recorded for the key.
+	Find(ctx context.Context, key string) (*LoginAttempt, error)
+	// RecordFailure counts a failure, starting over when the previous
+	// one is older than window, and returns the updated state.
+	RecordFailure(ctx context.Context, key string, window time.Duration) (*LoginAttempt, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+}
+
+type clientIPKeyType string
+
+const clientIPKey clientIPKeyType = "BookNest-ClientIP"
+
+// WithClientIP stores the caller's IP for per-IP attempt tracking
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// ClientIPFromContext returns the IP stored by WithClientIP
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
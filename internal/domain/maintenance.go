@@ -0,0 +1,24 @@
+package domain
+
+import "context"
+
+// AdvisoryLockRepository coordinates background jobs between replicas
+type AdvisoryLockRepository interface {
+	// TryXactLock takes the named lock until the surrounding transaction
+	// ends. It returns false when another session holds it.
+	TryXactLock(ctx context.Context, name string) (bool, error)
+}
+
+// AccountPurger anonymises the accounts whose deletion grace period ended
+type AccountPurger interface {
+	PurgeDeletedUsers(ctx context.Context) (int, error)
+}
+
+type MaintenanceService interface {
+	// PurgeVerificationTokens returns how many tokens were removed. It does
+	// nothing when another replica is already purging.
+	PurgeVerificationTokens(ctx context.Context) (int64, error)
+	// PurgeDeletedAccounts returns how many accounts were anonymised. It
+	// does nothing when another replica is already purging.
+	PurgeDeletedAccounts(ctx context.Context) (int, error)
+}
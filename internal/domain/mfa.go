@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenUseMFAPending marks access tokens that only allow completing
+// the second login step or enrolling an authenticator.
+const TokenUseMFAPending = "mfa_pending"
+
+// MFACredential defines model for a user's TOTP authenticator.
+// Enrollment starts with EnabledAt unset until the first code is confirmed.
+type MFACredential struct {
+	UserID       uuid.UUID  `gorm:"type:uuid;primaryKey" db:"user_id" json:"user_id"`
+	Secret       string     `gorm:"not null" db:"secret" json:"-"`
+	EnabledAt    *time.Time `db:"enabled_at" json:"enabled_at,omitempty"`
+	LastUsedStep int64      `gorm:"default:0" db:"last_used_step" json:"-"`
+	BaseEntity
+}
+
+// MFARecoveryCode defines model for a single-use recovery code
+type MFARecoveryCode struct {
+	ID       uuid.UUID  `gorm:"type:uuid;primaryKey" db:"id" json:"id"`
+	UserID   uuid.UUID  `gorm:"type:uuid;index" db:"user_id" json:"user_id"`
+	CodeHash string     `gorm:"not null" db:"code_hash" json:"-"`
+	UsedAt   *time.Time `db:"used_at" json:"used_at,omitempty"`
+	BaseEntity
+}
+
+// MFASetup is returned when an authenticator enrollment starts
+type MFASetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+} // @name MFASetup
+
+// MFACodeInput carries a TOTP or recovery code
+type MFACodeInput struct {
+	Code string `json:"code" binding:"required"`
+} // @name MFACodeInput
+
+// MFALoginInput is used for the second login step
+type MFALoginInput struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+} // @name MFALoginInput
+
+type MFARepository interface {
+	// Upsert stores a new pending secret, replacing any unconfirmed one.
+	Upsert(ctx context.Context, credential *MFACredential) error
+	FindByUserID(ctx context.Context, userID uuid.UUID) (*MFACredential, error)
+	Enable(ctx context.Context, userID uuid.UUID) error
+	// UseStep records a verified time step and reports false when it
+	// is not newer than the last one, which means the code was replayed.
+	UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	Delete(ctx context.Context, userID uuid.UUID) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+	// UseRecoveryCode consumes an unused code and reports whether one matched.
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+}
@@ -0,0 +1,49 @@
+package domain
+
+import "context"
+
+// NotificationTemplate names a message template, e.g. "email_verification"
+type NotificationTemplate string
+
+const (
+	TemplateEmailVerification  NotificationTemplate = "email_verification"
+	TemplateMobileVerification NotificationTemplate = "mobile_verification"
+	TemplateLoginOTP           NotificationTemplate = "login_otp"
+	TemplateMagicLink          NotificationTemplate = "magic_link"
+	TemplatePasswordReset      NotificationTemplate = "password_reset"
+	TemplateInvitation         NotificationTemplate = "invitation"
+	TemplateNewDevice          NotificationTemplate = "new_device"
+	TemplateOrderPlaced        NotificationTemplate = "order_placed"
+	TemplateOrderPaid          NotificationTemplate = "order_paid"
+	TemplateOrderCancelled     NotificationTemplate = "order_cancelled"
+)
+
+// EmailMessage is a rendered email ready to be delivered
+type EmailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// SMSMessage is a rendered text message ready to be delivered
+type SMSMessage struct {
+	To   string
+	Body string
+}
+
+// EmailSender delivers a single email, e.g. over SMTP
+type EmailSender interface {
+	SendEmail(ctx context.Context, msg EmailMessage) error
+}
+
+// SMSSender delivers a single text message
+type SMSSender interface {
+	SendSMS(ctx context.Context, msg SMSMessage) error
+}
+
+// Notifier renders a template and delivers it, retrying failed attempts.
+// data is passed to the template as is.
+type Notifier interface {
+	Email(ctx context.Context, to string, template NotificationTemplate, data any) error
+	SMS(ctx context.Context, to string, template NotificationTemplate, data any) error
+}
@@ -17,6 +17,9 @@ type Order struct {
 	PaymentMethod *PaymentMethod `gorm:"type:payment_method" json:"payment_method,omitempty"`
 	PaymentStatus *PaymentStatus `gorm:"type:payment_status" json:"payment_status,omitempty"`
 	Status        OrderStatus    `gorm:"type:order_status;default:PENDING" json:"status"`
+	// The addresses as they were at checkout, nil for older orders
+	ShippingAddress *OrderAddress `gorm:"type:jsonb;serializer:json" json:"shipping_address,omitempty"`
+	BillingAddress  *OrderAddress `gorm:"type:jsonb;serializer:json" json:"billing_address,omitempty"`
 	BaseEntity
 } // @name Order
 
@@ -46,8 +49,12 @@ type OrderView struct {
 	Items []OrderItemDetail `json:"items"`
 }
 
+// CheckoutInput picks the addresses from the address book. Omitted
+// addresses fall back to the defaults; billing falls back to shipping.
 type CheckoutInput struct {
-	PaymentMethod PaymentMethod `json:"payment_method" binding:"required"`
+	PaymentMethod     PaymentMethod `json:"payment_method" binding:"required"`
+	ShippingAddressID *uuid.UUID    `json:"shipping_address_id"`
+	BillingAddressID  *uuid.UUID    `json:"billing_address_id"`
 }
 
 type PaymentConfirmInput struct {
@@ -58,20 +65,23 @@ type PaymentConfirmInput struct {
 type OrderRepository interface {
 	CreateOrder(ctx context.Context, order *Order) error
 	CreateOrderItems(ctx context.Context, items []OrderItem) error
-	ListOrdersByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]OrderView, error)
-	ListOrders(ctx context.Context, limit, offset int) ([]OrderView, error)
+	ListOrdersByUser(ctx context.Context, userID uuid.UUID, q QueryOptions) (Page[OrderView], error)
+	ListOrders(ctx context.Context, q QueryOptions) (Page[OrderView], error)
 	GetOrderByID(ctx context.Context, orderID uuid.UUID) (Order, error)
 	GetOrderItems(ctx context.Context, orderID uuid.UUID) ([]OrderItemDetail, error)
 	UpdateOrderPayment(ctx context.Context, orderID uuid.UUID, status PaymentStatus, method PaymentMethod) error
 	UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, status OrderStatus) error
 	DecrementStock(ctx context.Context, items []OrderItem) error
+	// AnonymizeAddresses strips the names, street lines and phone numbers
+	// from the address snapshots of the user's orders.
+	AnonymizeAddresses(ctx context.Context, userID uuid.UUID) error
 }
 
 type OrderService interface {
 	Checkout(ctx context.Context, userID uuid.UUID, input CheckoutInput) (OrderView, error)
 	ConfirmPayment(ctx context.Context, userID uuid.UUID, input PaymentConfirmInput) (OrderView, error)
-	ListUserOrders(ctx context.Context, userID uuid.UUID, limit, offset int) ([]OrderView, error)
-	ListAllOrders(ctx context.Context, limit, offset int) ([]OrderView, error)
+	ListUserOrders(ctx context.Context, userID uuid.UUID, q QueryOptions) (Page[OrderView], error)
+	ListAllOrders(ctx context.Context, q QueryOptions) (Page[OrderView], error)
 }
 
 type OrderController interface {
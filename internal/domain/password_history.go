@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PreviousPassword defines model for a password a user replaced.
+// Only the hash is kept, to stop recent passwords from being reused.
+type PreviousPassword struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey" db:"id" json:"id"`
+	UserID       uuid.UUID `gorm:"type:uuid;index" db:"user_id" json:"user_id"`
+	PasswordHash string    `gorm:"not null" db:"password_hash" json:"-"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+type PasswordHistoryRepository interface {
+	Add(ctx context.Context, userID uuid.UUID, passwordHash string) error
+	// ListRecent returns up to limit hashes, newest first.
+	ListRecent(ctx context.Context, userID uuid.UUID, limit int) ([]string, error)
+	// Prune keeps the newest keep entries of the user.
+	Prune(ctx context.Context, userID uuid.UUID, keep int) error
+	DeleteByUser(ctx context.Context, userID uuid.UUID) error
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken defines model for a rotating refresh token.
+// Every login starts a new family; each refresh replaces the presented token
+// with a new one in the same family.
+type RefreshToken struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" db:"id" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;index" db:"user_id" json:"user_id"`
+	FamilyID   uuid.UUID  `gorm:"type:uuid;index" db:"family_id" json:"family_id"`
+	TokenHash  string     `gorm:"not null;uniqueIndex" db:"token_hash" json:"-"`
+	ExpiresAt  time.Time  `db:"expires_at" json:"expires_at"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	ReplacedBy *uuid.UUID `gorm:"type:uuid" db:"replaced_by" json:"replaced_by,omitempty"`
+	BaseEntity
+}
+
+// AuthTokens is the token pair returned on login and refresh.
+// When MFARequired is set only MFAToken is issued, and it must be exchanged
+// on the second login step; ExpiresIn then refers to the MFA token.
+type AuthTokens struct {
+	AccessToken           string `json:"token,omitempty"`
+	RefreshToken          string `json:"refresh_token,omitempty"`
+	TokenType             string `json:"token_type"`
+	ExpiresIn             int64  `json:"expires_in"`
+	MFARequired           bool   `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
+	MFAToken              string `json:"mfa_token,omitempty"`
+} // @name AuthTokens
+
+// RefreshTokenInput is used for refresh and logout
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+} // @name RefreshTokenInput
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *RefreshToken) error
+	FindByHash(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	// Revoke marks a single token as revoked and reports whether it was still active.
+	Revoke(ctx context.Context, id uuid.UUID, replacedBy *uuid.UUID) (bool, error)
+	RevokeFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeAllByUser(ctx context.Context, userID uuid.UUID) error
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrSessionNotFound is returned for unknown, foreign or already revoked sessions
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session defines model for a login on one device.
+// Its ID doubles as the refresh token family ID and is carried in the
+// sid claim of access tokens.
+type Session struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" db:"id" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;index" db:"user_id" json:"user_id"`
+	UserAgent  string     `db:"user_agent" json:"user_agent"`
+	IPAddress  string     `db:"ip_address" json:"ip_address"`
+	LastSeenAt time.Time  `db:"last_seen_at" json:"last_seen_at"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	// Current marks the session of the token used for the request
+	Current bool `gorm:"-" db:"-" json:"current"`
+	BaseEntity
+} // @name Session
+
+type userAgentKeyType string
+
+const userAgentKey userAgentKeyType = "BookNest-UserAgent"
+
+// WithUserAgent stores the caller's user agent for new sessions
+func WithUserAgent(ctx context.Context, userAgent string) context.Context {
+	return context.WithValue(ctx, userAgentKey, userAgent)
+}
+
+// UserAgentFromContext returns the user agent stored by WithUserAgent
+func UserAgentFromContext(ctx context.Context) string {
+	userAgent, _ := ctx.Value(userAgentKey).(string)
+	return userAgent
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *Session) error
+	// ListActiveByUser returns the sessions that were not revoked, most recently seen first.
+	ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]Session, error)
+	// IsRevoked reports whether the session was revoked. Unknown IDs are not,
+	// as refresh token families from before sessions were recorded have none.
+	IsRevoked(ctx context.Context, id uuid.UUID) (bool, error)
+	// IsKnownDevice reports whether the user signed in from userAgent before.
+	// A user without any session has nothing to compare against and gets true.
+	IsKnownDevice(ctx context.Context, userID uuid.UUID, userAgent string) (bool, error)
+	Touch(ctx context.Context, id uuid.UUID) error
+	// Revoke ends a session of the user and reports whether it was still active.
+	Revoke(ctx context.Context, userID, id uuid.UUID) (bool, error)
+	RevokeAllByUser(ctx context.Context, userID uuid.UUID) error
+}
@@ -1,5 +1,7 @@
 package domain
 
+import "errors"
+
 type SortOrder string
 
 const (
@@ -7,6 +9,10 @@ const (
 	Desc SortOrder = "desc"
 )
 
+// ErrInvalidCursor is returned for a cursor that is malformed or was made
+// for another sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 type SortOptions struct {
 	Field string
 	Order SortOrder
@@ -16,4 +22,20 @@ type QueryOptions struct {
 	Limit  uint64
 	Offset uint64
 	Sort   *SortOptions
+	// Cursor, the next or previous cursor of a page, continues from that
+	// page instead of skipping Offset rows
+	Cursor string
+}
+
+// PageCursors continue a list after or before a page. Each is empty when
+// there is nothing more that way.
+type PageCursors struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// Page is a page of a list
+type Page[T any] struct {
+	Items []T `json:"items"`
+	PageCursors
 }
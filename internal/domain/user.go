@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,19 +22,78 @@ type User struct {
 	IsActive       bool       `gorm:"default:false" json:"is_active"`
 	EmailVerified  bool       `gorm:"default:false" json:"email_verified"`
 	MobileVerified bool       `gorm:"default:false" json:"mobile_verified"`
+	// PasswordResetRequired is set by admins and blocks logins until the password is reset
+	PasswordResetRequired bool `gorm:"default:false" json:"password_reset_required"`
+	// PendingEmail and PendingMobile replace Email and Mobile once verified
+	PendingEmail  *string `json:"pending_email,omitempty"`
+	PendingMobile *string `json:"pending_mobile,omitempty"`
+	// DeletionScheduledAt is when the account gets anonymised, unless cancelled
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
 	BaseEntity
 } // @name User
 
+var (
+	ErrAccountSuspended      = errors.New("account is suspended")
+	ErrPasswordResetRequired = errors.New("password reset required")
+	// ErrWeakPassword is wrapped with the rule the password broke
+	ErrWeakPassword   = errors.New("password is too weak")
+	ErrPasswordReused = errors.New("password was used recently")
+)
+
+// UserFilter holds the admin user search criteria. Text fields match
+// partially and case-insensitively.
+type UserFilter struct {
+	Email          *string
+	Mobile         *string
+	Name           *string // first and last name
+	Role           *UserRole
+	IsActive       *bool
+	EmailVerified  *bool
+	MobileVerified *bool
+	CreatedFrom    *time.Time
+	CreatedTo      *time.Time
+}
+
+type UserSearchResult struct {
+	Items  []User `json:"items"`
+	Total  int64  `json:"total"`
+	Limit  uint64 `json:"limit"`
+	Offset uint64 `json:"offset"`
+	PageCursors
+} // @name UserSearchResult
+
+// UserExport is the personal data of a user, as handed out on request
+type UserExport struct {
+	ExportedAt time.Time        `json:"exported_at"`
+	Profile    User             `json:"profile"`
+	Orders     []OrderView      `json:"orders"`
+	Cart       []CartItemDetail `json:"cart"`
+	Addresses  []Address        `json:"addresses"`
+} // @name UserExport
+
+// SuspendInput is used by admins to suspend a user
+type SuspendInput struct {
+	Reason string `json:"reason"`
+} // @name SuspendInput
+
 // UserInput is used for creating or updating users
 type UserInput struct {
-	FirstName string   `json:"first_name" binding:"required,min=3"`
-	LastName  string   `json:"last_name" binding:"required"`
-	Email     string   `json:"email" binding:"required,email"`
-	Mobile    string   `json:"mobile" binding:"required,e164"`
-	Password  string   `json:"password" binding:"required,min=6"`
-	Role      UserRole `json:"role" binding:"required"`
+	FirstName string `json:"first_name" binding:"required,min=3"`
+	LastName  string `json:"last_name" binding:"required"`
+	Email     string `json:"email" binding:"required,email"`
+	Mobile    string `json:"mobile" binding:"required,e164"`
+	Password  string `json:"password" binding:"required,min=6"`
 } // @name UserInput
 
+// ProfileInput is used by users to update their own profile. Omitted
+// fields are left unchanged.
+type ProfileInput struct {
+	FirstName *string `json:"first_name" binding:"omitnil,min=3"`
+	LastName  *string `json:"last_name" binding:"omitnil,min=1"`
+	Email     *string `json:"email" binding:"omitnil,email"`
+	Mobile    *string `json:"mobile" binding:"omitnil,e164"`
+} // @name ProfileInput
+
 // ForgotPasswordInput is used for forgot password
 type ForgotPasswordInput struct {
 	Email  string `json:"email"`
@@ -47,27 +107,115 @@ type LoginInput struct {
 	Password string `json:"password" binding:"required"`
 } // @name LoginInput
 
+// LoginOTPRequestInput is used to request a one-time login code
+type LoginOTPRequestInput struct {
+	Email  string `json:"email"`
+	Mobile string `json:"mobile"`
+} // @name LoginOTPRequestInput
+
+// LoginOTPVerifyInput is used to exchange a one-time login code for tokens
+type LoginOTPVerifyInput struct {
+	Email  string `json:"email"`
+	Mobile string `json:"mobile"`
+	OTP    string `json:"otp" binding:"required,len=6,numeric"`
+} // @name LoginOTPVerifyInput
+
+// MagicLinkRequestInput is used to request a login link by email. With
+// BindDevice the link only works together with the returned device code.
+type MagicLinkRequestInput struct {
+	Email      string `json:"email" binding:"required,email"`
+	BindDevice bool   `json:"bind_device"`
+} // @name MagicLinkRequestInput
+
+// MagicLinkLoginInput is used to exchange a login link for tokens
+type MagicLinkLoginInput struct {
+	Token      string `json:"token" binding:"required"`
+	DeviceCode string `json:"device_code"`
+} // @name MagicLinkLoginInput
+
 type UserRepository interface {
 	Create(ctx context.Context, user *User) error
 	FindByID(ctx context.Context, id uuid.UUID) (User, error)
 	FindByEmail(ctx context.Context, email string) (User, error)
 	FindByMobile(ctx context.Context, mobile string) (User, error)
 	Update(ctx context.Context, user *User) error
+	// UpdateRole is kept apart from Update so roles only change on purpose.
+	UpdateRole(ctx context.Context, id uuid.UUID, role UserRole) error
+	SetActive(ctx context.Context, id uuid.UUID, active bool) error
+	SetPasswordResetRequired(ctx context.Context, id uuid.UUID, required bool) error
+	// SetPendingEmail and SetPendingMobile store an address awaiting
+	// verification, nil clears it. Confirming moves it into place and
+	// reports false when nothing was pending.
+	SetPendingEmail(ctx context.Context, id uuid.UUID, email *string) error
+	SetPendingMobile(ctx context.Context, id uuid.UUID, mobile *string) error
+	ConfirmPendingEmail(ctx context.Context, id uuid.UUID) (bool, error)
+	ConfirmPendingMobile(ctx context.Context, id uuid.UUID) (bool, error)
+	Search(ctx context.Context, filter UserFilter, pagination QueryOptions) (Page[User], int64, error)
+	// SetDeletionSchedule sets when the account gets deleted, nil cancels.
+	SetDeletionSchedule(ctx context.Context, id uuid.UUID, at *time.Time) error
+	FindDueForDeletion(ctx context.Context, before time.Time, limit int) ([]User, error)
+	// Delete soft deletes the user and replaces the personal data with
+	// placeholders. The row stays, so orders keep their user.
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
 type UserService interface {
 	FindUser(ctx context.Context, id uuid.UUID) (User, error)
+	UpdateProfile(ctx context.Context, userID uuid.UUID, in ProfileInput) (User, error)
 	Register(ctx context.Context, in UserInput) error
-	Login(ctx context.Context, in LoginInput) (token string, err error)
-	ForgotPassword(ctx context.Context, in ForgotPasswordInput) (string, error)
+	Login(ctx context.Context, in LoginInput) (AuthTokens, error)
+	RefreshToken(ctx context.Context, rawToken string) (AuthTokens, error)
+	Logout(ctx context.Context, rawToken string) error
+	LogoutAll(ctx context.Context, userID uuid.UUID) error
+	// ListSessions marks the session with currentID as the current one.
+	ListSessions(ctx context.Context, userID, currentID uuid.UUID) ([]Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	RequestLoginOTP(ctx context.Context, in LoginOTPRequestInput) error
+	LoginWithOTP(ctx context.Context, in LoginOTPVerifyInput) (AuthTokens, error)
+	// RequestMagicLink returns the device code when the link is bound.
+	RequestMagicLink(ctx context.Context, in MagicLinkRequestInput) (string, error)
+	LoginWithMagicLink(ctx context.Context, in MagicLinkLoginInput) (AuthTokens, error)
+	LoginWithMFA(ctx context.Context, in MFALoginInput) (AuthTokens, error)
+	// StartOIDCLogin returns the URL of the provider's login page.
+	StartOIDCLogin(ctx context.Context, provider string) (string, error)
+	CompleteOIDCLogin(ctx context.Context, provider, code, state string) (AuthTokens, error)
+	SetupMFA(ctx context.Context, userID uuid.UUID) (MFASetup, error)
+	EnableMFA(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	DisableMFA(ctx context.Context, userID uuid.UUID, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	ForgotPassword(ctx context.Context, in ForgotPasswordInput) error
 	ResetPassword(ctx context.Context, userID uuid.UUID, newPassword string) error
 	ResetPasswordWithToken(ctx context.Context, rawToken, newPassword string) error
 	VerifyEmail(ctx context.Context, rawToken string) error
-	VerifyMobile(ctx context.Context, otp string) error
+	VerifyMobile(ctx context.Context, userID uuid.UUID, otp string) error
 	ResendEmailVerification(ctx context.Context, userID uuid.UUID) error
 	ResendMobileOTP(ctx context.Context, userID uuid.UUID) error
-	DeleteUser(ctx context.Context, id uuid.UUID) error
+	// ScheduleDeletion returns when the account will be anonymised.
+	ScheduleDeletion(ctx context.Context, actorID, userID uuid.UUID) (time.Time, error)
+	CancelDeletion(ctx context.Context, userID uuid.UUID) error
+	ExportData(ctx context.Context, userID uuid.UUID) (UserExport, error)
+	// PurgeDeletedUsers anonymises accounts whose grace period ended.
+	PurgeDeletedUsers(ctx context.Context) (int, error)
+	UnlockUser(ctx context.Context, id uuid.UUID) error
+	InviteUser(ctx context.Context, adminID uuid.UUID, in InvitationInput) (Invitation, error)
+	AcceptInvitation(ctx context.Context, in AcceptInvitationInput) error
+	ChangeUserRole(ctx context.Context, adminID, userID uuid.UUID, role UserRole) error
+	SearchUsers(ctx context.Context, filter UserFilter, pagination QueryOptions) (*UserSearchResult, error)
+	SuspendUser(ctx context.Context, adminID, userID uuid.UUID, reason string) error
+	ReactivateUser(ctx context.Context, adminID, userID uuid.UUID) error
+	ForcePasswordReset(ctx context.Context, adminID, userID uuid.UUID) error
+	// Impersonate issues a token acting as userID for customer support.
+	Impersonate(ctx context.Context, adminID, userID uuid.UUID, reason string) (ImpersonationToken, error)
+	// CreateAPIKey returns the raw key, which is only shown once.
+	CreateAPIKey(ctx context.Context, adminID uuid.UUID, in APIKeyInput) (CreatedAPIKey, error)
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, adminID, id uuid.UUID) error
+	// AuthenticateAPIKey returns ErrInvalidAPIKey for unknown, expired or
+	// revoked keys and for keys whose admin can no longer log in.
+	AuthenticateAPIKey(ctx context.Context, rawKey string) (APIKeyPrincipal, error)
+	// CheckVerified returns a *VerificationRequiredError when the user has
+	// not verified the channels the action needs.
+	CheckVerified(ctx context.Context, userID uuid.UUID, action ProtectedAction) error
 }
 
 type UserController interface {
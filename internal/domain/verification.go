@@ -14,6 +14,7 @@ const (
 	VerificationMobile VerificationTokenType = "MOBILE_VERIFICATION"
 	PasswordReset      VerificationTokenType = "PASSWORD_RESET"
 	LoginOTP           VerificationTokenType = "LOGIN_OTP"
+	LoginMagicLink     VerificationTokenType = "LOGIN_MAGIC_LINK"
 )
 
 type VerificationToken struct {
@@ -23,8 +24,11 @@ type VerificationToken struct {
 	TokenHash string                `gorm:"not null" db:"token_hash" json:"token_hash"`
 	ExpiresAt time.Time             `gorm:"index" db:"expires_at" json:"expires_at"`
 	IsUsed    bool                  `gorm:"default:false" db:"is_used" json:"is_used"`
+	Attempts  int                   `gorm:"default:0" db:"attempts" json:"attempts"`
 
 	UsedAt   *time.Time        `db:"used_at" json:"used_at"`
+	// DeviceHash binds a magic link to the device that asked for it
+	DeviceHash *string `db:"device_hash" json:"-"`
 
 	BaseEntity
 }
@@ -41,5 +45,16 @@ type VerificationTokenRepository interface {
 
 	Create(ctx context.Context, token *VerificationToken) error
 	Update(ctx context.Context, token *VerificationToken) error
+	// Consume marks an unused token as used and reports false when it was
+	// already used, so a token is only redeemed once under concurrency
+	Consume(ctx context.Context, id uuid.UUID) (bool, error)
+	// RecordFailedAttempt counts a wrong guess in a single statement and
+	// burns the token once maxAttempts is reached. It returns the new
+	// count, or 0 when the token was already used.
+	RecordFailedAttempt(ctx context.Context, id uuid.UUID, maxAttempts int) (int, error)
 	Delete(ctx context.Context, id uuid.UUID) error
+
+	// PurgeStale removes tokens that expired, were used or were deleted
+	// before cutoff and returns how many went
+	PurgeStale(ctx context.Context, cutoff time.Time) (int64, error)
 }
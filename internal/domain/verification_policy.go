@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"strings"
+)
+
+// VerificationChannel is an address the user proves they own
+type VerificationChannel string // @name VerificationChannel
+
+const (
+	ChannelEmail  VerificationChannel = "email"
+	ChannelMobile VerificationChannel = "mobile"
+)
+
+// IsVerified reports whether user verified the channel
+func (c VerificationChannel) IsVerified(user User) bool {
+	switch c {
+	case ChannelEmail:
+		return user.EmailVerified
+	case ChannelMobile:
+		return user.MobileVerified
+	default:
+		return false
+	}
+}
+
+// ProtectedAction names an action that may require verified channels
+type ProtectedAction string // @name ProtectedAction
+
+const (
+	ActionCheckout    ProtectedAction = "checkout"
+	ActionPostReview  ProtectedAction = "review.post"
+	ActionChangeEmail ProtectedAction = "profile.change_email"
+)
+
+// VerificationRequiredError lists the channels to verify before the action
+type VerificationRequiredError struct {
+	Action  ProtectedAction
+	Missing []VerificationChannel
+}
+
+func (e *VerificationRequiredError) Error() string {
+	missing := make([]string, 0, len(e.Missing))
+	for _, channel := range e.Missing {
+		missing = append(missing, string(channel))
+	}
+	return "verify your " + strings.Join(missing, " and ") + " first"
+}
@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+	"booknest/internal/http/routes"
+	"booknest/internal/middleware"
+)
+
+type addressController struct {
+	service domain.AddressService
+	auth    *middleware.Auth
+}
+
+func NewAddressController(service domain.AddressService, auth *middleware.Auth) domain.AddressController {
+	return &addressController{service: service, auth: auth}
+}
+
+func (c *addressController) RegisterRoutes(r *gin.Engine) {
+	protected := r.Group("")
+	protected.Use(c.auth.JWTAuthMiddleware())
+	{
+		protected.GET(routes.MeAddressesRoute, c.List)
+		protected.GET(routes.MeAddressRoute, c.GetByID)
+	}
+
+	owner := r.Group("")
+	owner.Use(c.auth.JWTAuthMiddleware(), middleware.DenyImpersonation())
+	{
+		owner.POST(routes.MeAddressesRoute, c.Create)
+		owner.PUT(routes.MeAddressRoute, c.Update)
+		owner.DELETE(routes.MeAddressRoute, c.Delete)
+	}
+}
+
+// respondAddressError writes a 404 for a missing address and a 400 otherwise
+func respondAddressError(ctx *gin.Context, err error) {
+	if errors.Is(err, domain.ErrAddressNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// List godoc
+// @Summary      List addresses
+// @Description  Lists the authenticated user's address book, defaults first
+// @Tags         Addresses
+// @Produce      json
+// @Success      200  {array}   domain.Address
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/addresses [get]
+func (c *addressController) List(ctx *gin.Context) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	addresses, err := c.service.List(ctx, userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, addresses)
+}
+
+// GetByID godoc
+// @Summary      Get address
+// @Description  Fetches an address of the authenticated user
+// @Tags         Addresses
+// @Produce      json
+// @Param        id   path      string  true  "Address ID"
+// @Success      200  {object}  domain.Address
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/addresses/{id} [get]
+func (c *addressController) GetByID(ctx *gin.Context) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid address id"})
+		return
+	}
+
+	address, err := c.service.Get(ctx, userID, id)
+	if err != nil {
+		respondAddressError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, address)
+}
+
+// Create godoc
+// @Summary      Add address
+// @Description  Adds an address to the authenticated user's address book. The first address becomes the default for shipping and billing.
+// @Tags         Addresses
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      domain.AddressInput  true  "Address input"
+// @Success      201      {object}  domain.Address
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/addresses [post]
+func (c *addressController) Create(ctx *gin.Context) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var input domain.AddressInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	address, err := c.service.Create(ctx, userID, input)
+	if err != nil {
+		respondAddressError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, address)
+}
+
+// Update godoc
+// @Summary      Replace address
+// @Description  Replaces an address of the authenticated user. Orders already placed keep the address they were placed with.
+// @Tags         Addresses
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string               true  "Address ID"
+// @Param        payload  body      domain.AddressInput  true  "Address input"
+// @Success      200      {object}  domain.Address
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/addresses/{id} [put]
+func (c *addressController) Update(ctx *gin.Context) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid address id"})
+		return
+	}
+
+	var input domain.AddressInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	address, err := c.service.Update(ctx, userID, id, input)
+	if err != nil {
+		respondAddressError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, address)
+}
+
+// Delete godoc
+// @Summary      Delete address
+// @Description  Removes an address from the authenticated user's address book
+// @Tags         Addresses
+// @Produce      json
+// @Param        id   path      string  true  "Address ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/addresses/{id} [delete]
+func (c *addressController) Delete(ctx *gin.Context) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid address id"})
+		return
+	}
+
+	if err := c.service.Delete(ctx, userID, id); err != nil {
+		respondAddressError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "address deleted"})
+}
@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+)
+
+type mockAddressServiceController struct {
+	listFunc   func(ctx context.Context, userID uuid.UUID) ([]domain.Address, error)
+	getFunc    func(ctx context.Context, userID, id uuid.UUID) (domain.Address, error)
+	createFunc func(ctx context.Context, userID uuid.UUID, in domain.AddressInput) (domain.Address, error)
+	updateFunc func(ctx context.Context, userID, id uuid.UUID, in domain.AddressInput) (domain.Address, error)
+	deleteFunc func(ctx context.Context, userID, id uuid.UUID) error
+}
+
+func (m *mockAddressServiceController) List(ctx context.Context, userID uuid.UUID) ([]domain.Address, error) {
+	if m.listFunc != nil {
+		return m.listFunc(ctx, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+func (m *mockAddressServiceController) Get(ctx context.Context, userID, id uuid.UUID) (domain.Address, error) {
+	if m.getFunc != nil {
+		return m.getFunc(ctx, userID, id)
+	}
+	return domain.Address{}, errors.New("not implemented")
+}
+func (m *mockAddressServiceController) Create(ctx context.Context, userID uuid.UUID, in domain.AddressInput) (domain.Address, error) {
+	if m.createFunc != nil {
+		return m.createFunc(ctx, userID, in)
+	}
+	return domain.Address{}, errors.New("not implemented")
+}
+func (m *mockAddressServiceController) Update(ctx context.Context, userID, id uuid.UUID, in domain.AddressInput) (domain.Address, error) {
+	if m.updateFunc != nil {
+		return m.updateFunc(ctx, userID, id, in)
+	}
+	return domain.Address{}, errors.New("not implemented")
+}
+func (m *mockAddressServiceController) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	if m.deleteFunc != nil {
+		return m.deleteFunc(ctx, userID, id)
+	}
+	return errors.New("not implemented")
+}
+
+func TestAddressControllerCreate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+	svc := &mockAddressServiceController{
+		createFunc: func(ctx context.Context, gotUserID uuid.UUID, in domain.AddressInput) (domain.Address, error) {
+			if gotUserID != userID || in.PostalCode != "10115" {
+				t.Fatalf("unexpected create input")
+			}
+			return domain.Address{ID: uuid.New(), UserID: userID, IsDefaultShipping: true}, nil
+		},
+	}
+	ctl := NewAddressController(svc, newTestAuth(t)).(*addressController)
+
+	body, _ := json.Marshal(domain.AddressInput{
+		FullName:   "Ada Reader",
+		Line1:      "Invalidenstr. 1",
+		City:       "Berlin",
+		PostalCode: "10115",
+		Country:    "DE",
+	})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", userID.String())
+	c.Request = httptest.NewRequest(http.MethodPost, "/me/addresses", bytes.NewBuffer(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	ctl.Create(c)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAddressControllerGetNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := &mockAddressServiceController{
+		getFunc: func(ctx context.Context, userID, id uuid.UUID) (domain.Address, error) {
+			return domain.Address{}, domain.ErrAddressNotFound
+		},
+	}
+	ctl := NewAddressController(svc, newTestAuth(t)).(*addressController)
+
+	id := uuid.New()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", uuid.New().String())
+	c.Params = gin.Params{{Key: "id", Value: id.String()}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/me/addresses/"+id.String(), nil)
+
+	ctl.GetByID(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestAddressControllerDeleteInvalidID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctl := NewAddressController(&mockAddressServiceController{}, newTestAuth(t)).(*addressController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", uuid.New().String())
+	c.Params = gin.Params{{Key: "id", Value: "not-a-uuid"}}
+	c.Request = httptest.NewRequest(http.MethodDelete, "/me/addresses/not-a-uuid", nil)
+
+	ctl.Delete(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
@@ -2,7 +2,6 @@ package controller
 
 import (
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -14,22 +13,24 @@ import (
 
 type authorController struct {
 	service domain.AuthorService
+	auth    *middleware.Auth
 }
 
-func NewAuthorController(service domain.AuthorService) domain.AuthorController {
-	return &authorController{service: service}
+func NewAuthorController(service domain.AuthorService, auth *middleware.Auth) domain.AuthorController {
+	return &authorController{service: service, auth: auth}
 }
 
 func (c *authorController) RegisterRoutes(r *gin.Engine) {
 	protected := r.Group("")
-	protected.Use(middleware.JWTAuthMiddleware())
+	protected.Use(c.auth.JWTAuthMiddleware())
 	{
 		protected.GET(routes.AuthorsRoute, c.List)
 		protected.GET(routes.AuthorByIDRoute, c.GetByID)
 	}
 
+	// Catalog integrations may use an API key instead of a JWT
 	admin := r.Group("")
-	admin.Use(middleware.JWTAuthMiddleware(), middleware.RequireAdmin())
+	admin.Use(c.auth.JWTOrAPIKeyAuthMiddleware(domain.ScopeBooksWrite), middleware.RequireAdmin())
 	{
 		admin.POST(routes.AuthorsRoute, c.Create)
 		admin.PUT(routes.AuthorByIDRoute, c.Update)
@@ -48,6 +49,7 @@ func (c *authorController) RegisterRoutes(r *gin.Engine) {
 // @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Router       /authors [post]
 func (c *authorController) Create(ctx *gin.Context) {
 	var input domain.AuthorInput
@@ -67,29 +69,27 @@ func (c *authorController) Create(ctx *gin.Context) {
 
 // List godoc
 // @Summary      List authors
-// @Description  Lists authors
+// @Description  Lists authors by name
 // @Tags         Authors
 // @Produce      json
-// @Param        limit   query  int  false  "Result limit"
-// @Param        offset  query  int  false  "Result offset"
-// @Success      200  {array}  domain.Author
+// @Param        limit   query  int     false  "Result limit (default 20, max 100)"
+// @Param        offset  query  int     false  "Result offset"
+// @Param        cursor  query  string  false  "next_cursor or prev_cursor of a page, instead of offset"
+// @Success      200  {object}  domain.Page[domain.Author]
+// @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Security     BearerAuth
 // @Router       /authors [get]
 func (c *authorController) List(ctx *gin.Context) {
-	limit := 20
-	offset := 0
-
-	if v := ctx.Query("limit"); v != "" {
-		limit, _ = strconv.Atoi(v)
-	}
-	if v := ctx.Query("offset"); v != "" {
-		offset, _ = strconv.Atoi(v)
+	q, err := parsePageOptions(ctx, 20)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	authors, err := c.service.List(ctx, limit, offset)
+	authors, err := c.service.List(ctx, q)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondListError(ctx, err)
 		return
 	}
 
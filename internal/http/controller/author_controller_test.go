@@ -17,7 +17,7 @@ import (
 
 type mockAuthorService struct {
 	findByIDFunc func(ctx context.Context, id uuid.UUID) (*domain.Author, error)
-	listFunc     func(ctx context.Context, limit, offset int) ([]domain.Author, error)
+	listFunc     func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Author], error)
 	createFunc   func(ctx context.Context, input domain.AuthorInput) (*domain.Author, error)
 	updateFunc   func(ctx context.Context, id uuid.UUID, input domain.AuthorInput) (*domain.Author, error)
 	deleteFunc   func(ctx context.Context, id uuid.UUID) error
@@ -30,11 +30,11 @@ func (m *mockAuthorService) FindByID(ctx context.Context, id uuid.UUID) (*domain
 	return nil, errors.New("not implemented")
 }
 
-func (m *mockAuthorService) List(ctx context.Context, limit, offset int) ([]domain.Author, error) {
+func (m *mockAuthorService) List(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Author], error) {
 	if m.listFunc != nil {
-		return m.listFunc(ctx, limit, offset)
+		return m.listFunc(ctx, q)
 	}
-	return []domain.Author{}, nil
+	return domain.Page[domain.Author]{Items: []domain.Author{}}, nil
 }
 
 func (m *mockAuthorService) Create(ctx context.Context, input domain.AuthorInput) (*domain.Author, error) {
@@ -67,7 +67,7 @@ func TestAuthorControllerCreate(t *testing.T) {
 		}
 		return &domain.Author{ID: uuid.New(), Name: input.Name}, nil
 	}}
-	ctl := NewAuthorController(svc).(*authorController)
+	ctl := NewAuthorController(svc, newTestAuth(t)).(*authorController)
 
 	body, _ := json.Marshal(domain.AuthorInput{Name: "Author Name"})
 	w := httptest.NewRecorder()
@@ -86,11 +86,11 @@ func TestAuthorControllerListAndGetByID(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	id := uuid.New()
 	svc := &mockAuthorService{
-		listFunc: func(ctx context.Context, limit, offset int) ([]domain.Author, error) {
-			if limit != 7 || offset != 3 {
-				t.Fatalf("unexpected pagination: %d/%d", limit, offset)
+		listFunc: func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Author], error) {
+			if q.Limit != 7 || q.Offset != 3 {
+				t.Fatalf("unexpected pagination: %d/%d", q.Limit, q.Offset)
 			}
-			return []domain.Author{{ID: id, Name: "A"}}, nil
+			return domain.Page[domain.Author]{Items: []domain.Author{{ID: id, Name: "A"}}}, nil
 		},
 		findByIDFunc: func(ctx context.Context, gotID uuid.UUID) (*domain.Author, error) {
 			if gotID != id {
@@ -99,7 +99,7 @@ func TestAuthorControllerListAndGetByID(t *testing.T) {
 			return &domain.Author{ID: id, Name: "A"}, nil
 		},
 	}
-	ctl := NewAuthorController(svc).(*authorController)
+	ctl := NewAuthorController(svc, newTestAuth(t)).(*authorController)
 
 	lw := httptest.NewRecorder()
 	lc, _ := gin.CreateTestContext(lw)
@@ -121,7 +121,7 @@ func TestAuthorControllerListAndGetByID(t *testing.T) {
 
 func TestAuthorControllerDeleteInvalidID(t *testing.T) {
 	gin.SetMode(gin.TestMode)
-	ctl := NewAuthorController(&mockAuthorService{}).(*authorController)
+	ctl := NewAuthorController(&mockAuthorService{}, newTestAuth(t)).(*authorController)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -1,8 +1,13 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -13,22 +18,25 @@ import (
 
 type bookController struct {
 	service domain.BookService
+	auth    *middleware.Auth
 }
 
-func NewBookController(service domain.BookService) domain.BookController {
-	return &bookController{service: service}
+func NewBookController(service domain.BookService, auth *middleware.Auth) domain.BookController {
+	return &bookController{service: service, auth: auth}
 }
 
 func (c *bookController) RegisterRoutes(r *gin.Engine) {
 	public := r.Group("/books")
 	{
 		public.POST("/filter", c.filterBooks)
+		public.GET("/suggest", c.suggestBooks)
 		public.GET("/:id", c.getBook)
 		public.GET("", c.listBooks)
 	}
 
+	// Catalog integrations may use an API key instead of a JWT
 	admin := r.Group("/books")
-	admin.Use(middleware.JWTAuthMiddleware(), middleware.RequireAdmin())
+	admin.Use(c.auth.JWTOrAPIKeyAuthMiddleware(domain.ScopeBooksWrite), middleware.RequireAdmin())
 	{
 		admin.POST("", c.createBook)
 		admin.PUT("/:id", c.updateBook)
@@ -47,6 +55,7 @@ func (c *bookController) RegisterRoutes(r *gin.Engine) {
 // @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Router       /books [post]
 func (c *bookController) createBook(ctx *gin.Context) {
 	var input domain.BookInput
@@ -92,23 +101,48 @@ func (c *bookController) getBook(ctx *gin.Context) {
 
 // listBooks godoc
 // @Summary      List books
-// @Description  Returns a list of books
+// @Description  Lists books matching the filters, newest first unless sorted. List parameters take comma separated values or repeat
 // @Tags         Books
 // @Produce      json
-// @Success      200  {array}  domain.Book
+// @Param        search         query  string  false  "Search title, description, author, publisher and categories by word prefix, or an exact ISBN"
+// @Param        min_price      query  number  false  "Minimum price"
+// @Param        max_price      query  number  false  "Maximum price"
+// @Param        is_active      query  bool    false  "Active or inactive books only"
+// @Param        min_stock      query  int     false  "Minimum available stock"
+// @Param        ids            query  string  false  "Book IDs"
+// @Param        author_ids     query  string  false  "Author IDs"
+// @Param        publisher_ids  query  string  false  "Publisher IDs"
+// @Param        category_ids   query  string  false  "Category IDs, books in any of them"
+// @Param        price_bands    query  string  false  "Boundaries of the price facet"
+// @Param        sort           query  string  false  "Sort field: created_at, price, name, available_stock or relevance (needs search)"
+// @Param        order          query  string  false  "Sort order: asc or desc"
+// @Param        limit          query  int     false  "Result limit (default 10, max 100)"
+// @Param        offset         query  int     false  "Result offset"
+// @Param        cursor         query  string  false  "next_cursor or prev_cursor of a page, instead of offset"
+// @Success      200  {object}  domain.BookSearchResult
+// @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /books [get]
 func (c *bookController) listBooks(ctx *gin.Context) {
-	limit := 10
-	offset := 0
+	filter, err := parseBookFilter(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	books, err := c.service.ListBooks(ctx, limit, offset)
+	q, err := parseBookQueryOptions(ctx, filter)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, books)
+	result, err := c.service.FilterByCriteria(ctx, filter, q)
+	if err != nil {
+		respondListError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
 }
 
 // filterBooks godoc
@@ -117,9 +151,12 @@ func (c *bookController) listBooks(ctx *gin.Context) {
 // @Tags         Books
 // @Accept       json
 // @Produce      json
-// @Param        search  query  string  false  "Search by name, author, or ISBN"
-// @Param        limit   query  int     false  "Result limit"
+// @Param        search  query  string  false  "Search title, description, author, publisher and categories by word prefix, or an exact ISBN"
+// @Param        sort    query  string  false  "Sort field: created_at, price, name, available_stock or relevance (needs search)"
+// @Param        order   query  string  false  "Sort order: asc or desc"
+// @Param        limit   query  int     false  "Result limit (default 10, max 100)"
 // @Param        offset  query  int     false  "Result offset"
+// @Param        cursor  query  string  false  "next_cursor or prev_cursor of a page, instead of offset"
 // @Param        payload  body  domain.BookFilter  false  "Book filter payload"
 // @Success      200  {object}  domain.BookSearchResult
 // @Failure      400  {object}  map[string]string
@@ -136,29 +173,50 @@ func (c *bookController) filterBooks(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if len(filter.PriceBands) > domain.MaxPriceBands {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid PriceBands: at most %d values", domain.MaxPriceBands)})
+		return
+	}
 
-	limit := uint64(10)
-	offset := uint64(0)
+	q, err := parseBookQueryOptions(ctx, filter)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if v := ctx.Query("limit"); v != "" {
-		limit, _ = strconv.ParseUint(v, 10, 64)
+	result, err := c.service.FilterByCriteria(ctx, filter, q)
+	if err != nil {
+		respondListError(ctx, err)
+		return
 	}
 
-	if v := ctx.Query("offset"); v != "" {
-		offset, _ = strconv.ParseUint(v, 10, 64)
+	ctx.JSON(http.StatusOK, result)
+}
+
+// suggestBooks godoc
+// @Summary      Suggest books
+// @Description  Autocompletes a partial query with the best matching titles, authors, categories and ISBNs of active books. Tolerates typos. Queries shorter than 2 characters get no suggestions.
+// @Tags         Books
+// @Produce      json
+// @Param        q  query  string  true  "Partial query"
+// @Success      200  {object}  domain.BookSuggestions
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /books/suggest [get]
+func (c *bookController) suggestBooks(ctx *gin.Context) {
+	query, ok := ctx.GetQuery("q")
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
 	}
 
-	result, err := c.service.FilterByCriteria(
-		ctx,
-		filter,
-		domain.QueryOptions{Limit: limit, Offset: offset},
-	)
+	suggestions, err := c.service.Suggest(ctx, query)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, result)
+	ctx.JSON(http.StatusOK, suggestions)
 }
 
 func (c *bookController) updateBook(ctx *gin.Context) {
@@ -197,3 +255,154 @@ func (c *bookController) deleteBook(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Book deleted successfully"})
 }
+
+const (
+	defaultBookPageSize = 10
+
+	// maxFilterIDs caps the values of each ID list parameter
+	maxFilterIDs = 100
+)
+
+var bookSortFields = map[string]bool{
+	domain.SortByCreatedAt: true,
+	domain.SortByPrice:     true,
+	domain.SortByName:      true,
+	domain.SortByStock:     true,
+	domain.SortByRelevance: true,
+}
+
+// parseBookFilter reads a domain.BookFilter from the query parameters
+func parseBookFilter(ctx *gin.Context) (domain.BookFilter, error) {
+	var filter domain.BookFilter
+
+	if v := strings.TrimSpace(ctx.Query("search")); v != "" {
+		filter.Search = &v
+	}
+
+	for key, dst := range map[string]**float64{
+		"min_price": &filter.MinPrice,
+		"max_price": &filter.MaxPrice,
+	} {
+		if v := ctx.Query(key); v != "" {
+			f, err := parseNonNegative(v)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s: must be a non-negative number", key)
+			}
+			*dst = &f
+		}
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return filter, errors.New("min_price must not be greater than max_price")
+	}
+
+	if v := ctx.Query("is_active"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, errors.New("invalid is_active: must be true or false")
+		}
+		filter.IsActive = &b
+	}
+
+	if v := ctx.Query("min_stock"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return filter, errors.New("invalid min_stock: must be a non-negative integer")
+		}
+		filter.MinStock = &n
+	}
+
+	for key, dst := range map[string]*[]uuid.UUID{
+		"ids":           &filter.IDs,
+		"author_ids":    &filter.AuthorIDs,
+		"publisher_ids": &filter.PublisherIDs,
+		"category_ids":  &filter.CategoryIDs,
+	} {
+		values := queryList(ctx, key)
+		if len(values) > maxFilterIDs {
+			return filter, fmt.Errorf("invalid %s: at most %d values", key, maxFilterIDs)
+		}
+		for _, v := range values {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s: %q is not a UUID", key, v)
+			}
+			*dst = append(*dst, id)
+		}
+	}
+
+	bands := queryList(ctx, "price_bands")
+	if len(bands) > domain.MaxPriceBands {
+		return filter, fmt.Errorf("invalid price_bands: at most %d values", domain.MaxPriceBands)
+	}
+	for _, v := range bands {
+		f, err := parseNonNegative(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid price_bands: %q is not a non-negative number", v)
+		}
+		filter.PriceBands = append(filter.PriceBands, f)
+	}
+
+	return filter, nil
+}
+
+// parseBookQueryOptions reads the sort and pagination query parameters.
+// An order without a sort applies to the default sort, the creation time.
+// Relevance ranks the matches of the filter's search, so it needs one.
+func parseBookQueryOptions(ctx *gin.Context, filter domain.BookFilter) (domain.QueryOptions, error) {
+	q, err := parsePageOptions(ctx, defaultBookPageSize)
+	if err != nil {
+		return q, err
+	}
+
+	field := ctx.Query("sort")
+	if field != "" && !bookSortFields[field] {
+		return q, errors.New("invalid sort: must be one of created_at, price, name, available_stock or relevance")
+	}
+	if field == domain.SortByRelevance && !hasSearchWords(filter.Search) {
+		return q, errors.New("invalid sort: relevance needs a search")
+	}
+
+	order := domain.SortOrder(ctx.Query("order"))
+	if order != "" && order != domain.Asc && order != domain.Desc {
+		return q, errors.New("invalid order: must be asc or desc")
+	}
+
+	if field != "" || order != "" {
+		if field == "" {
+			field = domain.SortByCreatedAt
+		}
+		q.Sort = &domain.SortOptions{Field: field, Order: order}
+	}
+
+	return q, nil
+}
+
+// hasSearchWords tells whether search has a word to match, the search
+// is ignored otherwise
+func hasSearchWords(search *string) bool {
+	return search != nil && strings.IndexFunc(*search, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) >= 0
+}
+
+// queryList returns the values of a list parameter, given comma separated,
+// repeated or both
+func queryList(ctx *gin.Context, key string) []string {
+	var values []string
+	for _, raw := range ctx.QueryArray(key) {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+func parseNonNegative(v string) (float64, error) {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, errors.New("not a non-negative number")
+	}
+	return f, nil
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -18,10 +19,11 @@ import (
 type mockBookServiceController struct {
 	createBookFunc      func(ctx context.Context, input domain.BookInput) (*domain.Book, error)
 	getBookFunc         func(ctx context.Context, id uuid.UUID) (*domain.Book, error)
-	listBooksFunc       func(ctx context.Context, limit, offset int) ([]domain.Book, error)
+	listBooksFunc       func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Book], error)
 	filterByCriteriaFun func(ctx context.Context, filter domain.BookFilter, q domain.QueryOptions) (*domain.BookSearchResult, error)
 	updateBookFunc      func(ctx context.Context, id uuid.UUID, input domain.BookInput) (*domain.Book, error)
 	deleteBookFunc      func(ctx context.Context, id uuid.UUID) error
+	suggestFunc         func(ctx context.Context, query string) (*domain.BookSuggestions, error)
 }
 
 func (m *mockBookServiceController) CreateBook(ctx context.Context, input domain.BookInput) (*domain.Book, error) {
@@ -36,11 +38,11 @@ func (m *mockBookServiceController) GetBook(ctx context.Context, id uuid.UUID) (
 	}
 	return nil, errors.New("not implemented")
 }
-func (m *mockBookServiceController) ListBooks(ctx context.Context, limit, offset int) ([]domain.Book, error) {
+func (m *mockBookServiceController) ListBooks(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Book], error) {
 	if m.listBooksFunc != nil {
-		return m.listBooksFunc(ctx, limit, offset)
+		return m.listBooksFunc(ctx, q)
 	}
-	return []domain.Book{}, nil
+	return domain.Page[domain.Book]{Items: []domain.Book{}}, nil
 }
 func (m *mockBookServiceController) FilterByCriteria(ctx context.Context, filter domain.BookFilter, q domain.QueryOptions) (*domain.BookSearchResult, error) {
 	if m.filterByCriteriaFun != nil {
@@ -61,6 +63,13 @@ func (m *mockBookServiceController) DeleteBook(ctx context.Context, id uuid.UUID
 	return nil
 }
 
+func (m *mockBookServiceController) Suggest(ctx context.Context, query string) (*domain.BookSuggestions, error) {
+	if m.suggestFunc != nil {
+		return m.suggestFunc(ctx, query)
+	}
+	return &domain.BookSuggestions{}, nil
+}
+
 func TestBookControllerGetAndList(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	id := uuid.New()
@@ -71,14 +80,14 @@ func TestBookControllerGetAndList(t *testing.T) {
 			}
 			return &domain.Book{ID: id, Name: "Book"}, nil
 		},
-		listBooksFunc: func(ctx context.Context, limit, offset int) ([]domain.Book, error) {
-			if limit != 10 || offset != 0 {
-				t.Fatalf("expected defaults 10/0, got %d/%d", limit, offset)
+		filterByCriteriaFun: func(ctx context.Context, filter domain.BookFilter, q domain.QueryOptions) (*domain.BookSearchResult, error) {
+			if q.Limit != 10 || q.Offset != 0 || q.Sort != nil {
+				t.Fatalf("expected defaults 10/0 and no sort, got %+v", q)
 			}
-			return []domain.Book{{ID: id, Name: "Book"}}, nil
+			return &domain.BookSearchResult{Items: []domain.BookHit{{Book: domain.Book{ID: id, Name: "Book"}}}, Total: 1}, nil
 		},
 	}
-	ctl := NewBookController(svc).(*bookController)
+	ctl := NewBookController(svc, newTestAuth(t)).(*bookController)
 
 	gw := httptest.NewRecorder()
 	gc, _ := gin.CreateTestContext(gw)
@@ -96,6 +105,10 @@ func TestBookControllerGetAndList(t *testing.T) {
 	if lw.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", lw.Code)
 	}
+	var result domain.BookSearchResult
+	if err := json.Unmarshal(lw.Body.Bytes(), &result); err != nil || result.Total != 1 || len(result.Items) != 1 {
+		t.Fatalf("unexpected body: %s", lw.Body.String())
+	}
 }
 
 func TestBookControllerFilterBooks(t *testing.T) {
@@ -108,14 +121,17 @@ func TestBookControllerFilterBooks(t *testing.T) {
 			if q.Limit != 5 || q.Offset != 2 {
 				t.Fatalf("unexpected pagination: %+v", q)
 			}
-			return &domain.BookSearchResult{Items: []domain.Book{}, Total: 0, Limit: q.Limit, Offset: q.Offset}, nil
+			if q.Sort == nil || q.Sort.Field != domain.SortByRelevance {
+				t.Fatalf("expected relevance sorting, got %+v", q.Sort)
+			}
+			return &domain.BookSearchResult{Items: []domain.BookHit{}, Total: 0, Limit: q.Limit, Offset: q.Offset}, nil
 		},
 	}
-	ctl := NewBookController(svc).(*bookController)
+	ctl := NewBookController(svc, newTestAuth(t)).(*bookController)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Request = httptest.NewRequest(http.MethodPost, "/books/filter?search=harry&limit=5&offset=2", bytes.NewBufferString(`{}`))
+	c.Request = httptest.NewRequest(http.MethodPost, "/books/filter?search=harry&sort=relevance&limit=5&offset=2", bytes.NewBufferString(`{}`))
 	c.Request.Header.Set("Content-Type", "application/json")
 
 	ctl.filterBooks(c)
@@ -124,9 +140,194 @@ func TestBookControllerFilterBooks(t *testing.T) {
 	}
 }
 
+// TestBookControllerFilterBooksValidation tests that the filter endpoint
+// refuses the same query parameters as GET /books
+func TestBookControllerFilterBooksValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctl := NewBookController(&mockBookServiceController{
+		filterByCriteriaFun: func(ctx context.Context, filter domain.BookFilter, q domain.QueryOptions) (*domain.BookSearchResult, error) {
+			t.Fatalf("should not search")
+			return nil, nil
+		},
+	}, newTestAuth(t)).(*bookController)
+
+	tests := []struct {
+		query string
+		body  string
+		want  string
+	}{
+		{"sort=isbn", `{}`, "invalid sort: must be one of created_at, price, name, available_stock or relevance"},
+		{"sort=price&order=up", `{}`, "invalid order: must be asc or desc"},
+		{"sort=relevance", `{}`, "invalid sort: relevance needs a search"},
+		{"sort=relevance", `{"Search": "  "}`, "invalid sort: relevance needs a search"},
+		{"limit=abc", `{}`, "invalid limit: must be between 1 and 100"},
+		{"limit=18446744073709551615", `{}`, "invalid limit: must be between 1 and 100"},
+		{"offset=x", `{}`, "invalid offset: must be a non-negative integer"},
+		{"offset=10&cursor=abc", `{}`, "offset and cursor cannot be combined"},
+		{"", `{"PriceBands": [` + strings.Repeat("1,", domain.MaxPriceBands) + `1]}`, "invalid PriceBands: at most 20 values"},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/books/filter?"+tt.query, bytes.NewBufferString(tt.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		ctl.filterBooks(c)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", tt.query, w.Code)
+		}
+		var body map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["error"] != tt.want {
+			t.Fatalf("%s: expected %q, got %s", tt.query, tt.want, w.Body.String())
+		}
+	}
+}
+
+func TestBookControllerListBooksQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authorID := uuid.New()
+	otherAuthorID := uuid.New()
+	categoryID := uuid.New()
+
+	svc := &mockBookServiceController{
+		filterByCriteriaFun: func(ctx context.Context, filter domain.BookFilter, q domain.QueryOptions) (*domain.BookSearchResult, error) {
+			if filter.Search == nil || *filter.Search != "dune" {
+				t.Fatalf("expected the search, got %+v", filter.Search)
+			}
+			if *filter.MinPrice != 5 || *filter.MaxPrice != 20.5 || !*filter.IsActive || *filter.MinStock != 1 {
+				t.Fatalf("unexpected filter: %+v", filter)
+			}
+			if len(filter.AuthorIDs) != 2 || filter.AuthorIDs[1] != otherAuthorID || len(filter.CategoryIDs) != 1 || filter.CategoryIDs[0] != categoryID {
+				t.Fatalf("unexpected ids: %+v", filter)
+			}
+			if len(filter.PriceBands) != 2 || filter.PriceBands[1] != 30 {
+				t.Fatalf("unexpected price bands: %+v", filter.PriceBands)
+			}
+			if q.Limit != 25 || q.Cursor != "abc" || q.Sort == nil || q.Sort.Field != domain.SortByPrice || q.Sort.Order != domain.Desc {
+				t.Fatalf("unexpected query options: %+v", q)
+			}
+			return &domain.BookSearchResult{Items: []domain.BookHit{}}, nil
+		},
+	}
+	ctl := NewBookController(svc, newTestAuth(t)).(*bookController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/books?search=dune&min_price=5&max_price=20.5&is_active=true&min_stock=1"+
+		"&author_ids="+authorID.String()+","+otherAuthorID.String()+"&category_ids="+categoryID.String()+
+		"&price_bands=15&price_bands=30&sort=price&order=desc&limit=25&cursor=abc", nil)
+
+	ctl.listBooks(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBookControllerListBooksValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctl := NewBookController(&mockBookServiceController{
+		filterByCriteriaFun: func(ctx context.Context, filter domain.BookFilter, q domain.QueryOptions) (*domain.BookSearchResult, error) {
+			t.Fatalf("should not search")
+			return nil, nil
+		},
+	}, newTestAuth(t)).(*bookController)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"min_price=cheap", "invalid min_price: must be a non-negative number"},
+		{"max_price=-1", "invalid max_price: must be a non-negative number"},
+		{"min_price=20&max_price=10", "min_price must not be greater than max_price"},
+		{"is_active=maybe", "invalid is_active: must be true or false"},
+		{"min_stock=1.5", "invalid min_stock: must be a non-negative integer"},
+		{"author_ids=abc", `invalid author_ids: "abc" is not a UUID`},
+		{"price_bands=10,x", `invalid price_bands: "x" is not a non-negative number`},
+		{"price_bands=" + strings.Repeat("1,", domain.MaxPriceBands) + "1", "invalid price_bands: at most 20 values"},
+		{"sort=isbn", "invalid sort: must be one of created_at, price, name, available_stock or relevance"},
+		{"order=up", "invalid order: must be asc or desc"},
+		{"sort=relevance", "invalid sort: relevance needs a search"},
+		{"sort=relevance&search=--", "invalid sort: relevance needs a search"},
+		{"limit=0", "invalid limit: must be between 1 and 100"},
+		{"limit=101", "invalid limit: must be between 1 and 100"},
+		{"offset=-2", "invalid offset: must be a non-negative integer"},
+		{"offset=10&cursor=abc", "offset and cursor cannot be combined"},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/books?"+tt.query, nil)
+
+		ctl.listBooks(c)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", tt.query, w.Code)
+		}
+		var body map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["error"] != tt.want {
+			t.Fatalf("%s: expected %q, got %s", tt.query, tt.want, w.Body.String())
+		}
+	}
+}
+
+func TestBookControllerListBooksCursor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := &mockBookServiceController{
+		filterByCriteriaFun: func(ctx context.Context, filter domain.BookFilter, q domain.QueryOptions) (*domain.BookSearchResult, error) {
+			return nil, domain.ErrInvalidCursor
+		},
+	}
+	ctl := NewBookController(svc, newTestAuth(t)).(*bookController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/books?cursor=abc", nil)
+
+	ctl.listBooks(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad cursor, got %d", w.Code)
+	}
+}
+
+func TestBookControllerSuggestBooks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := &mockBookServiceController{
+		suggestFunc: func(ctx context.Context, query string) (*domain.BookSuggestions, error) {
+			if query != "hary pot" {
+				t.Fatalf("unexpected query: %q", query)
+			}
+			return &domain.BookSuggestions{Titles: []domain.Suggestion{{ID: uuid.New(), Text: "Harry Potter"}}}, nil
+		},
+	}
+	ctl := NewBookController(svc, newTestAuth(t)).(*bookController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/books/suggest?q=hary+pot", nil)
+
+	ctl.suggestBooks(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var body domain.BookSuggestions
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || len(body.Titles) != 1 {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+
+	mw := httptest.NewRecorder()
+	mc, _ := gin.CreateTestContext(mw)
+	mc.Request = httptest.NewRequest(http.MethodGet, "/books/suggest", nil)
+
+	ctl.suggestBooks(mc)
+	if mw.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without q, got %d", mw.Code)
+	}
+}
+
 func TestBookControllerCreateValidation(t *testing.T) {
 	gin.SetMode(gin.TestMode)
-	ctl := NewBookController(&mockBookServiceController{}).(*bookController)
+	ctl := NewBookController(&mockBookServiceController{}, newTestAuth(t)).(*bookController)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -13,15 +13,16 @@ import (
 
 type cartController struct {
 	service domain.CartService
+	auth    *middleware.Auth
 }
 
-func NewCartController(service domain.CartService) domain.CartController {
-	return &cartController{service: service}
+func NewCartController(service domain.CartService, auth *middleware.Auth) domain.CartController {
+	return &cartController{service: service, auth: auth}
 }
 
 func (c *cartController) RegisterRoutes(r *gin.Engine) {
 	protected := r.Group("")
-	protected.Use(middleware.JWTAuthMiddleware())
+	protected.Use(c.auth.JWTAuthMiddleware())
 	{
 		protected.GET(routes.CartRoute, c.GetCart)
 		protected.POST(routes.CartItemsRoute, c.AddItem)
@@ -56,7 +56,7 @@ func (m *mockCartServiceController) Clear(ctx context.Context, userID uuid.UUID)
 
 func TestCartControllerGetCartUnauthorized(t *testing.T) {
 	gin.SetMode(gin.TestMode)
-	ctl := NewCartController(&mockCartServiceController{}).(*cartController)
+	ctl := NewCartController(&mockCartServiceController{}, newTestAuth(t)).(*cartController)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -86,7 +86,7 @@ func TestCartControllerAddItemAndRemoveItem(t *testing.T) {
 			return domain.CartView{TotalItems: 0}, nil
 		},
 	}
-	ctl := NewCartController(svc).(*cartController)
+	ctl := NewCartController(svc, newTestAuth(t)).(*cartController)
 
 	body, _ := json.Marshal(domain.CartItemInput{BookID: bookID, Count: 2})
 	aw := httptest.NewRecorder()
@@ -121,7 +121,7 @@ func TestCartControllerClearCart(t *testing.T) {
 		}
 		return nil
 	}}
-	ctl := NewCartController(svc).(*cartController)
+	ctl := NewCartController(svc, newTestAuth(t)).(*cartController)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
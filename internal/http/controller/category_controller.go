@@ -2,7 +2,6 @@ package controller
 
 import (
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -14,22 +13,24 @@ import (
 
 type categoryController struct {
 	service domain.CategoryService
+	auth    *middleware.Auth
 }
 
-func NewCategoryController(service domain.CategoryService) domain.CategoryController {
-	return &categoryController{service: service}
+func NewCategoryController(service domain.CategoryService, auth *middleware.Auth) domain.CategoryController {
+	return &categoryController{service: service, auth: auth}
 }
 
 func (c *categoryController) RegisterRoutes(r *gin.Engine) {
 	protected := r.Group("")
-	protected.Use(middleware.JWTAuthMiddleware())
+	protected.Use(c.auth.JWTAuthMiddleware())
 	{
 		protected.GET(routes.CategoriesRoute, c.List)
 		protected.GET(routes.CategoryByIDRoute, c.GetByID)
 	}
 
+	// Catalog integrations may use an API key instead of a JWT
 	admin := r.Group("")
-	admin.Use(middleware.JWTAuthMiddleware(), middleware.RequireAdmin())
+	admin.Use(c.auth.JWTOrAPIKeyAuthMiddleware(domain.ScopeBooksWrite), middleware.RequireAdmin())
 	{
 		admin.POST(routes.CategoriesRoute, c.Create)
 		admin.PUT(routes.CategoryByIDRoute, c.Update)
@@ -54,19 +55,15 @@ func (c *categoryController) Create(ctx *gin.Context) {
 }
 
 func (c *categoryController) List(ctx *gin.Context) {
-	limit := 20
-	offset := 0
-
-	if v := ctx.Query("limit"); v != "" {
-		limit, _ = strconv.Atoi(v)
-	}
-	if v := ctx.Query("offset"); v != "" {
-		offset, _ = strconv.Atoi(v)
+	q, err := parsePageOptions(ctx, 20)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	categories, err := c.service.List(ctx, limit, offset)
+	categories, err := c.service.List(ctx, q)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondListError(ctx, err)
 		return
 	}
 
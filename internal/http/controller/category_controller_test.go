@@ -17,7 +17,7 @@ import (
 
 type mockCategoryService struct {
 	findByIDFunc func(ctx context.Context, id uuid.UUID) (*domain.Category, error)
-	listFunc     func(ctx context.Context, limit, offset int) ([]domain.Category, error)
+	listFunc     func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Category], error)
 	createFunc   func(ctx context.Context, input domain.CategoryInput) (*domain.Category, error)
 	updateFunc   func(ctx context.Context, id uuid.UUID, input domain.CategoryInput) (*domain.Category, error)
 	deleteFunc   func(ctx context.Context, id uuid.UUID) error
@@ -29,11 +29,11 @@ func (m *mockCategoryService) FindByID(ctx context.Context, id uuid.UUID) (*doma
 	}
 	return nil, errors.New("not implemented")
 }
-func (m *mockCategoryService) List(ctx context.Context, limit, offset int) ([]domain.Category, error) {
+func (m *mockCategoryService) List(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Category], error) {
 	if m.listFunc != nil {
-		return m.listFunc(ctx, limit, offset)
+		return m.listFunc(ctx, q)
 	}
-	return []domain.Category{}, nil
+	return domain.Page[domain.Category]{Items: []domain.Category{}}, nil
 }
 func (m *mockCategoryService) Create(ctx context.Context, input domain.CategoryInput) (*domain.Category, error) {
 	if m.createFunc != nil {
@@ -68,7 +68,7 @@ func TestCategoryControllerCreateAndGetByID(t *testing.T) {
 			return &domain.Category{ID: id, Name: "Fiction"}, nil
 		},
 	}
-	ctl := NewCategoryController(svc).(*categoryController)
+	ctl := NewCategoryController(svc, newTestAuth(t)).(*categoryController)
 
 	body, _ := json.Marshal(domain.CategoryInput{Name: "Fiction"})
 	cw := httptest.NewRecorder()
@@ -92,13 +92,13 @@ func TestCategoryControllerCreateAndGetByID(t *testing.T) {
 
 func TestCategoryControllerListDefaults(t *testing.T) {
 	gin.SetMode(gin.TestMode)
-	svc := &mockCategoryService{listFunc: func(ctx context.Context, limit, offset int) ([]domain.Category, error) {
-		if limit != 20 || offset != 0 {
-			t.Fatalf("expected defaults 20/0, got %d/%d", limit, offset)
+	svc := &mockCategoryService{listFunc: func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Category], error) {
+		if q.Limit != 20 || q.Offset != 0 {
+			t.Fatalf("expected defaults 20/0, got %d/%d", q.Limit, q.Offset)
 		}
-		return []domain.Category{}, nil
+		return domain.Page[domain.Category]{Items: []domain.Category{}}, nil
 	}}
-	ctl := NewCategoryController(svc).(*categoryController)
+	ctl := NewCategoryController(svc, newTestAuth(t)).(*categoryController)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -112,7 +112,7 @@ func TestCategoryControllerListDefaults(t *testing.T) {
 
 func TestCategoryControllerUpdateBadID(t *testing.T) {
 	gin.SetMode(gin.TestMode)
-	ctl := NewCategoryController(&mockCategoryService{}).(*categoryController)
+	ctl := NewCategoryController(&mockCategoryService{}, newTestAuth(t)).(*categoryController)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
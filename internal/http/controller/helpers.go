@@ -1,7 +1,12 @@
 package controller
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -40,3 +45,84 @@ func getUserRole(ctx *gin.Context) (domain.UserRole, error) {
 		return "", errors.New("invalid user_role type")
 	}
 }
+
+// withClientInfo passes the caller's IP and user agent to services that
+// track failed attempts and record sessions
+func withClientInfo(ctx *gin.Context) context.Context {
+	return domain.WithUserAgent(domain.WithClientIP(ctx, ctx.ClientIP()), ctx.Request.UserAgent())
+}
+
+// respondTooManyAttempts writes a 429 with Retry-After when err is a lockout
+func respondTooManyAttempts(ctx *gin.Context, err error) bool {
+	var lockErr *domain.TooManyAttemptsError
+	if !errors.As(err, &lockErr) {
+		return false
+	}
+
+	seconds := int(math.Ceil(lockErr.RetryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	ctx.Header("Retry-After", strconv.Itoa(seconds))
+	ctx.JSON(http.StatusTooManyRequests, gin.H{"error": lockErr.Error()})
+	return true
+}
+
+// respondPasswordRejected writes a 400 when the password policy refused a new password
+func respondPasswordRejected(ctx *gin.Context, err error) bool {
+	if !errors.Is(err, domain.ErrWeakPassword) && !errors.Is(err, domain.ErrPasswordReused) {
+		return false
+	}
+
+	ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	return true
+}
+
+// respondAccountBlocked writes a 403 when the account may not log in
+func respondAccountBlocked(ctx *gin.Context, err error) bool {
+	if !errors.Is(err, domain.ErrAccountSuspended) && !errors.Is(err, domain.ErrPasswordResetRequired) {
+		return false
+	}
+
+	ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	return true
+}
+
+// maxPageSize caps the limit of every list endpoint
+const maxPageSize = 100
+
+// parsePageOptions reads the limit, offset and cursor query parameters
+func parsePageOptions(ctx *gin.Context, defaultLimit uint64) (domain.QueryOptions, error) {
+	q := domain.QueryOptions{Limit: defaultLimit, Cursor: ctx.Query("cursor")}
+
+	if v := ctx.Query("limit"); v != "" {
+		limit, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || limit == 0 || limit > maxPageSize {
+			return q, fmt.Errorf("invalid limit: must be between 1 and %d", maxPageSize)
+		}
+		q.Limit = limit
+	}
+
+	if v := ctx.Query("offset"); v != "" {
+		offset, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || offset < 0 {
+			return q, errors.New("invalid offset: must be a non-negative integer")
+		}
+		if q.Cursor != "" {
+			return q, errors.New("offset and cursor cannot be combined")
+		}
+		q.Offset = uint64(offset)
+	}
+
+	return q, nil
+}
+
+// respondListError writes a 400 for a bad cursor and a 500 otherwise
+func respondListError(ctx *gin.Context, err error) {
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParsePageOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		query  string
+		limit  uint64
+		offset uint64
+		err    string
+	}{
+		{query: "", limit: 20},
+		{query: "limit=100&offset=5", limit: 100, offset: 5},
+		{query: "cursor=abc", limit: 20},
+		{query: "limit=abc", err: "invalid limit: must be between 1 and 100"},
+		{query: "limit=0", err: "invalid limit: must be between 1 and 100"},
+		{query: "limit=101", err: "invalid limit: must be between 1 and 100"},
+		{query: "limit=18446744073709551615", err: "invalid limit: must be between 1 and 100"},
+		{query: "offset=-1", err: "invalid offset: must be a non-negative integer"},
+		{query: "offset=9223372036854775808", err: "invalid offset: must be a non-negative integer"},
+		{query: "offset=10&cursor=abc", err: "offset and cursor cannot be combined"},
+	}
+
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/authors?"+tt.query, nil)
+
+		q, err := parsePageOptions(c, 20)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Fatalf("%s: expected %q, got %v", tt.query, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", tt.query, err)
+		}
+		if q.Limit != tt.limit || q.Offset != tt.offset {
+			t.Fatalf("%s: expected %d/%d, got %d/%d", tt.query, tt.limit, tt.offset, q.Limit, q.Offset)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package controller
 
 import (
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 
@@ -13,23 +12,26 @@ import (
 
 type orderController struct {
 	service domain.OrderService
+	auth    *middleware.Auth
 }
 
-func NewOrderController(service domain.OrderService) domain.OrderController {
-	return &orderController{service: service}
+func NewOrderController(service domain.OrderService, auth *middleware.Auth) domain.OrderController {
+	return &orderController{service: service, auth: auth}
 }
 
 func (c *orderController) RegisterRoutes(r *gin.Engine) {
 	protected := r.Group("")
-	protected.Use(middleware.JWTAuthMiddleware())
+	protected.Use(c.auth.JWTAuthMiddleware())
 	{
-		protected.POST(routes.OrderCheckoutRoute, c.Checkout)
-		protected.POST(routes.OrderConfirmRoute, c.ConfirmPayment)
+		// Support staff acting as the customer may look but not buy
+		protected.POST(routes.OrderCheckoutRoute, middleware.DenyImpersonation(), c.auth.RequireVerified(domain.ActionCheckout), c.Checkout)
+		protected.POST(routes.OrderConfirmRoute, middleware.DenyImpersonation(), c.ConfirmPayment)
 		protected.GET(routes.OrdersRoute, c.ListMyOrders)
 	}
 
+	// Reporting integrations may use an API key instead of a JWT
 	admin := r.Group("")
-	admin.Use(middleware.JWTAuthMiddleware(), middleware.RequireAdmin())
+	admin.Use(c.auth.JWTOrAPIKeyAuthMiddleware(domain.ScopeOrdersRead), middleware.RequireAdmin())
 	{
 		admin.GET(routes.AdminOrdersRoute, c.ListAllOrders)
 	}
@@ -45,6 +47,7 @@ func (c *orderController) RegisterRoutes(r *gin.Engine) {
 // @Success      201  {object}  domain.OrderView
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]interface{}  "Email or mobile not verified"
 // @Security     BearerAuth
 // @Router       /orders/checkout [post]
 func (c *orderController) Checkout(ctx *gin.Context) {
@@ -108,9 +111,11 @@ func (c *orderController) ConfirmPayment(ctx *gin.Context) {
 // @Description  Lists orders for the authenticated user
 // @Tags         Orders
 // @Produce      json
-// @Param        limit   query  int  false  "Result limit"
-// @Param        offset  query  int  false  "Result offset"
-// @Success      200  {array}  domain.OrderView
+// @Param        limit   query  int     false  "Result limit (default 10, max 100)"
+// @Param        offset  query  int     false  "Result offset"
+// @Param        cursor  query  string  false  "next_cursor or prev_cursor of a page, instead of offset"
+// @Success      200  {object}  domain.Page[domain.OrderView]
+// @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Security     BearerAuth
@@ -122,18 +127,15 @@ func (c *orderController) ListMyOrders(ctx *gin.Context) {
 		return
 	}
 
-	limit := 10
-	offset := 0
-	if v := ctx.Query("limit"); v != "" {
-		limit, _ = strconv.Atoi(v)
-	}
-	if v := ctx.Query("offset"); v != "" {
-		offset, _ = strconv.Atoi(v)
+	q, err := parsePageOptions(ctx, 10)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	orders, err := c.service.ListUserOrders(ctx, userID, limit, offset)
+	orders, err := c.service.ListUserOrders(ctx, userID, q)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondListError(ctx, err)
 		return
 	}
 
@@ -145,25 +147,25 @@ func (c *orderController) ListMyOrders(ctx *gin.Context) {
 // @Description  Lists all orders (admin only)
 // @Tags         Orders
 // @Produce      json
-// @Param        limit   query  int  false  "Result limit"
-// @Param        offset  query  int  false  "Result offset"
-// @Success      200  {array}  domain.OrderView
+// @Param        limit   query  int     false  "Result limit (default 10, max 100)"
+// @Param        offset  query  int     false  "Result offset"
+// @Param        cursor  query  string  false  "next_cursor or prev_cursor of a page, instead of offset"
+// @Success      200  {object}  domain.Page[domain.OrderView]
+// @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Router       /admin/orders [get]
 func (c *orderController) ListAllOrders(ctx *gin.Context) {
-	limit := 10
-	offset := 0
-	if v := ctx.Query("limit"); v != "" {
-		limit, _ = strconv.Atoi(v)
-	}
-	if v := ctx.Query("offset"); v != "" {
-		offset, _ = strconv.Atoi(v)
+	q, err := parsePageOptions(ctx, 10)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	orders, err := c.service.ListAllOrders(ctx, limit, offset)
+	orders, err := c.service.ListAllOrders(ctx, q)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondListError(ctx, err)
 		return
 	}
 
@@ -18,8 +18,8 @@ import (
 type mockOrderServiceController struct {
 	checkoutFunc       func(ctx context.Context, userID uuid.UUID, input domain.CheckoutInput) (domain.OrderView, error)
 	confirmPaymentFunc func(ctx context.Context, userID uuid.UUID, input domain.PaymentConfirmInput) (domain.OrderView, error)
-	listUserOrdersFunc func(ctx context.Context, userID uuid.UUID, limit, offset int) ([]domain.OrderView, error)
-	listAllOrdersFunc  func(ctx context.Context, limit, offset int) ([]domain.OrderView, error)
+	listUserOrdersFunc func(ctx context.Context, userID uuid.UUID, q domain.QueryOptions) (domain.Page[domain.OrderView], error)
+	listAllOrdersFunc  func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.OrderView], error)
 }
 
 func (m *mockOrderServiceController) Checkout(ctx context.Context, userID uuid.UUID, input domain.CheckoutInput) (domain.OrderView, error) {
@@ -34,17 +34,17 @@ func (m *mockOrderServiceController) ConfirmPayment(ctx context.Context, userID
 	}
 	return domain.OrderView{}, errors.New("not implemented")
 }
-func (m *mockOrderServiceController) ListUserOrders(ctx context.Context, userID uuid.UUID, limit, offset int) ([]domain.OrderView, error) {
+func (m *mockOrderServiceController) ListUserOrders(ctx context.Context, userID uuid.UUID, q domain.QueryOptions) (domain.Page[domain.OrderView], error) {
 	if m.listUserOrdersFunc != nil {
-		return m.listUserOrdersFunc(ctx, userID, limit, offset)
+		return m.listUserOrdersFunc(ctx, userID, q)
 	}
-	return []domain.OrderView{}, nil
+	return domain.Page[domain.OrderView]{Items: []domain.OrderView{}}, nil
 }
-func (m *mockOrderServiceController) ListAllOrders(ctx context.Context, limit, offset int) ([]domain.OrderView, error) {
+func (m *mockOrderServiceController) ListAllOrders(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.OrderView], error) {
 	if m.listAllOrdersFunc != nil {
-		return m.listAllOrdersFunc(ctx, limit, offset)
+		return m.listAllOrdersFunc(ctx, q)
 	}
-	return []domain.OrderView{}, nil
+	return domain.Page[domain.OrderView]{Items: []domain.OrderView{}}, nil
 }
 
 func TestOrderControllerCheckoutAndConfirm(t *testing.T) {
@@ -70,7 +70,7 @@ func TestOrderControllerCheckoutAndConfirm(t *testing.T) {
 			return domain.OrderView{Order: domain.Order{ID: orderID}}, nil
 		},
 	}
-	ctl := NewOrderController(svc).(*orderController)
+	ctl := NewOrderController(svc, newTestAuth(t)).(*orderController)
 
 	checkoutBody, _ := json.Marshal(domain.CheckoutInput{PaymentMethod: domain.PaymentCOD})
 	cw := httptest.NewRecorder()
@@ -103,20 +103,20 @@ func TestOrderControllerListEndpoints(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	userID := uuid.New()
 	svc := &mockOrderServiceController{
-		listUserOrdersFunc: func(ctx context.Context, gotUserID uuid.UUID, limit, offset int) ([]domain.OrderView, error) {
-			if gotUserID != userID || limit != 3 || offset != 1 {
+		listUserOrdersFunc: func(ctx context.Context, gotUserID uuid.UUID, q domain.QueryOptions) (domain.Page[domain.OrderView], error) {
+			if gotUserID != userID || q.Limit != 3 || q.Offset != 1 {
 				t.Fatalf("unexpected user list params")
 			}
-			return []domain.OrderView{}, nil
+			return domain.Page[domain.OrderView]{Items: []domain.OrderView{}}, nil
 		},
-		listAllOrdersFunc: func(ctx context.Context, limit, offset int) ([]domain.OrderView, error) {
-			if limit != 4 || offset != 2 {
+		listAllOrdersFunc: func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.OrderView], error) {
+			if q.Limit != 4 || q.Offset != 2 {
 				t.Fatalf("unexpected admin list params")
 			}
-			return []domain.OrderView{}, nil
+			return domain.Page[domain.OrderView]{Items: []domain.OrderView{}}, nil
 		},
 	}
-	ctl := NewOrderController(svc).(*orderController)
+	ctl := NewOrderController(svc, newTestAuth(t)).(*orderController)
 
 	uw := httptest.NewRecorder()
 	uc, _ := gin.CreateTestContext(uw)
@@ -134,11 +134,20 @@ func TestOrderControllerListEndpoints(t *testing.T) {
 	if aw.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", aw.Code)
 	}
+
+	// A limit that is not a page size is refused rather than read as zero
+	bw := httptest.NewRecorder()
+	bc, _ := gin.CreateTestContext(bw)
+	bc.Request = httptest.NewRequest(http.MethodGet, "/admin/orders?limit=18446744073709551615", nil)
+	ctl.ListAllOrders(bc)
+	if bw.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", bw.Code)
+	}
 }
 
 func TestOrderControllerUnauthorized(t *testing.T) {
 	gin.SetMode(gin.TestMode)
-	ctl := NewOrderController(&mockOrderServiceController{}).(*orderController)
+	ctl := NewOrderController(&mockOrderServiceController{}, newTestAuth(t)).(*orderController)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
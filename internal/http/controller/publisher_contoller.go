@@ -2,7 +2,6 @@ package controller
 
 import (
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -14,17 +13,18 @@ import (
 
 type publisherController struct {
 	service domain.PublisherService
+	auth    *middleware.Auth
 }
 
 // NewPublisherController creates a new publisher controller instance
-func NewPublisherController(service domain.PublisherService) domain.PublisherController {
-	return &publisherController{service: service}
+func NewPublisherController(service domain.PublisherService, auth *middleware.Auth) domain.PublisherController {
+	return &publisherController{service: service, auth: auth}
 }
 
 // RegisterRoutes registers all publisher routes
 func (c *publisherController) RegisterRoutes(r *gin.Engine) {
 	protected := r.Group("")
-	protected.Use(middleware.JWTAuthMiddleware())
+	protected.Use(c.auth.JWTAuthMiddleware())
 	{
 		protected.GET(routes.PublisherRoute, c.List)
 		protected.POST(routes.PublisherRoute, c.Create)
@@ -36,19 +36,15 @@ func (c *publisherController) RegisterRoutes(r *gin.Engine) {
 }
 
 func (c *publisherController) List(ctx *gin.Context) {
-	limit := 20
-	offset := 0
-
-	if v := ctx.Query("limit"); v != "" {
-		limit, _ = strconv.Atoi(v)
-	}
-	if v := ctx.Query("offset"); v != "" {
-		offset, _ = strconv.Atoi(v)
+	q, err := parsePageOptions(ctx, 20)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	publishers, err := c.service.List(ctx, limit, offset)
+	publishers, err := c.service.List(ctx, q)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondListError(ctx, err)
 		return
 	}
 
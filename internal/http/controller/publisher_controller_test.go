@@ -15,7 +15,7 @@ import (
 )
 
 type MockPublisherService struct {
-	ListFunc      func(ctx context.Context, limit, offset int) ([]domain.Publisher, error)
+	ListFunc      func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Publisher], error)
 	CreateFunc    func(ctx context.Context, in domain.PublisherInput) (*domain.Publisher, error)
 	UpdateFunc    func(ctx context.Context, id uuid.UUID, in domain.PublisherInput) (*domain.Publisher, error)
 	FindFunc      func(ctx context.Context, id uuid.UUID) (*domain.Publisher, error)
@@ -23,11 +23,11 @@ type MockPublisherService struct {
 	DeleteFunc    func(ctx context.Context, id uuid.UUID) error
 }
 
-func (m *MockPublisherService) List(ctx context.Context, limit, offset int) ([]domain.Publisher, error) {
+func (m *MockPublisherService) List(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Publisher], error) {
 	if m.ListFunc != nil {
-		return m.ListFunc(ctx, limit, offset)
+		return m.ListFunc(ctx, q)
 	}
-	return []domain.Publisher{}, nil
+	return domain.Page[domain.Publisher]{Items: []domain.Publisher{}}, nil
 }
 
 func (m *MockPublisherService) Create(ctx context.Context, in domain.PublisherInput) (*domain.Publisher, error) {
@@ -62,7 +62,7 @@ func TestCreatePublisher_Success(t *testing.T) {
 		},
 	}
 
-	controller := NewPublisherController(mockService)
+	controller := NewPublisherController(mockService, newTestAuth(t))
 	router := gin.New()
 	controller.RegisterRoutes(router)
 
@@ -107,7 +107,7 @@ func TestGetPublisher_Success(t *testing.T) {
 		},
 	}
 
-	controller := NewPublisherController(mockService)
+	controller := NewPublisherController(mockService, newTestAuth(t))
 	router := gin.New()
 	controller.RegisterRoutes(router)
 
@@ -137,7 +137,7 @@ func TestUpdatePublisher_Success(t *testing.T) {
 		},
 	}
 
-	controller := NewPublisherController(mockService)
+	controller := NewPublisherController(mockService, newTestAuth(t))
 	router := gin.New()
 	controller.RegisterRoutes(router)
 
@@ -170,7 +170,7 @@ func TestSetPublisherActive_Success(t *testing.T) {
 		},
 	}
 
-	controller := NewPublisherController(mockService)
+	controller := NewPublisherController(mockService, newTestAuth(t))
 	router := gin.New()
 	controller.RegisterRoutes(router)
 
@@ -201,7 +201,7 @@ func TestDeletePublisher_Success(t *testing.T) {
 		},
 	}
 
-	controller := NewPublisherController(mockService)
+	controller := NewPublisherController(mockService, newTestAuth(t))
 	router := gin.New()
 	controller.RegisterRoutes(router)
 
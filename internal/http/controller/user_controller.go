@@ -3,6 +3,8 @@ package controller
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -14,11 +16,12 @@ import (
 
 type userController struct {
 	service domain.UserService
+	auth    *middleware.Auth
 }
 
 // NewUserController creates a new user controller instance
-func NewUserController(service domain.UserService) domain.UserController {
-	return &userController{service: service}
+func NewUserController(service domain.UserService, auth *middleware.Auth) domain.UserController {
+	return &userController{service: service, auth: auth}
 }
 
 // RegisterRoutes registers all user routes
@@ -27,162 +30,1382 @@ func (c *userController) RegisterRoutes(r *gin.Engine) {
 	{
 		auth.POST(routes.RegisterRoute, c.Register)
 		auth.POST(routes.LoginRoute, c.Login)
+		auth.POST(routes.LoginOTPRoute, c.RequestLoginOTP)
+		auth.POST(routes.LoginOTPVerifyRoute, c.LoginWithOTP)
+		auth.POST(routes.MagicLinkRoute, c.RequestMagicLink)
+		auth.POST(routes.MagicLinkVerifyRoute, c.LoginWithMagicLink)
 		auth.POST(routes.ForgotPassword, c.ForgotPassword)
 		auth.POST(routes.ResetPasswordByToken, c.ResetPasswordWithToken)
+		auth.POST(routes.RefreshTokenRoute, c.RefreshToken)
+		auth.POST(routes.LogoutRoute, c.Logout)
+		auth.POST(routes.MFALoginRoute, c.LoginWithMFA)
+		auth.GET(routes.OIDCLoginRoute, c.StartOIDCLogin)
+		auth.GET(routes.OIDCCallbackRoute, c.CompleteOIDCLogin)
+		auth.POST(routes.AcceptInvitationRoute, c.AcceptInvitation)
+	}
+
+	// Enrollment also accepts the mfa_pending token, since admins
+	// cannot obtain a full token before enrolling an authenticator
+	enrollment := r.Group("")
+	enrollment.Use(c.auth.MFAPendingAuthMiddleware(), middleware.DenyImpersonation())
+	{
+		enrollment.POST(routes.MFASetupRoute, c.SetupMFA)
+		enrollment.POST(routes.MFAEnableRoute, c.EnableMFA)
 	}
 
 	protected := r.Group("")
-	protected.Use(middleware.JWTAuthMiddleware())
+	protected.Use(c.auth.JWTAuthMiddleware())
 	{
-		protected.GET(routes.UserRoute, c.GetUser)
-		protected.DELETE(routes.UserRoute, c.DeleteUser)
-		protected.POST(routes.VerifyEmailRoute, c.VerifyEmail)
-		protected.POST(routes.VerifyMobileRoute, c.VerifyMobile)
+		protected.GET(routes.MeRoute, c.GetProfile)
+		protected.GET(routes.MeExportRoute, c.ExportData)
+
+		// Users reach only their own ID, admins any
+		protected.GET(routes.UserRoute, middleware.RequireSelfOrAdmin("id"), c.GetUser)
 		protected.POST(routes.ResendEmailRoute, c.ResendEmailVerification)
 		protected.POST(routes.ResendMobileOTPRoute, c.ResendMobileOTP)
-		protected.POST(routes.ResetPasswordRoute, c.ResetPassword)
+		protected.GET(routes.MeSessionsRoute, c.ListSessions)
+	}
+
+	// Account and credential changes are left to the customer, so
+	// support staff acting as them cannot make them
+	owner := r.Group("")
+	owner.Use(c.auth.JWTAuthMiddleware(), middleware.DenyImpersonation())
+	{
+		owner.PATCH(routes.MeRoute, c.UpdateProfile)
+		owner.DELETE(routes.MeRoute, c.DeleteProfile)
+		owner.POST(routes.MeDeletionCancelRoute, c.CancelDeletion)
+		owner.DELETE(routes.UserRoute, middleware.RequireSelfOrAdmin("id"), c.DeleteUser)
+		owner.POST(routes.VerifyEmailRoute, c.VerifyEmail)
+		owner.POST(routes.VerifyMobileRoute, c.VerifyMobile)
+		owner.POST(routes.ResetPasswordRoute, c.ResetPassword)
+		owner.POST(routes.LogoutAllRoute, c.LogoutAll)
+		owner.DELETE(routes.MeSessionRoute, c.RevokeSession)
+		owner.POST(routes.MFADisableRoute, c.DisableMFA)
+		owner.POST(routes.MFARecoveryCodesRoute, c.RegenerateRecoveryCodes)
+	}
+
+	admin := r.Group("")
+	admin.Use(c.auth.JWTAuthMiddleware(), middleware.RequireAdmin())
+	{
+		admin.GET(routes.AdminUsersRoute, c.SearchUsers)
+		admin.POST(routes.AdminUserUnlockRoute, c.UnlockUser)
+		admin.PUT(routes.AdminUserRoleRoute, c.ChangeUserRole)
+		admin.POST(routes.AdminUserSuspendRoute, c.SuspendUser)
+		admin.POST(routes.AdminUserReactivateRoute, c.ReactivateUser)
+		admin.POST(routes.AdminUserForcePasswordResetRoute, c.ForcePasswordReset)
+		admin.POST(routes.AdminUserImpersonateRoute, c.ImpersonateUser)
+		admin.POST(routes.AdminInvitationsRoute, c.InviteUser)
+		admin.POST(routes.AdminAPIKeysRoute, c.CreateAPIKey)
+		admin.GET(routes.AdminAPIKeysRoute, c.ListAPIKeys)
+		admin.DELETE(routes.AdminAPIKeyRoute, c.RevokeAPIKey)
+	}
+}
+
+// Register godoc
+// @Summary      Register a new user
+// @Description  Creates a new user account with the USER role and sends email & mobile verification
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  domain.UserInput  true  "User registration input"
+// @Success      201  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /auth/register [post]
+func (c *userController) Register(ctx *gin.Context) {
+
+	var input domain.UserInput
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.service.Register(ctx, input); err != nil {
+		if respondPasswordRejected(ctx, err) {
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "User registered successfully. Please verify your email and mobile.",
+	})
+}
+
+// Login godoc
+// @Summary      Login user
+// @Description  Login using email or mobile and password. Returns a short-lived access token and a refresh token, or an mfa_token when a second factor is required
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  domain.LoginInput  true  "Login credentials"
+// @Success      200  {object}  domain.AuthTokens
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Router       /auth/login [post]
+func (c *userController) Login(ctx *gin.Context) {
+	var input domain.LoginInput
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validate that either email or mobile is provided
+	if input.Email == "" && input.Mobile == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "email or mobile is required"})
+		return
+	}
+
+	tokens, err := c.service.Login(withClientInfo(ctx), input)
+	if respondTooManyAttempts(ctx, err) {
+		return
+	}
+	if respondAccountBlocked(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		return
+	}
+
+	// The password was correct but a second factor is still needed
+	if tokens.MFARequired {
+		ctx.JSON(http.StatusOK, gin.H{
+			"mfa_required":            true,
+			"mfa_enrollment_required": tokens.MFAEnrollmentRequired,
+			"mfa_token":               tokens.MFAToken,
+			"expires_in":              tokens.ExpiresIn,
+			"message":                 "Two-factor authentication required",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"token_type":    tokens.TokenType,
+		"expires_in":    tokens.ExpiresIn,
+		"message":       "Login successful",
+	})
+}
+
+// RequestLoginOTP godoc
+// @Summary      Request login code
+// @Description  Sends a one-time login code to the registered email or mobile
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  domain.LoginOTPRequestInput  true  "Email or mobile"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /login/otp [post]
+func (c *userController) RequestLoginOTP(ctx *gin.Context) {
+	var input domain.LoginOTPRequestInput
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Email == "" && input.Mobile == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "email or mobile is required"})
+		return
+	}
+
+	err := c.service.RequestLoginOTP(withClientInfo(ctx), input)
+	if respondTooManyAttempts(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "If the account exists, a login code has been sent",
+	})
+}
+
+// LoginWithOTP godoc
+// @Summary      Login with code
+// @Description  Exchanges a one-time login code for an access token and a refresh token
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  domain.LoginOTPVerifyInput  true  "Email or mobile and code"
+// @Success      200  {object}  domain.AuthTokens
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Router       /login/otp/verify [post]
+func (c *userController) LoginWithOTP(ctx *gin.Context) {
+	var input domain.LoginOTPVerifyInput
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Email == "" && input.Mobile == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "email or mobile is required"})
+		return
+	}
+
+	tokens, err := c.service.LoginWithOTP(withClientInfo(ctx), input)
+	if respondTooManyAttempts(ctx, err) {
+		return
+	}
+	if respondAccountBlocked(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired code"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// RequestMagicLink godoc
+// @Summary      Request login link
+// @Description  Emails a single-use login link valid for 15 minutes. With bind_device the response carries a device_code that must be sent along with the link.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  domain.MagicLinkRequestInput  true  "Email and device binding"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /login/magic-link [post]
+func (c *userController) RequestMagicLink(ctx *gin.Context) {
+	var input domain.MagicLinkRequestInput
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deviceCode, err := c.service.RequestMagicLink(withClientInfo(ctx), input)
+	if respondTooManyAttempts(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"message": "If the account exists, a login link has been sent",
+	}
+	if deviceCode != "" {
+		response["device_code"] = deviceCode
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// LoginWithMagicLink godoc
+// @Summary      Login with link
+// @Description  Exchanges the token of a login link, and the device code when the link is bound, for an access token and a refresh token
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  domain.MagicLinkLoginInput  true  "Link token and device code"
+// @Success      200  {object}  domain.AuthTokens
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Router       /login/magic-link/verify [post]
+func (c *userController) LoginWithMagicLink(ctx *gin.Context) {
+	var input domain.MagicLinkLoginInput
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := c.service.LoginWithMagicLink(withClientInfo(ctx), input)
+	if respondTooManyAttempts(ctx, err) {
+		return
+	}
+	if respondAccountBlocked(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired link"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// LoginWithMFA godoc
+// @Summary      Complete two-factor login
+// @Description  Exchanges the mfa_token from login and a TOTP or recovery code for an access token and a refresh token
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  domain.MFALoginInput  true  "MFA token and code"
+// @Success      200  {object}  domain.AuthTokens
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Router       /login/2fa [post]
+func (c *userController) LoginWithMFA(ctx *gin.Context) {
+	var input domain.MFALoginInput
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := c.service.LoginWithMFA(withClientInfo(ctx), input)
+	if respondTooManyAttempts(ctx, err) {
+		return
+	}
+	if respondAccountBlocked(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// StartOIDCLogin godoc
+// @Summary      Sign in with an identity provider
+// @Description  Redirects to the provider's login page using the authorization code flow with PKCE
+// @Tags         Auth
+// @Param        provider  path  string  true  "Provider name, e.g. google"
+// @Success      302
+// @Failure      404  {object}  map[string]string
+// @Failure      502  {object}  map[string]string
+// @Router       /auth/oidc/{provider} [get]
+func (c *userController) StartOIDCLogin(ctx *gin.Context) {
+	authURL, err := c.service.StartOIDCLogin(ctx, ctx.Param("provider"))
+	if errors.Is(err, domain.ErrUnknownOIDCProvider) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "login provider is unavailable"})
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// CompleteOIDCLogin godoc
+// @Summary      Identity provider callback
+// @Description  Links the provider account to the user with the same verified email and returns BookNest tokens
+// @Tags         Auth
+// @Produce      json
+// @Param        provider  path   string  true   "Provider name, e.g. google"
+// @Param        code      query  string  false  "Authorization code"
+// @Param        state     query  string  true   "State from the authorization request"
+// @Success      200  {object}  domain.AuthTokens
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /auth/oidc/{provider}/callback [get]
+func (c *userController) CompleteOIDCLogin(ctx *gin.Context) {
+	var input domain.OIDCCallbackInput
+
+	if err := ctx.ShouldBindQuery(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The user cancelled or the provider refused the request
+	if input.Error != "" || input.Code == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "login was not completed", "reason": input.Error})
+		return
+	}
+
+	tokens, err := c.service.CompleteOIDCLogin(withClientInfo(ctx), ctx.Param("provider"), input.Code, input.State)
+	if errors.Is(err, domain.ErrUnknownOIDCProvider) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if respondAccountBlocked(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// SetupMFA godoc
+// @Summary      Start two-factor enrollment
+// @Description  Generates a TOTP secret and the otpauth:// provisioning URI to render as a QR code
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  domain.MFASetup
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /2fa/setup [post]
+func (c *userController) SetupMFA(ctx *gin.Context) {
+	userIDFromCtx, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	setup, err := c.service.SetupMFA(ctx, userIDFromCtx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, setup)
+}
+
+// EnableMFA godoc
+// @Summary      Confirm two-factor enrollment
+// @Description  Enables two-factor authentication with a code from the authenticator and returns the recovery codes once
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  domain.MFACodeInput  true  "TOTP code"
+// @Success      200  {object}  map[string][]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /2fa/enable [post]
+func (c *userController) EnableMFA(ctx *gin.Context) {
+	userIDFromCtx, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var input domain.MFACodeInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := c.service.EnableMFA(ctx, userIDFromCtx, input.Code)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"recovery_codes": codes,
+		"message":        "Two-factor authentication enabled. Store the recovery codes safely",
+	})
+}
+
+// DisableMFA godoc
+// @Summary      Disable two-factor authentication
+// @Description  Removes the authenticator and recovery codes. Not allowed for admins
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  domain.MFACodeInput  true  "TOTP or recovery code"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /2fa/disable [post]
+func (c *userController) DisableMFA(ctx *gin.Context) {
+	userIDFromCtx, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var input domain.MFACodeInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.service.DisableMFA(ctx, userIDFromCtx, input.Code); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary      Regenerate recovery codes
+// @Description  Replaces every recovery code with a new set
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  domain.MFACodeInput  true  "TOTP or recovery code"
+// @Success      200  {object}  map[string][]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /2fa/recovery-codes [post]
+func (c *userController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	userIDFromCtx, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var input domain.MFACodeInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := c.service.RegenerateRecoveryCodes(ctx, userIDFromCtx, input.Code)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"recovery_codes": codes,
+	})
+}
+
+// RefreshToken godoc
+// @Summary      Refresh access token
+// @Description  Exchanges a refresh token for a new access token and a rotated refresh token
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  domain.RefreshTokenInput  true  "Refresh token"
+// @Success      200  {object}  domain.AuthTokens
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Router       /token/refresh [post]
+func (c *userController) RefreshToken(ctx *gin.Context) {
+	var input domain.RefreshTokenInput
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := c.service.RefreshToken(ctx, input.RefreshToken)
+	if respondAccountBlocked(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// Logout godoc
+// @Summary      Logout
+// @Description  Revokes the refresh token and every token rotated from the same login
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  domain.RefreshTokenInput  true  "Refresh token"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /logout [post]
+func (c *userController) Logout(ctx *gin.Context) {
+	var input domain.RefreshTokenInput
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.service.Logout(ctx, input.RefreshToken); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Logged out successfully",
+	})
+}
+
+// LogoutAll godoc
+// @Summary      Logout from all devices
+// @Description  Revokes every refresh token of the authenticated user
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /logout-all [post]
+func (c *userController) LogoutAll(ctx *gin.Context) {
+	userIDFromCtx, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := c.service.LogoutAll(ctx, userIDFromCtx); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Logged out from all devices",
+	})
+}
+
+// ListSessions godoc
+// @Summary      List active sessions
+// @Description  Returns the devices the authenticated user is logged in on, most recently seen first
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {array}   domain.Session
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/sessions [get]
+func (c *userController) ListSessions(ctx *gin.Context) {
+	userIDFromCtx, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	// Tokens from before sessions were recorded have no current session
+	currentID, _ := uuid.Parse(ctx.GetString("session_id"))
+
+	sessions, err := c.service.ListSessions(ctx, userIDFromCtx, currentID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession godoc
+// @Summary      Revoke a session
+// @Description  Logs one device out; its access and refresh tokens stop working immediately
+// @Tags         Auth
+// @Produce      json
+// @Param        id   path      string  true  "Session ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/sessions/{id} [delete]
+func (c *userController) RevokeSession(ctx *gin.Context) {
+	userIDFromCtx, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	sessionID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+
+	err = c.service.RevokeSession(ctx, userIDFromCtx, sessionID)
+	if errors.Is(err, domain.ErrSessionNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked",
+	})
+}
+
+// GetProfile godoc
+// @Summary      Get own profile
+// @Description  Returns the logged in user, including email or mobile changes awaiting verification
+// @Tags         Users
+// @Produce      json
+// @Success      200  {object}  domain.User
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me [get]
+func (c *userController) GetProfile(ctx *gin.Context) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	user, err := c.service.FindUser(ctx, userID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
+
+// UpdateProfile godoc
+// @Summary      Update own profile
+// @Description  Updates the names right away. A new email or mobile is kept as pending_email/pending_mobile and a verification code is sent to it; the address is only replaced once verified. Sending the current address again cancels the change
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  domain.ProfileInput  true  "Fields to change"
+// @Success      200  {object}  domain.User
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]interface{}  "Mobile must be verified before changing the email"
+// @Security     BearerAuth
+// @Router       /me [patch]
+func (c *userController) UpdateProfile(ctx *gin.Context) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var input domain.ProfileInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := c.service.UpdateProfile(ctx, userID, input)
+	if middleware.RespondVerificationRequired(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
+
+// GetUser godoc
+// @Summary      Get user by ID
+// @Description  Fetch user details by user ID. Users can read only their own account, admins any account
+// @Tags         Users
+// @Produce      json
+// @Param        id   path  string  true  "User ID"
+// @Success      200  {object}  domain.User
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /users/{id} [get]
+func (c *userController) GetUser(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	user, err := c.service.FindUser(ctx, id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
+
+// DeleteProfile godoc
+// @Summary      Delete own account
+// @Description  Logs the user out everywhere and schedules the deletion. After a 30 day grace period the personal data is anonymised; orders are kept. Export the data first with GET /me/export
+// @Tags         Users
+// @Produce      json
+// @Success      202  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me [delete]
+func (c *userController) DeleteProfile(ctx *gin.Context) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	c.scheduleDeletion(ctx, userID, userID)
+}
+
+// CancelDeletion godoc
+// @Summary      Cancel account deletion
+// @Description  Keeps the account when its deletion is still in the grace period
+// @Tags         Users
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/deletion/cancel [post]
+func (c *userController) CancelDeletion(ctx *gin.Context) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := c.service.CancelDeletion(ctx, userID); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Account deletion cancelled",
+	})
+}
+
+// ExportData godoc
+// @Summary      Export own data
+// @Description  Downloads the profile, orders and cart of the logged in user as JSON
+// @Tags         Users
+// @Produce      json
+// @Success      200  {object}  domain.UserExport
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/export [get]
+func (c *userController) ExportData(ctx *gin.Context) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	export, err := c.service.ExportData(ctx, userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Browsers save the response instead of showing it
+	ctx.Header("Content-Disposition", `attachment; filename="booknest-export.json"`)
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, export)
+}
+
+// DeleteUser godoc
+// @Summary      Delete user account
+// @Description  Schedules the deletion like DELETE /me. Users can delete only their own account, admins any account
+// @Tags         Users
+// @Produce      json
+// @Param        id   path  string  true  "User ID"
+// @Success      202  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /users/{id} [delete]
+func (c *userController) DeleteUser(ctx *gin.Context) {
+	actorID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	c.scheduleDeletion(ctx, actorID, id)
+}
+
+func (c *userController) scheduleDeletion(ctx *gin.Context, actorID, userID uuid.UUID) {
+	at, err := c.service.ScheduleDeletion(ctx, actorID, userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"message":               "Account deletion scheduled",
+		"deletion_scheduled_at": at,
+	})
+}
+
+// UnlockUser godoc
+// @Summary      Unlock user account
+// @Description  Clears failed login attempts and lifts a lockout for the user (admin only)
+// @Tags         Admin
+// @Produce      json
+// @Param        id   path  string  true  "User ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/unlock [post]
+func (c *userController) UnlockUser(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := c.service.UnlockUser(ctx, id); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "User unlocked successfully",
+	})
+}
+
+// SearchUsers godoc
+// @Summary      Search users
+// @Description  Lists users matching the filters, newest first (admin only). Text filters match partially
+// @Tags         Admin
+// @Produce      json
+// @Param        email            query  string  false  "Email contains"
+// @Param        mobile           query  string  false  "Mobile contains"
+// @Param        name             query  string  false  "First or last name contains"
+// @Param        role             query  string  false  "Role (USER or ADMIN)"
+// @Param        active           query  bool    false  "Active or suspended"
+// @Param        email_verified   query  bool    false  "Email verified"
+// @Param        mobile_verified  query  bool    false  "Mobile verified"
+// @Param        created_from     query  string  false  "Created at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param        created_to       query  string  false  "Created before (RFC 3339 or YYYY-MM-DD)"
+// @Param        limit            query  int     false  "Result limit (default 20, max 100)"
+// @Param        offset           query  int     false  "Result offset"
+// @Param        cursor           query  string  false  "next_cursor or prev_cursor of a page, instead of offset"
+// @Success      200  {object}  domain.UserSearchResult
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/users [get]
+func (c *userController) SearchUsers(ctx *gin.Context) {
+	filter, err := parseUserFilter(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pagination := domain.QueryOptions{Cursor: ctx.Query("cursor")}
+	if v := ctx.Query("limit"); v != "" {
+		if pagination.Limit, err = strconv.ParseUint(v, 10, 64); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+	if v := ctx.Query("offset"); v != "" {
+		if pagination.Offset, err = strconv.ParseUint(v, 10, 64); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+			return
+		}
+	}
+
+	result, err := c.service.SearchUsers(ctx, filter, pagination)
+	if err != nil {
+		respondListError(ctx, err)
+		return
 	}
+
+	ctx.JSON(http.StatusOK, result)
 }
 
-// Register godoc
-// @Summary      Register a new user
-// @Description  Creates a new user account and sends email & mobile verification
-// @Tags         Auth
+// SuspendUser godoc
+// @Summary      Suspend user
+// @Description  Blocks logins of another user and revokes their sessions (admin only). The reason is kept in the audit log
+// @Tags         Admin
 // @Accept       json
 // @Produce      json
-// @Param        payload  body  domain.UserInput  true  "User registration input"
-// @Success      201  {object}  map[string]string
+// @Param        id       path  string               true   "User ID"
+// @Param        payload  body  domain.SuspendInput  false  "Reason"
+// @Success      200  {object}  map[string]string
 // @Failure      400  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
-// @Router       /auth/register [post]
-func (c *userController) Register(ctx *gin.Context) {
-
-	var input domain.UserInput
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/suspend [post]
+func (c *userController) SuspendUser(ctx *gin.Context) {
+	adminID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
 
-	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
 		return
 	}
 
-	if err := c.service.Register(ctx, input); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// The reason is optional, so an empty body is fine
+	var input domain.SuspendInput
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := c.service.SuspendUser(ctx, adminID, id, input.Reason); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{
-		"message": "User registered successfully. Please verify your email and mobile.",
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "User suspended successfully",
 	})
 }
 
-// Login godoc
-// @Summary      Login user
-// @Description  Login using email or mobile and password
-// @Tags         Auth
+// ImpersonateUser godoc
+// @Summary      Impersonate user
+// @Description  Issues a 10 minute access token acting as a customer, so support staff see their cart and orders (admin only). The token has no refresh token, cannot check out, change or delete the account, and every request made with it is recorded in the audit log together with the reason
+// @Tags         Admin
 // @Accept       json
 // @Produce      json
-// @Param        payload  body  domain.LoginInput  true  "Login credentials"
-// @Success      200  {object}  map[string]string
+// @Param        id       path  string                     true  "User ID"
+// @Param        payload  body  domain.ImpersonationInput  true  "Reason"
+// @Success      200  {object}  domain.ImpersonationToken
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
-// @Router       /auth/login [post]
-func (c *userController) Login(ctx *gin.Context) {
-	var input domain.LoginInput
+// @Failure      403  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/impersonate [post]
+func (c *userController) ImpersonateUser(ctx *gin.Context) {
+	adminID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
 
+	var input domain.ImpersonationInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Validate that either email or mobile is provided
-	if input.Email == "" && input.Mobile == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "email or mobile is required"})
+	token, err := c.service.Impersonate(ctx, adminID, id, input.Reason)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, token)
+}
+
+// ReactivateUser godoc
+// @Summary      Reactivate user
+// @Description  Lets a suspended user log in again (admin only)
+// @Tags         Admin
+// @Produce      json
+// @Param        id  path  string  true  "User ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/reactivate [post]
+func (c *userController) ReactivateUser(ctx *gin.Context) {
+	adminID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	token, err := c.service.Login(ctx, input)
+	id, err := uuid.Parse(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := c.service.ReactivateUser(ctx, adminID, id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"token":   token,
-		"message": "Login successful",
+		"message": "User reactivated successfully",
 	})
 }
 
-// GetUser godoc
-// @Summary      Get user by ID
-// @Description  Fetch user details by user ID
-// @Tags         Users
+// ForcePasswordReset godoc
+// @Summary      Force password reset
+// @Description  Revokes the user's sessions and emails a reset token. The user cannot log in until the password is changed (admin only)
+// @Tags         Admin
 // @Produce      json
-// @Param        id   path  string  true  "User ID"
-// @Success      200  {object}  domain.User
+// @Param        id  path  string  true  "User ID"
+// @Success      200  {object}  map[string]string
 // @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Security     BearerAuth
-// @Router       /users/{id} [get]
-func (c *userController) GetUser(ctx *gin.Context) {
+// @Router       /admin/users/{id}/force-password-reset [post]
+func (c *userController) ForcePasswordReset(ctx *gin.Context) {
+	adminID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
 	id, err := uuid.Parse(ctx.Param("id"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
 		return
 	}
 
-	user, err := c.service.FindUser(ctx, id)
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	if err := c.service.ForcePasswordReset(ctx, adminID, id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, user)
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Password reset required, a reset token has been sent",
+	})
 }
 
-// DeleteUser godoc
-// @Summary      Delete user account
-// @Description  User can delete only their own account
-// @Tags         Users
+// ChangeUserRole godoc
+// @Summary      Change user role
+// @Description  Changes the role of another user and records it in the audit log (admin only). The user's sessions are revoked
+// @Tags         Admin
+// @Accept       json
 // @Produce      json
-// @Param        id   path  string  true  "User ID"
+// @Param        id       path  string            true  "User ID"
+// @Param        payload  body  domain.RoleInput  true  "New role"
 // @Success      200  {object}  map[string]string
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
 // @Failure      403  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
 // @Security     BearerAuth
-// @Router       /users/{id} [delete]
-func (c *userController) DeleteUser(ctx *gin.Context) {
+// @Router       /admin/users/{id}/role [put]
+func (c *userController) ChangeUserRole(ctx *gin.Context) {
+	adminID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
 	id, err := uuid.Parse(ctx.Param("id"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
 		return
 	}
 
-	// Verify that the user can only delete their own account
-	userIDFromCtx, err := getUserID(ctx)
+	var input domain.RoleInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.service.ChangeUserRole(ctx, adminID, id, input.Role); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "User role updated successfully",
+	})
+}
+
+// InviteUser godoc
+// @Summary      Invite user
+// @Description  Emails an invitation to create an account with the given role (admin only). Invitations expire after 72 hours
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  domain.InvitationInput  true  "Invitation input"
+// @Success      201  {object}  domain.Invitation
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/invitations [post]
+func (c *userController) InviteUser(ctx *gin.Context) {
+	adminID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var input domain.InvitationInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitation, err := c.service.InviteUser(ctx, adminID, input)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, invitation)
+}
+
+// CreateAPIKey godoc
+// @Summary      Create API key
+// @Description  Creates a key for integrations, sent in the X-API-Key header (admin only). Requests made with it act as the creating admin, limited to its scopes. The key is only returned once
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  domain.APIKeyInput  true  "API key input"
+// @Success      201  {object}  domain.CreatedAPIKey
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/api-keys [post]
+func (c *userController) CreateAPIKey(ctx *gin.Context) {
+	adminID, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var input domain.APIKeyInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := c.service.CreateAPIKey(ctx, adminID, input)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, key)
+}
+
+// ListAPIKeys godoc
+// @Summary      List API keys
+// @Description  Lists every API key, revoked ones included, newest first (admin only)
+// @Tags         Admin
+// @Produce      json
+// @Success      200  {array}   domain.APIKey
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/api-keys [get]
+func (c *userController) ListAPIKeys(ctx *gin.Context) {
+	keys, err := c.service.ListAPIKeys(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary      Revoke API key
+// @Description  Stops an API key from working immediately (admin only)
+// @Tags         Admin
+// @Produce      json
+// @Param        id   path      string  true  "API key ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/api-keys/{id} [delete]
+func (c *userController) RevokeAPIKey(ctx *gin.Context) {
+	adminID, err := getUserID(ctx)
 	if err != nil {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	if userIDFromCtx != id {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "you can only delete your own account"})
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid api key id"})
 		return
 	}
 
-	if err := c.service.DeleteUser(ctx, id); err != nil {
+	err = c.service.RevokeAPIKey(ctx, adminID, id)
+	if errors.Is(err, domain.ErrAPIKeyNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"message": "User deleted successfully",
+		"message": "API key revoked",
+	})
+}
+
+// AcceptInvitation godoc
+// @Summary      Accept invitation
+// @Description  Creates the invited account with the role from the invitation
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        payload  body  domain.AcceptInvitationInput  true  "Invitation token and account details"
+// @Success      201  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Router       /invitations/accept [post]
+func (c *userController) AcceptInvitation(ctx *gin.Context) {
+	var input domain.AcceptInvitationInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.service.AcceptInvitation(withClientInfo(ctx), input); err != nil {
+		if respondTooManyAttempts(ctx, err) {
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Account created successfully. Please verify your mobile.",
 	})
 }
 
 // ForgotPassword godoc
 // @Summary      Forgot password
-// @Description  Sends password reset link or OTP to email/mobile
+// @Description  Sends a password reset token to the account's email or mobile
 // @Tags         Auth
 // @Accept       json
 // @Produce      json
@@ -203,15 +1426,14 @@ func (c *userController) ForgotPassword(ctx *gin.Context) {
 		return
 	}
 
-	resetToken, err := c.service.ForgotPassword(ctx, input)
-	if err != nil {
+	if err := c.service.ForgotPassword(ctx, input); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	// The token is only delivered to the account's email or mobile
 	ctx.JSON(http.StatusOK, gin.H{
-		"message":     "If the account exists, a password reset link has been sent",
-		"reset_token": resetToken,
+		"message": "If the account exists, a password reset link has been sent",
 	})
 }
 
@@ -226,6 +1448,7 @@ func (c *userController) ForgotPassword(ctx *gin.Context) {
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
 // @Router       /auth/reset-password/confirm [post]
 func (c *userController) ResetPasswordWithToken(ctx *gin.Context) {
 	var input struct {
@@ -238,7 +1461,11 @@ func (c *userController) ResetPasswordWithToken(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.service.ResetPasswordWithToken(ctx, input.Token, input.NewPassword); err != nil {
+	err := c.service.ResetPasswordWithToken(withClientInfo(ctx), input.Token, input.NewPassword)
+	if respondTooManyAttempts(ctx, err) {
+		return
+	}
+	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -287,8 +1514,16 @@ func (c *userController) VerifyEmail(ctx *gin.Context) {
 // @Param        payload  body  map[string]string  true  "OTP"
 // @Success      200  {object}  map[string]string
 // @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
 // @Router       /auth/verify-mobile [post]
 func (c *userController) VerifyMobile(ctx *gin.Context) {
+	userIDFromCtx, err := getUserID(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
 	var input struct {
 		OTP string `json:"otp" binding:"required"`
 	}
@@ -298,7 +1533,11 @@ func (c *userController) VerifyMobile(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.service.VerifyMobile(ctx, input.OTP); err != nil {
+	err = c.service.VerifyMobile(withClientInfo(ctx), userIDFromCtx, input.OTP)
+	if respondTooManyAttempts(ctx, err) {
+		return
+	}
+	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired OTP"})
 		return
 	}
@@ -374,7 +1613,7 @@ func (c *userController) ResendMobileOTP(ctx *gin.Context) {
 
 // ResetPassword godoc
 // @Summary      Reset password
-// @Description  Reset password for authenticated user
+// @Description  Reset password for authenticated user. The password must meet the strength policy and differ from the recent ones
 // @Tags         Auth
 // @Accept       json
 // @Produce      json
@@ -402,6 +1641,9 @@ func (c *userController) ResetPassword(ctx *gin.Context) {
 	}
 
 	if err := c.service.ResetPassword(ctx, userIDFromCtx, input.NewPassword); err != nil {
+		if respondPasswordRejected(ctx, err) {
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -410,3 +1652,63 @@ func (c *userController) ResetPassword(ctx *gin.Context) {
 		"message": "Password reset successfully",
 	})
 }
+
+// parseUserFilter reads the admin user search filters from the query string
+func parseUserFilter(ctx *gin.Context) (domain.UserFilter, error) {
+	var filter domain.UserFilter
+
+	for key, dst := range map[string]**string{
+		"email":  &filter.Email,
+		"mobile": &filter.Mobile,
+		"name":   &filter.Name,
+	} {
+		if v := ctx.Query(key); v != "" {
+			*dst = &v
+		}
+	}
+
+	if v := ctx.Query("role"); v != "" {
+		role := domain.UserRole(v)
+		if !role.IsValid() {
+			return filter, errors.New("invalid role")
+		}
+		filter.Role = &role
+	}
+
+	for key, dst := range map[string]**bool{
+		"active":          &filter.IsActive,
+		"email_verified":  &filter.EmailVerified,
+		"mobile_verified": &filter.MobileVerified,
+	} {
+		if v := ctx.Query(key); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return filter, errors.New("invalid " + key)
+			}
+			*dst = &b
+		}
+	}
+
+	for key, dst := range map[string]**time.Time{
+		"created_from": &filter.CreatedFrom,
+		"created_to":   &filter.CreatedTo,
+	} {
+		if v := ctx.Query(key); v != "" {
+			t, err := parseDateTime(v)
+			if err != nil {
+				return filter, errors.New("invalid " + key)
+			}
+			*dst = &t
+		}
+	}
+
+	return filter, nil
+}
+
+// parseDateTime accepts an RFC 3339 timestamp or a plain date
+func parseDateTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, v)
+}
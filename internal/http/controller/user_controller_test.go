@@ -5,29 +5,67 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"booknest/internal/domain"
+	"booknest/internal/middleware"
 )
 
 // MockUserService is a mock implementation of domain.UserService
 type MockUserService struct {
 	FindUserFunc                func(ctx context.Context, id uuid.UUID) (domain.User, error)
 	RegisterFunc                func(ctx context.Context, in domain.UserInput) error
-	LoginFunc                   func(ctx context.Context, in domain.LoginInput) (string, error)
-	ForgotPasswordFunc          func(ctx context.Context, in domain.ForgotPasswordInput) (string, error)
+	LoginFunc                   func(ctx context.Context, in domain.LoginInput) (domain.AuthTokens, error)
+	RefreshTokenFunc            func(ctx context.Context, rawToken string) (domain.AuthTokens, error)
+	LogoutFunc                  func(ctx context.Context, rawToken string) error
+	LogoutAllFunc               func(ctx context.Context, userID uuid.UUID) error
+	ListSessionsFunc            func(ctx context.Context, userID, currentID uuid.UUID) ([]domain.Session, error)
+	RevokeSessionFunc           func(ctx context.Context, userID, sessionID uuid.UUID) error
+	RequestLoginOTPFunc         func(ctx context.Context, in domain.LoginOTPRequestInput) error
+	LoginWithOTPFunc            func(ctx context.Context, in domain.LoginOTPVerifyInput) (domain.AuthTokens, error)
+	RequestMagicLinkFunc        func(ctx context.Context, in domain.MagicLinkRequestInput) (string, error)
+	LoginWithMagicLinkFunc      func(ctx context.Context, in domain.MagicLinkLoginInput) (domain.AuthTokens, error)
+	LoginWithMFAFunc            func(ctx context.Context, in domain.MFALoginInput) (domain.AuthTokens, error)
+	StartOIDCLoginFunc          func(ctx context.Context, provider string) (string, error)
+	CompleteOIDCLoginFunc       func(ctx context.Context, provider, code, state string) (domain.AuthTokens, error)
+	SetupMFAFunc                func(ctx context.Context, userID uuid.UUID) (domain.MFASetup, error)
+	EnableMFAFunc               func(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	DisableMFAFunc              func(ctx context.Context, userID uuid.UUID, code string) error
+	RegenerateRecoveryCodesFunc func(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	ForgotPasswordFunc          func(ctx context.Context, in domain.ForgotPasswordInput) error
 	ResetPasswordFunc           func(ctx context.Context, userID uuid.UUID, newPassword string) error
 	ResetPasswordWithTokenFunc  func(ctx context.Context, rawToken, newPassword string) error
 	VerifyEmailFunc             func(ctx context.Context, rawToken string) error
-	VerifyMobileFunc            func(ctx context.Context, otp string) error
+	VerifyMobileFunc            func(ctx context.Context, userID uuid.UUID, otp string) error
 	ResendEmailVerificationFunc func(ctx context.Context, userID uuid.UUID) error
 	ResendMobileOTPFunc         func(ctx context.Context, userID uuid.UUID) error
-	DeleteUserFunc              func(ctx context.Context, id uuid.UUID) error
+	ScheduleDeletionFunc        func(ctx context.Context, actorID, userID uuid.UUID) (time.Time, error)
+	CancelDeletionFunc          func(ctx context.Context, userID uuid.UUID) error
+	ExportDataFunc              func(ctx context.Context, userID uuid.UUID) (domain.UserExport, error)
+	PurgeDeletedUsersFunc       func(ctx context.Context) (int, error)
+	UnlockUserFunc              func(ctx context.Context, id uuid.UUID) error
+	InviteUserFunc              func(ctx context.Context, adminID uuid.UUID, in domain.InvitationInput) (domain.Invitation, error)
+	AcceptInvitationFunc        func(ctx context.Context, in domain.AcceptInvitationInput) error
+	ChangeUserRoleFunc          func(ctx context.Context, adminID, userID uuid.UUID, role domain.UserRole) error
+	SearchUsersFunc             func(ctx context.Context, filter domain.UserFilter, pagination domain.QueryOptions) (*domain.UserSearchResult, error)
+	SuspendUserFunc             func(ctx context.Context, adminID, userID uuid.UUID, reason string) error
+	ReactivateUserFunc          func(ctx context.Context, adminID, userID uuid.UUID) error
+	ForcePasswordResetFunc      func(ctx context.Context, adminID, userID uuid.UUID) error
+	UpdateProfileFunc           func(ctx context.Context, userID uuid.UUID, in domain.ProfileInput) (domain.User, error)
+	CreateAPIKeyFunc            func(ctx context.Context, adminID uuid.UUID, in domain.APIKeyInput) (domain.CreatedAPIKey, error)
+	ListAPIKeysFunc             func(ctx context.Context) ([]domain.APIKey, error)
+	RevokeAPIKeyFunc            func(ctx context.Context, adminID, id uuid.UUID) error
+	AuthenticateAPIKeyFunc      func(ctx context.Context, rawKey string) (domain.APIKeyPrincipal, error)
+	CheckVerifiedFunc           func(ctx context.Context, userID uuid.UUID, action domain.ProtectedAction) error
+	ImpersonateFunc             func(ctx context.Context, adminID, userID uuid.UUID, reason string) (domain.ImpersonationToken, error)
 }
 
 // Implement domain.UserService methods for MockUserService
@@ -45,18 +83,130 @@ func (m *MockUserService) Register(ctx context.Context, in domain.UserInput) err
 	return errors.New("not implemented")
 }
 
-func (m *MockUserService) Login(ctx context.Context, in domain.LoginInput) (string, error) {
+func (m *MockUserService) Login(ctx context.Context, in domain.LoginInput) (domain.AuthTokens, error) {
 	if m.LoginFunc != nil {
 		return m.LoginFunc(ctx, in)
 	}
+	return domain.AuthTokens{}, errors.New("not implemented")
+}
+
+func (m *MockUserService) RefreshToken(ctx context.Context, rawToken string) (domain.AuthTokens, error) {
+	if m.RefreshTokenFunc != nil {
+		return m.RefreshTokenFunc(ctx, rawToken)
+	}
+	return domain.AuthTokens{}, errors.New("not implemented")
+}
+
+func (m *MockUserService) Logout(ctx context.Context, rawToken string) error {
+	if m.LogoutFunc != nil {
+		return m.LogoutFunc(ctx, rawToken)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockUserService) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	if m.LogoutAllFunc != nil {
+		return m.LogoutAllFunc(ctx, userID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockUserService) ListSessions(ctx context.Context, userID, currentID uuid.UUID) ([]domain.Session, error) {
+	if m.ListSessionsFunc != nil {
+		return m.ListSessionsFunc(ctx, userID, currentID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockUserService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if m.RevokeSessionFunc != nil {
+		return m.RevokeSessionFunc(ctx, userID, sessionID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockUserService) RequestLoginOTP(ctx context.Context, in domain.LoginOTPRequestInput) error {
+	if m.RequestLoginOTPFunc != nil {
+		return m.RequestLoginOTPFunc(ctx, in)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockUserService) LoginWithOTP(ctx context.Context, in domain.LoginOTPVerifyInput) (domain.AuthTokens, error) {
+	if m.LoginWithOTPFunc != nil {
+		return m.LoginWithOTPFunc(ctx, in)
+	}
+	return domain.AuthTokens{}, errors.New("not implemented")
+}
+
+func (m *MockUserService) RequestMagicLink(ctx context.Context, in domain.MagicLinkRequestInput) (string, error) {
+	if m.RequestMagicLinkFunc != nil {
+		return m.RequestMagicLinkFunc(ctx, in)
+	}
+	return "", errors.New("not implemented")
+}
+
+func (m *MockUserService) LoginWithMagicLink(ctx context.Context, in domain.MagicLinkLoginInput) (domain.AuthTokens, error) {
+	if m.LoginWithMagicLinkFunc != nil {
+		return m.LoginWithMagicLinkFunc(ctx, in)
+	}
+	return domain.AuthTokens{}, errors.New("not implemented")
+}
+
+func (m *MockUserService) LoginWithMFA(ctx context.Context, in domain.MFALoginInput) (domain.AuthTokens, error) {
+	if m.LoginWithMFAFunc != nil {
+		return m.LoginWithMFAFunc(ctx, in)
+	}
+	return domain.AuthTokens{}, errors.New("not implemented")
+}
+
+func (m *MockUserService) StartOIDCLogin(ctx context.Context, provider string) (string, error) {
+	if m.StartOIDCLoginFunc != nil {
+		return m.StartOIDCLoginFunc(ctx, provider)
+	}
 	return "", errors.New("not implemented")
 }
 
-func (m *MockUserService) ForgotPassword(ctx context.Context, in domain.ForgotPasswordInput) (string, error) {
+func (m *MockUserService) CompleteOIDCLogin(ctx context.Context, provider, code, state string) (domain.AuthTokens, error) {
+	if m.CompleteOIDCLoginFunc != nil {
+		return m.CompleteOIDCLoginFunc(ctx, provider, code, state)
+	}
+	return domain.AuthTokens{}, errors.New("not implemented")
+}
+
+func (m *MockUserService) SetupMFA(ctx context.Context, userID uuid.UUID) (domain.MFASetup, error) {
+	if m.SetupMFAFunc != nil {
+		return m.SetupMFAFunc(ctx, userID)
+	}
+	return domain.MFASetup{}, errors.New("not implemented")
+}
+
+func (m *MockUserService) EnableMFA(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	if m.EnableMFAFunc != nil {
+		return m.EnableMFAFunc(ctx, userID, code)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockUserService) DisableMFA(ctx context.Context, userID uuid.UUID, code string) error {
+	if m.DisableMFAFunc != nil {
+		return m.DisableMFAFunc(ctx, userID, code)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockUserService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	if m.RegenerateRecoveryCodesFunc != nil {
+		return m.RegenerateRecoveryCodesFunc(ctx, userID, code)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockUserService) ForgotPassword(ctx context.Context, in domain.ForgotPasswordInput) error {
 	if m.ForgotPasswordFunc != nil {
 		return m.ForgotPasswordFunc(ctx, in)
 	}
-	return "", nil
+	return nil
 }
 
 func (m *MockUserService) ResetPassword(ctx context.Context, userID uuid.UUID, newPassword string) error {
@@ -80,9 +230,9 @@ func (m *MockUserService) VerifyEmail(ctx context.Context, rawToken string) erro
 	return errors.New("not implemented")
 }
 
-func (m *MockUserService) VerifyMobile(ctx context.Context, otp string) error {
+func (m *MockUserService) VerifyMobile(ctx context.Context, userID uuid.UUID, otp string) error {
 	if m.VerifyMobileFunc != nil {
-		return m.VerifyMobileFunc(ctx, otp)
+		return m.VerifyMobileFunc(ctx, userID, otp)
 	}
 	return errors.New("not implemented")
 }
@@ -101,23 +251,177 @@ func (m *MockUserService) ResendMobileOTP(ctx context.Context, userID uuid.UUID)
 	return errors.New("not implemented")
 }
 
-func (m *MockUserService) DeleteUser(ctx context.Context, id uuid.UUID) error {
-	if m.DeleteUserFunc != nil {
-		return m.DeleteUserFunc(ctx, id)
+func (m *MockUserService) ScheduleDeletion(ctx context.Context, actorID, userID uuid.UUID) (time.Time, error) {
+	if m.ScheduleDeletionFunc != nil {
+		return m.ScheduleDeletionFunc(ctx, actorID, userID)
+	}
+	return time.Time{}, errors.New("not implemented")
+}
+
+func (m *MockUserService) CancelDeletion(ctx context.Context, userID uuid.UUID) error {
+	if m.CancelDeletionFunc != nil {
+		return m.CancelDeletionFunc(ctx, userID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockUserService) ExportData(ctx context.Context, userID uuid.UUID) (domain.UserExport, error) {
+	if m.ExportDataFunc != nil {
+		return m.ExportDataFunc(ctx, userID)
+	}
+	return domain.UserExport{}, errors.New("not implemented")
+}
+
+func (m *MockUserService) PurgeDeletedUsers(ctx context.Context) (int, error) {
+	if m.PurgeDeletedUsersFunc != nil {
+		return m.PurgeDeletedUsersFunc(ctx)
+	}
+	return 0, errors.New("not implemented")
+}
+
+func (m *MockUserService) UnlockUser(ctx context.Context, id uuid.UUID) error {
+	if m.UnlockUserFunc != nil {
+		return m.UnlockUserFunc(ctx, id)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockUserService) InviteUser(ctx context.Context, adminID uuid.UUID, in domain.InvitationInput) (domain.Invitation, error) {
+	if m.InviteUserFunc != nil {
+		return m.InviteUserFunc(ctx, adminID, in)
+	}
+	return domain.Invitation{}, errors.New("not implemented")
+}
+
+func (m *MockUserService) AcceptInvitation(ctx context.Context, in domain.AcceptInvitationInput) error {
+	if m.AcceptInvitationFunc != nil {
+		return m.AcceptInvitationFunc(ctx, in)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockUserService) ChangeUserRole(ctx context.Context, adminID, userID uuid.UUID, role domain.UserRole) error {
+	if m.ChangeUserRoleFunc != nil {
+		return m.ChangeUserRoleFunc(ctx, adminID, userID, role)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockUserService) SearchUsers(ctx context.Context, filter domain.UserFilter, pagination domain.QueryOptions) (*domain.UserSearchResult, error) {
+	if m.SearchUsersFunc != nil {
+		return m.SearchUsersFunc(ctx, filter, pagination)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockUserService) SuspendUser(ctx context.Context, adminID, userID uuid.UUID, reason string) error {
+	if m.SuspendUserFunc != nil {
+		return m.SuspendUserFunc(ctx, adminID, userID, reason)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockUserService) ReactivateUser(ctx context.Context, adminID, userID uuid.UUID) error {
+	if m.ReactivateUserFunc != nil {
+		return m.ReactivateUserFunc(ctx, adminID, userID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockUserService) ForcePasswordReset(ctx context.Context, adminID, userID uuid.UUID) error {
+	if m.ForcePasswordResetFunc != nil {
+		return m.ForcePasswordResetFunc(ctx, adminID, userID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockUserService) UpdateProfile(ctx context.Context, userID uuid.UUID, in domain.ProfileInput) (domain.User, error) {
+	if m.UpdateProfileFunc != nil {
+		return m.UpdateProfileFunc(ctx, userID, in)
+	}
+	return domain.User{}, errors.New("not implemented")
+}
+
+func (m *MockUserService) CreateAPIKey(ctx context.Context, adminID uuid.UUID, in domain.APIKeyInput) (domain.CreatedAPIKey, error) {
+	if m.CreateAPIKeyFunc != nil {
+		return m.CreateAPIKeyFunc(ctx, adminID, in)
+	}
+	return domain.CreatedAPIKey{}, errors.New("not implemented")
+}
+
+func (m *MockUserService) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	if m.ListAPIKeysFunc != nil {
+		return m.ListAPIKeysFunc(ctx)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockUserService) RevokeAPIKey(ctx context.Context, adminID, id uuid.UUID) error {
+	if m.RevokeAPIKeyFunc != nil {
+		return m.RevokeAPIKeyFunc(ctx, adminID, id)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockUserService) AuthenticateAPIKey(ctx context.Context, rawKey string) (domain.APIKeyPrincipal, error) {
+	if m.AuthenticateAPIKeyFunc != nil {
+		return m.AuthenticateAPIKeyFunc(ctx, rawKey)
+	}
+	return domain.APIKeyPrincipal{}, errors.New("not implemented")
+}
+
+func (m *MockUserService) Impersonate(ctx context.Context, adminID, userID uuid.UUID, reason string) (domain.ImpersonationToken, error) {
+	if m.ImpersonateFunc != nil {
+		return m.ImpersonateFunc(ctx, adminID, userID, reason)
+	}
+	return domain.ImpersonationToken{}, errors.New("not implemented")
+}
+
+func (m *MockUserService) CheckVerified(ctx context.Context, userID uuid.UUID, action domain.ProtectedAction) error {
+	if m.CheckVerifiedFunc != nil {
+		return m.CheckVerifiedFunc(ctx, userID, action)
 	}
 	return errors.New("not implemented")
 }
 
+// stubAuthStore accepts every token and verification but no API key
+type stubAuthStore struct{}
+
+func (stubAuthStore) IsRevoked(ctx context.Context, id uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+func (stubAuthStore) AuthenticateAPIKey(ctx context.Context, rawKey string) (domain.APIKeyPrincipal, error) {
+	return domain.APIKeyPrincipal{}, domain.ErrInvalidAPIKey
+}
+
+func (stubAuthStore) CheckVerified(ctx context.Context, userID uuid.UUID, action domain.ProtectedAction) error {
+	return nil
+}
+
+func (stubAuthStore) Create(ctx context.Context, log *domain.AuditLog) error {
+	return nil
+}
+
+func newTestAuth(t *testing.T) *middleware.Auth {
+	t.Helper()
+	auth, err := middleware.NewAuth(stubAuthStore{}, stubAuthStore{}, stubAuthStore{}, stubAuthStore{})
+	if err != nil {
+		t.Fatalf("auth middleware: %v", err)
+	}
+	return auth
+}
+
 // TestLogin_Success tests successful login
 func TestLogin_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockService := &MockUserService{
-		LoginFunc: func(ctx context.Context, in domain.LoginInput) (string, error) {
-			return "valid.jwt.token", nil
+		LoginFunc: func(ctx context.Context, in domain.LoginInput) (domain.AuthTokens, error) {
+			return domain.AuthTokens{AccessToken: "valid.jwt.token", RefreshToken: "refresh-token"}, nil
 		},
 	}
 
-	controller := NewUserController(mockService)
+	controller := NewUserController(mockService, newTestAuth(t))
 	router := gin.New()
 	controller.RegisterRoutes(router)
 
@@ -142,18 +446,21 @@ func TestLogin_Success(t *testing.T) {
 	if response["token"] != "valid.jwt.token" {
 		t.Fatalf("expected token in response")
 	}
+	if response["refresh_token"] != "refresh-token" {
+		t.Fatalf("expected refresh token in response")
+	}
 }
 
 // TestLogin_InvalidCredentials tests login with invalid credentials
 func TestLogin_InvalidCredentials(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockService := &MockUserService{
-		LoginFunc: func(ctx context.Context, in domain.LoginInput) (string, error) {
-			return "", errors.New("invalid credentials")
+		LoginFunc: func(ctx context.Context, in domain.LoginInput) (domain.AuthTokens, error) {
+			return domain.AuthTokens{}, errors.New("invalid credentials")
 		},
 	}
 
-	controller := NewUserController(mockService)
+	controller := NewUserController(mockService, newTestAuth(t))
 	router := gin.New()
 	controller.RegisterRoutes(router)
 
@@ -178,7 +485,7 @@ func TestLogin_InvalidCredentials(t *testing.T) {
 func TestLogin_MissingEmailAndMobile(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockService := &MockUserService{}
-	controller := NewUserController(mockService)
+	controller := NewUserController(mockService, newTestAuth(t))
 	router := gin.New()
 	controller.RegisterRoutes(router)
 
@@ -213,7 +520,7 @@ func TestGetUser_Success(t *testing.T) {
 		},
 	}
 
-	controller := NewUserController(mockService)
+	controller := NewUserController(mockService, newTestAuth(t))
 	router := gin.New()
 	controller.RegisterRoutes(router)
 
@@ -235,7 +542,7 @@ func TestGetUser_Success(t *testing.T) {
 func TestGetUser_InvalidID(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockService := &MockUserService{}
-	controller := NewUserController(mockService)
+	controller := NewUserController(mockService, newTestAuth(t))
 	router := gin.New()
 	controller.RegisterRoutes(router)
 
@@ -253,12 +560,12 @@ func TestGetUser_InvalidID(t *testing.T) {
 func TestForgotPassword_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockService := &MockUserService{
-		ForgotPasswordFunc: func(ctx context.Context, in domain.ForgotPasswordInput) (string, error) {
-			return "reset-token-123", nil
+		ForgotPasswordFunc: func(ctx context.Context, in domain.ForgotPasswordInput) error {
+			return nil
 		},
 	}
 
-	controller := NewUserController(mockService)
+	controller := NewUserController(mockService, newTestAuth(t))
 	router := gin.New()
 	controller.RegisterRoutes(router)
 
@@ -279,8 +586,8 @@ func TestForgotPassword_Success(t *testing.T) {
 
 	var response map[string]interface{}
 	_ = json.Unmarshal(w.Body.Bytes(), &response)
-	if response["reset_token"] != "reset-token-123" {
-		t.Fatalf("expected reset token in response")
+	if _, ok := response["reset_token"]; ok {
+		t.Fatalf("reset token must only be sent to the account")
 	}
 }
 
@@ -289,7 +596,7 @@ func TestForgotPassword_MissingEmailAndMobile(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockService := &MockUserService{}
 
-	controller := NewUserController(mockService)
+	controller := NewUserController(mockService, newTestAuth(t))
 	router := gin.New()
 	controller.RegisterRoutes(router)
 
@@ -318,7 +625,7 @@ func TestResetPasswordWithToken_Success(t *testing.T) {
 		},
 	}
 
-	controller := NewUserController(mockService)
+	controller := NewUserController(mockService, newTestAuth(t))
 	router := gin.New()
 	controller.RegisterRoutes(router)
 
@@ -347,7 +654,7 @@ func TestVerifyEmail_Success(t *testing.T) {
 		},
 	}
 
-	controller := NewUserController(mockService)
+	controller := NewUserController(mockService, newTestAuth(t))
 	router := gin.New()
 	controller.RegisterRoutes(router)
 
@@ -374,12 +681,12 @@ func TestVerifyEmail_Success(t *testing.T) {
 func TestVerifyMobile_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	mockService := &MockUserService{
-		VerifyMobileFunc: func(ctx context.Context, otp string) error {
+		VerifyMobileFunc: func(ctx context.Context, userID uuid.UUID, otp string) error {
 			return nil
 		},
 	}
 
-	controller := NewUserController(mockService)
+	controller := NewUserController(mockService, newTestAuth(t))
 	router := gin.New()
 	controller.RegisterRoutes(router)
 
@@ -401,3 +708,1032 @@ func TestVerifyMobile_Success(t *testing.T) {
 		}
 	}
 }
+
+// TestRefreshToken_Success tests exchanging a refresh token
+func TestRefreshToken_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		RefreshTokenFunc: func(ctx context.Context, rawToken string) (domain.AuthTokens, error) {
+			if rawToken != "refresh-1" {
+				t.Fatalf("unexpected refresh token: %s", rawToken)
+			}
+			return domain.AuthTokens{AccessToken: "access-2", RefreshToken: "refresh-2"}, nil
+		},
+	}
+
+	controller := NewUserController(mockService, newTestAuth(t))
+	router := gin.New()
+	controller.RegisterRoutes(router)
+
+	body, _ := json.Marshal(domain.RefreshTokenInput{RefreshToken: "refresh-1"})
+	req := httptest.NewRequest("POST", "/token/refresh", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var response map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	if response["token"] != "access-2" || response["refresh_token"] != "refresh-2" {
+		t.Fatalf("expected rotated tokens in response, got %v", response)
+	}
+}
+
+// TestRefreshToken_Reused tests that a rejected refresh token returns 401
+func TestRefreshToken_Reused(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		RefreshTokenFunc: func(ctx context.Context, rawToken string) (domain.AuthTokens, error) {
+			return domain.AuthTokens{}, errors.New("refresh token reuse detected")
+		},
+	}
+
+	controller := NewUserController(mockService, newTestAuth(t))
+	router := gin.New()
+	controller.RegisterRoutes(router)
+
+	body, _ := json.Marshal(domain.RefreshTokenInput{RefreshToken: "refresh-1"})
+	req := httptest.NewRequest("POST", "/token/refresh", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+// TestLogout_Success tests revoking a refresh token
+func TestLogout_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	called := false
+	mockService := &MockUserService{
+		LogoutFunc: func(ctx context.Context, rawToken string) error {
+			called = rawToken == "refresh-1"
+			return nil
+		},
+	}
+
+	controller := NewUserController(mockService, newTestAuth(t))
+	router := gin.New()
+	controller.RegisterRoutes(router)
+
+	body, _ := json.Marshal(domain.RefreshTokenInput{RefreshToken: "refresh-1"})
+	req := httptest.NewRequest("POST", "/logout", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if !called {
+		t.Fatalf("expected Logout to be called with the refresh token")
+	}
+}
+
+// TestLogoutAll_RequiresAuth tests that logout-all is protected
+func TestLogoutAll_RequiresAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller := NewUserController(&MockUserService{}, newTestAuth(t))
+	router := gin.New()
+	controller.RegisterRoutes(router)
+
+	req := httptest.NewRequest("POST", "/logout-all", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+// TestRequestLoginOTP_Success tests requesting a login code
+func TestRequestLoginOTP_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		RequestLoginOTPFunc: func(ctx context.Context, in domain.LoginOTPRequestInput) error {
+			if in.Mobile != "+911234567890" {
+				t.Fatalf("unexpected mobile: %s", in.Mobile)
+			}
+			return nil
+		},
+	}
+
+	controller := NewUserController(mockService, newTestAuth(t))
+	router := gin.New()
+	controller.RegisterRoutes(router)
+
+	body, _ := json.Marshal(domain.LoginOTPRequestInput{Mobile: "+911234567890"})
+	req := httptest.NewRequest("POST", "/login/otp", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+}
+
+// TestRequestLoginOTP_Cooldown tests that a resend during the cooldown answers 429
+func TestRequestLoginOTP_Cooldown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		RequestLoginOTPFunc: func(ctx context.Context, in domain.LoginOTPRequestInput) error {
+			if domain.ClientIPFromContext(ctx) == "" {
+				t.Fatalf("expected the client IP to be passed on")
+			}
+			return &domain.TooManyAttemptsError{RetryAfter: 45 * time.Second}
+		},
+	}
+
+	router := gin.New()
+	NewUserController(mockService, newTestAuth(t)).RegisterRoutes(router)
+
+	body, _ := json.Marshal(domain.LoginOTPRequestInput{Mobile: "+911234567890"})
+	req := httptest.NewRequest("POST", "/login/otp", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "45" {
+		t.Fatalf("expected Retry-After 45, got %q", w.Header().Get("Retry-After"))
+	}
+}
+
+// TestRequestMagicLink_BoundDevice tests that the device code is returned for bound links
+func TestRequestMagicLink_BoundDevice(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		RequestMagicLinkFunc: func(ctx context.Context, in domain.MagicLinkRequestInput) (string, error) {
+			if in.Email != "test@example.com" || !in.BindDevice {
+				t.Fatalf("unexpected input: %+v", in)
+			}
+			return "device-code", nil
+		},
+	}
+
+	controller := NewUserController(mockService, newTestAuth(t))
+	router := gin.New()
+	controller.RegisterRoutes(router)
+
+	body, _ := json.Marshal(domain.MagicLinkRequestInput{Email: "test@example.com", BindDevice: true})
+	req := httptest.NewRequest("POST", "/login/magic-link", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var response map[string]string
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	if response["device_code"] != "device-code" {
+		t.Fatalf("expected the device code, got %v", response)
+	}
+}
+
+// TestLoginWithMagicLink_Invalid tests that a rejected link answers 401
+func TestLoginWithMagicLink_Invalid(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		LoginWithMagicLinkFunc: func(ctx context.Context, in domain.MagicLinkLoginInput) (domain.AuthTokens, error) {
+			return domain.AuthTokens{}, errors.New("invalid or expired link")
+		},
+	}
+
+	controller := NewUserController(mockService, newTestAuth(t))
+	router := gin.New()
+	controller.RegisterRoutes(router)
+
+	body, _ := json.Marshal(domain.MagicLinkLoginInput{Token: "abc"})
+	req := httptest.NewRequest("POST", "/login/magic-link/verify", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+// TestLoginWithOTP_InvalidCode tests rejecting a malformed code before calling the service
+func TestLoginWithOTP_InvalidCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller := NewUserController(&MockUserService{}, newTestAuth(t))
+	router := gin.New()
+	controller.RegisterRoutes(router)
+
+	body, _ := json.Marshal(domain.LoginOTPVerifyInput{Email: "test@example.com", OTP: "12ab"})
+	req := httptest.NewRequest("POST", "/login/otp/verify", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+// TestLoginWithOTP_Success tests exchanging a login code for tokens
+func TestLoginWithOTP_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		LoginWithOTPFunc: func(ctx context.Context, in domain.LoginOTPVerifyInput) (domain.AuthTokens, error) {
+			return domain.AuthTokens{AccessToken: "access", RefreshToken: "refresh"}, nil
+		},
+	}
+
+	controller := NewUserController(mockService, newTestAuth(t))
+	router := gin.New()
+	controller.RegisterRoutes(router)
+
+	body, _ := json.Marshal(domain.LoginOTPVerifyInput{Email: "test@example.com", OTP: "123456"})
+	req := httptest.NewRequest("POST", "/login/otp/verify", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+}
+
+// TestLogin_MFARequired tests that login returns only the mfa_token when a second factor is needed
+func TestLogin_MFARequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		LoginFunc: func(ctx context.Context, in domain.LoginInput) (domain.AuthTokens, error) {
+			return domain.AuthTokens{MFARequired: true, MFAEnrollmentRequired: true, MFAToken: "pending"}, nil
+		},
+	}
+
+	controller := NewUserController(mockService, newTestAuth(t))
+	router := gin.New()
+	controller.RegisterRoutes(router)
+
+	body, _ := json.Marshal(domain.LoginInput{Email: "admin@example.com", Password: "password123"})
+	req := httptest.NewRequest("POST", "/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var response map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	if response["mfa_token"] != "pending" || response["mfa_enrollment_required"] != true {
+		t.Fatalf("expected mfa token in response, got %v", response)
+	}
+	if _, ok := response["token"]; ok {
+		t.Fatalf("expected no access token before the second factor")
+	}
+}
+
+// TestLoginWithMFA_InvalidCode tests rejecting a wrong second factor
+func TestLoginWithMFA_InvalidCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		LoginWithMFAFunc: func(ctx context.Context, in domain.MFALoginInput) (domain.AuthTokens, error) {
+			return domain.AuthTokens{}, errors.New("invalid two-factor code")
+		},
+	}
+
+	controller := NewUserController(mockService, newTestAuth(t))
+	router := gin.New()
+	controller.RegisterRoutes(router)
+
+	body, _ := json.Marshal(domain.MFALoginInput{MFAToken: "pending", Code: "000000"})
+	req := httptest.NewRequest("POST", "/login/2fa", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+// TestEnableMFA_ReturnsRecoveryCodes tests confirming enrollment
+func TestEnableMFA_ReturnsRecoveryCodes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+	mockService := &MockUserService{
+		EnableMFAFunc: func(ctx context.Context, gotUserID uuid.UUID, code string) ([]string, error) {
+			if gotUserID != userID || code != "123456" {
+				t.Fatalf("unexpected enable input")
+			}
+			return []string{"AAAAA-BBBBB"}, nil
+		},
+	}
+	ctl := NewUserController(mockService, newTestAuth(t)).(*userController)
+
+	body, _ := json.Marshal(domain.MFACodeInput{Code: "123456"})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", userID.String())
+	c.Request = httptest.NewRequest(http.MethodPost, "/2fa/enable", bytes.NewBuffer(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	ctl.EnableMFA(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var response map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	if codes, ok := response["recovery_codes"].([]interface{}); !ok || len(codes) != 1 {
+		t.Fatalf("expected recovery codes in response, got %v", response)
+	}
+}
+
+// TestLogin_TooManyAttempts tests that a lockout returns 429 with Retry-After
+func TestLogin_TooManyAttempts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		LoginFunc: func(ctx context.Context, in domain.LoginInput) (domain.AuthTokens, error) {
+			if domain.ClientIPFromContext(ctx) == "" {
+				t.Fatalf("expected client IP in context")
+			}
+			return domain.AuthTokens{}, &domain.TooManyAttemptsError{RetryAfter: 90 * time.Second}
+		},
+	}
+
+	controller := NewUserController(mockService, newTestAuth(t))
+	router := gin.New()
+	controller.RegisterRoutes(router)
+
+	body, _ := json.Marshal(domain.LoginInput{Email: "test@example.com", Password: "password123"})
+	req := httptest.NewRequest("POST", "/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "90" {
+		t.Fatalf("expected Retry-After 90, got %q", w.Header().Get("Retry-After"))
+	}
+}
+
+// TestUnlockUser_Success tests that admins can lift a lockout
+func TestUnlockUser_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+	mockService := &MockUserService{
+		UnlockUserFunc: func(ctx context.Context, id uuid.UUID) error {
+			if id != userID {
+				t.Fatalf("unexpected user id")
+			}
+			return nil
+		},
+	}
+	ctl := NewUserController(mockService, newTestAuth(t)).(*userController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: userID.String()}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/admin/users/"+userID.String()+"/unlock", nil)
+
+	ctl.UnlockUser(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+}
+
+// TestChangeUserRole_Success tests that the acting admin and target are passed on
+func TestChangeUserRole_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	adminID := uuid.New()
+	userID := uuid.New()
+	mockService := &MockUserService{
+		ChangeUserRoleFunc: func(ctx context.Context, gotAdmin, gotUser uuid.UUID, role domain.UserRole) error {
+			if gotAdmin != adminID || gotUser != userID || role != domain.UserRoleAdmin {
+				t.Fatalf("unexpected role change %s %s %s", gotAdmin, gotUser, role)
+			}
+			return nil
+		},
+	}
+	ctl := NewUserController(mockService, newTestAuth(t)).(*userController)
+
+	body, _ := json.Marshal(domain.RoleInput{Role: domain.UserRoleAdmin})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", adminID.String())
+	c.Params = gin.Params{{Key: "id", Value: userID.String()}}
+	c.Request = httptest.NewRequest(http.MethodPut, "/admin/users/"+userID.String()+"/role", bytes.NewBuffer(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	ctl.ChangeUserRole(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+}
+
+// TestChangeUserRole_RequiresAdmin tests that the route is behind the admin group
+func TestChangeUserRole_RequiresAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller := NewUserController(&MockUserService{}, newTestAuth(t))
+	router := gin.New()
+	controller.RegisterRoutes(router)
+
+	body, _ := json.Marshal(domain.RoleInput{Role: domain.UserRoleAdmin})
+	req := httptest.NewRequest(http.MethodPut, "/admin/users/"+uuid.New().String()+"/role", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+// TestAcceptInvitation_Success tests accepting an invitation
+func TestAcceptInvitation_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		AcceptInvitationFunc: func(ctx context.Context, in domain.AcceptInvitationInput) error {
+			if in.Token != "invite-token" {
+				t.Fatalf("unexpected token %q", in.Token)
+			}
+			return nil
+		},
+	}
+
+	controller := NewUserController(mockService, newTestAuth(t))
+	router := gin.New()
+	controller.RegisterRoutes(router)
+
+	body, _ := json.Marshal(domain.AcceptInvitationInput{
+		Token:     "invite-token",
+		FirstName: "Ada",
+		LastName:  "Lovelace",
+		Mobile:    "+15550001234",
+		Password:  "password123",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/invitations/accept", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", w.Code)
+	}
+}
+
+// TestSearchUsers_ParsesFilters tests that query parameters become filters
+func TestSearchUsers_ParsesFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		SearchUsersFunc: func(ctx context.Context, filter domain.UserFilter, pagination domain.QueryOptions) (*domain.UserSearchResult, error) {
+			if filter.Email == nil || *filter.Email != "example.com" {
+				t.Fatalf("unexpected email filter %v", filter.Email)
+			}
+			if filter.Role == nil || *filter.Role != domain.UserRoleAdmin {
+				t.Fatalf("unexpected role filter %v", filter.Role)
+			}
+			if filter.IsActive == nil || *filter.IsActive {
+				t.Fatalf("expected suspended users only")
+			}
+			if filter.CreatedFrom == nil || !filter.CreatedFrom.Equal(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)) {
+				t.Fatalf("unexpected created_from %v", filter.CreatedFrom)
+			}
+			if pagination.Limit != 5 || pagination.Offset != 10 {
+				t.Fatalf("unexpected pagination %+v", pagination)
+			}
+			return &domain.UserSearchResult{Total: 1, Limit: 5, Offset: 10}, nil
+		},
+	}
+	ctl := NewUserController(mockService, newTestAuth(t)).(*userController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(
+		http.MethodGet,
+		"/admin/users?email=example.com&role=ADMIN&active=false&created_from=2026-01-02&limit=5&offset=10",
+		nil,
+	)
+
+	ctl.SearchUsers(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestSearchUsers_InvalidFilter tests that malformed filters are rejected
+func TestSearchUsers_InvalidFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctl := NewUserController(&MockUserService{}, newTestAuth(t)).(*userController)
+
+	for _, query := range []string{"role=ROOT", "active=maybe", "created_to=yesterday", "limit=-1"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/users?"+query, nil)
+
+		ctl.SearchUsers(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+// TestSuspendUser_WithoutBody tests that the reason is optional
+func TestSuspendUser_WithoutBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	adminID := uuid.New()
+	userID := uuid.New()
+	mockService := &MockUserService{
+		SuspendUserFunc: func(ctx context.Context, gotAdmin, gotUser uuid.UUID, reason string) error {
+			if gotAdmin != adminID || gotUser != userID || reason != "" {
+				t.Fatalf("unexpected suspension %s %s %q", gotAdmin, gotUser, reason)
+			}
+			return nil
+		},
+	}
+	ctl := NewUserController(mockService, newTestAuth(t)).(*userController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", adminID.String())
+	c.Params = gin.Params{{Key: "id", Value: userID.String()}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/admin/users/"+userID.String()+"/suspend", nil)
+
+	ctl.SuspendUser(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+}
+
+// TestImpersonateUser tests that the reason is required and passed on
+func TestImpersonateUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	adminID := uuid.New()
+	userID := uuid.New()
+	mockService := &MockUserService{
+		ImpersonateFunc: func(ctx context.Context, gotAdmin, gotUser uuid.UUID, reason string) (domain.ImpersonationToken, error) {
+			if gotAdmin != adminID || gotUser != userID || reason != "ticket 4711" {
+				t.Fatalf("unexpected impersonation %s %s %q", gotAdmin, gotUser, reason)
+			}
+			return domain.ImpersonationToken{AccessToken: "token", UserID: gotUser, ImpersonatorID: gotAdmin}, nil
+		},
+	}
+	ctl := NewUserController(mockService, newTestAuth(t)).(*userController)
+
+	for _, tt := range []struct {
+		body string
+		want int
+	}{
+		{`{"reason":"ticket 4711"}`, http.StatusOK},
+		{`{}`, http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("user_id", adminID.String())
+		c.Params = gin.Params{{Key: "id", Value: userID.String()}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/admin/users/"+userID.String()+"/impersonate", bytes.NewBufferString(tt.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		ctl.ImpersonateUser(c)
+
+		if w.Code != tt.want {
+			t.Fatalf("%s: expected %d, got %d", tt.body, tt.want, w.Code)
+		}
+	}
+}
+
+// TestLogin_Suspended tests that blocked accounts get a 403
+func TestLogin_Suspended(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		LoginFunc: func(ctx context.Context, in domain.LoginInput) (domain.AuthTokens, error) {
+			return domain.AuthTokens{}, domain.ErrAccountSuspended
+		},
+	}
+
+	controller := NewUserController(mockService, newTestAuth(t))
+	router := gin.New()
+	controller.RegisterRoutes(router)
+
+	body, _ := json.Marshal(domain.LoginInput{Email: "test@example.com", Password: "password123"})
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", w.Code)
+	}
+}
+
+// TestGetProfile_Success tests that the logged in user is returned
+func TestGetProfile_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+	mockService := &MockUserService{
+		FindUserFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+			if id != userID {
+				t.Fatalf("expected the caller's id, got %s", id)
+			}
+			return domain.User{ID: id, Email: "me@example.com"}, nil
+		},
+	}
+	ctl := NewUserController(mockService, newTestAuth(t)).(*userController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", userID.String())
+	c.Request = httptest.NewRequest(http.MethodGet, "/me", nil)
+
+	ctl.GetProfile(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+}
+
+// TestUpdateProfile_Success tests that only the given fields are passed on
+func TestUpdateProfile_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+	mockService := &MockUserService{
+		UpdateProfileFunc: func(ctx context.Context, id uuid.UUID, in domain.ProfileInput) (domain.User, error) {
+			if in.FirstName != nil || in.Mobile != nil {
+				t.Fatalf("expected omitted fields to stay nil, got %+v", in)
+			}
+			if in.Email == nil || *in.Email != "new@example.com" {
+				t.Fatalf("unexpected email %v", in.Email)
+			}
+			return domain.User{ID: id, Email: "old@example.com", PendingEmail: in.Email}, nil
+		},
+	}
+	ctl := NewUserController(mockService, newTestAuth(t)).(*userController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", userID.String())
+	c.Request = httptest.NewRequest(http.MethodPatch, "/me", bytes.NewBufferString(`{"email":"new@example.com"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	ctl.UpdateProfile(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var user domain.User
+	_ = json.Unmarshal(w.Body.Bytes(), &user)
+	if user.Email != "old@example.com" || user.PendingEmail == nil {
+		t.Fatalf("expected the change to be pending, got %+v", user)
+	}
+}
+
+// TestUpdateProfile_InvalidEmail tests that malformed addresses are rejected
+func TestUpdateProfile_InvalidEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctl := NewUserController(&MockUserService{}, newTestAuth(t)).(*userController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", uuid.New().String())
+	c.Request = httptest.NewRequest(http.MethodPatch, "/me", bytes.NewBufferString(`{"email":"not-an-email"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	ctl.UpdateProfile(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+// TestUpdateProfile_VerificationRequired tests that the missing channels are returned
+func TestUpdateProfile_VerificationRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		UpdateProfileFunc: func(ctx context.Context, id uuid.UUID, in domain.ProfileInput) (domain.User, error) {
+			return domain.User{}, &domain.VerificationRequiredError{
+				Action:  domain.ActionChangeEmail,
+				Missing: []domain.VerificationChannel{domain.ChannelMobile},
+			}
+		},
+	}
+	ctl := NewUserController(mockService, newTestAuth(t)).(*userController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", uuid.New().String())
+	c.Request = httptest.NewRequest(http.MethodPatch, "/me", bytes.NewBufferString(`{"email":"new@example.com"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	ctl.UpdateProfile(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"missing":["mobile"]`) {
+		t.Fatalf("expected the missing channel in the body, got %s", w.Body.String())
+	}
+}
+
+// TestDeleteProfile_Scheduled tests that deleting the own account schedules it
+func TestDeleteProfile_Scheduled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+	scheduled := time.Now().Add(30 * 24 * time.Hour)
+	mockService := &MockUserService{
+		ScheduleDeletionFunc: func(ctx context.Context, actorID, id uuid.UUID) (time.Time, error) {
+			if actorID != userID || id != userID {
+				t.Fatalf("expected the caller's id, got %s %s", actorID, id)
+			}
+			return scheduled, nil
+		},
+	}
+	ctl := NewUserController(mockService, newTestAuth(t)).(*userController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", userID.String())
+	c.Request = httptest.NewRequest(http.MethodDelete, "/me", nil)
+
+	ctl.DeleteProfile(c)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", w.Code)
+	}
+
+	var body map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	if body["deletion_scheduled_at"] == nil {
+		t.Fatalf("expected the deletion date, got %v", body)
+	}
+}
+
+// TestExportData_Download tests that the export is served as an attachment
+func TestExportData_Download(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+	mockService := &MockUserService{
+		ExportDataFunc: func(ctx context.Context, id uuid.UUID) (domain.UserExport, error) {
+			return domain.UserExport{Profile: domain.User{ID: id}}, nil
+		},
+	}
+	ctl := NewUserController(mockService, newTestAuth(t)).(*userController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", userID.String())
+	c.Request = httptest.NewRequest(http.MethodGet, "/me/export", nil)
+
+	ctl.ExportData(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Disposition"); got == "" {
+		t.Fatalf("expected an attachment header")
+	}
+}
+
+// TestListSessions_PassesCurrentSession tests that the token's session is marked as current
+func TestListSessions_PassesCurrentSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+	sessionID := uuid.New()
+	mockService := &MockUserService{
+		ListSessionsFunc: func(ctx context.Context, id, currentID uuid.UUID) ([]domain.Session, error) {
+			if id != userID || currentID != sessionID {
+				t.Fatalf("expected the caller and their session, got %s %s", id, currentID)
+			}
+			return []domain.Session{{ID: sessionID, Current: true}}, nil
+		},
+	}
+	ctl := NewUserController(mockService, newTestAuth(t)).(*userController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", userID.String())
+	c.Set("session_id", sessionID.String())
+	c.Request = httptest.NewRequest(http.MethodGet, "/me/sessions", nil)
+
+	ctl.ListSessions(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+}
+
+// TestRevokeSession_NotFound tests that foreign or unknown sessions return 404
+func TestRevokeSession_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		RevokeSessionFunc: func(ctx context.Context, userID, sessionID uuid.UUID) error {
+			return domain.ErrSessionNotFound
+		},
+	}
+	ctl := NewUserController(mockService, newTestAuth(t)).(*userController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", uuid.NewString())
+	c.Params = gin.Params{{Key: "id", Value: uuid.NewString()}}
+	c.Request = httptest.NewRequest(http.MethodDelete, "/me/sessions/x", nil)
+
+	ctl.RevokeSession(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+// TestStartOIDCLogin_Redirects tests that the user is sent to the provider
+func TestStartOIDCLogin_Redirects(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		StartOIDCLoginFunc: func(ctx context.Context, provider string) (string, error) {
+			if provider != "google" {
+				return "", domain.ErrUnknownOIDCProvider
+			}
+			return "https://accounts.example.com/authorize?state=abc", nil
+		},
+	}
+	router := gin.New()
+	NewUserController(mockService, newTestAuth(t)).RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/google", nil))
+
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected 302, got %d", w.Code)
+	}
+	if w.Header().Get("Location") != "https://accounts.example.com/authorize?state=abc" {
+		t.Fatalf("unexpected redirect %q", w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/unknown", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown provider, got %d", w.Code)
+	}
+}
+
+// TestCompleteOIDCLogin_ProviderError tests that a cancelled login never reaches the service
+func TestCompleteOIDCLogin_ProviderError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		CompleteOIDCLoginFunc: func(ctx context.Context, provider, code, state string) (domain.AuthTokens, error) {
+			t.Fatalf("should not complete a cancelled login")
+			return domain.AuthTokens{}, nil
+		},
+	}
+	router := gin.New()
+	NewUserController(mockService, newTestAuth(t)).RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/google/callback?state=abc&error=access_denied", nil))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+// TestCompleteOIDCLogin_Success tests that the callback returns BookNest tokens
+func TestCompleteOIDCLogin_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		CompleteOIDCLoginFunc: func(ctx context.Context, provider, code, state string) (domain.AuthTokens, error) {
+			if provider != "google" || code != "the-code" || state != "the-state" {
+				t.Fatalf("unexpected callback %s %s %s", provider, code, state)
+			}
+			return domain.AuthTokens{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer"}, nil
+		},
+	}
+	router := gin.New()
+	NewUserController(mockService, newTestAuth(t)).RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/google/callback?code=the-code&state=the-state", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var tokens domain.AuthTokens
+	_ = json.Unmarshal(w.Body.Bytes(), &tokens)
+	if tokens.AccessToken != "access" {
+		t.Fatalf("expected the access token, got %+v", tokens)
+	}
+}
+
+// TestCreateAPIKey_ReturnsKey tests that the raw key is returned on creation
+func TestCreateAPIKey_ReturnsKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	adminID := uuid.New()
+	mockService := &MockUserService{
+		CreateAPIKeyFunc: func(ctx context.Context, id uuid.UUID, in domain.APIKeyInput) (domain.CreatedAPIKey, error) {
+			if id != adminID {
+				t.Fatalf("expected the key to be created by %s, got %s", adminID, id)
+			}
+			return domain.CreatedAPIKey{APIKey: domain.APIKey{Name: in.Name, Scopes: in.Scopes}, Key: "bnk_secret"}, nil
+		},
+	}
+	ctl := NewUserController(mockService, newTestAuth(t)).(*userController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", adminID.String())
+	c.Request = httptest.NewRequest(http.MethodPost, "/admin/api-keys", strings.NewReader(`{"name":"warehouse","scopes":["books:write"]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	ctl.CreateAPIKey(c)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"key":"bnk_secret"`) {
+		t.Fatalf("expected the raw key in the response, got %s", w.Body.String())
+	}
+}
+
+// TestRevokeAPIKey_NotFound tests that unknown or revoked keys return 404
+func TestRevokeAPIKey_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		RevokeAPIKeyFunc: func(ctx context.Context, adminID, id uuid.UUID) error {
+			return domain.ErrAPIKeyNotFound
+		},
+	}
+	ctl := NewUserController(mockService, newTestAuth(t)).(*userController)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", uuid.NewString())
+	c.Params = gin.Params{{Key: "id", Value: uuid.NewString()}}
+	c.Request = httptest.NewRequest(http.MethodDelete, "/admin/api-keys/x", nil)
+
+	ctl.RevokeAPIKey(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+// TestRegister_WeakPassword tests that refused passwords are reported as bad requests
+func TestRegister_WeakPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := &MockUserService{
+		RegisterFunc: func(ctx context.Context, in domain.UserInput) error {
+			return fmt.Errorf("%w: use at least 8 characters", domain.ErrWeakPassword)
+		},
+	}
+
+	controller := NewUserController(mockService, newTestAuth(t))
+	router := gin.New()
+	controller.RegisterRoutes(router)
+
+	body, _ := json.Marshal(domain.UserInput{
+		FirstName: "Reader",
+		LastName:  "One",
+		Email:     "reader@example.com",
+		Mobile:    "+15550001234",
+		Password:  "secret",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,2 @@
+ALTER TABLE verification_tokens
+DROP COLUMN IF EXISTS attempts;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_audit_logs_target_user_id;
+DROP INDEX IF EXISTS idx_audit_logs_actor_id;
+DROP INDEX IF EXISTS idx_invitations_email;
+
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS invitations;
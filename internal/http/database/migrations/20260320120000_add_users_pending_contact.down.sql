@@ -0,0 +1,3 @@
+ALTER TABLE users
+DROP COLUMN IF EXISTS pending_mobile,
+DROP COLUMN IF EXISTS pending_email;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS pending_email VARCHAR(255) DEFAULT NULL,
+ADD COLUMN IF NOT EXISTS pending_mobile VARCHAR(255) DEFAULT NULL;
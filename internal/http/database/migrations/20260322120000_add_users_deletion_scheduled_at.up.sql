@@ -0,0 +1,7 @@
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMPTZ DEFAULT NULL;
+
+-- The purge job looks for accounts whose grace period ended
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_at
+ON users(deletion_scheduled_at)
+WHERE deletion_scheduled_at IS NOT NULL AND deleted_at IS NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_previous_passwords_user_id;
+
+DROP TABLE IF EXISTS previous_passwords;
@@ -0,0 +1,9 @@
+ALTER TABLE orders
+DROP COLUMN IF EXISTS billing_address,
+DROP COLUMN IF EXISTS shipping_address;
+
+DROP INDEX IF EXISTS idx_addresses_default_billing;
+DROP INDEX IF EXISTS idx_addresses_default_shipping;
+DROP INDEX IF EXISTS idx_addresses_user_id;
+
+DROP TABLE IF EXISTS addresses;
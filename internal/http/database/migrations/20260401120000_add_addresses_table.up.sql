@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS addresses (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    label VARCHAR(50) DEFAULT NULL,
+    full_name VARCHAR(255) NOT NULL,
+    line1 VARCHAR(255) NOT NULL,
+    line2 VARCHAR(255) DEFAULT NULL,
+    city VARCHAR(100) NOT NULL,
+    region VARCHAR(100) DEFAULT NULL,
+    postal_code VARCHAR(20) NOT NULL DEFAULT '',
+    country CHAR(2) NOT NULL,
+    phone VARCHAR(32) DEFAULT NULL,
+    is_default_shipping BOOLEAN NOT NULL DEFAULT FALSE,
+    is_default_billing BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- Foreign key --
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Indexes for performance
+CREATE INDEX idx_addresses_user_id ON addresses(user_id);
+
+-- At most one default of each kind per user
+CREATE UNIQUE INDEX idx_addresses_default_shipping ON addresses(user_id) WHERE is_default_shipping;
+CREATE UNIQUE INDEX idx_addresses_default_billing ON addresses(user_id) WHERE is_default_billing;
+
+-- Orders keep a copy of the addresses they were placed with
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS shipping_address JSONB DEFAULT NULL,
+ADD COLUMN IF NOT EXISTS billing_address JSONB DEFAULT NULL;
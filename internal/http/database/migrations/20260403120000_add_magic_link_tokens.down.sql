@@ -0,0 +1,5 @@
+ALTER TABLE verification_tokens
+DROP COLUMN IF EXISTS device_hash;
+
+-- Postgres cannot drop an enum value, so only the links go
+DELETE FROM verification_tokens WHERE type = 'LOGIN_MAGIC_LINK';
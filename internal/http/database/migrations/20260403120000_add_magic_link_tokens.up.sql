@@ -0,0 +1,5 @@
+ALTER TYPE VERIFICATION_TOKEN_TYPE ADD VALUE IF NOT EXISTS 'LOGIN_MAGIC_LINK';
+
+-- Hash of the device code a magic link is bound to, NULL when unbound
+ALTER TABLE verification_tokens
+ADD COLUMN IF NOT EXISTS device_hash TEXT NULL;
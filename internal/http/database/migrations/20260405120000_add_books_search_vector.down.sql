@@ -0,0 +1,14 @@
+DROP TRIGGER IF EXISTS trg_categories_search_vector ON categories;
+DROP TRIGGER IF EXISTS trg_publishers_search_vector ON publishers;
+DROP TRIGGER IF EXISTS trg_authors_search_vector ON authors;
+DROP TRIGGER IF EXISTS trg_book_categories_search_vector ON book_categories;
+DROP TRIGGER IF EXISTS trg_books_search_vector ON books;
+
+DROP FUNCTION IF EXISTS books_search_vector_trigger();
+DROP FUNCTION IF EXISTS refresh_book_search_vectors(UUID[]);
+DROP FUNCTION IF EXISTS book_search_document(UUID);
+
+DROP INDEX IF EXISTS idx_books_search_vector;
+
+ALTER TABLE books
+DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,79 @@
+ALTER TABLE books
+ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+-- Weighted search document of a book: title A, author B, publisher and
+-- categories C, description D
+CREATE OR REPLACE FUNCTION book_search_document(target_id UUID) RETURNS TSVECTOR AS $$
+  SELECT
+    setweight(to_tsvector('english', coalesce(b.name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(a.name, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(p.trading_name, '') || ' ' || coalesce(p.legal_name, '')), 'C') ||
+    setweight(to_tsvector('english', coalesce((
+      SELECT string_agg(c.name, ' ')
+      FROM book_categories bc
+      JOIN categories c ON c.id = bc.category_id
+      WHERE bc.book_id = b.id
+        AND bc.deleted_at IS NULL
+        AND c.deleted_at IS NULL
+    ), '')), 'C') ||
+    setweight(to_tsvector('english', coalesce(b.description, '')), 'D')
+  FROM books b
+  LEFT JOIN authors a ON a.id = b.author_id
+  LEFT JOIN publishers p ON p.id = b.publisher_id
+  WHERE b.id = target_id
+$$ LANGUAGE SQL STABLE;
+
+CREATE OR REPLACE FUNCTION refresh_book_search_vectors(target_ids UUID[]) RETURNS VOID AS $$
+  UPDATE books
+  SET search_vector = book_search_document(id)
+  WHERE id = ANY(target_ids)
+$$ LANGUAGE SQL;
+
+-- Keep the document in step with the book and everything it is built from
+CREATE OR REPLACE FUNCTION books_search_vector_trigger() RETURNS TRIGGER AS $$
+BEGIN
+  CASE TG_TABLE_NAME
+    WHEN 'books' THEN
+      PERFORM refresh_book_search_vectors(ARRAY[NEW.id]);
+    WHEN 'book_categories' THEN
+      IF TG_OP = 'DELETE' THEN
+        PERFORM refresh_book_search_vectors(ARRAY[OLD.book_id]);
+      ELSE
+        PERFORM refresh_book_search_vectors(ARRAY[NEW.book_id]);
+      END IF;
+    WHEN 'authors' THEN
+      PERFORM refresh_book_search_vectors(ARRAY(SELECT id FROM books WHERE author_id = NEW.id));
+    WHEN 'publishers' THEN
+      PERFORM refresh_book_search_vectors(ARRAY(SELECT id FROM books WHERE publisher_id = NEW.id));
+    WHEN 'categories' THEN
+      PERFORM refresh_book_search_vectors(ARRAY(SELECT book_id FROM book_categories WHERE category_id = NEW.id));
+  END CASE;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Listing the columns keeps the trigger's own update from firing it again
+CREATE TRIGGER trg_books_search_vector
+AFTER INSERT OR UPDATE OF name, description, author_id, publisher_id ON books
+FOR EACH ROW EXECUTE FUNCTION books_search_vector_trigger();
+
+CREATE TRIGGER trg_book_categories_search_vector
+AFTER INSERT OR UPDATE OR DELETE ON book_categories
+FOR EACH ROW EXECUTE FUNCTION books_search_vector_trigger();
+
+CREATE TRIGGER trg_authors_search_vector
+AFTER UPDATE OF name ON authors
+FOR EACH ROW EXECUTE FUNCTION books_search_vector_trigger();
+
+CREATE TRIGGER trg_publishers_search_vector
+AFTER UPDATE OF legal_name, trading_name ON publishers
+FOR EACH ROW EXECUTE FUNCTION books_search_vector_trigger();
+
+CREATE TRIGGER trg_categories_search_vector
+AFTER UPDATE OF name, deleted_at ON categories
+FOR EACH ROW EXECUTE FUNCTION books_search_vector_trigger();
+
+UPDATE books
+SET search_vector = book_search_document(id);
+
+CREATE INDEX IF NOT EXISTS idx_books_search_vector ON books USING GIN (search_vector);
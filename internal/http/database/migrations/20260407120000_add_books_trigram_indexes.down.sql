@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_authors_name_trgm;
+DROP INDEX IF EXISTS idx_books_name_trgm;
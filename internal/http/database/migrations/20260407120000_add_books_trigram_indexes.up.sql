@@ -0,0 +1,9 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Trigram indexes back the typo tolerant word similarity (<%) matching of
+-- GET /books/suggest
+CREATE INDEX IF NOT EXISTS idx_books_name_trgm
+ON books USING GIN (name gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_authors_name_trgm
+ON authors USING GIN (name gin_trgm_ops);
@@ -5,6 +5,7 @@ package routes
 // ====================
 const (
 	HealthRoute = "/health"
+	JWKSRoute   = "/.well-known/jwks.json"
 
 	BooksRoute  = "/books"
 	BookRoute   = "/book"
@@ -12,9 +13,21 @@ const (
 
 	UsersRoute = "/users"
 	UserRoute  = "/user/:id"
+	MeRoute    = "/me"
+
+	MeExportRoute         = "/me/export"
+	MeDeletionCancelRoute = "/me/deletion/cancel"
+	MeSessionsRoute       = "/me/sessions"
+	MeSessionRoute        = "/me/sessions/:id"
+	MeAddressesRoute      = "/me/addresses"
+	MeAddressRoute        = "/me/addresses/:id"
 
 	ForgotPassword       = "/forgot-password"
 	LoginRoute           = "/login"
+	LoginOTPRoute        = "/login/otp"
+	LoginOTPVerifyRoute  = "/login/otp/verify"
+	MagicLinkRoute       = "/login/magic-link"
+	MagicLinkVerifyRoute = "/login/magic-link/verify"
 	RegisterRoute        = "/register"
 	VerifyEmailRoute     = "/verify-email"
 	VerifyMobileRoute    = "/verify-mobile"
@@ -22,6 +35,18 @@ const (
 	ResendMobileOTPRoute = "/resend-mobile-otp"
 	ResetPasswordRoute   = "/reset-password"
 	ResetPasswordByToken = "/reset-password/confirm"
+	RefreshTokenRoute    = "/token/refresh"
+	LogoutRoute          = "/logout"
+	LogoutAllRoute       = "/logout-all"
+
+	OIDCLoginRoute    = "/auth/oidc/:provider"
+	OIDCCallbackRoute = "/auth/oidc/:provider/callback"
+
+	MFALoginRoute         = "/login/2fa"
+	MFASetupRoute         = "/2fa/setup"
+	MFAEnableRoute        = "/2fa/enable"
+	MFADisableRoute       = "/2fa/disable"
+	MFARecoveryCodesRoute = "/2fa/recovery-codes"
 
 	CartRoute      = "/cart"
 	CartItemsRoute = "/cart/items"
@@ -33,6 +58,18 @@ const (
 	OrderConfirmRoute  = "/orders/confirm"
 	AdminOrdersRoute   = "/admin/orders"
 
+	AdminUsersRoute                  = "/admin/users"
+	AdminUserUnlockRoute             = "/admin/users/:id/unlock"
+	AdminUserRoleRoute               = "/admin/users/:id/role"
+	AdminUserSuspendRoute            = "/admin/users/:id/suspend"
+	AdminUserReactivateRoute         = "/admin/users/:id/reactivate"
+	AdminUserForcePasswordResetRoute = "/admin/users/:id/force-password-reset"
+	AdminUserImpersonateRoute        = "/admin/users/:id/impersonate"
+	AdminInvitationsRoute            = "/admin/invitations"
+	AdminAPIKeysRoute                = "/admin/api-keys"
+	AdminAPIKeyRoute                 = "/admin/api-keys/:id"
+	AcceptInvitationRoute            = "/invitations/accept"
+
 	AuthorsRoute    = "/authors"
 	AuthorByIDRoute = "/authors/:id"
 
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"booknest/internal/domain"
+)
+
+// APIKeyHeader carries the key of integration clients
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator resolves a raw API key to the user it acts as
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, rawKey string) (domain.APIKeyPrincipal, error)
+}
+
+// JWTOrAPIKeyAuthMiddleware accepts either a Bearer JWT or an X-API-Key
+// granting scope, and injects the same user info as JWTAuthMiddleware
+func (a *Auth) JWTOrAPIKeyAuthMiddleware(scope domain.APIKeyScope) gin.HandlerFunc {
+	jwtMiddleware := a.jwtAuth(false)
+
+	return func(ctx *gin.Context) {
+		rawKey := ctx.GetHeader(APIKeyHeader)
+		if rawKey == "" {
+			jwtMiddleware(ctx)
+			return
+		}
+
+		principal, err := a.apiKeys.AuthenticateAPIKey(ctx, rawKey)
+		if errors.Is(err, domain.ErrInvalidAPIKey) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			ctx.Abort()
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not verify api key"})
+			ctx.Abort()
+			return
+		}
+
+		if !principal.HasScope(scope) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "api key lacks the " + string(scope) + " scope"})
+			ctx.Abort()
+			return
+		}
+
+		// Attach user info to context for downstream use
+		ctx.Set("user_id", principal.UserID.String())
+		ctx.Set("user_role", string(principal.Role))
+		ctx.Set("api_key_id", principal.KeyID.String())
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+)
+
+type stubAPIKeyAuthenticator struct {
+	keys map[string]domain.APIKeyPrincipal
+}
+
+func (s stubAPIKeyAuthenticator) AuthenticateAPIKey(ctx context.Context, rawKey string) (domain.APIKeyPrincipal, error) {
+	principal, ok := s.keys[rawKey]
+	if !ok {
+		return domain.APIKeyPrincipal{}, domain.ErrInvalidAPIKey
+	}
+	return principal, nil
+}
+
+// Test that API keys need the scope of the route
+func TestJWTOrAPIKeyAuthMiddleware_APIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	adminID := uuid.New()
+
+	auth := newTestAuth()
+	auth.apiKeys = stubAPIKeyAuthenticator{keys: map[string]domain.APIKeyPrincipal{
+		"bnk_books": {UserID: adminID, Role: domain.UserRoleAdmin, Scopes: []domain.APIKeyScope{domain.ScopeBooksWrite}},
+	}}
+
+	tests := []struct {
+		name string
+		key  string
+		want int
+	}{
+		{"key with scope", "bnk_books", http.StatusOK},
+		{"unknown key", "bnk_unknown", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(auth.JWTOrAPIKeyAuthMiddleware(domain.ScopeBooksWrite), RequireAdmin())
+			r.POST("/books", func(c *gin.Context) {
+				if got := c.GetString("user_id"); got != adminID.String() {
+					t.Fatalf("expected user_id %s, got %q", adminID, got)
+				}
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/books", nil)
+			req.Header.Set(APIKeyHeader, tt.key)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected %d got %d", tt.want, w.Code)
+			}
+		})
+	}
+
+	// The same key is refused where its scope does not reach
+	r := gin.New()
+	r.Use(auth.JWTOrAPIKeyAuthMiddleware(domain.ScopeOrdersRead))
+	r.GET("/admin/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/orders", nil)
+	req.Header.Set(APIKeyHeader, "bnk_books")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 got %d", w.Code)
+	}
+}
+
+// Test that Bearer tokens keep working on routes that accept API keys
+func TestJWTOrAPIKeyAuthMiddleware_JWT(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Signing first sets the secret the middleware loads
+	token := sessionToken(t, jwt.MapClaims{"user_id": "some-id"})
+
+	r := gin.New()
+	r.Use(newTestAuth().JWTOrAPIKeyAuthMiddleware(domain.ScopeBooksWrite))
+	r.GET("/private", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/private", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", w.Code)
+	}
+
+	// Without any credentials the JWT error is returned
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/private", nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 got %d", w.Code)
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import "errors"
+
+// Auth builds the middlewares that authenticate requests. Every store it
+// checks tokens and keys against is required, so a missed wiring call
+// stops the server at startup instead of skipping a check.
+type Auth struct {
+	sessions     SessionStore
+	apiKeys      APIKeyAuthenticator
+	verification VerificationChecker
+	audit        AuditRecorder
+}
+
+// NewAuth creates the authentication middlewares
+func NewAuth(
+	sessions SessionStore,
+	apiKeys APIKeyAuthenticator,
+	verification VerificationChecker,
+	audit AuditRecorder,
+) (*Auth, error) {
+	switch {
+	case sessions == nil:
+		return nil, errors.New("auth middleware: session store is required")
+	case apiKeys == nil:
+		return nil, errors.New("auth middleware: api key authenticator is required")
+	case verification == nil:
+		return nil, errors.New("auth middleware: verification checker is required")
+	case audit == nil:
+		return nil, errors.New("auth middleware: audit recorder is required")
+	}
+
+	return &Auth{
+		sessions:     sessions,
+		apiKeys:      apiKeys,
+		verification: verification,
+		audit:        audit,
+	}, nil
+}
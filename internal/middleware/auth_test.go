@@ -0,0 +1,43 @@
+package middleware
+
+import "testing"
+
+// newTestAuth returns middlewares whose stores let every request through
+func newTestAuth() *Auth {
+	return &Auth{
+		sessions:     stubSessionStore{},
+		apiKeys:      stubAPIKeyAuthenticator{},
+		verification: stubVerificationChecker{},
+		audit:        &stubAuditRecorder{},
+	}
+}
+
+// Test that the middlewares cannot be built with a dependency missing
+func TestNewAuth(t *testing.T) {
+	tests := []struct {
+		name         string
+		sessions     SessionStore
+		apiKeys      APIKeyAuthenticator
+		verification VerificationChecker
+		audit        AuditRecorder
+		wantErr      bool
+	}{
+		{"all dependencies", stubSessionStore{}, stubAPIKeyAuthenticator{}, stubVerificationChecker{}, &stubAuditRecorder{}, false},
+		{"no session store", nil, stubAPIKeyAuthenticator{}, stubVerificationChecker{}, &stubAuditRecorder{}, true},
+		{"no api key authenticator", stubSessionStore{}, nil, stubVerificationChecker{}, &stubAuditRecorder{}, true},
+		{"no verification checker", stubSessionStore{}, stubAPIKeyAuthenticator{}, nil, &stubAuditRecorder{}, true},
+		{"no audit recorder", stubSessionStore{}, stubAPIKeyAuthenticator{}, stubVerificationChecker{}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := NewAuth(tt.sessions, tt.apiKeys, tt.verification, tt.audit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && auth == nil {
+				t.Fatalf("expected middlewares")
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+)
+
+// AuditRecorder stores audit log entries
+type AuditRecorder interface {
+	Create(ctx context.Context, log *domain.AuditLog) error
+}
+
+// DenyImpersonation blocks the route for admins acting as a user, for
+// actions the customer has to take themselves. It runs after the JWT
+// middleware.
+func DenyImpersonation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, impersonated := ctx.Get("impersonator_id"); impersonated {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "not allowed while impersonating a user"})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// recordImpersonatedRequest logs and audits a finished request made by an
+// admin acting as a user, including the refused ones
+func (a *Auth) recordImpersonatedRequest(ctx *gin.Context, impersonatorID uuid.UUID) {
+	userID, _ := contextUserID(ctx)
+	path := ctx.FullPath()
+	if path == "" {
+		path = ctx.Request.URL.Path
+	}
+	status := ctx.Writer.Status()
+
+	slog.Info("Impersonated request",
+		"impersonator_id", impersonatorID,
+		"user_id", userID,
+		"method", ctx.Request.Method,
+		"path", ctx.Request.URL.Path,
+		"status", status,
+	)
+
+	err := a.audit.Create(ctx, &domain.AuditLog{
+		ActorID:      impersonatorID,
+		Action:       domain.AuditImpersonatedRequest,
+		TargetUserID: &userID,
+		Details: map[string]string{
+			"method": ctx.Request.Method,
+			"route":  path,
+			"path":   ctx.Request.URL.Path,
+			"status": strconv.Itoa(status),
+		},
+	})
+	if err != nil {
+		// The response is already written, so only the log is left
+		slog.Error("Cannot audit impersonated request", "impersonator_id", impersonatorID, "error", err)
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+)
+
+type stubAuditRecorder struct {
+	logs []*domain.AuditLog
+}
+
+func (s *stubAuditRecorder) Create(ctx context.Context, log *domain.AuditLog) error {
+	s.logs = append(s.logs, log)
+	return nil
+}
+
+func impersonationToken(t *testing.T, userID, adminID uuid.UUID) string {
+	t.Helper()
+	return sessionToken(t, jwt.MapClaims{
+		"user_id":   userID.String(),
+		"user_role": string(domain.UserRoleUser),
+		"token_use": domain.TokenUseImpersonation,
+		"act":       map[string]string{"sub": adminID.String()},
+	})
+}
+
+func impersonationRouter(recorder AuditRecorder) *gin.Engine {
+	auth := newTestAuth()
+	auth.audit = recorder
+
+	r := gin.New()
+	r.Use(auth.JWTAuthMiddleware())
+	r.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/orders/checkout", DenyImpersonation(), func(c *gin.Context) { c.Status(http.StatusCreated) })
+	return r
+}
+
+// Test that impersonated requests are audited and destructive routes blocked
+func TestJWTAuthMiddleware_Impersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID, adminID := uuid.New(), uuid.New()
+	token := impersonationToken(t, userID, adminID)
+
+	recorder := &stubAuditRecorder{}
+	r := impersonationRouter(recorder)
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/orders", http.StatusOK},
+		{http.MethodPost, "/orders/checkout", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Fatalf("%s %s: expected %d got %d", tt.method, tt.path, tt.want, w.Code)
+		}
+	}
+
+	if len(recorder.logs) != 2 {
+		t.Fatalf("expected both requests to be audited, got %d", len(recorder.logs))
+	}
+	for i, log := range recorder.logs {
+		if log.ActorID != adminID || log.TargetUserID == nil || *log.TargetUserID != userID || log.Action != domain.AuditImpersonatedRequest {
+			t.Fatalf("unexpected audit log %+v", log)
+		}
+		if log.Details["route"] != tests[i].path {
+			t.Fatalf("expected route %s, got %s", tests[i].path, log.Details["route"])
+		}
+	}
+	if recorder.logs[1].Details["status"] != "403" {
+		t.Fatalf("expected the refused checkout to be audited with its status")
+	}
+}
+
+// Test that regular tokens pass DenyImpersonation and are not audited
+func TestDenyImpersonation_RegularToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token := sessionToken(t, jwt.MapClaims{"user_id": uuid.NewString()})
+
+	recorder := &stubAuditRecorder{}
+
+	req := httptest.NewRequest(http.MethodPost, "/orders/checkout", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	impersonationRouter(recorder).ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 got %d", w.Code)
+	}
+	if len(recorder.logs) != 0 {
+		t.Fatalf("expected no audit log")
+	}
+}
+
+// Test that impersonation tokens without an actor are rejected
+func TestJWTAuthMiddleware_ImpersonationWithoutActor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token := sessionToken(t, jwt.MapClaims{
+		"user_id":   uuid.NewString(),
+		"token_use": domain.TokenUseImpersonation,
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	impersonationRouter(&stubAuditRecorder{}).ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 got %d", w.Code)
+	}
+}
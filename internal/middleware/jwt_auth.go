@@ -1,17 +1,43 @@
 package middleware
 
 import (
+	"log"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/jwtkeys"
 )
 
 // JWTAuthMiddleware verifies JWT token and injects user info into context
-func JWTAuthMiddleware() gin.HandlerFunc {
+func (a *Auth) JWTAuthMiddleware() gin.HandlerFunc {
+	return a.jwtAuth(false)
+}
+
+// MFAPendingAuthMiddleware also accepts the limited token issued between
+// the password and the second factor, so admins can enroll an authenticator
+func (a *Auth) MFAPendingAuthMiddleware() gin.HandlerFunc {
+	return a.jwtAuth(true)
+}
+
+func (a *Auth) jwtAuth(allowMFAPending bool) gin.HandlerFunc {
+	// Load the verification keys once per middleware instance
+	keys, keysErr := jwtkeys.FromEnv()
+	if keysErr != nil {
+		log.Printf("jwt keys: %v", keysErr)
+	}
+
 	return func(ctx *gin.Context) {
+		if keysErr != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "authentication is not configured"})
+			ctx.Abort()
+			return
+		}
+
 		// Extract token from Authorization header
 		authHeader := ctx.GetHeader("Authorization")
 
@@ -26,24 +52,8 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 		// Get the token string
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		// Parse and validate token
-		secret := os.Getenv("JWT_SECRET")
-		if secret == "" {
-			// Backward-compatible env key used in older local setups.
-			secret = os.Getenv("JWT_AUTH_SECRET")
-		}
-		if secret == "" {
-			secret = "booknest_secret" // fallback for local dev
-		}
-
-		// Parse token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(secret), nil
-		})
+		// Parse and validate token against the key named by its kid
+		token, err := keys.Parse(tokenString)
 
 		// Check for parsing errors or invalid token
 		if err != nil || !token.Valid {
@@ -60,11 +70,73 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Tokens waiting for the second factor only open enrollment routes
+		if claims["token_use"] == domain.TokenUseMFAPending && !allowMFAPending {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "two-factor authentication required"})
+			ctx.Abort()
+			return
+		}
+
+		// Logging a device out also ends its access tokens
+		sessionID, hasSession := sessionClaim(claims)
+		if hasSession {
+			revoked, err := a.sessions.IsRevoked(ctx, sessionID)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not verify session"})
+				ctx.Abort()
+				return
+			}
+			if revoked {
+				ctx.JSON(http.StatusUnauthorized, gin.H{"error": "session has been revoked"})
+				ctx.Abort()
+				return
+			}
+		}
+
+		// Impersonation tokens must name the admin acting as the user
+		impersonatorID, impersonated := impersonatorClaim(claims)
+		if claims["token_use"] == domain.TokenUseImpersonation && !impersonated {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+			ctx.Abort()
+			return
+		}
+
 		// Attach user info to context for downstream use
 		ctx.Set("user_id", claims["user_id"])
 		ctx.Set("email", claims["email"])
 		ctx.Set("user_role", claims["user_role"])
+		if hasSession {
+			ctx.Set("session_id", sessionID.String())
+		}
+		if impersonated {
+			ctx.Set("impersonator_id", impersonatorID.String())
+		}
 
 		ctx.Next()
+
+		if impersonated {
+			a.recordImpersonatedRequest(ctx, impersonatorID)
+		}
 	}
 }
+
+// sessionClaim reads the sid claim; tokens issued before sessions were
+// recorded and mfa_pending tokens have none
+func sessionClaim(claims jwt.MapClaims) (uuid.UUID, bool) {
+	raw, _ := claims["sid"].(string)
+	id, err := uuid.Parse(raw)
+	return id, err == nil
+}
+
+// impersonatorClaim reads the admin ID from the act claim of
+// impersonation tokens
+func impersonatorClaim(claims jwt.MapClaims) (uuid.UUID, bool) {
+	if claims["token_use"] != domain.TokenUseImpersonation {
+		return uuid.Nil, false
+	}
+
+	act, _ := claims["act"].(map[string]interface{})
+	raw, _ := act["sub"].(string)
+	id, err := uuid.Parse(raw)
+	return id, err == nil
+}
@@ -1,10 +1,16 @@
 package middleware
 
 import (
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/x509"
+    "encoding/pem"
     "net/http"
     "net/http/httptest"
     "os"
+    "path/filepath"
     "testing"
+    "time"
 
     "github.com/gin-gonic/gin"
     "github.com/golang-jwt/jwt/v5"
@@ -13,7 +19,7 @@ import (
 func TestJWTAuthMiddleware_MissingHeader(t *testing.T) {
     gin.SetMode(gin.TestMode)
     r := gin.New()
-    r.Use(JWTAuthMiddleware())
+    r.Use(newTestAuth().JWTAuthMiddleware())
     r.GET("/private", func(c *gin.Context) { c.Status(200) })
 
     req := httptest.NewRequest("GET", "/private", nil)
@@ -28,7 +34,7 @@ func TestJWTAuthMiddleware_MissingHeader(t *testing.T) {
 func TestJWTAuthMiddleware_InvalidToken(t *testing.T) {
     gin.SetMode(gin.TestMode)
     r := gin.New()
-    r.Use(JWTAuthMiddleware())
+    r.Use(newTestAuth().JWTAuthMiddleware())
     r.GET("/private", func(c *gin.Context) { c.Status(200) })
 
     req := httptest.NewRequest("GET", "/private", nil)
@@ -57,7 +63,7 @@ func TestJWTAuthMiddleware_ValidToken(t *testing.T) {
     }
 
     r := gin.New()
-    r.Use(JWTAuthMiddleware())
+    r.Use(newTestAuth().JWTAuthMiddleware())
     r.GET("/private", func(c *gin.Context) {
         // handler should see user info set by middleware
         if _, ok := c.Get("user_id"); !ok {
@@ -78,3 +84,103 @@ func TestJWTAuthMiddleware_ValidToken(t *testing.T) {
         t.Fatalf("expected 200 got %d", w.Code)
     }
 }
+
+func TestJWTAuthMiddleware_RS256Token(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+
+    private, err := rsa.GenerateKey(rand.Reader, 2048)
+    if err != nil {
+        t.Fatalf("failed to generate key: %v", err)
+    }
+    keyPath := filepath.Join(t.TempDir(), "signing.pem")
+    pemBytes := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)})
+    if err := os.WriteFile(keyPath, pemBytes, 0o600); err != nil {
+        t.Fatalf("failed to write key: %v", err)
+    }
+    t.Setenv("JWT_SIGNING_KEY_FILE", keyPath)
+    t.Setenv("JWT_SIGNING_KEY_ID", "test-kid")
+
+    r := gin.New()
+    r.Use(newTestAuth().JWTAuthMiddleware())
+    r.GET("/private", func(c *gin.Context) { c.Status(200) })
+
+    sign := func(kid string, method jwt.SigningMethod, key interface{}) string {
+        token := jwt.NewWithClaims(method, jwt.MapClaims{
+            "user_id": "some-id",
+            "exp":     time.Now().Add(time.Minute).Unix(),
+        })
+        token.Header["kid"] = kid
+        s, err := token.SignedString(key)
+        if err != nil {
+            t.Fatalf("failed to sign token: %v", err)
+        }
+        return s
+    }
+
+    tests := []struct {
+        name     string
+        token    string
+        expected int
+    }{
+        {"valid RS256 token", sign("test-kid", jwt.SigningMethodRS256, private), http.StatusOK},
+        {"unknown kid", sign("other-kid", jwt.SigningMethodRS256, private), http.StatusUnauthorized},
+        {"HMAC token is rejected", sign("test-kid", jwt.SigningMethodHS256, []byte("booknest_secret")), http.StatusUnauthorized},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            req := httptest.NewRequest("GET", "/private", nil)
+            req.Header.Set("Authorization", "Bearer "+tt.token)
+            w := httptest.NewRecorder()
+            r.ServeHTTP(w, req)
+
+            if w.Code != tt.expected {
+                t.Fatalf("expected %d got %d", tt.expected, w.Code)
+            }
+        })
+    }
+}
+
+func TestJWTAuthMiddleware_MFAPendingToken(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    t.Setenv("JWT_SIGNING_KEY_FILE", "")
+    t.Setenv("JWT_SECRET", "test_jwt_secret")
+
+    token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+        "user_id":   "some-id",
+        "user_role": "ADMIN",
+        "token_use": "mfa_pending",
+        "exp":       time.Now().Add(time.Minute).Unix(),
+    })
+    s, err := token.SignedString([]byte("test_jwt_secret"))
+    if err != nil {
+        t.Fatalf("failed to sign token: %v", err)
+    }
+
+    auth := newTestAuth()
+    r := gin.New()
+    r.GET("/private", auth.JWTAuthMiddleware(), func(c *gin.Context) { c.Status(200) })
+    r.POST("/2fa/setup", auth.MFAPendingAuthMiddleware(), func(c *gin.Context) { c.Status(200) })
+
+    tests := []struct {
+        method   string
+        path     string
+        expected int
+    }{
+        {"GET", "/private", http.StatusUnauthorized},
+        {"POST", "/2fa/setup", http.StatusOK},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.path, func(t *testing.T) {
+            req := httptest.NewRequest(tt.method, tt.path, nil)
+            req.Header.Set("Authorization", "Bearer "+s)
+            w := httptest.NewRecorder()
+            r.ServeHTTP(w, req)
+
+            if w.Code != tt.expected {
+                t.Fatalf("expected %d got %d", tt.expected, w.Code)
+            }
+        })
+    }
+}
@@ -4,33 +4,57 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	"booknest/internal/domain"
 )
 
 func RequireAdmin() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		raw, exists := ctx.Get("user_role")
-		if !exists {
+		role, ok := contextRole(ctx)
+		if !ok {
 			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 			ctx.Abort()
 			return
 		}
 
-		role := domain.UserRole("")
-		switch v := raw.(type) {
-		case string:
-			role = domain.UserRole(v)
-		case domain.UserRole:
-			role = v
-		default:
+		if role != domain.UserRoleAdmin {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequireSelfOrAdmin lets users through only when the path parameter
+// param holds their own user_id. Admins may reach any user.
+func RequireSelfOrAdmin(param string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		role, ok := contextRole(ctx)
+		if !ok {
 			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 			ctx.Abort()
 			return
 		}
 
-		if role != domain.UserRoleAdmin {
-			ctx.JSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+		if role == domain.UserRoleAdmin {
+			ctx.Next()
+			return
+		}
+
+		self, ok := contextUserID(ctx)
+		if !ok {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			ctx.Abort()
+			return
+		}
+
+		// Compare parsed IDs so the casing of the path does not matter
+		target, err := uuid.Parse(ctx.Param(param))
+		if err != nil || target != self {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "you can only access your own account"})
 			ctx.Abort()
 			return
 		}
@@ -38,3 +62,35 @@ func RequireAdmin() gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// contextRole reads the role set by the JWT middleware
+func contextRole(ctx *gin.Context) (domain.UserRole, bool) {
+	raw, exists := ctx.Get("user_role")
+	if !exists {
+		return "", false
+	}
+
+	switch v := raw.(type) {
+	case string:
+		return domain.UserRole(v), true
+	case domain.UserRole:
+		return v, true
+	default:
+		return "", false
+	}
+}
+
+// contextUserID reads the user_id set by the JWT middleware
+func contextUserID(ctx *gin.Context) (uuid.UUID, bool) {
+	raw, _ := ctx.Get("user_id")
+
+	switch v := raw.(type) {
+	case string:
+		id, err := uuid.Parse(v)
+		return id, err == nil
+	case uuid.UUID:
+		return v, true
+	default:
+		return uuid.Nil, false
+	}
+}
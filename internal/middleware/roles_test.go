@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+)
+
+func selfOrAdminRouter(userID string, role domain.UserRole) *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Set("user_role", string(role))
+		c.Next()
+	})
+	r.GET("/user/:id", RequireSelfOrAdmin("id"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+// Test that RequireSelfOrAdmin lets users reach only their own ID
+func TestRequireSelfOrAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	self := uuid.New()
+
+	tests := []struct {
+		name   string
+		role   domain.UserRole
+		target string
+		want   int
+	}{
+		{"own id", domain.UserRoleUser, self.String(), http.StatusOK},
+		{"own id upper case", domain.UserRoleUser, strings.ToUpper(self.String()), http.StatusOK},
+		{"other id", domain.UserRoleUser, uuid.NewString(), http.StatusForbidden},
+		{"invalid id", domain.UserRoleUser, "not-a-uuid", http.StatusForbidden},
+		{"admin", domain.UserRoleAdmin, uuid.NewString(), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			selfOrAdminRouter(self.String(), tt.role).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/"+tt.target, nil))
+
+			if w.Code != tt.want {
+				t.Fatalf("expected %d got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
+
+// Test that RequireSelfOrAdmin rejects requests without JWT claims
+func TestRequireSelfOrAdmin_NoClaims(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/user/:id", RequireSelfOrAdmin("id"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/"+uuid.NewString(), nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 got %d", w.Code)
+	}
+}
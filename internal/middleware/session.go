@@ -0,0 +1,12 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// SessionStore tells whether the login session behind an access token was revoked
+type SessionStore interface {
+	IsRevoked(ctx context.Context, id uuid.UUID) (bool, error)
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+type stubSessionStore struct {
+	revoked map[uuid.UUID]bool
+}
+
+func (s stubSessionStore) IsRevoked(ctx context.Context, id uuid.UUID) (bool, error) {
+	return s.revoked[id], nil
+}
+
+func sessionToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	os.Setenv("JWT_SECRET", "test_jwt_secret")
+
+	s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test_jwt_secret"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return s
+}
+
+// Test that access tokens of revoked sessions are rejected
+func TestJWTAuthMiddleware_RevokedSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	active, revoked := uuid.New(), uuid.New()
+
+	auth := newTestAuth()
+	auth.sessions = stubSessionStore{revoked: map[uuid.UUID]bool{revoked: true}}
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		want   int
+	}{
+		{"active session", jwt.MapClaims{"user_id": "some-id", "sid": active.String()}, http.StatusOK},
+		{"revoked session", jwt.MapClaims{"user_id": "some-id", "sid": revoked.String()}, http.StatusUnauthorized},
+		{"no session claim", jwt.MapClaims{"user_id": "some-id"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(auth.JWTAuthMiddleware())
+			r.GET("/private", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/private", nil)
+			req.Header.Set("Authorization", "Bearer "+sessionToken(t, tt.claims))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected %d got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
+
+// Test that the session ID is passed on to handlers
+func TestJWTAuthMiddleware_SetsSessionID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sessionID := uuid.New()
+
+	r := gin.New()
+	r.Use(newTestAuth().JWTAuthMiddleware())
+	r.GET("/private", func(c *gin.Context) {
+		if got := c.GetString("session_id"); got != sessionID.String() {
+			t.Fatalf("expected session_id %s, got %q", sessionID, got)
+		}
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/private", nil)
+	req.Header.Set("Authorization", "Bearer "+sessionToken(t, jwt.MapClaims{"user_id": "some-id", "sid": sessionID.String()}))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", w.Code)
+	}
+}
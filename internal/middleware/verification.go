@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+)
+
+// VerificationChecker tells whether a user verified the channels an action needs
+type VerificationChecker interface {
+	CheckVerified(ctx context.Context, userID uuid.UUID, action domain.ProtectedAction) error
+}
+
+// RequireVerified rejects users who have not verified the channels the
+// action needs, telling the client which ones are missing. It runs after
+// the JWT middleware.
+func (a *Auth) RequireVerified(action domain.ProtectedAction) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID, ok := contextUserID(ctx)
+		if !ok {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			ctx.Abort()
+			return
+		}
+
+		err := a.verification.CheckVerified(ctx, userID, action)
+		if RespondVerificationRequired(ctx, err) {
+			ctx.Abort()
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not check verification"})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RespondVerificationRequired writes a 403 naming the channels the user
+// has to verify before the action. Handlers that enforce the policy
+// themselves use it too, so clients always get the same body.
+func RespondVerificationRequired(ctx *gin.Context, err error) bool {
+	var verifyErr *domain.VerificationRequiredError
+	if !errors.As(err, &verifyErr) {
+		return false
+	}
+
+	ctx.JSON(http.StatusForbidden, gin.H{
+		"error":   verifyErr.Error(),
+		"action":  verifyErr.Action,
+		"missing": verifyErr.Missing,
+	})
+	return true
+}
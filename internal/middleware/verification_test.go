@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+)
+
+type stubVerificationChecker struct {
+	err error
+}
+
+func (s stubVerificationChecker) CheckVerified(ctx context.Context, userID uuid.UUID, action domain.ProtectedAction) error {
+	return s.err
+}
+
+func verifiedRouter(checker VerificationChecker, userID string) *gin.Engine {
+	auth := newTestAuth()
+	auth.verification = checker
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if userID != "" {
+			c.Set("user_id", userID)
+		}
+		c.Next()
+	})
+	r.POST("/checkout", auth.RequireVerified(domain.ActionCheckout), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+// Test that RequireVerified tells the client which channels are missing
+func TestRequireVerified_Missing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	checker := stubVerificationChecker{err: &domain.VerificationRequiredError{
+		Action:  domain.ActionCheckout,
+		Missing: []domain.VerificationChannel{domain.ChannelMobile},
+	}}
+
+	w := httptest.NewRecorder()
+	verifiedRouter(checker, uuid.NewString()).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/checkout", nil))
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 got %d", w.Code)
+	}
+
+	var body struct {
+		Action  string   `json:"action"`
+		Missing []string `json:"missing"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Action != "checkout" || len(body.Missing) != 1 || body.Missing[0] != "mobile" {
+		t.Fatalf("unexpected body %s", w.Body.String())
+	}
+}
+
+// Test that RequireVerified passes verified users and guards its own failures
+func TestRequireVerified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		checker VerificationChecker
+		userID  string
+		want    int
+	}{
+		{"verified", stubVerificationChecker{}, uuid.NewString(), http.StatusOK},
+		{"no claims", stubVerificationChecker{}, "", http.StatusUnauthorized},
+		{"lookup error", stubVerificationChecker{err: errors.New("connection reset")}, uuid.NewString(), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			verifiedRouter(tt.checker, tt.userID).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/checkout", nil))
+
+			if w.Code != tt.want {
+				t.Fatalf("expected %d got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package jwtkeys
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// config holds the environment values the key ring is built from
+type config struct {
+	signingKeyFile   string
+	signingKeyID     string
+	verificationKeys string
+	secret           string
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[config]*KeyRing{}
+)
+
+// FromEnv builds the key ring from the environment:
+//
+//	JWT_SIGNING_KEY_FILE        PEM encoded RSA (RS256) or Ed25519 (EdDSA) private key
+//	JWT_SIGNING_KEY_ID          kid of the signing key (derived from the key when empty)
+//	JWT_VERIFICATION_KEY_FILES  comma separated "kid=path" PEM keys still accepted during rotation
+//
+// Without a signing key file it falls back to HS256 with JWT_SECRET.
+// Key rings are cached per configuration, so key files are read once.
+func FromEnv() (*KeyRing, error) {
+	cfg := config{
+		signingKeyFile:   os.Getenv("JWT_SIGNING_KEY_FILE"),
+		signingKeyID:     os.Getenv("JWT_SIGNING_KEY_ID"),
+		verificationKeys: os.Getenv("JWT_VERIFICATION_KEY_FILES"),
+		secret:           os.Getenv("JWT_SECRET"),
+	}
+	if cfg.secret == "" {
+		// Backward-compatible env key used in older local setups.
+		cfg.secret = os.Getenv("JWT_AUTH_SECRET")
+	}
+	if cfg.secret == "" {
+		cfg.secret = "booknest_secret" // fallback for local dev
+	}
+
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	if ring, ok := cache[cfg]; ok {
+		return ring, nil
+	}
+
+	ring, err := load(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	cache[cfg] = ring
+	return ring, nil
+}
+
+func load(cfg config) (*KeyRing, error) {
+	// Legacy shared secret
+	if cfg.signingKeyFile == "" {
+		return NewKeyRing(NewHMACKey([]byte(cfg.secret)))
+	}
+
+	signing, err := loadKeyFile(cfg.signingKeyID, cfg.signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if signing.signer == nil {
+		return nil, errors.New("jwtkeys: JWT_SIGNING_KEY_FILE must contain a private key")
+	}
+
+	var verification []*Key
+	for _, entry := range strings.Split(cfg.verificationKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, path, found := strings.Cut(entry, "=")
+		if !found {
+			id, path = "", entry
+		}
+
+		key, err := loadKeyFile(strings.TrimSpace(id), strings.TrimSpace(path))
+		if err != nil {
+			return nil, err
+		}
+		verification = append(verification, key)
+	}
+
+	return NewKeyRing(signing, verification...)
+}
+
+// loadKeyFile reads a PEM private or public key
+func loadKeyFile(id, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwtkeys: read %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("jwtkeys: %s is not PEM encoded", path)
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwtkeys: parse %s: %w", path, err)
+		}
+		return NewPrivateKey(id, private)
+	case "RSA PRIVATE KEY":
+		private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwtkeys: parse %s: %w", path, err)
+		}
+		return NewPrivateKey(id, private)
+	case "PUBLIC KEY":
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwtkeys: parse %s: %w", path, err)
+		}
+		return NewPublicKey(id, public)
+	case "RSA PUBLIC KEY":
+		public, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwtkeys: parse %s: %w", path, err)
+		}
+		return NewPublicKey(id, public)
+	default:
+		return nil, fmt.Errorf("jwtkeys: unsupported PEM block %q in %s", block.Type, path)
+	}
+}
@@ -0,0 +1,79 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func TestFromEnv_HMACFallback(t *testing.T) {
+	t.Setenv("JWT_SIGNING_KEY_FILE", "")
+	t.Setenv("JWT_SECRET", "env-secret")
+
+	ring, err := FromEnv()
+	require.NoError(t, err)
+	require.Equal(t, "HS256", ring.Algorithm())
+
+	again, err := FromEnv()
+	require.NoError(t, err)
+	require.Same(t, ring, again, "expected the key ring to be cached")
+}
+
+func TestFromEnv_SigningAndVerificationKeys(t *testing.T) {
+	signing, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signingPath := writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(signing))
+
+	previous, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	previousDER, err := x509.MarshalPKIXPublicKey(previous)
+	require.NoError(t, err)
+	previousPath := writePEM(t, "PUBLIC KEY", previousDER)
+
+	t.Setenv("JWT_SIGNING_KEY_FILE", signingPath)
+	t.Setenv("JWT_SIGNING_KEY_ID", "2026-03")
+	t.Setenv("JWT_VERIFICATION_KEY_FILES", "2026-01="+previousPath)
+
+	ring, err := FromEnv()
+	require.NoError(t, err)
+	require.Equal(t, "RS256", ring.Algorithm())
+
+	set := ring.JWKS()
+	require.Len(t, set.Keys, 2)
+	require.Equal(t, "2026-01", set.Keys[0].Kid)
+	require.Equal(t, "2026-03", set.Keys[1].Kid)
+}
+
+func TestFromEnv_PublicSigningKey(t *testing.T) {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+
+	t.Setenv("JWT_SIGNING_KEY_FILE", writePEM(t, "PUBLIC KEY", der))
+	t.Setenv("JWT_VERIFICATION_KEY_FILES", "")
+
+	_, err = FromEnv()
+	require.Error(t, err)
+}
+
+func TestFromEnv_MissingFile(t *testing.T) {
+	t.Setenv("JWT_SIGNING_KEY_FILE", filepath.Join(t.TempDir(), "missing.pem"))
+
+	_, err := FromEnv()
+	require.Error(t, err)
+}
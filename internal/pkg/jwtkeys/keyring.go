@@ -0,0 +1,149 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a single JWT key identified by its kid
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	// signer is the private key (or HMAC secret) and is nil for verify-only keys
+	signer any
+	// verifier is the public key (or HMAC secret)
+	verifier any
+}
+
+// KeyRing signs tokens with one key and verifies tokens signed by any of its keys.
+// Keeping the previous and the next key in the ring allows rotation without
+// invalidating tokens that are still in flight.
+type KeyRing struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewKeyRing creates a key ring. The signing key is always accepted for verification.
+func NewKeyRing(signing *Key, verification ...*Key) (*KeyRing, error) {
+	if signing == nil || signing.signer == nil {
+		return nil, errors.New("jwtkeys: a signing key is required")
+	}
+
+	ring := &KeyRing{
+		signing: signing,
+		keys:    map[string]*Key{signing.ID: signing},
+	}
+
+	for _, key := range verification {
+		if _, exists := ring.keys[key.ID]; exists {
+			return nil, fmt.Errorf("jwtkeys: duplicate key id %q", key.ID)
+		}
+		ring.keys[key.ID] = key
+	}
+
+	return ring, nil
+}
+
+// NewHMACKey creates the legacy shared-secret key. It is never published in the JWKS.
+func NewHMACKey(secret []byte) *Key {
+	return &Key{
+		Method:   jwt.SigningMethodHS256,
+		signer:   secret,
+		verifier: secret,
+	}
+}
+
+// NewPrivateKey creates a signing key from an RSA or Ed25519 private key.
+// An empty id derives the kid from the public key.
+func NewPrivateKey(id string, private any) (*Key, error) {
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		return newKey(id, jwt.SigningMethodRS256, k, &k.PublicKey)
+	case ed25519.PrivateKey:
+		return newKey(id, jwt.SigningMethodEdDSA, k, k.Public())
+	default:
+		return nil, fmt.Errorf("jwtkeys: unsupported private key type %T", private)
+	}
+}
+
+// NewPublicKey creates a verify-only key from an RSA or Ed25519 public key
+func NewPublicKey(id string, public any) (*Key, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return newKey(id, jwt.SigningMethodRS256, nil, k)
+	case ed25519.PublicKey:
+		return newKey(id, jwt.SigningMethodEdDSA, nil, k)
+	default:
+		return nil, fmt.Errorf("jwtkeys: unsupported public key type %T", public)
+	}
+}
+
+func newKey(id string, method jwt.SigningMethod, signer, verifier any) (*Key, error) {
+	if id == "" {
+		derived, err := thumbprint(verifier)
+		if err != nil {
+			return nil, err
+		}
+		id = derived
+	}
+
+	return &Key{
+		ID:       id,
+		Method:   method,
+		signer:   signer,
+		verifier: verifier,
+	}, nil
+}
+
+// thumbprint derives a stable kid from the DER encoded public key
+func thumbprint(public any) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
+
+// Algorithm returns the signing algorithm, e.g. "RS256"
+func (k *KeyRing) Algorithm() string {
+	return k.signing.Method.Alg()
+}
+
+// Sign signs the claims with the current signing key and sets the kid header
+func (k *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.Method, claims)
+	if k.signing.ID != "" {
+		token.Header["kid"] = k.signing.ID
+	}
+
+	return token.SignedString(k.signing.signer)
+}
+
+// Parse validates the token against the key named by its kid header.
+// Tokens without a kid are only accepted by the legacy HMAC key.
+func (k *KeyRing) Parse(tokenString string, opts ...jwt.ParserOption) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("jwtkeys: unknown key id %q", kid)
+		}
+
+		// Never let the token choose a different algorithm than the key's
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, jwt.ErrSignatureInvalid
+		}
+
+		return key.verifier, nil
+	}, opts...)
+}
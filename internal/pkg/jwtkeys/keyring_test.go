@@ -0,0 +1,200 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func newRSAKey(t *testing.T, id string) (*Key, *rsa.PrivateKey) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	key, err := NewPrivateKey(id, private)
+	require.NoError(t, err)
+	return key, private
+}
+
+func newEd25519Key(t *testing.T, id string) (*Key, ed25519.PrivateKey) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	key, err := NewPrivateKey(id, private)
+	require.NoError(t, err)
+	return key, private
+}
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"user_id": "some-id",
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+func TestKeyRing_SignAndParseRS256(t *testing.T) {
+	key, _ := newRSAKey(t, "rsa-1")
+	ring, err := NewKeyRing(key)
+	require.NoError(t, err)
+
+	signed, err := ring.Sign(testClaims())
+	require.NoError(t, err)
+
+	token, err := ring.Parse(signed)
+	require.NoError(t, err)
+	require.True(t, token.Valid)
+	require.Equal(t, "rsa-1", token.Header["kid"])
+	require.Equal(t, "RS256", token.Method.Alg())
+}
+
+func TestKeyRing_SignAndParseEdDSA(t *testing.T) {
+	key, _ := newEd25519Key(t, "")
+	ring, err := NewKeyRing(key)
+	require.NoError(t, err)
+
+	signed, err := ring.Sign(testClaims())
+	require.NoError(t, err)
+
+	token, err := ring.Parse(signed)
+	require.NoError(t, err)
+	require.Equal(t, "EdDSA", token.Method.Alg())
+	require.NotEmpty(t, token.Header["kid"], "expected a derived kid")
+}
+
+func TestKeyRing_RotationAcceptsPreviousKey(t *testing.T) {
+	oldKey, oldPrivate := newRSAKey(t, "old")
+	newKey, _ := newEd25519Key(t, "new")
+
+	oldRing, err := NewKeyRing(oldKey)
+	require.NoError(t, err)
+	signedWithOld, err := oldRing.Sign(testClaims())
+	require.NoError(t, err)
+
+	// After rotation the old key is kept for verification only
+	previous, err := NewPublicKey("old", &oldPrivate.PublicKey)
+	require.NoError(t, err)
+	ring, err := NewKeyRing(newKey, previous)
+	require.NoError(t, err)
+
+	token, err := ring.Parse(signedWithOld)
+	require.NoError(t, err)
+	require.True(t, token.Valid)
+
+	signedWithNew, err := ring.Sign(testClaims())
+	require.NoError(t, err)
+	token, err = ring.Parse(signedWithNew)
+	require.NoError(t, err)
+	require.Equal(t, "new", token.Header["kid"])
+}
+
+func TestKeyRing_RejectsUnknownKid(t *testing.T) {
+	signingKey, _ := newRSAKey(t, "a")
+	otherKey, _ := newRSAKey(t, "b")
+
+	ring, err := NewKeyRing(signingKey)
+	require.NoError(t, err)
+	other, err := NewKeyRing(otherKey)
+	require.NoError(t, err)
+
+	signed, err := other.Sign(testClaims())
+	require.NoError(t, err)
+
+	_, err = ring.Parse(signed)
+	require.Error(t, err)
+}
+
+func TestKeyRing_RejectsAlgorithmMismatch(t *testing.T) {
+	key, private := newRSAKey(t, "rsa-1")
+	ring, err := NewKeyRing(key)
+	require.NoError(t, err)
+
+	// Sign with a different algorithm but claim the RSA kid
+	token := jwt.NewWithClaims(jwt.SigningMethodRS512, testClaims())
+	token.Header["kid"] = "rsa-1"
+	signed, err := token.SignedString(private)
+	require.NoError(t, err)
+
+	_, err = ring.Parse(signed)
+	require.Error(t, err)
+}
+
+func TestKeyRing_HMACWithoutKid(t *testing.T) {
+	ring, err := NewKeyRing(NewHMACKey([]byte("secret")))
+	require.NoError(t, err)
+
+	signed, err := ring.Sign(testClaims())
+	require.NoError(t, err)
+
+	token, err := ring.Parse(signed)
+	require.NoError(t, err)
+	require.Nil(t, token.Header["kid"])
+	require.Empty(t, ring.JWKS().Keys, "shared secrets must not be published")
+}
+
+func TestKeyRing_DuplicateKid(t *testing.T) {
+	a, _ := newRSAKey(t, "same")
+	b, _ := newRSAKey(t, "same")
+
+	_, err := NewKeyRing(a, b)
+	require.Error(t, err)
+}
+
+func TestKeyRing_JWKS(t *testing.T) {
+	rsaKey, _ := newRSAKey(t, "rsa")
+	_, edPrivate := newEd25519Key(t, "")
+	edPublic, err := NewPublicKey("ed", edPrivate.Public())
+	require.NoError(t, err)
+
+	ring, err := NewKeyRing(rsaKey, edPublic)
+	require.NoError(t, err)
+
+	set := ring.JWKS()
+	require.Len(t, set.Keys, 2)
+
+	require.Equal(t, "ed", set.Keys[0].Kid)
+	require.Equal(t, "OKP", set.Keys[0].Kty)
+	require.Equal(t, "Ed25519", set.Keys[0].Crv)
+	require.NotEmpty(t, set.Keys[0].X)
+
+	require.Equal(t, "rsa", set.Keys[1].Kid)
+	require.Equal(t, "RSA", set.Keys[1].Kty)
+	require.Equal(t, "RS256", set.Keys[1].Alg)
+	require.Equal(t, "AQAB", set.Keys[1].E)
+	require.NotEmpty(t, set.Keys[1].N)
+}
+
+func TestJWK_PublicKeyRoundTrip(t *testing.T) {
+	_, rsaPrivate := newRSAKey(t, "rsa")
+	rsaKey, err := NewPublicKey("rsa", &rsaPrivate.PublicKey)
+	require.NoError(t, err)
+	_, edPrivate := newEd25519Key(t, "")
+	edKey, err := NewPublicKey("ed", edPrivate.Public())
+	require.NoError(t, err)
+
+	ring, err := NewKeyRing(NewHMACKey([]byte("secret")), rsaKey, edKey)
+	require.NoError(t, err)
+
+	for _, jwk := range ring.JWKS().Keys {
+		public, err := jwk.PublicKey()
+		require.NoError(t, err, jwk.Kid)
+
+		switch jwk.Kid {
+		case "rsa":
+			require.True(t, rsaPrivate.PublicKey.Equal(public))
+		case "ed":
+			require.True(t, edPrivate.Public().(ed25519.PublicKey).Equal(public))
+		}
+	}
+}
+
+func TestJWK_PublicKeyUnsupported(t *testing.T) {
+	_, err := JWK{Kty: "oct"}.PublicKey()
+	require.Error(t, err)
+
+	_, err = JWK{Kty: "OKP", Crv: "X25519", X: "AA"}.PublicKey()
+	require.Error(t, err)
+}
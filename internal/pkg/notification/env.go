@@ -0,0 +1,96 @@
+package notification
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"booknest/internal/domain"
+)
+
+const defaultFrom = "BookNest <no-reply@booknest.local>"
+
+// FromEnv builds the notifier from the environment:
+//
+//	NOTIFY_EMAIL_DRIVER   "outbox" (default) or "smtp"
+//	NOTIFY_SMS_DRIVER     "outbox" (default)
+//	NOTIFY_OUTBOX_DIR     directory the outbox driver writes to (default "outbox")
+//	NOTIFY_FROM           sender of emails (default "BookNest <no-reply@booknest.local>")
+//	NOTIFY_TEMPLATE_DIR   *.tmpl files replacing the built-in templates of the same name
+//	SMTP_HOST, SMTP_PORT (default 587), SMTP_USERNAME, SMTP_PASSWORD
+func FromEnv() (*Notifier, error) {
+	from := os.Getenv("NOTIFY_FROM")
+	if from == "" {
+		from = defaultFrom
+	}
+
+	outboxDir := os.Getenv("NOTIFY_OUTBOX_DIR")
+	if outboxDir == "" {
+		outboxDir = "outbox"
+	}
+
+	templates, err := LoadTemplates(os.Getenv("NOTIFY_TEMPLATE_DIR"))
+	if err != nil {
+		return nil, err
+	}
+
+	var email domain.EmailSender
+	switch driver := os.Getenv("NOTIFY_EMAIL_DRIVER"); driver {
+	case "":
+		warnOutboxDefault("NOTIFY_EMAIL_DRIVER", outboxDir)
+		fallthrough
+	case "outbox":
+		email, err = NewOutboxSender(outboxDir, from)
+	case "smtp":
+		email, err = smtpFromEnv(from)
+	default:
+		err = fmt.Errorf("notification: unknown NOTIFY_EMAIL_DRIVER %q", driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sms domain.SMSSender
+	switch driver := os.Getenv("NOTIFY_SMS_DRIVER"); driver {
+	case "":
+		warnOutboxDefault("NOTIFY_SMS_DRIVER", outboxDir)
+		fallthrough
+	case "outbox":
+		sms, err = NewOutboxSender(outboxDir, from)
+	default:
+		err = fmt.Errorf("notification: unknown NOTIFY_SMS_DRIVER %q", driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewNotifier(email, sms, templates, DefaultRetryPolicy), nil
+}
+
+// warnOutboxDefault makes a missing driver setting visible, since the
+// outbox only writes files and nothing reaches the users
+func warnOutboxDefault(key, dir string) {
+	slog.Warn(
+		key+" is not set, notifications are written to the outbox and NOT delivered",
+		"dir", dir,
+	)
+}
+
+func smtpFromEnv(from string) (*SMTPSender, error) {
+	port := 0
+	if raw := os.Getenv("SMTP_PORT"); raw != "" {
+		var err error
+		if port, err = strconv.Atoi(raw); err != nil {
+			return nil, fmt.Errorf("notification: invalid SMTP_PORT %q", raw)
+		}
+	}
+
+	return NewSMTPSender(SMTPConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     from,
+	})
+}
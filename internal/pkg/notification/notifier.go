@@ -0,0 +1,156 @@
+package notification
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"booknest/internal/domain"
+)
+
+// RetryPolicy controls how often a failed delivery is retried. The delay
+// between attempts starts at Backoff and doubles after every attempt.
+type RetryPolicy struct {
+	Attempts int
+	Backoff  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, Backoff: 2 * time.Second}
+
+// Notifier renders templates and hands the messages to the senders
+type Notifier struct {
+	email     domain.EmailSender
+	sms       domain.SMSSender
+	templates *Templates
+	retry     RetryPolicy
+}
+
+func NewNotifier(
+	email domain.EmailSender,
+	sms domain.SMSSender,
+	templates *Templates,
+	retry RetryPolicy,
+) *Notifier {
+	if retry.Attempts < 1 {
+		retry.Attempts = 1
+	}
+
+	return &Notifier{
+		email:     email,
+		sms:       sms,
+		templates: templates,
+		retry:     retry,
+	}
+}
+
+func (n *Notifier) Email(
+	ctx context.Context,
+	to string,
+	template domain.NotificationTemplate,
+	data any,
+) error {
+	msg, err := n.templates.Email(template, to, data)
+	if err != nil {
+		n.logFailure("email", template, to, 0, err)
+		return err
+	}
+
+	return n.deliver(ctx, "email", template, to, func(ctx context.Context) error {
+		return n.email.SendEmail(ctx, msg)
+	})
+}
+
+func (n *Notifier) SMS(
+	ctx context.Context,
+	to string,
+	template domain.NotificationTemplate,
+	data any,
+) error {
+	msg, err := n.templates.SMS(template, to, data)
+	if err != nil {
+		n.logFailure("sms", template, to, 0, err)
+		return err
+	}
+
+	return n.deliver(ctx, "sms", template, to, func(ctx context.Context) error {
+		return n.sms.SendSMS(ctx, msg)
+	})
+}
+
+// deliver retries send with exponential back-off and logs the final failure
+func (n *Notifier) deliver(
+	ctx context.Context,
+	channel string,
+	template domain.NotificationTemplate,
+	to string,
+	send func(ctx context.Context) error,
+) error {
+	delay := n.retry.Backoff
+
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		if err = send(ctx); err == nil {
+			return nil
+		}
+		if attempt == n.retry.Attempts {
+			break
+		}
+
+		slog.Warn(
+			"Notification attempt failed, retrying",
+			"channel", channel,
+			"template", template,
+			"attempt", attempt,
+			"error", err,
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			n.logFailure(channel, template, to, attempt, ctx.Err())
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+
+	n.logFailure(channel, template, to, attempt, err)
+	return err
+}
+
+// logFailure never logs the message itself, since it may contain a code
+func (n *Notifier) logFailure(
+	channel string,
+	template domain.NotificationTemplate,
+	to string,
+	attempts int,
+	err error,
+) {
+	slog.Error(
+		"Notification delivery failed",
+		"channel", channel,
+		"template", template,
+		"to", maskRecipient(to),
+		"attempts", attempts,
+		"error", err,
+	)
+}
+
+// maskRecipient keeps just enough of an address to correlate failures,
+// example: "jo***@example.com" or "***4567"
+func maskRecipient(to string) string {
+	if local, host, ok := strings.Cut(to, "@"); ok {
+		if len(local) > 2 {
+			local = local[:2]
+		}
+		return local + "***@" + host
+	}
+
+	if len(to) > 4 {
+		return "***" + to[len(to)-4:]
+	}
+	return "***"
+}
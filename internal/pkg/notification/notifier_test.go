@@ -0,0 +1,103 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"booknest/internal/domain"
+)
+
+type stubSender struct {
+	failures int
+	emails   []domain.EmailMessage
+	sms      []domain.SMSMessage
+	calls    int
+}
+
+func (s *stubSender) SendEmail(ctx context.Context, msg domain.EmailMessage) error {
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("relay unavailable")
+	}
+	s.emails = append(s.emails, msg)
+	return nil
+}
+
+func (s *stubSender) SendSMS(ctx context.Context, msg domain.SMSMessage) error {
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("gateway unavailable")
+	}
+	s.sms = append(s.sms, msg)
+	return nil
+}
+
+func newTestNotifier(t *testing.T, sender *stubSender, attempts int) *Notifier {
+	templates, err := LoadTemplates("")
+	require.NoError(t, err)
+	return NewNotifier(sender, sender, templates, RetryPolicy{Attempts: attempts, Backoff: time.Millisecond})
+}
+
+func TestNotifier_EmailRetriesUntilDelivered(t *testing.T) {
+	sender := &stubSender{failures: 2}
+	notifier := newTestNotifier(t, sender, 3)
+
+	err := notifier.Email(context.Background(), "reader@example.com", domain.TemplateLoginOTP, map[string]any{
+		"Name": "Ada",
+		"Code": "123456",
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 3, sender.calls)
+	require.Len(t, sender.emails, 1)
+	require.Equal(t, "reader@example.com", sender.emails[0].To)
+	require.Contains(t, sender.emails[0].Body, "123456")
+}
+
+func TestNotifier_GivesUpAfterAttempts(t *testing.T) {
+	sender := &stubSender{failures: 5}
+	notifier := newTestNotifier(t, sender, 2)
+
+	err := notifier.SMS(context.Background(), "+15550001234", domain.TemplateMobileVerification, map[string]any{
+		"Code": "123456",
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 2, sender.calls)
+}
+
+func TestNotifier_StopsWhenContextIsDone(t *testing.T) {
+	sender := &stubSender{failures: 5}
+	templates, err := LoadTemplates("")
+	require.NoError(t, err)
+	notifier := NewNotifier(sender, sender, templates, RetryPolicy{Attempts: 3, Backoff: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = notifier.SMS(ctx, "+15550001234", domain.TemplateMobileVerification, map[string]any{"Code": "1"})
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 1, sender.calls)
+}
+
+func TestNotifier_RenderErrorIsNotSent(t *testing.T) {
+	sender := &stubSender{}
+	notifier := newTestNotifier(t, sender, 3)
+
+	// The verification template has no sms block
+	err := notifier.SMS(context.Background(), "+15550001234", domain.TemplateEmailVerification, map[string]any{"Code": "1"})
+
+	require.Error(t, err)
+	require.Zero(t, sender.calls)
+}
+
+func TestMaskRecipient(t *testing.T) {
+	require.Equal(t, "re***@example.com", maskRecipient("reader@example.com"))
+	require.Equal(t, "***1234", maskRecipient("+15550001234"))
+	require.Equal(t, "***", maskRecipient("123"))
+}
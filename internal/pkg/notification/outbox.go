@@ -0,0 +1,79 @@
+package notification
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"time"
+
+	"booknest/internal/domain"
+)
+
+// OutboxSender writes every message to a file instead of delivering it,
+// for local development and tests. Emails are stored as .eml files that
+// open in any mail client, text messages as .sms files.
+type OutboxSender struct {
+	dir  string
+	from *mail.Address
+}
+
+func NewOutboxSender(dir, from string) (*OutboxSender, error) {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("notification: invalid sender %q: %w", from, err)
+	}
+
+	// Messages contain login codes, so keep them private to the user
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("notification: create outbox: %w", err)
+	}
+
+	return &OutboxSender{dir: dir, from: sender}, nil
+}
+
+func (o *OutboxSender) SendEmail(ctx context.Context, msg domain.EmailMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("notification: invalid recipient: %w", err)
+	}
+
+	data, err := buildEmail(o.from, to, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return o.write("eml", data)
+}
+
+func (o *OutboxSender) SendSMS(ctx context.Context, msg domain.SMSMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return o.write("sms", []byte(fmt.Sprintf("To: %s\n\n%s\n", msg.To, msg.Body)))
+}
+
+// write stores data under a name that sorts by time of sending
+func (o *OutboxSender) write(ext string, data []byte) error {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf(
+		"%s-%s.%s",
+		time.Now().UTC().Format("20060102T150405.000000000"),
+		hex.EncodeToString(suffix),
+		ext,
+	)
+
+	return os.WriteFile(filepath.Join(o.dir, name), data, 0o600)
+}
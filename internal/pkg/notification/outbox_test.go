@@ -0,0 +1,66 @@
+package notification
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"booknest/internal/domain"
+)
+
+func TestOutboxSender_WritesMessages(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	sender, err := NewOutboxSender(dir, defaultFrom)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, sender.SendEmail(ctx, domain.EmailMessage{
+		To:      "reader@example.com",
+		Subject: "Verify your email",
+		Body:    "Code: abc",
+	}))
+	require.NoError(t, sender.SendSMS(ctx, domain.SMSMessage{To: "+15550001234", Body: "Code: 123456"}))
+
+	emails, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, emails, 1)
+
+	data, err := os.ReadFile(emails[0])
+	require.NoError(t, err)
+	require.Contains(t, string(data), "To: <reader@example.com>\r\n")
+	require.Contains(t, string(data), "Code: abc")
+
+	texts, err := filepath.Glob(filepath.Join(dir, "*.sms"))
+	require.NoError(t, err)
+	require.Len(t, texts, 1)
+
+	data, err = os.ReadFile(texts[0])
+	require.NoError(t, err)
+	require.Equal(t, "To: +15550001234\n\nCode: 123456\n", string(data))
+}
+
+func TestFromEnv_Drivers(t *testing.T) {
+	t.Setenv("NOTIFY_OUTBOX_DIR", t.TempDir())
+	t.Setenv("NOTIFY_EMAIL_DRIVER", "")
+	t.Setenv("NOTIFY_SMS_DRIVER", "")
+
+	notifier, err := FromEnv()
+	require.NoError(t, err)
+	require.IsType(t, &OutboxSender{}, notifier.email)
+
+	t.Setenv("NOTIFY_EMAIL_DRIVER", "smtp")
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	t.Setenv("SMTP_PORT", "2525")
+
+	notifier, err = FromEnv()
+	require.NoError(t, err)
+	require.IsType(t, &SMTPSender{}, notifier.email)
+	require.Equal(t, "smtp.example.com:2525", notifier.email.(*SMTPSender).addr)
+
+	t.Setenv("NOTIFY_EMAIL_DRIVER", "carrier-pigeon")
+	_, err = FromEnv()
+	require.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package notification
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"booknest/internal/domain"
+)
+
+func TestSMTPSender_SendEmail(t *testing.T) {
+	sender, err := NewSMTPSender(SMTPConfig{
+		Host:     "smtp.example.com",
+		Username: "user",
+		Password: "secret",
+		From:     "BookNest <no-reply@booknest.dev>",
+	})
+	require.NoError(t, err)
+
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg []byte
+	sender.sendMail = func(ctx context.Context, addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, msg
+		return nil
+	}
+
+	err = sender.SendEmail(context.Background(), domain.EmailMessage{
+		To:      "reader@example.com",
+		Subject: "Your code",
+		Body:    "Code: 123456",
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "smtp.example.com:587", gotAddr)
+	require.Equal(t, "no-reply@booknest.dev", gotFrom)
+	require.Equal(t, []string{"reader@example.com"}, gotTo)
+
+	msg := string(gotMsg)
+	require.Contains(t, msg, "Subject: Your code\r\n")
+	require.Contains(t, msg, "Content-Type: text/plain; charset=utf-8\r\n")
+	require.True(t, strings.HasSuffix(msg, "Code: 123456\r\n"))
+}
+
+func TestSMTPSender_RejectsHeaderInjection(t *testing.T) {
+	sender, err := NewSMTPSender(SMTPConfig{Host: "smtp.example.com", From: "no-reply@booknest.dev"})
+	require.NoError(t, err)
+	sender.sendMail = func(ctx context.Context, addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		t.Fatalf("message must not be sent")
+		return nil
+	}
+
+	err = sender.SendEmail(context.Background(), domain.EmailMessage{
+		To:      "reader@example.com",
+		Subject: "Hi\r\nBcc: victim@example.com",
+	})
+	require.Error(t, err)
+
+	err = sender.SendEmail(context.Background(), domain.EmailMessage{
+		To:      "reader@example.com\r\nBcc: victim@example.com",
+		Subject: "Hi",
+	})
+	require.Error(t, err)
+}
+
+func TestSMTPSender_StopsAtContextDeadline(t *testing.T) {
+	// A relay that accepts the connection but never greets
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			<-done
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+	portNum, err := net.LookupPort("tcp", port)
+	require.NoError(t, err)
+
+	sender, err := NewSMTPSender(SMTPConfig{Host: host, Port: portNum, From: "no-reply@booknest.dev"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = sender.SendEmail(ctx, domain.EmailMessage{To: "reader@example.com", Subject: "Hi", Body: "Hello"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 2*time.Second)
+}
@@ -0,0 +1,114 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+
+	"booknest/internal/domain"
+)
+
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+// Templates renders messages from "<name>.tmpl" files. Each file defines
+// a "subject" and "body" block for email and/or an "sms" block.
+type Templates struct {
+	set map[domain.NotificationTemplate]*template.Template
+}
+
+// LoadTemplates returns the built-in templates, with any *.tmpl file in
+// dir replacing the built-in template of the same name.
+func LoadTemplates(dir string) (*Templates, error) {
+	builtin, err := fs.Sub(builtinTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Templates{set: map[domain.NotificationTemplate]*template.Template{}}
+	if err := t.parse(builtin); err != nil {
+		return nil, err
+	}
+
+	if dir != "" {
+		if err := t.parse(os.DirFS(dir)); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+func (t *Templates) parse(fsys fs.FS) error {
+	paths, err := fs.Glob(fsys, "*.tmpl")
+	if err != nil {
+		return err
+	}
+
+	for _, p := range paths {
+		name := strings.TrimSuffix(path.Base(p), ".tmpl")
+
+		tmpl, err := template.New(name).Option("missingkey=error").ParseFS(fsys, p)
+		if err != nil {
+			return fmt.Errorf("notification: parse %s: %w", p, err)
+		}
+		t.set[domain.NotificationTemplate(name)] = tmpl
+	}
+
+	return nil
+}
+
+// Email renders the "subject" and "body" blocks of the template
+func (t *Templates) Email(
+	name domain.NotificationTemplate,
+	to string,
+	data any,
+) (domain.EmailMessage, error) {
+	subject, err := t.render(name, "subject", data)
+	if err != nil {
+		return domain.EmailMessage{}, err
+	}
+
+	body, err := t.render(name, "body", data)
+	if err != nil {
+		return domain.EmailMessage{}, err
+	}
+
+	return domain.EmailMessage{To: to, Subject: subject, Body: body}, nil
+}
+
+// SMS renders the "sms" block of the template
+func (t *Templates) SMS(
+	name domain.NotificationTemplate,
+	to string,
+	data any,
+) (domain.SMSMessage, error) {
+	body, err := t.render(name, "sms", data)
+	if err != nil {
+		return domain.SMSMessage{}, err
+	}
+
+	return domain.SMSMessage{To: to, Body: body}, nil
+}
+
+func (t *Templates) render(name domain.NotificationTemplate, block string, data any) (string, error) {
+	tmpl, ok := t.set[name]
+	if !ok {
+		return "", fmt.Errorf("notification: unknown template %q", name)
+	}
+	if tmpl.Lookup(block) == nil {
+		return "", fmt.Errorf("notification: template %q has no %q block", name, block)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, block, data); err != nil {
+		return "", fmt.Errorf("notification: render %s/%s: %w", name, block, err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,84 @@
+package notification
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"booknest/internal/domain"
+)
+
+func TestLoadTemplates_BuiltinTemplatesRender(t *testing.T) {
+	templates, err := LoadTemplates("")
+	require.NoError(t, err)
+
+	data := map[string]any{
+		"Name":        "Ada",
+		"Code":        "123456",
+		"Link":        "https://booknest.example/login/magic?token=abc",
+		"OrderNumber": "BN-1",
+		"Total":       42.5,
+	}
+
+	emails := []domain.NotificationTemplate{
+		domain.TemplateEmailVerification,
+		domain.TemplateLoginOTP,
+		domain.TemplateMagicLink,
+		domain.TemplatePasswordReset,
+		domain.TemplateOrderPlaced,
+		domain.TemplateOrderPaid,
+		domain.TemplateOrderCancelled,
+	}
+	for _, name := range emails {
+		msg, err := templates.Email(name, "reader@example.com", data)
+		require.NoError(t, err, name)
+		require.NotEmpty(t, msg.Subject, name)
+		require.NotEmpty(t, msg.Body, name)
+	}
+
+	texts := []domain.NotificationTemplate{
+		domain.TemplateMobileVerification,
+		domain.TemplateLoginOTP,
+		domain.TemplatePasswordReset,
+	}
+	for _, name := range texts {
+		msg, err := templates.SMS(name, "+15550001234", data)
+		require.NoError(t, err, name)
+		require.Contains(t, msg.Body, "123456", name)
+	}
+
+	msg, err := templates.Email(domain.TemplateOrderPaid, "reader@example.com", data)
+	require.NoError(t, err)
+	require.Equal(t, "Order BN-1 confirmed", msg.Subject)
+	require.Contains(t, msg.Body, "42.50")
+}
+
+func TestLoadTemplates_MissingKey(t *testing.T) {
+	templates, err := LoadTemplates("")
+	require.NoError(t, err)
+
+	_, err = templates.Email(domain.TemplateLoginOTP, "reader@example.com", map[string]any{"Name": "Ada"})
+	require.Error(t, err)
+}
+
+func TestLoadTemplates_DirOverridesBuiltin(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dir, "login_otp.tmpl"),
+		[]byte(`{{define "sms"}}Code: {{.Code}}{{end}}`),
+		0o600,
+	))
+
+	templates, err := LoadTemplates(dir)
+	require.NoError(t, err)
+
+	msg, err := templates.SMS(domain.TemplateLoginOTP, "+15550001234", map[string]any{"Code": "123456"})
+	require.NoError(t, err)
+	require.Equal(t, "Code: 123456", msg.Body)
+
+	// Templates that were not overridden are still available
+	_, err = templates.SMS(domain.TemplateMobileVerification, "+15550001234", map[string]any{"Code": "1"})
+	require.NoError(t, err)
+}
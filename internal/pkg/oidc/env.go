@@ -0,0 +1,50 @@
+package oidc
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"booknest/internal/domain"
+)
+
+// FromEnv builds the providers listed in OIDC_PROVIDERS, e.g. "google,github".
+// Each provider NAME is configured with:
+//
+//	OIDC_<NAME>_ISSUER          issuer URL, discovery is read from <issuer>/.well-known/openid-configuration
+//	OIDC_<NAME>_CLIENT_ID       client registered at the provider
+//	OIDC_<NAME>_CLIENT_SECRET   optional for public clients
+//	OIDC_<NAME>_REDIRECT_URL    e.g. https://api.booknest.dev/auth/oidc/google/callback
+//	OIDC_<NAME>_SCOPES          space separated (default "openid email profile")
+//
+// Without OIDC_PROVIDERS no provider is configured.
+func FromEnv() (map[string]domain.OIDCProvider, error) {
+	providers := make(map[string]domain.OIDCProvider)
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, exists := providers[name]; exists {
+			return nil, fmt.Errorf("oidc: provider %q is listed twice", name)
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider, err := NewProvider(Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		providers[name] = provider
+	}
+
+	return providers, nil
+}
@@ -0,0 +1,199 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	"booknest/internal/pkg/oidc/oidctest"
+)
+
+const redirectURL = "http://booknest.test/auth/oidc/stub/callback"
+
+func newStubProvider(t *testing.T) (*oidctest.Server, *Provider) {
+	server := oidctest.NewServer("booknest")
+	t.Cleanup(server.Close)
+
+	provider, err := NewProvider(Config{
+		Name:        "stub",
+		Issuer:      server.URL,
+		ClientID:    "booknest",
+		RedirectURL: redirectURL,
+	}, server.Client())
+	require.NoError(t, err)
+
+	return server, provider
+}
+
+// authorize follows the stub's login and returns the code and state of the redirect
+func authorize(t *testing.T, authURL string) (string, string) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, "booknest.test", location.Host)
+
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	server, provider := newStubProvider(t)
+	server.SetIdentity(oidctest.Identity{Subject: "42", Email: "reader@example.com", EmailVerified: true})
+
+	verifier, err := RandomString()
+	require.NoError(t, err)
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-1", "nonce-1", CodeChallenge(verifier))
+	require.NoError(t, err)
+
+	code, state := authorize(t, authURL)
+	require.Equal(t, "state-1", state)
+
+	identity, err := provider.Exchange(context.Background(), code, verifier, "nonce-1")
+
+	require.NoError(t, err)
+	require.Equal(t, "42", identity.Subject)
+	require.Equal(t, "reader@example.com", identity.Email)
+	require.True(t, identity.EmailVerified)
+}
+
+func TestProvider_ExchangeWrongVerifier(t *testing.T) {
+	_, provider := newStubProvider(t)
+
+	verifier, err := RandomString()
+	require.NoError(t, err)
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", "nonce", CodeChallenge(verifier))
+	require.NoError(t, err)
+	code, _ := authorize(t, authURL)
+
+	// A stolen code is useless without the verifier
+	_, err = provider.Exchange(context.Background(), code, "another-verifier", "nonce")
+	require.Error(t, err)
+}
+
+func TestProvider_ExchangeNonceMismatch(t *testing.T) {
+	_, provider := newStubProvider(t)
+
+	verifier, err := RandomString()
+	require.NoError(t, err)
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", "nonce", CodeChallenge(verifier))
+	require.NoError(t, err)
+	code, _ := authorize(t, authURL)
+
+	_, err = provider.Exchange(context.Background(), code, verifier, "other-nonce")
+	require.ErrorContains(t, err, "nonce")
+}
+
+func TestProvider_VerifyIDToken(t *testing.T) {
+	server, provider := newStubProvider(t)
+	meta, err := provider.discover(context.Background())
+	require.NoError(t, err)
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":            server.URL,
+			"aud":            "booknest",
+			"sub":            "42",
+			"email":          "reader@example.com",
+			"email_verified": "true",
+			"nonce":          "nonce",
+			"iat":            time.Now().Unix(),
+			"exp":            time.Now().Add(time.Minute).Unix(),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+	}{
+		{"other audience", func(c jwt.MapClaims) { c["aud"] = "someone-else" }},
+		{"other issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{"no expiry", func(c jwt.MapClaims) { delete(c, "exp") }},
+		{"no subject", func(c jwt.MapClaims) { delete(c, "sub") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(claims)
+			raw, err := server.SignIDToken(claims)
+			require.NoError(t, err)
+
+			_, err = provider.verifyIDToken(context.Background(), meta, raw, "nonce")
+			require.Error(t, err)
+		})
+	}
+
+	// String booleans are accepted for email_verified
+	raw, err := server.SignIDToken(valid())
+	require.NoError(t, err)
+	identity, err := provider.verifyIDToken(context.Background(), meta, raw, "nonce")
+	require.NoError(t, err)
+	require.True(t, identity.EmailVerified)
+}
+
+func TestProvider_DiscoveryIssuerMismatch(t *testing.T) {
+	server := oidctest.NewServer("booknest")
+	defer server.Close()
+
+	// The configured issuer must match the one in the discovery document
+	provider, err := NewProvider(Config{
+		Name:        "stub",
+		Issuer:      server.URL + "/",
+		ClientID:    "booknest",
+		RedirectURL: redirectURL,
+	}, server.Client())
+	require.NoError(t, err)
+
+	_, err = provider.AuthCodeURL(context.Background(), "state", "nonce", "challenge")
+	require.ErrorContains(t, err, "issuer")
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// Example from RFC 7636 appendix B
+	require.Equal(t,
+		"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"),
+	)
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("OIDC_PROVIDERS", "Google, stub")
+	t.Setenv("OIDC_GOOGLE_ISSUER", "https://accounts.google.com")
+	t.Setenv("OIDC_GOOGLE_CLIENT_ID", "client")
+	t.Setenv("OIDC_GOOGLE_REDIRECT_URL", redirectURL)
+	t.Setenv("OIDC_STUB_ISSUER", "http://localhost:9999")
+	t.Setenv("OIDC_STUB_CLIENT_ID", "client")
+	t.Setenv("OIDC_STUB_REDIRECT_URL", redirectURL)
+	t.Setenv("OIDC_STUB_SCOPES", "openid email")
+
+	providers, err := FromEnv()
+
+	require.NoError(t, err)
+	require.Len(t, providers, 2)
+	require.Contains(t, providers, "google")
+	require.Equal(t, []string{"openid", "email"}, providers["stub"].(*Provider).cfg.Scopes)
+}
+
+func TestFromEnv_MissingClientID(t *testing.T) {
+	t.Setenv("OIDC_PROVIDERS", "google")
+	t.Setenv("OIDC_GOOGLE_ISSUER", "https://accounts.google.com")
+
+	_, err := FromEnv()
+	require.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package password
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// FromEnv builds the policy from the environment:
+//
+//	PASSWORD_HASH_ALGORITHM       "argon2id" (default) or "bcrypt"
+//	PASSWORD_BCRYPT_COST          default 12
+//	PASSWORD_ARGON2_MEMORY_KIB    default 19456
+//	PASSWORD_ARGON2_ITERATIONS    default 2
+//	PASSWORD_ARGON2_PARALLELISM   default 1
+//	PASSWORD_MIN_LENGTH           default 8
+//	PASSWORD_MIN_CLASSES          default 2, out of lower, upper, digits and symbols
+//	PASSWORD_HISTORY_SIZE         default 5, 0 allows reusing passwords
+//	PASSWORD_DENYLIST_FILE        optional file of breached passwords, one per line
+//
+// Changing the algorithm or costs rehashes passwords on the next login.
+func FromEnv() (*Policy, error) {
+	p := Default()
+
+	switch algorithm := Algorithm(os.Getenv("PASSWORD_HASH_ALGORITHM")); algorithm {
+	case "":
+	case Argon2id, Bcrypt:
+		p.Algorithm = algorithm
+	default:
+		return nil, fmt.Errorf("password: unknown PASSWORD_HASH_ALGORITHM %q", algorithm)
+	}
+
+	ints := []struct {
+		name string
+		dst  *int
+		min  int
+	}{
+		{"PASSWORD_BCRYPT_COST", &p.BcryptCost, bcrypt.MinCost},
+		{"PASSWORD_MIN_LENGTH", &p.MinLength, 1},
+		{"PASSWORD_MIN_CLASSES", &p.MinClasses, 0},
+		{"PASSWORD_HISTORY_SIZE", &p.HistorySize, 0},
+	}
+	for _, v := range ints {
+		if err := intFromEnv(v.name, v.dst, v.min); err != nil {
+			return nil, err
+		}
+	}
+	if p.BcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("password: PASSWORD_BCRYPT_COST must be at most %d", bcrypt.MaxCost)
+	}
+
+	memory, iterations, parallelism := int(p.Argon2.Memory), int(p.Argon2.Iterations), int(p.Argon2.Parallelism)
+	if err := intFromEnv("PASSWORD_ARGON2_MEMORY_KIB", &memory, 8); err != nil {
+		return nil, err
+	}
+	if err := intFromEnv("PASSWORD_ARGON2_ITERATIONS", &iterations, 1); err != nil {
+		return nil, err
+	}
+	if err := intFromEnv("PASSWORD_ARGON2_PARALLELISM", &parallelism, 1); err != nil {
+		return nil, err
+	}
+	if parallelism > 255 {
+		return nil, fmt.Errorf("password: PASSWORD_ARGON2_PARALLELISM must be at most 255")
+	}
+	p.Argon2.Memory = uint32(memory)
+	p.Argon2.Iterations = uint32(iterations)
+	p.Argon2.Parallelism = uint8(parallelism)
+
+	if path := os.Getenv("PASSWORD_DENYLIST_FILE"); path != "" {
+		if err := p.LoadDenylist(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+func intFromEnv(name string, dst *int, min int) error {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return nil
+	}
+
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < min {
+		return fmt.Errorf("password: invalid %s %q", name, raw)
+	}
+
+	*dst = v
+	return nil
+}
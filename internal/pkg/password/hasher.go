@@ -0,0 +1,146 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algorithm names how new password hashes are computed
+type Algorithm string
+
+const (
+	Bcrypt   Algorithm = "bcrypt"
+	Argon2id Algorithm = "argon2id"
+)
+
+// Argon2Params are the argon2id cost settings
+type Argon2Params struct {
+	// Memory is in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follow the OWASP recommendation (19 MiB, 2 passes)
+var DefaultArgon2Params = Argon2Params{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+const DefaultBcryptCost = 12
+
+// Hasher computes new hashes with one algorithm and verifies hashes of
+// either, so the algorithm can change without locking anyone out
+type Hasher struct {
+	Algorithm  Algorithm
+	BcryptCost int
+	Argon2     Argon2Params
+}
+
+var errMalformedHash = errors.New("password: malformed argon2id hash")
+
+// Hash returns the encoded hash of raw, e.g.
+// "$argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>" or a bcrypt hash
+func (h Hasher) Hash(raw string) (string, error) {
+	switch h.Algorithm {
+	case Bcrypt:
+		hashed, err := bcrypt.GenerateFromPassword([]byte(raw), h.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashed), nil
+	case Argon2id:
+		salt := make([]byte, h.Argon2.SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(raw), salt, h.Argon2.Iterations, h.Argon2.Memory, h.Argon2.Parallelism, h.Argon2.KeyLength)
+		return encodeArgon2(h.Argon2, salt, key), nil
+	default:
+		return "", fmt.Errorf("password: unknown algorithm %q", h.Algorithm)
+	}
+}
+
+// Verify reports whether raw matches hash, whichever algorithm made it
+func (h Hasher) Verify(hash, raw string) bool {
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(raw)) == nil
+	}
+
+	params, salt, key, err := decodeArgon2(hash)
+	if err != nil {
+		return false
+	}
+	other := argon2.IDKey([]byte(raw), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
+
+// NeedsRehash reports whether hash was made with another algorithm or
+// other costs than new hashes are
+func (h Hasher) NeedsRehash(hash string) bool {
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		if h.Algorithm != Bcrypt {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != h.BcryptCost
+	}
+
+	if h.Algorithm != Argon2id {
+		return true
+	}
+	params, _, _, err := decodeArgon2(hash)
+	return err != nil || params != h.Argon2
+}
+
+func encodeArgon2(p Argon2Params, salt, key []byte) string {
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func decodeArgon2(hash string) (Argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+
+	var p Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil || p.Iterations == 0 || p.Parallelism == 0 {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
+}
@@ -0,0 +1,107 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fastArgon2 keeps the tests quick; production uses DefaultArgon2Params
+var fastArgon2 = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHasher_RoundTrip(t *testing.T) {
+	for _, h := range []Hasher{
+		{Algorithm: Argon2id, Argon2: fastArgon2},
+		{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost},
+	} {
+		t.Run(string(h.Algorithm), func(t *testing.T) {
+			hash, err := h.Hash("correct horse")
+			if err != nil {
+				t.Fatalf("hash: %v", err)
+			}
+
+			if !h.Verify(hash, "correct horse") {
+				t.Fatalf("expected the password to match")
+			}
+			if h.Verify(hash, "wrong horse") {
+				t.Fatalf("expected another password not to match")
+			}
+			if h.NeedsRehash(hash) {
+				t.Fatalf("a fresh hash should not need rehashing")
+			}
+		})
+	}
+}
+
+func TestHasher_Argon2Format(t *testing.T) {
+	h := Hasher{Algorithm: Argon2id, Argon2: fastArgon2}
+
+	hash, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("unexpected encoding %q", hash)
+	}
+
+	other, _ := h.Hash("correct horse")
+	if hash == other {
+		t.Fatalf("expected a random salt per hash")
+	}
+}
+
+// Test that hashes made with another algorithm or cost are upgraded
+func TestHasher_NeedsRehash(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+	weakArgon, _ := Hasher{Algorithm: Argon2id, Argon2: fastArgon2}.Hash("correct horse")
+
+	stronger := fastArgon2
+	stronger.Iterations = 2
+
+	tests := []struct {
+		name   string
+		hasher Hasher
+		hash   string
+		want   bool
+	}{
+		{"bcrypt to argon2id", Hasher{Algorithm: Argon2id, Argon2: fastArgon2}, string(legacy), true},
+		{"higher bcrypt cost", Hasher{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost + 1}, string(legacy), true},
+		{"same bcrypt cost", Hasher{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost}, string(legacy), false},
+		{"argon2id to bcrypt", Hasher{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost}, weakArgon, true},
+		{"more argon2id passes", Hasher{Algorithm: Argon2id, Argon2: stronger}, weakArgon, true},
+		{"malformed", Hasher{Algorithm: Argon2id, Argon2: fastArgon2}, "$argon2id$broken", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.hash); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// Old hashes keep verifying whatever the configured algorithm
+	if !(Hasher{Algorithm: Argon2id, Argon2: fastArgon2}).Verify(string(legacy), "correct horse") {
+		t.Fatalf("expected bcrypt hashes to verify under argon2id")
+	}
+}
+
+func TestHasher_MalformedHash(t *testing.T) {
+	h := Hasher{Algorithm: Argon2id, Argon2: fastArgon2}
+
+	for _, hash := range []string{
+		"",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=0$c2FsdA$a2V5",
+	} {
+		if h.Verify(hash, "anything") {
+			t.Fatalf("expected %q not to verify", hash)
+		}
+	}
+}
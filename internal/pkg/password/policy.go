@@ -0,0 +1,133 @@
+package password
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"booknest/internal/domain"
+)
+
+// MaxBytes is the longest accepted password. bcrypt cannot hash more, and
+// the limit also applies to argon2id so switching back never locks anyone out.
+const MaxBytes = 72
+
+// Policy decides how passwords are hashed and which ones are accepted
+type Policy struct {
+	Hasher
+	MinLength int
+	// MinClasses is how many of lower case, upper case, digits and
+	// symbols a password has to mix
+	MinClasses int
+	// HistorySize is how many recent passwords, the current one included,
+	// cannot be chosen again. 0 disables the check.
+	HistorySize int
+
+	denylist map[string]struct{}
+}
+
+// Default returns the policy used when nothing is configured
+func Default() *Policy {
+	return &Policy{
+		Hasher: Hasher{
+			Algorithm:  Argon2id,
+			BcryptCost: DefaultBcryptCost,
+			Argon2:     DefaultArgon2Params,
+		},
+		MinLength:   8,
+		MinClasses:  2,
+		HistorySize: 5,
+	}
+}
+
+// Deny adds passwords that are rejected regardless of the other rules
+func (p *Policy) Deny(passwords ...string) {
+	if p.denylist == nil {
+		p.denylist = make(map[string]struct{}, len(passwords))
+	}
+	for _, password := range passwords {
+		p.denylist[strings.ToLower(password)] = struct{}{}
+	}
+}
+
+// LoadDenylist reads breached passwords from a file with one password per
+// line, such as a SecLists or Have I Been Pwned export. Blank lines and
+// lines starting with # are skipped.
+func (p *Policy) LoadDenylist(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("password: open denylist: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.Deny(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("password: read denylist: %w", err)
+	}
+
+	return nil
+}
+
+// Check returns an error wrapping domain.ErrWeakPassword when raw breaks
+// a rule. personal holds account details, such as the email, that the
+// password must not contain.
+func (p *Policy) Check(raw string, personal ...string) error {
+	if utf8.RuneCountInString(raw) < p.MinLength {
+		return fmt.Errorf("%w: use at least %d characters", domain.ErrWeakPassword, p.MinLength)
+	}
+	if len(raw) > MaxBytes {
+		return fmt.Errorf("%w: use at most %d bytes", domain.ErrWeakPassword, MaxBytes)
+	}
+	if characterClasses(raw) < p.MinClasses {
+		return fmt.Errorf("%w: mix at least %d of lower case, upper case, digits and symbols", domain.ErrWeakPassword, p.MinClasses)
+	}
+
+	lower := strings.ToLower(raw)
+	if _, denied := p.denylist[lower]; denied {
+		return fmt.Errorf("%w: it appears in a list of breached passwords", domain.ErrWeakPassword)
+	}
+
+	for _, detail := range personal {
+		// The part of an email before the @ is what people reuse
+		detail, _, _ = strings.Cut(strings.ToLower(detail), "@")
+		if len(detail) >= 4 && strings.Contains(lower, detail) {
+			return fmt.Errorf("%w: it must not contain your name, email or mobile", domain.ErrWeakPassword)
+		}
+	}
+
+	return nil
+}
+
+func characterClasses(raw string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range raw {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	return classes
+}
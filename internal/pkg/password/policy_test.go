@@ -0,0 +1,95 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"booknest/internal/domain"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	p := Default()
+	p.Deny("Password123")
+
+	tests := []struct {
+		name     string
+		password string
+		personal []string
+		wantErr  bool
+	}{
+		{"strong", "blue-Harbor-42", nil, false},
+		{"too short", "aB3$", nil, true},
+		{"too long", strings.Repeat("aB3$", 19), nil, true},
+		{"single class", "lowercaseonly", nil, true},
+		{"denied, any casing", "PASSWORD123", nil, true},
+		{"contains the email", "Jane.doe-2024", []string{"jane.doe@example.com"}, true},
+		{"short name is ignored", "Bob-harbor-42", []string{"Bob"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Check(tt.password, tt.personal...)
+
+			if tt.wantErr && !errors.Is(err, domain.ErrWeakPassword) {
+				t.Fatalf("expected a weak password error, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPolicy_LoadDenylist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	content := "# top passwords\nqwerty123\n\n  Letmein!  \n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write denylist: %v", err)
+	}
+
+	p := Default()
+	if err := p.LoadDenylist(path); err != nil {
+		t.Fatalf("load denylist: %v", err)
+	}
+
+	for _, denied := range []string{"qwerty123", "letmein!"} {
+		if err := p.Check(denied); !errors.Is(err, domain.ErrWeakPassword) {
+			t.Fatalf("expected %q to be denied, got %v", denied, err)
+		}
+	}
+
+	if err := p.LoadDenylist(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Fatalf("expected an error for a missing file")
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "bcrypt")
+	t.Setenv("PASSWORD_BCRYPT_COST", "11")
+	t.Setenv("PASSWORD_HISTORY_SIZE", "0")
+
+	p, err := FromEnv()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if p.Algorithm != Bcrypt || p.BcryptCost != 11 || p.HistorySize != 0 {
+		t.Fatalf("unexpected policy %+v", p)
+	}
+	if p.MinLength != 8 {
+		t.Fatalf("expected the default min length, got %d", p.MinLength)
+	}
+
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "md5")
+	if _, err := FromEnv(); err == nil {
+		t.Fatalf("expected an unknown algorithm to be rejected")
+	}
+
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "")
+	t.Setenv("PASSWORD_BCRYPT_COST", "99")
+	if _, err := FromEnv(); err == nil {
+		t.Fatalf("expected an out of range cost to be rejected")
+	}
+}
@@ -0,0 +1,83 @@
+// Package postalcode checks postal codes against the format of their country.
+package postalcode
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrRequired = errors.New("postal code is required")
+	ErrInvalid  = errors.New("postal code has an invalid format for the country")
+	ErrNotUsed  = errors.New("the country does not use postal codes")
+)
+
+// formats holds the countries we ship to most, keyed by ISO 3166-1 alpha-2
+// code. Codes are matched upper case with surrounding spaces trimmed.
+var formats = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^\d{4}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"DK": regexp.MustCompile(`^\d{4}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"IE": regexp.MustCompile(`^[A-Z]\d[\dW] ?[A-Z\d]{4}$`),
+	"IN": regexp.MustCompile(`^[1-9]\d{5}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"NO": regexp.MustCompile(`^\d{4}$`),
+	"NZ": regexp.MustCompile(`^\d{4}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"PT": regexp.MustCompile(`^\d{4}-\d{3}$`),
+	"SE": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"SG": regexp.MustCompile(`^\d{6}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+// withoutCodes lists countries whose addresses have no postal code
+var withoutCodes = map[string]bool{
+	"AE": true,
+	"HK": true,
+	"QA": true,
+}
+
+// other countries get a loose check
+var generic = regexp.MustCompile(`^[A-Z\d][A-Z\d -]{1,8}[A-Z\d]$`)
+
+// Normalize upper-cases the code and trims surrounding spaces
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate checks code against the format of country
+func Validate(country, code string) error {
+	country = strings.ToUpper(country)
+	code = Normalize(code)
+
+	if withoutCodes[country] {
+		if code != "" {
+			return ErrNotUsed
+		}
+		return nil
+	}
+
+	if code == "" {
+		return ErrRequired
+	}
+
+	format, ok := formats[country]
+	if !ok {
+		format = generic
+	}
+	if !format.MatchString(code) {
+		return ErrInvalid
+	}
+	return nil
+}
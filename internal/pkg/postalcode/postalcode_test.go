@@ -0,0 +1,38 @@
+package postalcode
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		country string
+		code    string
+		want    error
+	}{
+		{"US", "94103", nil},
+		{"US", "94103-1234", nil},
+		{"US", "9410", ErrInvalid},
+		{"GB", "sw1a 1aa", nil},
+		{"GB", "SW1A1AA", nil},
+		{"CA", "K1A 0B1", nil},
+		{"DE", "1011", ErrInvalid},
+		{"IN", "011001", ErrInvalid},
+		{"NL", "1012 AB", nil},
+		{"de", "10115", nil},
+		{"FR", "", ErrRequired},
+		{"HK", "", nil},
+		{"HK", "999077", ErrNotUsed},
+		{"KE", "00100", nil},
+		{"KE", "!!", ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.country+" "+tt.code, func(t *testing.T) {
+			if err := Validate(tt.country, tt.code); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
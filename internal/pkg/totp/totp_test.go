@@ -0,0 +1,65 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA1 test key from RFC 6238 appendix B
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, want := range vectors {
+		got, err := Code(rfcSecret, Step(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		require.Equal(t, want, got, "time %d", unix)
+	}
+}
+
+func TestValidate_AllowsSkew(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	previous, err := Code(secret, Step(now)-1)
+	require.NoError(t, err)
+
+	step, ok := Validate(secret, previous, now, 1)
+	require.True(t, ok)
+	require.Equal(t, Step(now)-1, step)
+
+	_, ok = Validate(secret, previous, now, 0)
+	require.False(t, ok)
+}
+
+func TestValidate_RejectsMalformedCode(t *testing.T) {
+	_, ok := Validate(rfcSecret, "12345", time.Now(), 1)
+	require.False(t, ok)
+
+	_, ok = Validate("not base32!", "123456", time.Now(), 1)
+	require.False(t, ok)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("BookNest", "admin@example.com", "SECRET")
+	require.True(t, strings.HasPrefix(uri, "otpauth://totp/BookNest:admin@example.com?"))
+
+	parsed, err := url.Parse(uri)
+	require.NoError(t, err)
+	require.Equal(t, "SECRET", parsed.Query().Get("secret"))
+	require.Equal(t, "BookNest", parsed.Query().Get("issuer"))
+	require.Equal(t, "6", parsed.Query().Get("digits"))
+}
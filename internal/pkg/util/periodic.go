@@ -0,0 +1,31 @@
+package util
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RunEvery calls fn once per interval until ctx is done. Errors are logged
+// under name and do not stop the loop. The first call happens after one
+// interval, so starting the server stays cheap.
+func RunEvery(
+	ctx context.Context,
+	name string,
+	interval time.Duration,
+	fn func(ctx context.Context) error,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := fn(ctx); err != nil {
+				slog.Error("Background job failed", "job", name, "error", err)
+			}
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -81,3 +82,28 @@ func TestWithTransaction_BeginError(t *testing.T) {
 	require.ErrorIs(t, err, expectedErr)
 	require.False(t, called)
 }
+
+func TestRunEvery_StopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := make(chan struct{}, 10)
+	done := make(chan struct{})
+	go func() {
+		RunEvery(ctx, "test", time.Millisecond, func(ctx context.Context) error {
+			calls <- struct{}{}
+			return errors.New("keeps running")
+		})
+		close(done)
+	}()
+
+	// Errors must not stop the loop
+	<-calls
+	<-calls
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected RunEvery to return once the context is done")
+	}
+}
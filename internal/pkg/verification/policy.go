@@ -0,0 +1,80 @@
+package verification
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"booknest/internal/domain"
+)
+
+// Policy maps actions to the channels that must be verified first.
+// Actions without an entry need nothing.
+type Policy map[domain.ProtectedAction][]domain.VerificationChannel
+
+// Default returns the policy used when nothing is configured
+func Default() Policy {
+	return Policy{
+		domain.ActionCheckout:   {domain.ChannelEmail, domain.ChannelMobile},
+		domain.ActionPostReview: {domain.ChannelEmail},
+		// A verified mobile proves it is the owner moving the account
+		domain.ActionChangeEmail: {domain.ChannelMobile},
+	}
+}
+
+// Check returns a *domain.VerificationRequiredError when user has not
+// verified every channel the action requires
+func (p Policy) Check(action domain.ProtectedAction, user domain.User) error {
+	var missing []domain.VerificationChannel
+	for _, channel := range p[action] {
+		if !channel.IsVerified(user) {
+			missing = append(missing, channel)
+		}
+	}
+
+	if len(missing) > 0 {
+		return &domain.VerificationRequiredError{Action: action, Missing: missing}
+	}
+	return nil
+}
+
+// envNames are the variables overriding the default of each action
+var envNames = map[domain.ProtectedAction]string{
+	domain.ActionCheckout:    "VERIFY_BEFORE_CHECKOUT",
+	domain.ActionPostReview:  "VERIFY_BEFORE_REVIEW",
+	domain.ActionChangeEmail: "VERIFY_BEFORE_EMAIL_CHANGE",
+}
+
+// FromEnv builds the policy from the environment:
+//
+//	VERIFY_BEFORE_CHECKOUT       default "email,mobile"
+//	VERIFY_BEFORE_REVIEW         default "email"
+//	VERIFY_BEFORE_EMAIL_CHANGE   default "mobile"
+//
+// Each takes a comma separated list of "email" and "mobile", or "none".
+func FromEnv() (Policy, error) {
+	p := Default()
+
+	for action, name := range envNames {
+		raw := strings.TrimSpace(os.Getenv(name))
+		if raw == "" {
+			continue
+		}
+		if raw == "none" {
+			delete(p, action)
+			continue
+		}
+
+		var channels []domain.VerificationChannel
+		for _, part := range strings.Split(raw, ",") {
+			channel := domain.VerificationChannel(strings.TrimSpace(part))
+			if channel != domain.ChannelEmail && channel != domain.ChannelMobile {
+				return nil, fmt.Errorf("verification: invalid %s %q", name, raw)
+			}
+			channels = append(channels, channel)
+		}
+		p[action] = channels
+	}
+
+	return p, nil
+}
@@ -0,0 +1,72 @@
+package verification
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"booknest/internal/domain"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	p := Default()
+
+	tests := []struct {
+		name    string
+		action  domain.ProtectedAction
+		user    domain.User
+		missing []domain.VerificationChannel
+	}{
+		{"nothing verified", domain.ActionCheckout, domain.User{}, []domain.VerificationChannel{domain.ChannelEmail, domain.ChannelMobile}},
+		{"email only", domain.ActionCheckout, domain.User{EmailVerified: true}, []domain.VerificationChannel{domain.ChannelMobile}},
+		{"both verified", domain.ActionCheckout, domain.User{EmailVerified: true, MobileVerified: true}, nil},
+		{"email change needs mobile", domain.ActionChangeEmail, domain.User{EmailVerified: true}, []domain.VerificationChannel{domain.ChannelMobile}},
+		{"unlisted action", domain.ProtectedAction("wishlist.add"), domain.User{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Check(tt.action, tt.user)
+
+			if tt.missing == nil {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+
+			var verifyErr *domain.VerificationRequiredError
+			if !errors.As(err, &verifyErr) {
+				t.Fatalf("expected a verification error, got %v", err)
+			}
+			if verifyErr.Action != tt.action || !reflect.DeepEqual(verifyErr.Missing, tt.missing) {
+				t.Fatalf("unexpected error %+v", verifyErr)
+			}
+		})
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("VERIFY_BEFORE_CHECKOUT", "email")
+	t.Setenv("VERIFY_BEFORE_REVIEW", "none")
+
+	p, err := FromEnv()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !reflect.DeepEqual(p[domain.ActionCheckout], []domain.VerificationChannel{domain.ChannelEmail}) {
+		t.Fatalf("unexpected checkout channels %v", p[domain.ActionCheckout])
+	}
+	if _, ok := p[domain.ActionPostReview]; ok {
+		t.Fatalf("expected reviews to need nothing")
+	}
+	if !reflect.DeepEqual(p[domain.ActionChangeEmail], Default()[domain.ActionChangeEmail]) {
+		t.Fatalf("expected the default for email changes")
+	}
+
+	t.Setenv("VERIFY_BEFORE_CHECKOUT", "email,fax")
+	if _, err := FromEnv(); err == nil {
+		t.Fatalf("expected an unknown channel to be rejected")
+	}
+}
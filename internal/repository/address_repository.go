@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+)
+
+type addressRepo struct {
+	db   domain.DBExecer
+	gorm *gorm.DB
+	sb   squirrel.StatementBuilderType
+}
+
+func NewAddressRepo(db *pgxpool.Pool, gormDB *gorm.DB) domain.AddressRepository {
+	return &addressRepo{
+		db:   db,
+		gorm: gormDB,
+		sb:   squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// defaultColumn names the flag column of an address kind
+func defaultColumn(kind domain.AddressKind) (string, error) {
+	switch kind {
+	case domain.AddressShipping:
+		return "is_default_shipping", nil
+	case domain.AddressBilling:
+		return "is_default_billing", nil
+	default:
+		return "", fmt.Errorf("unknown address kind %q", kind)
+	}
+}
+
+func (r *addressRepo) ListByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]domain.Address, error) {
+
+	var addresses []domain.Address
+
+	err := r.gorm.
+		WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&addresses).
+		Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
+func (r *addressRepo) FindByID(
+	ctx context.Context,
+	userID uuid.UUID,
+	id uuid.UUID,
+) (domain.Address, error) {
+
+	var address domain.Address
+
+	err := r.gorm.
+		WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&address).
+		Error
+
+	return address, err
+}
+
+func (r *addressRepo) FindDefault(
+	ctx context.Context,
+	userID uuid.UUID,
+	kind domain.AddressKind,
+) (domain.Address, error) {
+
+	column, err := defaultColumn(kind)
+	if err != nil {
+		return domain.Address{}, err
+	}
+
+	var address domain.Address
+
+	err = r.gorm.
+		WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where(column+" = ?", true).
+		First(&address).
+		Error
+
+	return address, err
+}
+
+func (r *addressRepo) Create(
+	ctx context.Context,
+	address *domain.Address,
+) error {
+
+	query, args, err := r.sb.
+		Insert("addresses").
+		Columns(
+			"user_id",
+			"label",
+			"full_name",
+			"line1",
+			"line2",
+			"city",
+			"region",
+			"postal_code",
+			"country",
+			"phone",
+			"is_default_shipping",
+			"is_default_billing",
+		).
+		Values(
+			address.UserID,
+			address.Label,
+			address.FullName,
+			address.Line1,
+			address.Line2,
+			address.City,
+			address.Region,
+			address.PostalCode,
+			address.Country,
+			address.Phone,
+			address.IsDefaultShipping,
+			address.IsDefaultBilling,
+		).
+		Suffix("RETURNING id, created_at, updated_at").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	row := queryRowWithTx(ctx, r.db, query, args...)
+
+	return row.Scan(
+		&address.ID,
+		&address.CreatedAt,
+		&address.UpdatedAt,
+	)
+}
+
+func (r *addressRepo) Update(
+	ctx context.Context,
+	address *domain.Address,
+) error {
+
+	query, args, err := r.sb.
+		Update("addresses").
+		Set("label", address.Label).
+		Set("full_name", address.FullName).
+		Set("line1", address.Line1).
+		Set("line2", address.Line2).
+		Set("city", address.City).
+		Set("region", address.Region).
+		Set("postal_code", address.PostalCode).
+		Set("country", address.Country).
+		Set("phone", address.Phone).
+		Set("is_default_shipping", address.IsDefaultShipping).
+		Set("is_default_billing", address.IsDefaultBilling).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{
+			"id":      address.ID,
+			"user_id": address.UserID,
+		}).
+		Suffix("RETURNING updated_at").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	row := queryRowWithTx(ctx, r.db, query, args...)
+	return row.Scan(&address.UpdatedAt)
+}
+
+func (r *addressRepo) ClearDefault(
+	ctx context.Context,
+	userID uuid.UUID,
+	kind domain.AddressKind,
+) error {
+
+	column, err := defaultColumn(kind)
+	if err != nil {
+		return err
+	}
+
+	query, args, err := r.sb.
+		Update("addresses").
+		Set(column, false).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{
+			"user_id": userID,
+			column:    true,
+		}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+// PromoteDefault makes the oldest address of the user the default of the
+// kind. It is used after the default was deleted, so there is none to clear.
+func (r *addressRepo) PromoteDefault(
+	ctx context.Context,
+	userID uuid.UUID,
+	kind domain.AddressKind,
+) error {
+
+	column, err := defaultColumn(kind)
+	if err != nil {
+		return err
+	}
+
+	query, args, err := r.sb.
+		Update("addresses").
+		Set(column, true).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Expr(
+			"id = (SELECT id FROM addresses WHERE user_id = ? ORDER BY created_at ASC, id ASC LIMIT 1)",
+			userID,
+		)).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+func (r *addressRepo) Delete(
+	ctx context.Context,
+	userID uuid.UUID,
+	id uuid.UUID,
+) (bool, error) {
+
+	// Orders keep their own copy, so the row can go
+	query, args, err := r.sb.
+		Delete("addresses").
+		Where(squirrel.Eq{
+			"id":      id,
+			"user_id": userID,
+		}).
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := execWithTxTag(ctx, r.db, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *addressRepo) DeleteByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) error {
+
+	query, args, err := r.sb.
+		Delete("addresses").
+		Where(squirrel.Eq{"user_id": userID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
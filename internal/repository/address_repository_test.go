@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/require"
+
+	"booknest/internal/domain"
+)
+
+func TestAddressRepo_ListAndFind(t *testing.T) {
+	db := setupTestDB(t, &domain.Address{})
+
+	userID := uuid.New()
+	now := time.Now()
+	addresses := []domain.Address{
+		{ID: uuid.New(), UserID: userID, FullName: "Home", Line1: "1 Main St", City: "Springfield", PostalCode: "12345", Country: "US", IsDefaultShipping: true, CreatedAt: now.Add(-time.Hour)},
+		{ID: uuid.New(), UserID: userID, FullName: "Office", Line1: "2 Side St", City: "Springfield", PostalCode: "12345", Country: "US", IsDefaultBilling: true, CreatedAt: now},
+		{ID: uuid.New(), UserID: uuid.New(), FullName: "Other", Line1: "3 Elm St", City: "Shelbyville", PostalCode: "54321", Country: "US", IsDefaultShipping: true},
+	}
+	require.NoError(t, db.Create(&addresses).Error)
+
+	repo := &addressRepo{gorm: db}
+
+	list, err := repo.ListByUser(context.Background(), userID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, "Home", list[0].FullName)
+
+	found, err := repo.FindByID(context.Background(), userID, addresses[1].ID)
+	require.NoError(t, err)
+	require.Equal(t, "Office", found.FullName)
+
+	// Another user's address is not found
+	_, err = repo.FindByID(context.Background(), userID, addresses[2].ID)
+	require.Error(t, err)
+
+	shipping, err := repo.FindDefault(context.Background(), userID, domain.AddressShipping)
+	require.NoError(t, err)
+	require.Equal(t, addresses[0].ID, shipping.ID)
+
+	billing, err := repo.FindDefault(context.Background(), userID, domain.AddressBilling)
+	require.NoError(t, err)
+	require.Equal(t, addresses[1].ID, billing.ID)
+}
+
+func TestAddressRepo_Create(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &addressRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	address := &domain.Address{
+		UserID:            uuid.New(),
+		FullName:          "Jane Doe",
+		Line1:             "1 Main St",
+		City:              "Springfield",
+		PostalCode:        "12345",
+		Country:           "US",
+		IsDefaultShipping: true,
+	}
+
+	id := uuid.New()
+	now := time.Now()
+	mock.ExpectQuery("INSERT INTO addresses").
+		WithArgs(address.UserID, address.Label, "Jane Doe", "1 Main St", address.Line2, "Springfield", address.Region, "12345", "US", address.Phone, true, false).
+		WillReturnRows(
+			pgxmock.NewRows([]string{"id", "created_at", "updated_at"}).
+				AddRow(id, now, now),
+		)
+
+	err = repo.Create(context.Background(), address)
+
+	require.NoError(t, err)
+	require.Equal(t, id, address.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddressRepo_ClearDefault(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &addressRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	userID := uuid.New()
+
+	mock.ExpectExec(`UPDATE addresses SET is_default_billing = \$1, updated_at = NOW\(\) WHERE is_default_billing = \$2 AND user_id = \$3`).
+		WithArgs(false, true, userID.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	require.NoError(t, repo.ClearDefault(context.Background(), userID, domain.AddressBilling))
+	require.Error(t, repo.ClearDefault(context.Background(), userID, domain.AddressKind("pickup")))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddressRepo_PromoteDefault(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &addressRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	userID := uuid.New()
+
+	mock.ExpectExec(`UPDATE addresses SET is_default_shipping = \$1, updated_at = NOW\(\) WHERE id = \(SELECT id FROM addresses WHERE user_id = \$2 ORDER BY created_at ASC, id ASC LIMIT 1\)`).
+		WithArgs(true, userID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	require.NoError(t, repo.PromoteDefault(context.Background(), userID, domain.AddressShipping))
+	require.Error(t, repo.PromoteDefault(context.Background(), userID, domain.AddressKind("pickup")))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddressRepo_Delete(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &addressRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	userID, id := uuid.New(), uuid.New()
+
+	mock.ExpectExec("DELETE FROM addresses").
+		WithArgs(id.String(), userID.String()).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec("DELETE FROM addresses").
+		WithArgs(id.String(), userID.String()).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	deleted, err := repo.Delete(context.Background(), userID, id)
+	require.NoError(t, err)
+	require.True(t, deleted)
+
+	deleted, err = repo.Delete(context.Background(), userID, id)
+	require.NoError(t, err)
+	require.False(t, deleted)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
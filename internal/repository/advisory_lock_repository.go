@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"booknest/internal/domain"
+)
+
+type advisoryLockRepo struct {
+	db domain.DBExecer
+}
+
+func NewAdvisoryLockRepo(db *pgxpool.Pool) domain.AdvisoryLockRepository {
+	return &advisoryLockRepo{db: db}
+}
+
+// TryXactLock must run in a transaction; on its own the lock would be
+// released as soon as the statement finishes
+func (r *advisoryLockRepo) TryXactLock(
+	ctx context.Context,
+	name string,
+) (bool, error) {
+
+	query := `SELECT pg_try_advisory_xact_lock(hashtext($1))`
+
+	var locked bool
+	err := queryRowWithTx(ctx, r.db, query, name).Scan(&locked)
+	return locked, err
+}
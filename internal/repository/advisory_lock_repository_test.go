@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdvisoryLockRepo_TryXactLock(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &advisoryLockRepo{db: mock}
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock\(hashtext\(\$1\)\)`).
+		WithArgs("maintenance:test").
+		WillReturnRows(pgxmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+	mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock`).
+		WithArgs("maintenance:test").
+		WillReturnRows(pgxmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(false))
+
+	locked, err := repo.TryXactLock(context.Background(), "maintenance:test")
+	require.NoError(t, err)
+	require.True(t, locked)
+
+	locked, err = repo.TryXactLock(context.Background(), "maintenance:test")
+	require.NoError(t, err)
+	require.False(t, locked)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
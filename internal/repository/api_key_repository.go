@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+)
+
+type apiKeyRepo struct {
+	db   domain.DBExecer
+	gorm *gorm.DB
+	sb   squirrel.StatementBuilderType
+}
+
+func NewAPIKeyRepo(db *pgxpool.Pool, gormDB *gorm.DB) domain.APIKeyRepository {
+	return &apiKeyRepo{
+		db:   db,
+		gorm: gormDB,
+		sb:   squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (r *apiKeyRepo) Create(
+	ctx context.Context,
+	key *domain.APIKey,
+) error {
+
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []domain.APIKeyScope{}
+	}
+	raw, err := json.Marshal(scopes)
+	if err != nil {
+		return err
+	}
+
+	query, args, err := r.sb.
+		Insert("api_keys").
+		Columns(
+			"name",
+			"prefix",
+			"key_hash",
+			"scopes",
+			"created_by",
+			"expires_at",
+		).
+		Values(
+			key.Name,
+			key.Prefix,
+			key.KeyHash,
+			string(raw),
+			key.CreatedBy,
+			key.ExpiresAt,
+		).
+		Suffix("RETURNING id, created_at, updated_at").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	row := queryRowWithTx(ctx, r.db, query, args...)
+
+	return row.Scan(
+		&key.ID,
+		&key.CreatedAt,
+		&key.UpdatedAt,
+	)
+}
+
+func (r *apiKeyRepo) FindByHash(
+	ctx context.Context,
+	keyHash string,
+) (*domain.APIKey, error) {
+
+	var key domain.APIKey
+
+	err := r.gorm.
+		WithContext(ctx).
+		Where("key_hash = ?", keyHash).
+		First(&key).
+		Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *apiKeyRepo) List(
+	ctx context.Context,
+) ([]domain.APIKey, error) {
+
+	var keys []domain.APIKey
+
+	err := r.gorm.
+		WithContext(ctx).
+		Order("created_at DESC").
+		Find(&keys).
+		Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *apiKeyRepo) Touch(
+	ctx context.Context,
+	id uuid.UUID,
+) error {
+
+	// Skips the write while the last recorded use is recent, so busy
+	// integrations do not update the row on every request
+	query, args, err := r.sb.
+		Update("api_keys").
+		Set("last_used_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": id}).
+		Where("(last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+func (r *apiKeyRepo) Revoke(
+	ctx context.Context,
+	id uuid.UUID,
+) (bool, error) {
+
+	query, args, err := r.sb.
+		Update("api_keys").
+		Set("revoked_at", squirrel.Expr("NOW()")).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": id}).
+		Where("revoked_at IS NULL").
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := execWithTxTag(ctx, r.db, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/require"
+
+	"booknest/internal/domain"
+)
+
+func TestAPIKeyRepo_FindByHash(t *testing.T) {
+	db := setupTestDB(t, &domain.APIKey{})
+
+	key := domain.APIKey{
+		ID:        uuid.New(),
+		Name:      "warehouse",
+		Prefix:    "bnk_1a2b3c4d",
+		KeyHash:   "hash",
+		Scopes:    []domain.APIKeyScope{domain.ScopeBooksWrite, domain.ScopeOrdersRead},
+		CreatedBy: uuid.New(),
+	}
+	require.NoError(t, db.Create(&key).Error)
+
+	repo := &apiKeyRepo{gorm: db}
+
+	found, err := repo.FindByHash(context.Background(), "hash")
+
+	require.NoError(t, err)
+	require.Equal(t, key.ID, found.ID)
+	require.Equal(t, key.Scopes, found.Scopes)
+
+	_, err = repo.FindByHash(context.Background(), "other")
+	require.Error(t, err)
+}
+
+func TestAPIKeyRepo_List(t *testing.T) {
+	db := setupTestDB(t, &domain.APIKey{})
+
+	now := time.Now()
+	keys := []domain.APIKey{
+		{ID: uuid.New(), Name: "old", KeyHash: "a", BaseEntity: domain.BaseEntity{CreatedAt: now.Add(-time.Hour)}},
+		{ID: uuid.New(), Name: "new", KeyHash: "b", BaseEntity: domain.BaseEntity{CreatedAt: now}},
+		{ID: uuid.New(), Name: "revoked", KeyHash: "c", RevokedAt: &now, BaseEntity: domain.BaseEntity{CreatedAt: now.Add(-2 * time.Hour)}},
+	}
+	require.NoError(t, db.Create(&keys).Error)
+
+	repo := &apiKeyRepo{gorm: db}
+
+	found, err := repo.List(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, found, 3)
+	require.Equal(t, "new", found[0].Name)
+	require.Equal(t, "revoked", found[2].Name)
+}
+
+func TestAPIKeyRepo_Create(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &apiKeyRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	key := &domain.APIKey{
+		Name:      "warehouse",
+		Prefix:    "bnk_1a2b3c4d",
+		KeyHash:   "hash",
+		Scopes:    []domain.APIKeyScope{domain.ScopeBooksWrite},
+		CreatedBy: uuid.New(),
+	}
+
+	id := uuid.New()
+	now := time.Now()
+	mock.ExpectQuery("INSERT INTO api_keys").
+		WithArgs("warehouse", "bnk_1a2b3c4d", "hash", `["books:write"]`, key.CreatedBy, key.ExpiresAt).
+		WillReturnRows(
+			pgxmock.NewRows([]string{"id", "created_at", "updated_at"}).
+				AddRow(id, now, now),
+		)
+
+	err = repo.Create(context.Background(), key)
+
+	require.NoError(t, err)
+	require.Equal(t, id, key.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyRepo_Revoke(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &apiKeyRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	id := uuid.New()
+
+	mock.ExpectExec("UPDATE api_keys").
+		WithArgs(id.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("UPDATE api_keys").
+		WithArgs(id.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	revoked, err := repo.Revoke(context.Background(), id)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = repo.Revoke(context.Background(), id)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+)
+
+type auditRepo struct {
+	db   domain.DBExecer
+	gorm *gorm.DB
+	sb   squirrel.StatementBuilderType
+}
+
+func NewAuditRepo(db *pgxpool.Pool, gormDB *gorm.DB) domain.AuditRepository {
+	return &auditRepo{
+		db:   db,
+		gorm: gormDB,
+		sb:   squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (r *auditRepo) Create(
+	ctx context.Context,
+	log *domain.AuditLog,
+) error {
+
+	details := log.Details
+	if details == nil {
+		details = map[string]string{}
+	}
+	raw, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	query, args, err := r.sb.
+		Insert("audit_logs").
+		Columns(
+			"actor_id",
+			"action",
+			"target_user_id",
+			"details",
+		).
+		Values(
+			log.ActorID,
+			log.Action,
+			log.TargetUserID,
+			string(raw),
+		).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	row := queryRowWithTx(ctx, r.db, query, args...)
+
+	return row.Scan(
+		&log.ID,
+		&log.CreatedAt,
+	)
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/require"
+
+	"booknest/internal/domain"
+)
+
+func TestAuditRepo_Create(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &auditRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	target := uuid.New()
+	log := &domain.AuditLog{
+		ActorID:      uuid.New(),
+		Action:       domain.AuditRoleChanged,
+		TargetUserID: &target,
+		Details:      map[string]string{"from": "USER", "to": "ADMIN"},
+	}
+	id := uuid.New()
+
+	mock.ExpectQuery("INSERT INTO audit_logs").
+		WithArgs(
+			log.ActorID,
+			domain.AuditRoleChanged,
+			&target,
+			`{"from":"USER","to":"ADMIN"}`,
+		).
+		WillReturnRows(
+			pgxmock.NewRows([]string{"id", "created_at"}).
+				AddRow(id, time.Now()),
+		)
+
+	err = repo.Create(context.Background(), log)
+
+	require.NoError(t, err)
+	require.Equal(t, id, log.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
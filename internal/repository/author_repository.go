@@ -43,16 +43,24 @@ func (r *authorRepo) FindByName(ctx context.Context, name string) (domain.Author
 	return author, err
 }
 
-func (r *authorRepo) List(ctx context.Context, limit, offset int) ([]domain.Author, error) {
-	var authors []domain.Author
+func (r *authorRepo) List(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Author], error) {
+	keys := newKeyset(domain.SortByName, "name", "id", keyText, domain.Asc)
+	cursor, err := keys.decode(q.Cursor)
+	if err != nil {
+		return domain.Page[domain.Author]{}, err
+	}
 
-	err := r.gorm.WithContext(ctx).
-		Limit(limit).
-		Offset(offset).
-		Order("name ASC").
+	authors := make([]domain.Author, 0)
+	err = r.gorm.WithContext(ctx).
+		Scopes(keys.scope(cursor, q.Offset, q.Limit)).
 		Find(&authors).Error
+	if err != nil {
+		return domain.Page[domain.Author]{}, err
+	}
 
-	return authors, err
+	return keysetPage(keys, cursor, q.Offset, q.Limit, authors, func(author domain.Author) (any, uuid.UUID) {
+		return author.Name, author.ID
+	}), nil
 }
 
 func (r *authorRepo) Create(ctx context.Context, author *domain.Author) error {
@@ -3,6 +3,9 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"slices"
+	"strings"
+	"unicode"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
@@ -11,13 +14,87 @@ import (
 	"booknest/internal/domain"
 )
 
-var allowedBookSortColumns = map[string]string{
-	domain.SortByCreatedAt: "b.created_at",
-	domain.SortByPrice:     "b.price",
-	domain.SortByName:      "b.name",
-	domain.SortByStock:     "b.available_stock",
+var allowedBookSortColumns = map[string]sortColumn{
+	domain.SortByCreatedAt: {"b.created_at", keyTime},
+	domain.SortByPrice:     {"b.price", keyNumber},
+	domain.SortByName:      {"b.name", keyText},
+	domain.SortByStock:     {"b.available_stock", keyInt},
 }
 
+const (
+	// bookRank weighs matches in books.search_vector by part: description
+	// (D), publisher and categories (C), author (B) and title (A)
+	bookRank = "ts_rank('{0.1, 0.2, 0.4, 1.0}', b.search_vector, search_query)"
+)
+
+// The whole title is returned, the description as its best fragments.
+// Clients render them as HTML, so the stored text is escaped first and
+// the <mark> tags are the only markup left.
+var (
+	bookNameHeadline        = "ts_headline('english', " + htmlEscaped("b.name") + ", search_query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')"
+	bookDescriptionHeadline = "ts_headline('english', " + htmlEscaped("b.description") + ", search_query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10')"
+)
+
+// htmlEscaped wraps a text column in the SQL that escapes it like
+// html.EscapeString, ampersands first
+func htmlEscaped(column string) string {
+	expr := column
+	for _, r := range [][2]string{
+		{"&", "&amp;"},
+		{"<", "&lt;"},
+		{">", "&gt;"},
+		{`"`, "&#34;"},
+		{"''", "&#39;"},
+	} {
+		expr = "replace(" + expr + ", '" + r[0] + "', '" + r[1] + "')"
+	}
+	return expr
+}
+
+// bookSuggestQuery lists the best matches of $1 per kind, at most $2 each,
+// in a single round trip. $3 is the ISBN prefix, empty when $1 is not one.
+// Titles and authors match on the trigram indexes; categories are few
+// enough to scan.
+const bookSuggestQuery = `
+(SELECT 'title', b.id, b.name, NULL::text
+  FROM books b
+  WHERE b.deleted_at IS NULL AND b.is_active AND $1 <% b.name
+  ORDER BY word_similarity($1, b.name) DESC, b.name
+  LIMIT $2)
+UNION ALL
+(SELECT 'author', a.id, a.name, NULL::text
+  FROM authors a
+  WHERE a.deleted_at IS NULL AND $1 <% a.name
+    AND EXISTS (
+      SELECT 1 FROM books b
+      WHERE b.author_id = a.id AND b.deleted_at IS NULL AND b.is_active
+    )
+  ORDER BY word_similarity($1, a.name) DESC, a.name
+  LIMIT $2)
+UNION ALL
+(SELECT 'category', c.id, c.name, NULL::text
+  FROM categories c
+  WHERE c.deleted_at IS NULL AND $1 <% c.name
+    AND EXISTS (
+      SELECT 1 FROM book_categories bc
+      JOIN books b ON b.id = bc.book_id
+      WHERE bc.category_id = c.id AND bc.deleted_at IS NULL
+        AND b.deleted_at IS NULL AND b.is_active
+    )
+  ORDER BY word_similarity($1, c.name) DESC, c.name
+  LIMIT $2)
+UNION ALL
+(SELECT 'isbn', b.id, b.isbn, b.name
+  FROM books b
+  WHERE b.deleted_at IS NULL AND b.is_active
+    AND $3 <> '' AND upper(translate(b.isbn, '- ', '')) LIKE $3 || '%'
+  ORDER BY b.isbn
+  LIMIT $2)`
+
+// suggestSimilarity loosens pg_trgm.word_similarity_threshold from its 0.6
+// default so that a typo or two still matches
+const suggestSimilarity = "0.4"
+
 type bookRepository struct {
 	db  *gorm.DB
 	sql *sql.DB
@@ -45,54 +122,87 @@ func (r *bookRepository) FindByID(ctx context.Context, id uuid.UUID) (*domain.Bo
 	return &book, nil
 }
 
-func (r *bookRepository) FilterByCriteria(ctx context.Context, filter domain.BookFilter, q domain.QueryOptions) ([]domain.Book, int64, error) {
+func (r *bookRepository) FilterByCriteria(ctx context.Context, filter domain.BookFilter, q domain.QueryOptions) (domain.Page[domain.BookHit], int64, *domain.BookFacets, error) {
+	_, tsQuery := bookSearchTerms(filter)
+	ranked := tsQuery != ""
+
+	field, order := bookSort(q.Sort, ranked)
+	keys := bookKeyset(field, order)
+	cursor, err := keys.decode(q.Cursor)
+	if err != nil {
+		return domain.Page[domain.BookHit]{}, 0, nil, err
+	}
 
 	// ---------- DATA QUERY ----------
 	dataQuery := buildBookBaseQuery()
+	if ranked {
+		dataQuery = dataQuery.Columns(bookNameHeadline, bookDescriptionHeadline)
+	}
+	// The rank is the sort key of relevance cursors
+	relevance := field == domain.SortByRelevance
+	if relevance {
+		dataQuery = dataQuery.Column(bookRank)
+	}
 	dataQuery = applyBookFilters(dataQuery, filter)
-	dataQuery = applyBookSorting(dataQuery, q.Sort)
-
-	dataQuery = dataQuery.
-		OrderBy("b.created_at DESC").
-		Limit(q.Limit).
-		Offset(q.Offset).
+	dataQuery = keys.apply(dataQuery, cursor, q.Offset, q.Limit).
 		PlaceholderFormat(sq.Dollar)
 
 	sqlQuery, args, err := dataQuery.ToSql()
 	if err != nil {
-		return nil, 0, err
+		return domain.Page[domain.BookHit]{}, 0, nil, err
 	}
 
 	rows, err := r.sql.QueryContext(ctx, sqlQuery, args...)
 	if err != nil {
-		return nil, 0, err
+		return domain.Page[domain.BookHit]{}, 0, nil, err
 	}
 	defer rows.Close()
 
-	books := make([]domain.Book, 0)
+	books := make([]domain.BookHit, 0)
+	ranks := map[uuid.UUID]float32{}
 	for rows.Next() {
-		var book domain.Book
-		err := rows.Scan(
-			&book.ID,
-			&book.Name,
-			&book.AuthorID,
-			&book.AvailableStock,
-			&book.ImageURL,
-			&book.IsActive,
-			&book.Description,
-			&book.ISBN,
-			&book.Price,
-			&book.DiscountPercentage,
-			&book.PublisherID,
-			&book.CreatedAt,
-			&book.UpdatedAt,
-		)
-		if err != nil {
-			return nil, 0, err
-		}
-		books = append(books, book)
+		var hit domain.BookHit
+		dest := []any{
+			&hit.ID,
+			&hit.Name,
+			&hit.AuthorID,
+			&hit.AvailableStock,
+			&hit.ImageURL,
+			&hit.IsActive,
+			&hit.Description,
+			&hit.ISBN,
+			&hit.Price,
+			&hit.DiscountPercentage,
+			&hit.PublisherID,
+			&hit.CreatedAt,
+			&hit.UpdatedAt,
+		}
+		if ranked {
+			hit.Highlight = &domain.BookHighlight{}
+			dest = append(dest, &hit.Highlight.Name, &hit.Highlight.Description)
+		}
+		var rank float32
+		if relevance {
+			dest = append(dest, &rank)
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return domain.Page[domain.BookHit]{}, 0, nil, err
+		}
+		books = append(books, hit)
+		ranks[hit.ID] = rank
+	}
+	if err := rows.Err(); err != nil {
+		return domain.Page[domain.BookHit]{}, 0, nil, err
 	}
 
+	page := keysetPage(keys, cursor, q.Offset, q.Limit, books, func(hit domain.BookHit) (any, uuid.UUID) {
+		if relevance {
+			return float64(ranks[hit.ID]), hit.ID
+		}
+		return bookSortKey(field, hit.Book), hit.ID
+	})
+
 	// ---------- COUNT QUERY ----------
 	countQuery := sq.
 		Select("COUNT(DISTINCT b.id)").
@@ -104,26 +214,178 @@ func (r *bookRepository) FilterByCriteria(ctx context.Context, filter domain.Boo
 
 	countSQL, countArgs, err := countQuery.ToSql()
 	if err != nil {
-		return nil, 0, err
+		return domain.Page[domain.BookHit]{}, 0, nil, err
 	}
 
 	var total int64
 	if err := r.sql.QueryRowContext(ctx, countSQL, countArgs...).Scan(&total); err != nil {
-		return nil, 0, err
+		return domain.Page[domain.BookHit]{}, 0, nil, err
+	}
+
+	// ---------- FACET QUERIES ----------
+	facets, err := r.facets(ctx, filter)
+	if err != nil {
+		return domain.Page[domain.BookHit]{}, 0, nil, err
 	}
 
-	return books, total, nil
+	return page, total, facets, nil
 }
 
-func (r *bookRepository) List(ctx context.Context, limit, offset int) ([]domain.Book, error) {
-	var books []domain.Book
-	err := r.db.WithContext(ctx).
+// facets runs one aggregate query per facet, each without its own criterion
+func (r *bookRepository) facets(ctx context.Context, filter domain.BookFilter) (*domain.BookFacets, error) {
+	facets := &domain.BookFacets{}
+
+	var err error
+	if facets.Categories, err = r.facetCounts(ctx, categoryFacetQuery(filter)); err != nil {
+		return nil, err
+	}
+	if facets.Authors, err = r.facetCounts(ctx, authorFacetQuery(filter)); err != nil {
+		return nil, err
+	}
+	if facets.Publishers, err = r.facetCounts(ctx, publisherFacetQuery(filter)); err != nil {
+		return nil, err
+	}
+
+	bands := priceBands(filter.PriceBands)
+	counts, err := r.priceCounts(ctx, priceFacetQuery(filter, bands))
+	if err != nil {
+		return nil, err
+	}
+	facets.Prices = priceBuckets(bands, counts)
+
+	inStockSQL, inStockArgs, err := inStockFacetQuery(filter).PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.sql.QueryRowContext(ctx, inStockSQL, inStockArgs...).Scan(&facets.InStock); err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+// facetCounts reads id, name and count rows
+func (r *bookRepository) facetCounts(ctx context.Context, q sq.SelectBuilder) ([]domain.FacetCount, error) {
+	query, args, err := q.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.sql.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]domain.FacetCount, 0)
+	for rows.Next() {
+		var count domain.FacetCount
+		if err := rows.Scan(&count.ID, &count.Name, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// priceCounts reads bucket and count rows, keyed by bucket
+func (r *bookRepository) priceCounts(ctx context.Context, q sq.SelectBuilder) (map[int]int64, error) {
+	query, args, err := q.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.sql.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[int]int64{}
+	for rows.Next() {
+		var bucket sql.NullInt64
+		var count int64
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, err
+		}
+		// Books without a price fall in no bucket
+		if bucket.Valid {
+			counts[int(bucket.Int64)] = count
+		}
+	}
+	return counts, rows.Err()
+}
+
+func (r *bookRepository) Suggest(ctx context.Context, query string, limit int) (*domain.BookSuggestions, error) {
+	tx, err := r.sql.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Local to the transaction, like SET LOCAL
+	if _, err := tx.ExecContext(ctx, "SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)", suggestSimilarity); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, bookSuggestQuery, query, limit, isbnPrefix(query))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := &domain.BookSuggestions{
+		Titles:     []domain.Suggestion{},
+		Authors:    []domain.Suggestion{},
+		Categories: []domain.Suggestion{},
+		ISBNs:      []domain.Suggestion{},
+	}
+	for rows.Next() {
+		var kind string
+		var title sql.NullString
+		var suggestion domain.Suggestion
+		if err := rows.Scan(&kind, &suggestion.ID, &suggestion.Text, &title); err != nil {
+			return nil, err
+		}
+		suggestion.Title = title.String
+
+		switch kind {
+		case "title":
+			suggestions.Titles = append(suggestions.Titles, suggestion)
+		case "author":
+			suggestions.Authors = append(suggestions.Authors, suggestion)
+		case "category":
+			suggestions.Categories = append(suggestions.Categories, suggestion)
+		case "isbn":
+			suggestions.ISBNs = append(suggestions.ISBNs, suggestion)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, tx.Commit()
+}
+
+func (r *bookRepository) List(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Book], error) {
+	keys := newKeyset(domain.SortByCreatedAt, "created_at", "id", keyTime, domain.Desc)
+	cursor, err := keys.decode(q.Cursor)
+	if err != nil {
+		return domain.Page[domain.Book]{}, err
+	}
+
+	books := make([]domain.Book, 0)
+	err = r.db.WithContext(ctx).
 		Preload("Categories").
-		Limit(limit).
-		Offset(offset).
-		Order("created_at DESC").
+		Scopes(keys.scope(cursor, q.Offset, q.Limit)).
 		Find(&books).Error
-	return books, err
+	if err != nil {
+		return domain.Page[domain.Book]{}, err
+	}
+
+	return keysetPage(keys, cursor, q.Offset, q.Limit, books, func(book domain.Book) (any, uuid.UUID) {
+		return book.CreatedAt, book.ID
+	}), nil
 }
 
 func (r *bookRepository) Update(ctx context.Context, book *domain.Book) error {
@@ -138,7 +400,6 @@ func buildBookBaseQuery() sq.SelectBuilder {
 	return sq.Select(
 		"b.id",
 		"b.name",
-		"b.author_name",
 		"b.author_id",
 		"b.available_stock",
 		"b.image_url",
@@ -160,15 +421,16 @@ func applyBookFilters(
 	filter domain.BookFilter,
 ) sq.SelectBuilder {
 
-	if filter.Search != nil {
-		search := "%" + *filter.Search + "%"
-		q = q.Where(
-			sq.Or{
-				sq.ILike{"b.name": search},
-				sq.ILike{"b.author_name": search},
-				sq.ILike{"b.isbn": search},
-			},
-		)
+	if term, tsQuery := bookSearchTerms(filter); tsQuery != "" {
+		// The query is joined once so ranking and snippets can use it
+		q = q.
+			JoinClause("CROSS JOIN to_tsquery('english', ?) AS search_query", tsQuery).
+			Where(sq.Or{
+				sq.Expr("b.search_vector @@ search_query"),
+				sq.Eq{"b.isbn": term},
+			})
+	} else if term != "" {
+		q = q.Where(sq.Eq{"b.isbn": term})
 	}
 
 	if filter.MinPrice != nil {
@@ -200,34 +462,224 @@ func applyBookFilters(
 	}
 
 	if len(filter.CategoryIDs) > 0 {
-		q = q.
-			Join("book_categories bc ON bc.book_id = b.id").
+		// A subquery rather than a join, so books in several of the
+		// categories are listed and counted once
+		q = q.Where(sq.Expr("EXISTS (?)", sq.
+			Select("1").
+			From("book_categories bc").
+			Where("bc.book_id = b.id").
 			Where("bc.deleted_at IS NULL").
-			Where(sq.Eq{"bc.category_id": filter.CategoryIDs})
+			Where(sq.Eq{"bc.category_id": filter.CategoryIDs}),
+		))
 	}
 
 	return q
 }
 
-func applyBookSorting(
-	q sq.SelectBuilder,
-	sort *domain.SortOptions,
-) sq.SelectBuilder {
+// facetLimit caps the values listed per facet, most matches first
+const facetLimit = 50
+
+func facetBaseQuery(columns ...string) sq.SelectBuilder {
+	return sq.Select(columns...).
+		From("books b").
+		Where("b.deleted_at IS NULL")
+}
+
+func categoryFacetQuery(filter domain.BookFilter) sq.SelectBuilder {
+	filter.CategoryIDs = nil
+
+	q := facetBaseQuery("c.id", "c.name", "COUNT(DISTINCT b.id) AS matches").
+		Join("book_categories fc ON fc.book_id = b.id AND fc.deleted_at IS NULL").
+		Join("categories c ON c.id = fc.category_id AND c.deleted_at IS NULL")
+
+	return applyBookFilters(q, filter).
+		GroupBy("c.id", "c.name").
+		OrderBy("matches DESC", "c.name").
+		Limit(facetLimit)
+}
+
+func authorFacetQuery(filter domain.BookFilter) sq.SelectBuilder {
+	filter.AuthorIDs = nil
+
+	q := facetBaseQuery("a.id", "a.name", "COUNT(b.id) AS matches").
+		Join("authors a ON a.id = b.author_id")
+
+	return applyBookFilters(q, filter).
+		GroupBy("a.id", "a.name").
+		OrderBy("matches DESC", "a.name").
+		Limit(facetLimit)
+}
+
+func publisherFacetQuery(filter domain.BookFilter) sq.SelectBuilder {
+	filter.PublisherIDs = nil
+
+	q := facetBaseQuery("p.id", "p.trading_name", "COUNT(b.id) AS matches").
+		Join("publishers p ON p.id = b.publisher_id")
+
+	return applyBookFilters(q, filter).
+		GroupBy("p.id", "p.trading_name").
+		OrderBy("matches DESC", "p.trading_name").
+		Limit(facetLimit)
+}
+
+// priceFacetQuery counts the books per band. width_bucket numbers prices
+// below the first boundary 0 and from the last boundary len(bands).
+func priceFacetQuery(filter domain.BookFilter, bands []float64) sq.SelectBuilder {
+	filter.MinPrice = nil
+	filter.MaxPrice = nil
+
+	placeholders := make([]string, len(bands))
+	args := make([]any, len(bands))
+	for i, band := range bands {
+		placeholders[i] = "?"
+		args[i] = band
+	}
+	bucket := sq.Expr("width_bucket(b.price, ARRAY["+strings.Join(placeholders, ", ")+"]::numeric[]) AS bucket", args...)
 
-	// Default sort
+	q := sq.Select().
+		Column(bucket).
+		Column("COUNT(b.id)").
+		From("books b").
+		Where("b.deleted_at IS NULL")
+
+	return applyBookFilters(q, filter).GroupBy("bucket")
+}
+
+func inStockFacetQuery(filter domain.BookFilter) sq.SelectBuilder {
+	filter.MinStock = nil
+
+	q := facetBaseQuery("COUNT(b.id)").
+		Where("b.available_stock > 0")
+
+	return applyBookFilters(q, filter)
+}
+
+// priceBands sorts and dedupes the requested boundaries, ignoring negative
+// ones, and falls back to domain.DefaultPriceBands
+func priceBands(requested []float64) []float64 {
+	bands := make([]float64, 0, len(requested))
+	for _, band := range requested {
+		if band >= 0 {
+			bands = append(bands, band)
+		}
+	}
+	slices.Sort(bands)
+	bands = slices.Compact(bands)
+
+	if len(bands) == 0 {
+		return domain.DefaultPriceBands
+	}
+	if len(bands) > domain.MaxPriceBands {
+		bands = bands[:domain.MaxPriceBands]
+	}
+	return bands
+}
+
+// priceBuckets lists every band, empty ones included, with the counts
+// of priceFacetQuery
+func priceBuckets(bands []float64, counts map[int]int64) []domain.PriceBucket {
+	buckets := make([]domain.PriceBucket, 0, len(bands)+1)
+	for i := 0; i <= len(bands); i++ {
+		var bucket domain.PriceBucket
+		if i > 0 {
+			bucket.Min = &bands[i-1]
+		}
+		if i < len(bands) {
+			bucket.Max = &bands[i]
+		}
+		bucket.Count = counts[i]
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
+// bookSearchTerms returns the trimmed search of filter and the prefix
+// tsquery built from it, e.g. "Harry pot" gives "harry:* & pot:*".
+// Punctuation is dropped, so the tsquery is empty when only that is left.
+func bookSearchTerms(filter domain.BookFilter) (string, string) {
+	if filter.Search == nil {
+		return "", ""
+	}
+	term := strings.TrimSpace(*filter.Search)
+
+	words := strings.FieldsFunc(strings.ToLower(term), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+
+	return term, strings.Join(words, " & ")
+}
+
+// minISBNPrefix is the shortest prefix looked up as an ISBN
+const minISBNPrefix = 3
+
+// isbnPrefix returns query without hyphens and spaces when that looks like
+// the start of an ISBN, digits with an optional trailing check X, else ""
+func isbnPrefix(query string) string {
+	prefix := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(query))
+	if len(prefix) < minISBNPrefix {
+		return ""
+	}
+	for i, r := range prefix {
+		if r >= '0' && r <= '9' {
+			continue
+		}
+		if r == 'X' && i == len(prefix)-1 {
+			continue
+		}
+		return ""
+	}
+	return prefix
+}
+
+// bookSort returns the field and order books are listed in: the newest
+// first unless sorted by an allowed field. Relevance needs a text search
+// and lists the best matches first unless asked otherwise.
+func bookSort(sort *domain.SortOptions, ranked bool) (string, domain.SortOrder) {
 	if sort == nil {
-		return q.OrderBy("b.created_at DESC")
+		return domain.SortByCreatedAt, domain.Desc
 	}
 
-	column, ok := allowedBookSortColumns[sort.Field]
-	if !ok {
-		return q.OrderBy("b.created_at DESC")
+	if sort.Field == domain.SortByRelevance {
+		if !ranked {
+			return domain.SortByCreatedAt, domain.Desc
+		}
+		if sort.Order == domain.Asc {
+			return domain.SortByRelevance, domain.Asc
+		}
+		return domain.SortByRelevance, domain.Desc
 	}
 
-	order := "ASC"
+	if _, ok := allowedBookSortColumns[sort.Field]; !ok {
+		return domain.SortByCreatedAt, domain.Desc
+	}
 	if sort.Order == domain.Desc {
-		order = "DESC"
+		return sort.Field, domain.Desc
 	}
+	return sort.Field, domain.Asc
+}
 
-	return q.OrderBy(column + " " + order)
+// bookKeyset pages books in a sort of bookSort
+func bookKeyset(field string, order domain.SortOrder) keyset {
+	if field == domain.SortByRelevance {
+		return newKeyset(field, bookRank, "b.id", keyNumber, order)
+	}
+	column := allowedBookSortColumns[field]
+	return newKeyset(field, column.name, "b.id", column.kind, order)
+}
+
+// bookSortKey returns the value of book that field sorts by
+func bookSortKey(field string, book domain.Book) any {
+	switch field {
+	case domain.SortByPrice:
+		return book.Price
+	case domain.SortByName:
+		return book.Name
+	case domain.SortByStock:
+		return int64(book.AvailableStock)
+	default:
+		return book.CreatedAt
+	}
 }
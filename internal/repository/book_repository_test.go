@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"html"
 	"strings"
 	"testing"
 
@@ -64,10 +65,11 @@ func TestBookRepo_FindByIDAndList(t *testing.T) {
 	require.Equal(t, publisherID, found.Publisher.ID)
 	require.Len(t, found.Categories, 1)
 
-	list, err := repo.List(ctx, 10, 0)
+	list, err := repo.List(ctx, domain.QueryOptions{Limit: 10})
 	require.NoError(t, err)
-	require.Len(t, list, 1)
-	require.Len(t, list[0].Categories, 1)
+	require.Len(t, list.Items, 1)
+	require.Len(t, list.Items[0].Categories, 1)
+	require.Empty(t, list.NextCursor)
 
 	found.Name = "Updated"
 	require.NoError(t, repo.Update(ctx, found))
@@ -104,18 +106,150 @@ func TestBookQueryHelpers_FilterAndSort(t *testing.T) {
 		PublisherIDs: []uuid.UUID{publisherID},
 		CategoryIDs:  []uuid.UUID{categoryID},
 	})
-	q = applyBookSorting(q, &domain.SortOptions{Field: domain.SortByPrice, Order: domain.Desc})
+	q = bookKeyset(bookSort(&domain.SortOptions{Field: domain.SortByPrice, Order: domain.Desc}, true)).
+		apply(q, nil, 0, 10)
 
 	sqlText, args, err := q.PlaceholderFormat(sq.Dollar).ToSql()
 	require.NoError(t, err)
-	require.True(t, strings.Contains(sqlText, "book_categories bc ON bc.book_id = b.id"))
-	require.True(t, strings.Contains(sqlText, "b.price DESC"))
+	require.True(t, strings.Contains(sqlText, "CROSS JOIN to_tsquery('english', $1) AS search_query"))
+	require.True(t, strings.Contains(sqlText, "(b.search_vector @@ search_query OR b.isbn = $2)"))
+	require.Equal(t, "harry:*", args[0])
+	require.True(t, strings.Contains(sqlText, "EXISTS (SELECT 1 FROM book_categories bc WHERE bc.book_id = b.id AND bc.deleted_at IS NULL AND bc.category_id IN ($10))"))
+	require.True(t, strings.HasSuffix(sqlText, "ORDER BY b.price DESC, b.id DESC LIMIT 11"))
 	require.NotEmpty(t, args)
 
-	defaultSorted, _, err := applyBookSorting(
-		sq.Select("b.id").From("books b"),
-		nil,
-	).PlaceholderFormat(sq.Dollar).ToSql()
+	field, order := bookSort(nil, false)
+	defaultSorted, _, err := bookKeyset(field, order).
+		apply(sq.Select("b.id").From("books b"), nil, 0, 10).
+		PlaceholderFormat(sq.Dollar).ToSql()
 	require.NoError(t, err)
-	require.True(t, strings.Contains(defaultSorted, "b.created_at DESC"))
+	require.True(t, strings.Contains(defaultSorted, "ORDER BY b.created_at DESC, b.id DESC"))
+}
+
+func TestBookFacetQueries_IgnoreOwnCriterion(t *testing.T) {
+	minPrice := 5.0
+	minStock := 1
+	authorID := uuid.New()
+	publisherID := uuid.New()
+	categoryID := uuid.New()
+
+	filter := domain.BookFilter{
+		MinPrice:     &minPrice,
+		MinStock:     &minStock,
+		AuthorIDs:    []uuid.UUID{authorID},
+		PublisherIDs: []uuid.UUID{publisherID},
+		CategoryIDs:  []uuid.UUID{categoryID},
+	}
+
+	tests := []struct {
+		name    string
+		query   sq.SelectBuilder
+		without string
+	}{
+		{"categories", categoryFacetQuery(filter), "bc.category_id"},
+		{"authors", authorFacetQuery(filter), "b.author_id IN"},
+		{"publishers", publisherFacetQuery(filter), "b.publisher_id IN"},
+		{"prices", priceFacetQuery(filter, domain.DefaultPriceBands), "b.price >="},
+		{"in stock", inStockFacetQuery(filter), "b.available_stock >="},
+	}
+
+	criteria := []string{"bc.category_id", "b.author_id IN", "b.publisher_id IN", "b.price >=", "b.available_stock >="}
+	for _, tt := range tests {
+		sqlText, _, err := tt.query.PlaceholderFormat(sq.Dollar).ToSql()
+		require.NoError(t, err, tt.name)
+
+		for _, criterion := range criteria {
+			if criterion == tt.without {
+				require.NotContains(t, sqlText, criterion, tt.name)
+			} else {
+				require.Contains(t, sqlText, criterion, tt.name)
+			}
+		}
+	}
+}
+
+func TestPriceFacetQuery(t *testing.T) {
+	sqlText, args, err := priceFacetQuery(domain.BookFilter{}, []float64{10, 20}).PlaceholderFormat(sq.Dollar).ToSql()
+
+	require.NoError(t, err)
+	require.Equal(t, "SELECT width_bucket(b.price, ARRAY[$1, $2]::numeric[]) AS bucket, COUNT(b.id) FROM books b WHERE b.deleted_at IS NULL GROUP BY bucket", sqlText)
+	require.Equal(t, []any{10.0, 20.0}, args)
+}
+
+func TestPriceBands(t *testing.T) {
+	require.Equal(t, domain.DefaultPriceBands, priceBands(nil))
+	require.Equal(t, domain.DefaultPriceBands, priceBands([]float64{-5}))
+	require.Equal(t, []float64{0, 15, 30}, priceBands([]float64{30, 15, 0, 15, -1}))
+}
+
+func TestPriceBuckets(t *testing.T) {
+	buckets := priceBuckets([]float64{10, 20}, map[int]int64{0: 4, 2: 7})
+
+	require.Len(t, buckets, 3)
+	require.Nil(t, buckets[0].Min)
+	require.Equal(t, 10.0, *buckets[0].Max)
+	require.Equal(t, int64(4), buckets[0].Count)
+	require.Equal(t, 10.0, *buckets[1].Min)
+	require.Equal(t, 20.0, *buckets[1].Max)
+	require.Equal(t, int64(0), buckets[1].Count)
+	require.Equal(t, 20.0, *buckets[2].Min)
+	require.Nil(t, buckets[2].Max)
+	require.Equal(t, int64(7), buckets[2].Count)
+}
+
+func TestBookSearchTerms(t *testing.T) {
+	tests := []struct {
+		search      string
+		wantTerm    string
+		wantTSQuery string
+	}{
+		{"Harry pot", "Harry pot", "harry:* & pot:*"},
+		{"  o'brien & co!  ", "o'brien & co!", "o:* & brien:* & co:*"},
+		{"978-0-13", "978-0-13", "978:* & 0:* & 13:*"},
+		{"&|!", "&|!", ""},
+	}
+
+	for _, tt := range tests {
+		search := tt.search
+		term, tsQuery := bookSearchTerms(domain.BookFilter{Search: &search})
+		require.Equal(t, tt.wantTerm, term, tt.search)
+		require.Equal(t, tt.wantTSQuery, tsQuery, tt.search)
+	}
+
+	term, tsQuery := bookSearchTerms(domain.BookFilter{})
+	require.Empty(t, term)
+	require.Empty(t, tsQuery)
+}
+
+func TestBookSort_Relevance(t *testing.T) {
+	relevance := &domain.SortOptions{Field: domain.SortByRelevance}
+
+	field, order := bookSort(relevance, true)
+	require.Equal(t, domain.SortByRelevance, field)
+	require.Equal(t, domain.Desc, order)
+	require.Equal(t, []string{bookRank + " DESC", "b.id DESC"}, bookKeyset(field, order).orderBy(nil))
+
+	// Without a text search there is nothing to rank
+	field, order = bookSort(relevance, false)
+	require.Equal(t, domain.SortByCreatedAt, field)
+	require.Equal(t, domain.Desc, order)
+
+	// Unknown fields fall back too, known ones sort ascending by default
+	field, _ = bookSort(&domain.SortOptions{Field: "isbn"}, false)
+	require.Equal(t, domain.SortByCreatedAt, field)
+	field, order = bookSort(&domain.SortOptions{Field: domain.SortByPrice}, false)
+	require.Equal(t, domain.SortByPrice, field)
+	require.Equal(t, domain.Asc, order)
+}
+
+func TestHTMLEscaped(t *testing.T) {
+	db := setupTestDB(t)
+
+	raw := `<script>alert("x")</script> & Tom's <b>bold</b> &amp;`
+	var escaped string
+	require.NoError(t, db.Raw("SELECT "+htmlEscaped("?"), raw).Scan(&escaped).Error)
+
+	require.Equal(t, html.EscapeString(raw), escaped)
+	require.Contains(t, bookNameHeadline, htmlEscaped("b.name"))
+	require.Contains(t, bookDescriptionHeadline, htmlEscaped("b.description"))
 }
@@ -180,3 +180,16 @@ func (r *cartRepo) ClearCart(
 	`
 	return execWithTx(ctx, r.db, query, cartID)
 }
+
+func (r *cartRepo) ClearUserCart(
+	ctx context.Context,
+	userID uuid.UUID,
+) error {
+	query := `
+		UPDATE cart_items
+		SET deleted_at = NOW()
+		WHERE cart_id IN (SELECT id FROM carts WHERE user_id = $1)
+		  AND deleted_at IS NULL;
+	`
+	return execWithTx(ctx, r.db, query, userID)
+}
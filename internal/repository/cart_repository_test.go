@@ -55,5 +55,12 @@ func TestCartRepo_UpsertAndRemoveAndClear(t *testing.T) {
 		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
 	require.NoError(t, repo.ClearCart(context.Background(), cartID))
 
+	// Clearing by user must not create a cart
+	userID := uuid.New()
+	mock.ExpectExec(`UPDATE cart_items SET deleted_at = NOW\(\) WHERE cart_id IN \(SELECT id FROM carts WHERE user_id = \$1\)`).
+		WithArgs(userID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	require.NoError(t, repo.ClearUserCart(context.Background(), userID))
+
 	require.NoError(t, mock.ExpectationsWereMet())
 }
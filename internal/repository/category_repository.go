@@ -43,17 +43,25 @@ func (r *categoryRepo) FindByName(ctx context.Context, name string) (domain.Cate
 	return category, err
 }
 
-func (r *categoryRepo) List(ctx context.Context, limit, offset int) ([]domain.Category, error) {
-	var categories []domain.Category
+func (r *categoryRepo) List(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Category], error) {
+	keys := newKeyset(domain.SortByName, "name", "id", keyText, domain.Asc)
+	cursor, err := keys.decode(q.Cursor)
+	if err != nil {
+		return domain.Page[domain.Category]{}, err
+	}
 
-	err := r.gorm.WithContext(ctx).
+	categories := make([]domain.Category, 0)
+	err = r.gorm.WithContext(ctx).
 		Where("deleted_at IS NULL").
-		Limit(limit).
-		Offset(offset).
-		Order("name ASC").
+		Scopes(keys.scope(cursor, q.Offset, q.Limit)).
 		Find(&categories).Error
+	if err != nil {
+		return domain.Page[domain.Category]{}, err
+	}
 
-	return categories, err
+	return keysetPage(keys, cursor, q.Offset, q.Limit, categories, func(category domain.Category) (any, uuid.UUID) {
+		return category.Name, category.ID
+	}), nil
 }
 
 func (r *categoryRepo) Create(ctx context.Context, category *domain.Category) error {
@@ -29,10 +29,21 @@ func TestCategoryRepo_CRUDAndList(t *testing.T) {
 	second := &domain.Category{ID: uuid.New(), Name: "Science"}
 	require.NoError(t, repo.Create(ctx, second))
 
-	list, err := repo.List(ctx, 1, 0)
+	list, err := repo.List(ctx, domain.QueryOptions{Limit: 1})
 	require.NoError(t, err)
-	require.Len(t, list, 1)
-	require.Equal(t, "Fiction", list[0].Name)
+	require.Len(t, list.Items, 1)
+	require.Equal(t, "Fiction", list.Items[0].Name)
+	require.Empty(t, list.PrevCursor)
+
+	next, err := repo.List(ctx, domain.QueryOptions{Limit: 1, Cursor: list.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, next.Items, 1)
+	require.Equal(t, "Science", next.Items[0].Name)
+	require.Empty(t, next.NextCursor)
+
+	prev, err := repo.List(ctx, domain.QueryOptions{Limit: 1, Cursor: next.PrevCursor})
+	require.NoError(t, err)
+	require.Equal(t, list.Items, prev.Items)
 
 	category.Name = "Fiction Updated"
 	require.NoError(t, repo.Update(ctx, category))
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+)
+
+type identityRepo struct {
+	db   domain.DBExecer
+	gorm *gorm.DB
+	sb   squirrel.StatementBuilderType
+}
+
+func NewIdentityRepo(db *pgxpool.Pool, gormDB *gorm.DB) domain.IdentityRepository {
+	return &identityRepo{
+		db:   db,
+		gorm: gormDB,
+		sb:   squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (r *identityRepo) FindByProviderSubject(
+	ctx context.Context,
+	provider string,
+	subject string,
+) (*domain.UserIdentity, error) {
+
+	var identity domain.UserIdentity
+
+	err := r.gorm.
+		WithContext(ctx).
+		Where("provider = ? AND subject = ?", provider, subject).
+		First(&identity).
+		Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+func (r *identityRepo) Create(
+	ctx context.Context,
+	identity *domain.UserIdentity,
+) error {
+
+	query, args, err := r.sb.
+		Insert("user_identities").
+		Columns(
+			"user_id",
+			"provider",
+			"subject",
+			"email",
+		).
+		Values(
+			identity.UserID,
+			identity.Provider,
+			identity.Subject,
+			identity.Email,
+		).
+		Suffix("RETURNING id, created_at, updated_at").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	row := queryRowWithTx(ctx, r.db, query, args...)
+
+	return row.Scan(
+		&identity.ID,
+		&identity.CreatedAt,
+		&identity.UpdatedAt,
+	)
+}
+
+func (r *identityRepo) DeleteByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) error {
+
+	query, args, err := r.sb.
+		Delete("user_identities").
+		Where(squirrel.Eq{"user_id": userID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+func (r *identityRepo) CreateLoginState(
+	ctx context.Context,
+	state *domain.OIDCLoginState,
+) error {
+
+	query, args, err := r.sb.
+		Insert("oidc_login_states").
+		Columns(
+			"state_hash",
+			"provider",
+			"code_verifier",
+			"nonce",
+			"expires_at",
+		).
+		Values(
+			state.StateHash,
+			state.Provider,
+			state.CodeVerifier,
+			state.Nonce,
+			state.ExpiresAt,
+		).
+		Suffix("RETURNING created_at").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	row := queryRowWithTx(ctx, r.db, query, args...)
+
+	return row.Scan(&state.CreatedAt)
+}
+
+func (r *identityRepo) ConsumeLoginState(
+	ctx context.Context,
+	stateHash string,
+) (*domain.OIDCLoginState, error) {
+
+	query, args, err := r.sb.
+		Delete("oidc_login_states").
+		Where(squirrel.Eq{"state_hash": stateHash}).
+		Where("expires_at > NOW()").
+		Suffix("RETURNING state_hash, provider, code_verifier, nonce, expires_at, created_at").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var state domain.OIDCLoginState
+
+	row := queryRowWithTx(ctx, r.db, query, args...)
+	if err := row.Scan(
+		&state.StateHash,
+		&state.Provider,
+		&state.CodeVerifier,
+		&state.Nonce,
+		&state.ExpiresAt,
+		&state.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/require"
+
+	"booknest/internal/domain"
+)
+
+func TestIdentityRepo_FindByProviderSubject(t *testing.T) {
+	db := setupTestDB(t, &domain.UserIdentity{})
+
+	identity := domain.UserIdentity{ID: uuid.New(), UserID: uuid.New(), Provider: "google", Subject: "42"}
+	require.NoError(t, db.Create(&identity).Error)
+
+	repo := &identityRepo{gorm: db}
+
+	found, err := repo.FindByProviderSubject(context.Background(), "google", "42")
+	require.NoError(t, err)
+	require.Equal(t, identity.UserID, found.UserID)
+
+	// The same subject at another provider is a different person
+	_, err = repo.FindByProviderSubject(context.Background(), "github", "42")
+	require.Error(t, err)
+}
+
+func TestIdentityRepo_Create(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &identityRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	identity := &domain.UserIdentity{UserID: uuid.New(), Provider: "google", Subject: "42", Email: "reader@example.com"}
+
+	id := uuid.New()
+	mock.ExpectQuery("INSERT INTO user_identities").
+		WithArgs(identity.UserID, "google", "42", "reader@example.com").
+		WillReturnRows(
+			pgxmock.NewRows([]string{"id", "created_at", "updated_at"}).
+				AddRow(id, time.Now(), time.Now()),
+		)
+
+	err = repo.Create(context.Background(), identity)
+
+	require.NoError(t, err)
+	require.Equal(t, id, identity.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdentityRepo_DeleteByUser(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &identityRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	userID := uuid.New()
+	mock.ExpectExec("DELETE FROM user_identities").
+		WithArgs(userID.String()).
+		WillReturnResult(pgxmock.NewResult("DELETE", 2))
+
+	err = repo.DeleteByUser(context.Background(), userID)
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdentityRepo_ConsumeLoginState(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &identityRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	now := time.Now()
+	mock.ExpectQuery(`DELETE FROM oidc_login_states WHERE state_hash = \$1 AND expires_at > NOW\(\) RETURNING`).
+		WithArgs("hash").
+		WillReturnRows(
+			pgxmock.NewRows([]string{"state_hash", "provider", "code_verifier", "nonce", "expires_at", "created_at"}).
+				AddRow("hash", "google", "verifier", "nonce", now.Add(time.Minute), now),
+		)
+	mock.ExpectQuery("DELETE FROM oidc_login_states").
+		WithArgs("hash").
+		WillReturnError(pgx.ErrNoRows)
+
+	state, err := repo.ConsumeLoginState(context.Background(), "hash")
+	require.NoError(t, err)
+	require.Equal(t, "verifier", state.CodeVerifier)
+
+	// The second callback with the same state finds nothing
+	_, err = repo.ConsumeLoginState(context.Background(), "hash")
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+)
+
+type invitationRepo struct {
+	db   domain.DBExecer
+	gorm *gorm.DB
+	sb   squirrel.StatementBuilderType
+}
+
+func NewInvitationRepo(db *pgxpool.Pool, gormDB *gorm.DB) domain.InvitationRepository {
+	return &invitationRepo{
+		db:   db,
+		gorm: gormDB,
+		sb:   squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (r *invitationRepo) Create(
+	ctx context.Context,
+	invitation *domain.Invitation,
+) error {
+
+	query, args, err := r.sb.
+		Insert("invitations").
+		Columns(
+			"email",
+			"role",
+			"token_hash",
+			"invited_by",
+			"expires_at",
+		).
+		Values(
+			invitation.Email,
+			invitation.Role,
+			invitation.TokenHash,
+			invitation.InvitedBy,
+			invitation.ExpiresAt,
+		).
+		Suffix("RETURNING id, created_at, updated_at").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	row := queryRowWithTx(ctx, r.db, query, args...)
+
+	return row.Scan(
+		&invitation.ID,
+		&invitation.CreatedAt,
+		&invitation.UpdatedAt,
+	)
+}
+
+func (r *invitationRepo) FindByTokenHash(
+	ctx context.Context,
+	tokenHash string,
+) (*domain.Invitation, error) {
+
+	var invitation domain.Invitation
+
+	err := r.gorm.
+		WithContext(ctx).
+		Where("token_hash = ?", tokenHash).
+		First(&invitation).
+		Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+func (r *invitationRepo) MarkAccepted(
+	ctx context.Context,
+	id uuid.UUID,
+) (bool, error) {
+
+	query, args, err := r.sb.
+		Update("invitations").
+		Set("accepted_at", squirrel.Expr("NOW()")).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": id}).
+		Where("accepted_at IS NULL").
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := execWithTxTag(ctx, r.db, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/require"
+
+	"booknest/internal/domain"
+)
+
+func newInvitationRepoWithMock(t *testing.T) (*invitationRepo, pgxmock.PgxPoolIface) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(mock.Close)
+
+	return &invitationRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}, mock
+}
+
+func TestInvitationRepo_Create(t *testing.T) {
+	repo, mock := newInvitationRepoWithMock(t)
+
+	invitation := &domain.Invitation{
+		Email:     "admin@example.com",
+		Role:      domain.UserRoleAdmin,
+		TokenHash: "hash",
+		InvitedBy: uuid.New(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	id := uuid.New()
+
+	mock.ExpectQuery("INSERT INTO invitations").
+		WithArgs(
+			invitation.Email,
+			invitation.Role,
+			invitation.TokenHash,
+			invitation.InvitedBy,
+			invitation.ExpiresAt,
+		).
+		WillReturnRows(
+			pgxmock.NewRows([]string{"id", "created_at", "updated_at"}).
+				AddRow(id, time.Now(), time.Now()),
+		)
+
+	err := repo.Create(context.Background(), invitation)
+
+	require.NoError(t, err)
+	require.Equal(t, id, invitation.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInvitationRepo_FindByTokenHash(t *testing.T) {
+	db := setupTestDB(t, &domain.Invitation{})
+
+	invitation := domain.Invitation{
+		ID:        uuid.New(),
+		Email:     "admin@example.com",
+		Role:      domain.UserRoleAdmin,
+		TokenHash: "hash",
+		InvitedBy: uuid.New(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	require.NoError(t, db.Create(&invitation).Error)
+
+	repo := &invitationRepo{gorm: db}
+
+	found, err := repo.FindByTokenHash(context.Background(), "hash")
+	require.NoError(t, err)
+	require.Equal(t, invitation.ID, found.ID)
+	require.Equal(t, domain.UserRoleAdmin, found.Role)
+
+	_, err = repo.FindByTokenHash(context.Background(), "other")
+	require.Error(t, err)
+}
+
+func TestInvitationRepo_MarkAccepted(t *testing.T) {
+	repo, mock := newInvitationRepoWithMock(t)
+
+	id := uuid.New()
+
+	mock.ExpectExec("UPDATE invitations").
+		WithArgs(id.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("UPDATE invitations").
+		WithArgs(id.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	accepted, err := repo.MarkAccepted(context.Background(), id)
+	require.NoError(t, err)
+	require.True(t, accepted)
+
+	// A second accept loses the race
+	accepted, err = repo.MarkAccepted(context.Background(), id)
+	require.NoError(t, err)
+	require.False(t, accepted)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+)
+
+// keyKind is the type a sort key is compared as
+type keyKind int
+
+const (
+	keyText keyKind = iota
+	keyNumber
+	keyInt
+	keyTime
+)
+
+// sortColumn is a column a list may be sorted by
+type sortColumn struct {
+	name string
+	kind keyKind
+}
+
+// keyset pages a list ordered by a sort key, with the id breaking ties.
+// Rather than skipping an offset, a page reads on from the key and id of
+// the row next to it, which an opaque cursor carries.
+type keyset struct {
+	sort   string // e.g. "price:desc", cursors of another sort are refused
+	column string
+	id     string
+	kind   keyKind
+	desc   bool
+}
+
+func newKeyset(field, column, id string, kind keyKind, order domain.SortOrder) keyset {
+	return keyset{
+		sort:   field + ":" + string(order),
+		column: column,
+		id:     id,
+		kind:   kind,
+		desc:   order == domain.Desc,
+	}
+}
+
+// pageCursor is a decoded cursor. Before marks a previous cursor, which
+// reads the rows before the key instead of after it.
+type pageCursor struct {
+	Sort   string    `json:"s"`
+	Key    any       `json:"k"`
+	ID     uuid.UUID `json:"i"`
+	Before bool      `json:"b,omitempty"`
+}
+
+// decode returns nil for an empty cursor and domain.ErrInvalidCursor for
+// one that is malformed or of another sort
+func (k keyset) decode(raw string) (*pageCursor, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != k.sort || c.ID == uuid.Nil {
+		return nil, domain.ErrInvalidCursor
+	}
+	if c.Key, err = k.parseKey(c.Key); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// parseKey turns a key read back from JSON into the type it compares as
+func (k keyset) parseKey(key any) (any, error) {
+	switch k.kind {
+	case keyText:
+		if s, ok := key.(string); ok {
+			return s, nil
+		}
+	case keyNumber:
+		if f, ok := key.(float64); ok {
+			return f, nil
+		}
+	case keyInt:
+		if f, ok := key.(float64); ok && f == math.Trunc(f) {
+			return int64(f), nil
+		}
+	case keyTime:
+		if s, ok := key.(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return nil, domain.ErrInvalidCursor
+}
+
+func (k keyset) encode(key any, id uuid.UUID, before bool) string {
+	if t, ok := key.(time.Time); ok {
+		key = t.Format(time.RFC3339Nano)
+	}
+
+	// Only basic types and a uuid, which cannot fail to marshal
+	data, _ := json.Marshal(pageCursor{Sort: k.sort, Key: key, ID: id, Before: before})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// descending tells the order rows are read in, which for a previous
+// cursor is the reverse of the list order
+func (k keyset) descending(c *pageCursor) bool {
+	return k.desc != (c != nil && c.Before)
+}
+
+// where selects the rows past the cursor in reading order
+func (k keyset) where(c *pageCursor) (string, []any) {
+	op := ">"
+	if k.descending(c) {
+		op = "<"
+	}
+	return fmt.Sprintf("(%s, %s) %s (?, ?)", k.column, k.id, op), []any{c.Key, c.ID}
+}
+
+func (k keyset) orderBy(c *pageCursor) []string {
+	dir := "ASC"
+	if k.descending(c) {
+		dir = "DESC"
+	}
+	return []string{k.column + " " + dir, k.id + " " + dir}
+}
+
+// maxKeysetLimit bounds a page however large a limit was asked for, and
+// keeps the extra row read by apply from overflowing
+const maxKeysetLimit = 1000
+
+func clampLimit(limit uint64) uint64 {
+	return min(limit, maxKeysetLimit)
+}
+
+// apply reads one row more than limit, which tells whether there is a
+// further page. The offset only counts without a cursor.
+func (k keyset) apply(q sq.SelectBuilder, c *pageCursor, offset, limit uint64) sq.SelectBuilder {
+	limit = clampLimit(limit)
+	if c != nil {
+		cond, args := k.where(c)
+		q = q.Where(cond, args...)
+	} else if offset > 0 {
+		q = q.Offset(offset)
+	}
+	return q.OrderBy(k.orderBy(c)...).Limit(limit + 1)
+}
+
+// scope is apply for GORM queries
+func (k keyset) scope(c *pageCursor, offset, limit uint64) func(*gorm.DB) *gorm.DB {
+	limit = clampLimit(limit)
+	return func(db *gorm.DB) *gorm.DB {
+		if c != nil {
+			cond, args := k.where(c)
+			db = db.Where(cond, args...)
+		} else if offset > 0 {
+			db = db.Offset(int(offset))
+		}
+		for _, order := range k.orderBy(c) {
+			db = db.Order(order)
+		}
+		return db.Limit(int(limit) + 1)
+	}
+}
+
+// keysetPage makes a page of the rows read by a query with k applied:
+// it drops the extra row, restores the list order and sets the cursors.
+// key returns the sort key and id of a row.
+func keysetPage[T any](
+	k keyset,
+	c *pageCursor,
+	offset, limit uint64,
+	rows []T,
+	key func(T) (any, uuid.UUID),
+) domain.Page[T] {
+
+	limit = clampLimit(limit)
+	more := uint64(len(rows)) > limit
+	if more {
+		rows = rows[:limit]
+	}
+
+	before := c != nil && c.Before
+	if before {
+		slices.Reverse(rows)
+	}
+
+	page := domain.Page[T]{Items: rows}
+	if len(rows) == 0 {
+		return page
+	}
+
+	// Reading forwards there is more before when we came from somewhere,
+	// reading backwards there is more after: the page we came from
+	if more && before || !before && (c != nil || offset > 0) {
+		firstKey, firstID := key(rows[0])
+		page.PrevCursor = k.encode(firstKey, firstID, true)
+	}
+	if more && !before || before {
+		lastKey, lastID := key(rows[len(rows)-1])
+		page.NextCursor = k.encode(lastKey, lastID, false)
+	}
+
+	return page
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+)
+
+func TestKeyset_CursorRoundTrip(t *testing.T) {
+	id := uuid.New()
+	at := time.Date(2026, 4, 1, 10, 30, 0, 123456000, time.UTC)
+
+	tests := []struct {
+		keys keyset
+		key  any
+		want any
+	}{
+		{newKeyset("created_at", "created_at", "id", keyTime, domain.Desc), at, at},
+		{newKeyset("price", "b.price", "b.id", keyNumber, domain.Asc), 12.5, 12.5},
+		{newKeyset("available_stock", "b.available_stock", "b.id", keyInt, domain.Asc), int64(7), int64(7)},
+		{newKeyset("name", "name", "id", keyText, domain.Asc), "Dune", "Dune"},
+	}
+
+	for _, tt := range tests {
+		c, err := tt.keys.decode(tt.keys.encode(tt.key, id, true))
+		require.NoError(t, err, tt.keys.sort)
+		require.Equal(t, tt.want, c.Key, tt.keys.sort)
+		require.Equal(t, id, c.ID, tt.keys.sort)
+		require.True(t, c.Before, tt.keys.sort)
+	}
+}
+
+func TestKeyset_DecodeRejects(t *testing.T) {
+	byName := newKeyset("name", "name", "id", keyText, domain.Asc)
+	byPrice := newKeyset("price", "b.price", "b.id", keyNumber, domain.Asc)
+
+	c, err := byName.decode("")
+	require.NoError(t, err)
+	require.Nil(t, c)
+
+	for _, raw := range []string{
+		"%%%",
+		"bm90IGpzb24",
+		byName.encode("Dune", uuid.New(), false),
+		byPrice.encode("Dune", uuid.New(), false),
+		byName.encode("Dune", uuid.Nil, false),
+	} {
+		_, err := byPrice.decode(raw)
+		require.ErrorIs(t, err, domain.ErrInvalidCursor, raw)
+	}
+}
+
+func TestKeyset_Directions(t *testing.T) {
+	keys := newKeyset("created_at", "created_at", "id", keyTime, domain.Desc)
+	id := uuid.New()
+
+	require.Equal(t, []string{"created_at DESC", "id DESC"}, keys.orderBy(nil))
+
+	after := &pageCursor{Key: time.Now(), ID: id}
+	cond, args := keys.where(after)
+	require.Equal(t, "(created_at, id) < (?, ?)", cond)
+	require.Equal(t, id, args[1])
+
+	// The page before a cursor is read backwards
+	before := &pageCursor{Key: time.Now(), ID: id, Before: true}
+	cond, _ = keys.where(before)
+	require.Equal(t, "(created_at, id) > (?, ?)", cond)
+	require.Equal(t, []string{"created_at ASC", "id ASC"}, keys.orderBy(before))
+}
+
+func TestKeysetPage(t *testing.T) {
+	keys := newKeyset("name", "name", "id", keyText, domain.Asc)
+	key := func(name string) (any, uuid.UUID) { return name, uuid.NewSHA1(uuid.Nil, []byte(name)) }
+
+	// First page, with one row more than the limit
+	page := keysetPage(keys, nil, 0, 2, []string{"a", "b", "c"}, key)
+	require.Equal(t, []string{"a", "b"}, page.Items)
+	require.NotEmpty(t, page.NextCursor)
+	require.Empty(t, page.PrevCursor)
+
+	next, err := keys.decode(page.NextCursor)
+	require.NoError(t, err)
+	require.Equal(t, "b", next.Key)
+
+	// Last page, reached by a cursor
+	page = keysetPage(keys, next, 0, 2, []string{"c"}, key)
+	require.Equal(t, []string{"c"}, page.Items)
+	require.Empty(t, page.NextCursor)
+	require.NotEmpty(t, page.PrevCursor)
+
+	// Going back, rows come in reverse and there is more before
+	prev, err := keys.decode(page.PrevCursor)
+	require.NoError(t, err)
+	page = keysetPage(keys, prev, 0, 1, []string{"b", "a"}, key)
+	require.Equal(t, []string{"b"}, page.Items)
+	require.NotEmpty(t, page.NextCursor)
+	require.NotEmpty(t, page.PrevCursor)
+
+	// A page skipped into by offset can go back too
+	page = keysetPage(keys, nil, 2, 2, []string{"c"}, key)
+	require.NotEmpty(t, page.PrevCursor)
+
+	page = keysetPage(keys, nil, 0, 2, []string{}, key)
+	require.Empty(t, page.Items)
+	require.Empty(t, page.NextCursor)
+}
+
+func TestKeyset_ClampsLimit(t *testing.T) {
+	keys := newKeyset("name", "name", "id", keyText, domain.Asc)
+
+	// The extra row must not wrap the largest limit around to zero
+	query, _, err := keys.apply(sq.Select("id").From("authors"), nil, 0, math.MaxUint64).ToSql()
+	require.NoError(t, err)
+	require.Contains(t, query, "LIMIT 1001")
+
+	// Nor turn it negative, which GORM takes as no limit at all
+	db := setupTestDB(t, &domain.Author{})
+	stmt := db.Session(&gorm.Session{DryRun: true}).
+		Scopes(keys.scope(nil, 0, math.MaxUint64)).
+		Find(&[]domain.Author{}).
+		Statement
+	require.Contains(t, stmt.SQL.String(), "LIMIT 1001")
+
+	key := func(n int) (any, uuid.UUID) { return float64(n), uuid.New() }
+	rows := make([]int, maxKeysetLimit+1)
+	page := keysetPage(keys, nil, 0, math.MaxUint64, rows, key)
+	require.Len(t, page.Items, maxKeysetLimit)
+	require.NotEmpty(t, page.NextCursor)
+}
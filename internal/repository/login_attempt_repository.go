@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+)
+
+type loginAttemptRepo struct {
+	db   domain.DBExecer
+	gorm *gorm.DB
+	sb   squirrel.StatementBuilderType
+}
+
+// NewLoginAttemptRepo stores attempts in Postgres so every replica sees them
+func NewLoginAttemptRepo(db *pgxpool.Pool, gormDB *gorm.DB) domain.LoginAttemptRepository {
+	return &loginAttemptRepo{
+		db:   db,
+		gorm: gormDB,
+		sb:   squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (r *loginAttemptRepo) Find(
+	ctx context.Context,
+	key string,
+) (*domain.LoginAttempt, error) {
+
+	var attempts []domain.LoginAttempt
+
+	err := r.gorm.
+		WithContext(ctx).
+		Where("key = ?", key).
+		Limit(1).
+		Find(&attempts).
+		Error
+
+	if err != nil {
+		return nil, err
+	}
+	if len(attempts) == 0 {
+		return nil, nil
+	}
+
+	return &attempts[0], nil
+}
+
+// RecordFailure increments the counter in a single statement so
+// concurrent failures on different replicas are all counted.
+func (r *loginAttemptRepo) RecordFailure(
+	ctx context.Context,
+	key string,
+	window time.Duration,
+) (*domain.LoginAttempt, error) {
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, NOW())
+		ON CONFLICT (key) DO UPDATE
+		SET failures = CASE
+		        WHEN login_attempts.last_failure_at < NOW() - make_interval(secs => $2) THEN 1
+		        ELSE login_attempts.failures + 1
+		    END,
+		    last_failure_at = NOW(),
+		    updated_at = NOW()
+		RETURNING key, failures, last_failure_at, locked_until;
+	`
+
+	row := queryRowWithTx(ctx, r.db, query, key, window.Seconds())
+
+	var attempt domain.LoginAttempt
+	err := row.Scan(
+		&attempt.Key,
+		&attempt.Failures,
+		&attempt.LastFailureAt,
+		&attempt.LockedUntil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+func (r *loginAttemptRepo) Lock(
+	ctx context.Context,
+	key string,
+	until time.Time,
+) error {
+
+	query, args, err := r.sb.
+		Update("login_attempts").
+		Set("locked_until", until).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"key": key}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+func (r *loginAttemptRepo) Reset(
+	ctx context.Context,
+	key string,
+) error {
+
+	query, args, err := r.sb.
+		Delete("login_attempts").
+		Where(squirrel.Eq{"key": key}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+// memoryAttemptLimit bounds the in-process store; stale keys are
+// dropped once it is reached.
+const memoryAttemptLimit = 10000
+
+type memoryLoginAttemptRepo struct {
+	mu       sync.Mutex
+	attempts map[string]domain.LoginAttempt
+	now      func() time.Time
+}
+
+// NewMemoryLoginAttemptRepo keeps attempts in process memory.
+// Suitable for a single instance; use NewLoginAttemptRepo with replicas.
+func NewMemoryLoginAttemptRepo() domain.LoginAttemptRepository {
+	return &memoryLoginAttemptRepo{
+		attempts: map[string]domain.LoginAttempt{},
+		now:      time.Now,
+	}
+}
+
+func (r *memoryLoginAttemptRepo) Find(
+	ctx context.Context,
+	key string,
+) (*domain.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (r *memoryLoginAttemptRepo) RecordFailure(
+	ctx context.Context,
+	key string,
+	window time.Duration,
+) (*domain.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		if len(r.attempts) >= memoryAttemptLimit {
+			r.prune(now, window)
+		}
+		attempt = domain.LoginAttempt{Key: key}
+	}
+
+	if attempt.LastFailureAt.Before(now.Add(-window)) {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+
+	r.attempts[key] = attempt
+	return &attempt, nil
+}
+
+func (r *memoryLoginAttemptRepo) Lock(
+	ctx context.Context,
+	key string,
+	until time.Time,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		return nil
+	}
+	attempt.LockedUntil = &until
+	r.attempts[key] = attempt
+	return nil
+}
+
+func (r *memoryLoginAttemptRepo) Reset(
+	ctx context.Context,
+	key string,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
+
+// prune drops keys that are neither locked nor inside the window
+func (r *memoryLoginAttemptRepo) prune(now time.Time, window time.Duration) {
+	for key, attempt := range r.attempts {
+		locked := attempt.LockedUntil != nil && attempt.LockedUntil.After(now)
+		if !locked && attempt.LastFailureAt.Before(now.Add(-window)) {
+			delete(r.attempts, key)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/require"
+
+	"booknest/internal/domain"
+)
+
+func TestLoginAttemptRepo_Find(t *testing.T) {
+	db := setupTestDB(t, &domain.LoginAttempt{})
+
+	attempt := domain.LoginAttempt{
+		Key:           "ip:10.0.0.1",
+		Failures:      3,
+		LastFailureAt: time.Now(),
+	}
+	require.NoError(t, db.Create(&attempt).Error)
+
+	repo := &loginAttemptRepo{gorm: db}
+
+	found, err := repo.Find(context.Background(), "ip:10.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, 3, found.Failures)
+
+	// Unknown keys have no state
+	found, err = repo.Find(context.Background(), "ip:10.0.0.2")
+	require.NoError(t, err)
+	require.Nil(t, found)
+}
+
+func TestLoginAttemptRepo_RecordFailure(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &loginAttemptRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	now := time.Now()
+	mock.ExpectQuery("INSERT INTO login_attempts").
+		WithArgs("user:1", float64(3600)).
+		WillReturnRows(
+			pgxmock.NewRows([]string{"key", "failures", "last_failure_at", "locked_until"}).
+				AddRow("user:1", 4, now, (*time.Time)(nil)),
+		)
+
+	attempt, err := repo.RecordFailure(context.Background(), "user:1", time.Hour)
+
+	require.NoError(t, err)
+	require.Equal(t, 4, attempt.Failures)
+	require.Nil(t, attempt.LockedUntil)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoginAttemptRepo_LockAndReset(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &loginAttemptRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	until := time.Now().Add(time.Minute)
+	mock.ExpectExec("UPDATE login_attempts").
+		WithArgs(until, "user:1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("DELETE FROM login_attempts").
+		WithArgs("user:1").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	require.NoError(t, repo.Lock(context.Background(), "user:1", until))
+	require.NoError(t, repo.Reset(context.Background(), "user:1"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMemoryLoginAttemptRepo_RecordFailure(t *testing.T) {
+	now := time.Now()
+	repo := &memoryLoginAttemptRepo{
+		attempts: map[string]domain.LoginAttempt{},
+		now:      func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		attempt, err := repo.RecordFailure(ctx, "user:1", time.Hour)
+		require.NoError(t, err)
+		require.Equal(t, i, attempt.Failures)
+	}
+
+	// Failures older than the window no longer count
+	now = now.Add(2 * time.Hour)
+	attempt, err := repo.RecordFailure(ctx, "user:1", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 1, attempt.Failures)
+
+	until := now.Add(time.Minute)
+	require.NoError(t, repo.Lock(ctx, "user:1", until))
+	found, err := repo.Find(ctx, "user:1")
+	require.NoError(t, err)
+	require.True(t, found.LockedUntil.Equal(until))
+
+	require.NoError(t, repo.Reset(ctx, "user:1"))
+	found, err = repo.Find(ctx, "user:1")
+	require.NoError(t, err)
+	require.Nil(t, found)
+}
+
+func TestMemoryLoginAttemptRepo_PrunesStaleKeys(t *testing.T) {
+	now := time.Now()
+	repo := &memoryLoginAttemptRepo{
+		attempts: map[string]domain.LoginAttempt{},
+		now:      func() time.Time { return now },
+	}
+
+	locked := now.Add(time.Hour)
+	for i := 0; i < memoryAttemptLimit; i++ {
+		repo.attempts[fmt.Sprintf("ip:%d", i)] = domain.LoginAttempt{LastFailureAt: now.Add(-2 * time.Hour)}
+	}
+	repo.attempts["locked"] = domain.LoginAttempt{LastFailureAt: now.Add(-2 * time.Hour), LockedUntil: &locked}
+
+	_, err := repo.RecordFailure(context.Background(), "new", time.Hour)
+	require.NoError(t, err)
+
+	require.Len(t, repo.attempts, 2, "expected only the locked and the new key to remain")
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+)
+
+type mfaRepo struct {
+	db   domain.DBExecer
+	gorm *gorm.DB
+	sb   squirrel.StatementBuilderType
+}
+
+func NewMFARepo(db *pgxpool.Pool, gormDB *gorm.DB) domain.MFARepository {
+	return &mfaRepo{
+		db:   db,
+		gorm: gormDB,
+		sb:   squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// Upsert never replaces the secret of an enabled authenticator.
+func (r *mfaRepo) Upsert(
+	ctx context.Context,
+	credential *domain.MFACredential,
+) error {
+	query := `
+		INSERT INTO mfa_credentials (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret,
+		    last_used_step = 0,
+		    updated_at = NOW()
+		WHERE mfa_credentials.enabled_at IS NULL
+		RETURNING created_at, updated_at;
+	`
+
+	row := queryRowWithTx(ctx, r.db, query, credential.UserID, credential.Secret)
+
+	return row.Scan(
+		&credential.CreatedAt,
+		&credential.UpdatedAt,
+	)
+}
+
+func (r *mfaRepo) FindByUserID(
+	ctx context.Context,
+	userID uuid.UUID,
+) (*domain.MFACredential, error) {
+
+	var credential domain.MFACredential
+
+	err := r.gorm.
+		WithContext(ctx).
+		Where("user_id = ?", userID).
+		First(&credential).
+		Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &credential, nil
+}
+
+func (r *mfaRepo) Enable(
+	ctx context.Context,
+	userID uuid.UUID,
+) error {
+
+	query, args, err := r.sb.
+		Update("mfa_credentials").
+		Set("enabled_at", squirrel.Expr("NOW()")).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"user_id": userID}).
+		Where("enabled_at IS NULL").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+func (r *mfaRepo) UseStep(
+	ctx context.Context,
+	userID uuid.UUID,
+	step int64,
+) (bool, error) {
+
+	query, args, err := r.sb.
+		Update("mfa_credentials").
+		Set("last_used_step", step).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"user_id": userID}).
+		Where(squirrel.Lt{"last_used_step": step}).
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := execWithTxTag(ctx, r.db, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// Delete removes the authenticator and its recovery codes for good,
+// since the secret must not outlive the enrollment.
+func (r *mfaRepo) Delete(
+	ctx context.Context,
+	userID uuid.UUID,
+) error {
+
+	query, args, err := r.sb.
+		Delete("mfa_recovery_codes").
+		Where(squirrel.Eq{"user_id": userID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if err := execWithTx(ctx, r.db, query, args...); err != nil {
+		return err
+	}
+
+	query, args, err = r.sb.
+		Delete("mfa_credentials").
+		Where(squirrel.Eq{"user_id": userID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+func (r *mfaRepo) ReplaceRecoveryCodes(
+	ctx context.Context,
+	userID uuid.UUID,
+	codeHashes []string,
+) error {
+
+	query, args, err := r.sb.
+		Delete("mfa_recovery_codes").
+		Where(squirrel.Eq{"user_id": userID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if err := execWithTx(ctx, r.db, query, args...); err != nil {
+		return err
+	}
+
+	if len(codeHashes) == 0 {
+		return nil
+	}
+
+	insert := r.sb.
+		Insert("mfa_recovery_codes").
+		Columns("user_id", "code_hash")
+	for _, hash := range codeHashes {
+		insert = insert.Values(userID, hash)
+	}
+
+	query, args, err = insert.ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+func (r *mfaRepo) UseRecoveryCode(
+	ctx context.Context,
+	userID uuid.UUID,
+	codeHash string,
+) (bool, error) {
+
+	query, args, err := r.sb.
+		Update("mfa_recovery_codes").
+		Set("used_at", squirrel.Expr("NOW()")).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{
+			"user_id":   userID,
+			"code_hash": codeHash,
+		}).
+		Where("used_at IS NULL").
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := execWithTxTag(ctx, r.db, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
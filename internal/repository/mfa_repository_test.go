@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/require"
+
+	"booknest/internal/domain"
+)
+
+func newMFARepoWithMock(t *testing.T) (*mfaRepo, pgxmock.PgxPoolIface) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(mock.Close)
+
+	return &mfaRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}, mock
+}
+
+func TestMFARepo_FindByUserID(t *testing.T) {
+	db := setupTestDB(t, &domain.MFACredential{})
+
+	now := time.Now()
+	credential := domain.MFACredential{
+		UserID:    uuid.New(),
+		Secret:    "SECRET",
+		EnabledAt: &now,
+	}
+	require.NoError(t, db.Create(&credential).Error)
+
+	repo := &mfaRepo{gorm: db}
+
+	found, err := repo.FindByUserID(context.Background(), credential.UserID)
+
+	require.NoError(t, err)
+	require.Equal(t, "SECRET", found.Secret)
+	require.NotNil(t, found.EnabledAt)
+
+	_, err = repo.FindByUserID(context.Background(), uuid.New())
+	require.Error(t, err)
+}
+
+func TestMFARepo_Upsert(t *testing.T) {
+	repo, mock := newMFARepoWithMock(t)
+
+	credential := &domain.MFACredential{
+		UserID: uuid.New(),
+		Secret: "SECRET",
+	}
+
+	mock.ExpectQuery("INSERT INTO mfa_credentials").
+		WithArgs(credential.UserID, "SECRET").
+		WillReturnRows(
+			pgxmock.NewRows([]string{"created_at", "updated_at"}).
+				AddRow(time.Now(), time.Now()),
+		)
+
+	err := repo.Upsert(context.Background(), credential)
+
+	require.NoError(t, err)
+	require.False(t, credential.CreatedAt.IsZero())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMFARepo_UseStep(t *testing.T) {
+	repo, mock := newMFARepoWithMock(t)
+
+	userID := uuid.New()
+
+	mock.ExpectExec("UPDATE mfa_credentials").
+		WithArgs(int64(42), userID.String(), int64(42)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("UPDATE mfa_credentials").
+		WithArgs(int64(42), userID.String(), int64(42)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	used, err := repo.UseStep(context.Background(), userID, 42)
+	require.NoError(t, err)
+	require.True(t, used)
+
+	// The same step again is a replay
+	used, err = repo.UseStep(context.Background(), userID, 42)
+	require.NoError(t, err)
+	require.False(t, used)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMFARepo_ReplaceRecoveryCodes(t *testing.T) {
+	repo, mock := newMFARepoWithMock(t)
+
+	userID := uuid.New()
+
+	mock.ExpectExec("DELETE FROM mfa_recovery_codes").
+		WithArgs(userID.String()).
+		WillReturnResult(pgxmock.NewResult("DELETE", 10))
+	mock.ExpectExec("INSERT INTO mfa_recovery_codes").
+		WithArgs(userID, "hash_a", userID, "hash_b").
+		WillReturnResult(pgxmock.NewResult("INSERT", 2))
+
+	err := repo.ReplaceRecoveryCodes(context.Background(), userID, []string{"hash_a", "hash_b"})
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMFARepo_UseRecoveryCode(t *testing.T) {
+	repo, mock := newMFARepoWithMock(t)
+
+	userID := uuid.New()
+
+	mock.ExpectExec("UPDATE mfa_recovery_codes").
+		WithArgs("hash_a", userID.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	used, err := repo.UseRecoveryCode(context.Background(), userID, "hash_a")
+
+	require.NoError(t, err)
+	require.False(t, used)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMFARepo_Delete(t *testing.T) {
+	repo, mock := newMFARepoWithMock(t)
+
+	userID := uuid.New()
+
+	mock.ExpectExec("DELETE FROM mfa_recovery_codes").
+		WithArgs(userID.String()).
+		WillReturnResult(pgxmock.NewResult("DELETE", 10))
+	mock.ExpectExec("DELETE FROM mfa_credentials").
+		WithArgs(userID.String()).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	err := repo.Delete(context.Background(), userID)
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
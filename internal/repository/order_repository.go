@@ -2,10 +2,12 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"booknest/internal/domain"
@@ -33,12 +35,23 @@ func (r *orderRepo) CreateOrder(ctx context.Context, order *domain.Order) error
 			payment_method,
 			payment_status,
 			status,
+			shipping_address,
+			billing_address,
 			created_at,
 			updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
 		RETURNING created_at, updated_at;
 	`
 
+	shipping, err := orderAddressJSON(order.ShippingAddress)
+	if err != nil {
+		return err
+	}
+	billing, err := orderAddressJSON(order.BillingAddress)
+	if err != nil {
+		return err
+	}
+
 	row := queryRowWithTx(
 		ctx,
 		r.db,
@@ -50,6 +63,8 @@ func (r *orderRepo) CreateOrder(ctx context.Context, order *domain.Order) error
 		order.PaymentMethod,
 		order.PaymentStatus,
 		order.Status,
+		shipping,
+		billing,
 	)
 
 	return row.Scan(&order.CreatedAt, &order.UpdatedAt)
@@ -90,119 +105,90 @@ func (r *orderRepo) CreateOrderItems(ctx context.Context, items []domain.OrderIt
 func (r *orderRepo) ListOrdersByUser(
 	ctx context.Context,
 	userID uuid.UUID,
-	limit,
-	offset int,
-) ([]domain.OrderView, error) {
-	query := `
-		SELECT
-			id,
-			order_number,
-			total_price,
-			user_id,
-			payment_method,
-			payment_status,
-			status,
-			created_at,
-			updated_at
-		FROM orders
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3;
-	`
+	q domain.QueryOptions,
+) (domain.Page[domain.OrderView], error) {
+	return r.listOrders(ctx, squirrel.Eq{"user_id": userID}, q)
+}
 
-	rows, err := queryWithTx(ctx, r.db, query, userID, limit, offset)
+func (r *orderRepo) ListOrders(
+	ctx context.Context,
+	q domain.QueryOptions,
+) (domain.Page[domain.OrderView], error) {
+	return r.listOrders(ctx, nil, q)
+}
+
+// listOrders pages the orders matching where, all when it is nil, newest
+// first
+func (r *orderRepo) listOrders(
+	ctx context.Context,
+	where squirrel.Sqlizer,
+	q domain.QueryOptions,
+) (domain.Page[domain.OrderView], error) {
+	keys := newKeyset(domain.SortByCreatedAt, "created_at", "id", keyTime, domain.Desc)
+	cursor, err := keys.decode(q.Cursor)
 	if err != nil {
-		return nil, err
+		return domain.Page[domain.OrderView]{}, err
 	}
-	defer rows.Close()
-
-	orders := make([]domain.OrderView, 0)
-	for rows.Next() {
-		var order domain.Order
-		if err := rows.Scan(
-			&order.ID,
-			&order.OrderNumber,
-			&order.TotalPrice,
-			&order.UserID,
-			&order.PaymentMethod,
-			&order.PaymentStatus,
-			&order.Status,
-			&order.CreatedAt,
-			&order.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-
-		items, err := r.GetOrderItems(ctx, order.ID)
-		if err != nil {
-			return nil, err
-		}
 
-		orders = append(orders, domain.OrderView{
-			Order: order,
-			Items: items,
-		})
+	builder := r.sb.
+		Select(
+			"id",
+			"order_number",
+			"total_price",
+			"user_id",
+			"payment_method",
+			"payment_status",
+			"status",
+			"shipping_address",
+			"billing_address",
+			"created_at",
+			"updated_at",
+		).
+		From("orders")
+	if where != nil {
+		builder = builder.Where(where)
 	}
 
-	return orders, rows.Err()
-}
-
-func (r *orderRepo) ListOrders(
-	ctx context.Context,
-	limit,
-	offset int,
-) ([]domain.OrderView, error) {
-	query := `
-		SELECT
-			id,
-			order_number,
-			total_price,
-			user_id,
-			payment_method,
-			payment_status,
-			status,
-			created_at,
-			updated_at
-		FROM orders
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2;
-	`
+	query, args, err := keys.apply(builder, cursor, q.Offset, q.Limit).ToSql()
+	if err != nil {
+		return domain.Page[domain.OrderView]{}, err
+	}
 
-	rows, err := queryWithTx(ctx, r.db, query, limit, offset)
+	rows, err := queryWithTx(ctx, r.db, query, args...)
 	if err != nil {
-		return nil, err
+		return domain.Page[domain.OrderView]{}, err
 	}
 	defer rows.Close()
 
-	orders := make([]domain.OrderView, 0)
+	orders := make([]domain.Order, 0)
 	for rows.Next() {
-		var order domain.Order
-		if err := rows.Scan(
-			&order.ID,
-			&order.OrderNumber,
-			&order.TotalPrice,
-			&order.UserID,
-			&order.PaymentMethod,
-			&order.PaymentStatus,
-			&order.Status,
-			&order.CreatedAt,
-			&order.UpdatedAt,
-		); err != nil {
-			return nil, err
+		order, err := scanOrder(rows)
+		if err != nil {
+			return domain.Page[domain.OrderView]{}, err
 		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return domain.Page[domain.OrderView]{}, err
+	}
+	rows.Close()
+
+	page := keysetPage(keys, cursor, q.Offset, q.Limit, orders, func(order domain.Order) (any, uuid.UUID) {
+		return order.CreatedAt, order.ID
+	})
 
+	// Items are read after the rows are closed, so this also works on the
+	// single connection of a transaction
+	views := make([]domain.OrderView, 0, len(page.Items))
+	for _, order := range page.Items {
 		items, err := r.GetOrderItems(ctx, order.ID)
 		if err != nil {
-			return nil, err
+			return domain.Page[domain.OrderView]{}, err
 		}
-
-		orders = append(orders, domain.OrderView{
-			Order: order,
-			Items: items,
-		})
+		views = append(views, domain.OrderView{Order: order, Items: items})
 	}
 
-	return orders, rows.Err()
+	return domain.Page[domain.OrderView]{Items: views, PageCursors: page.PageCursors}, nil
 }
 
 func (r *orderRepo) GetOrderByID(
@@ -218,25 +204,14 @@ func (r *orderRepo) GetOrderByID(
 			payment_method,
 			payment_status,
 			status,
+			shipping_address,
+			billing_address,
 			created_at,
 			updated_at
 		FROM orders
 		WHERE id = $1;
 	`
-	var order domain.Order
-	row := queryRowWithTx(ctx, r.db, query, orderID)
-	err := row.Scan(
-		&order.ID,
-		&order.OrderNumber,
-		&order.TotalPrice,
-		&order.UserID,
-		&order.PaymentMethod,
-		&order.PaymentStatus,
-		&order.Status,
-		&order.CreatedAt,
-		&order.UpdatedAt,
-	)
-	return order, err
+	return scanOrder(queryRowWithTx(ctx, r.db, query, orderID))
 }
 
 func (r *orderRepo) GetOrderItems(
@@ -318,6 +293,31 @@ func (r *orderRepo) UpdateOrderStatus(
 	return execWithTx(ctx, r.db, query, args...)
 }
 
+// anonymizedAddressFields are removed from the snapshots of a deleted
+// account. The rest locates the order for tax and stays on the invoice.
+const anonymizedAddressFields = "{full_name,line1,line2,phone}"
+
+func (r *orderRepo) AnonymizeAddresses(
+	ctx context.Context,
+	userID uuid.UUID,
+) error {
+	query, args, err := r.sb.
+		Update("orders").
+		Set("shipping_address", squirrel.Expr("shipping_address - ?::text[]", anonymizedAddressFields)).
+		Set("billing_address", squirrel.Expr("billing_address - ?::text[]", anonymizedAddressFields)).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"user_id": userID}).
+		Where(squirrel.Or{
+			squirrel.NotEq{"shipping_address": nil},
+			squirrel.NotEq{"billing_address": nil},
+		}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	return execWithTx(ctx, r.db, query, args...)
+}
+
 func (r *orderRepo) DecrementStock(
 	ctx context.Context,
 	items []domain.OrderItem,
@@ -341,3 +341,61 @@ func (r *orderRepo) DecrementStock(
 	}
 	return nil
 }
+
+// scanOrder reads the columns selected by the order queries, in order
+func scanOrder(row pgx.Row) (domain.Order, error) {
+	var order domain.Order
+	var shipping, billing []byte
+
+	if err := row.Scan(
+		&order.ID,
+		&order.OrderNumber,
+		&order.TotalPrice,
+		&order.UserID,
+		&order.PaymentMethod,
+		&order.PaymentStatus,
+		&order.Status,
+		&shipping,
+		&billing,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	); err != nil {
+		return domain.Order{}, err
+	}
+
+	var err error
+	if order.ShippingAddress, err = decodeOrderAddress(shipping); err != nil {
+		return domain.Order{}, err
+	}
+	if order.BillingAddress, err = decodeOrderAddress(billing); err != nil {
+		return domain.Order{}, err
+	}
+
+	return order, nil
+}
+
+// orderAddressJSON encodes an address snapshot for a jsonb column.
+// Orders placed before the address book have none.
+func orderAddressJSON(address *domain.OrderAddress) (any, error) {
+	if address == nil {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(address)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+func decodeOrderAddress(raw []byte) (*domain.OrderAddress, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	var address domain.OrderAddress
+	if err := json.Unmarshal(raw, &address); err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
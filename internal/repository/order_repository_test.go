@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
@@ -56,3 +58,156 @@ func TestOrderRepo_DecrementStock(t *testing.T) {
 
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+var orderColumns = []string{
+	"id",
+	"order_number",
+	"total_price",
+	"user_id",
+	"payment_method",
+	"payment_status",
+	"status",
+	"shipping_address",
+	"billing_address",
+	"created_at",
+	"updated_at",
+}
+
+func TestOrderRepo_CreateOrderSnapshotsAddresses(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &orderRepo{db: mock, sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)}
+	order := &domain.Order{
+		ID:          uuid.New(),
+		OrderNumber: "BN-1001",
+		TotalPrice:  42.5,
+		UserID:      uuid.New(),
+		Status:      domain.OrderPending,
+		ShippingAddress: &domain.OrderAddress{
+			FullName:   "Ada Lovelace",
+			Line1:      "12 Analytical Row",
+			City:       "London",
+			PostalCode: "SW1A 1AA",
+			Country:    "GB",
+		},
+	}
+	shipping, err := json.Marshal(order.ShippingAddress)
+	require.NoError(t, err)
+	now := time.Now()
+
+	// Orders without a billing address store NULL rather than "null"
+	mock.ExpectQuery("INSERT INTO orders").
+		WithArgs(order.ID, order.OrderNumber, order.TotalPrice, order.UserID, order.PaymentMethod, order.PaymentStatus, order.Status, string(shipping), nil).
+		WillReturnRows(pgxmock.NewRows([]string{"created_at", "updated_at"}).AddRow(now, now))
+
+	require.NoError(t, repo.CreateOrder(context.Background(), order))
+	require.Equal(t, now, order.CreatedAt)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOrderRepo_GetOrderByIDReadsSnapshots(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &orderRepo{db: mock, sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)}
+	withSnapshot, legacy := uuid.New(), uuid.New()
+	userID := uuid.New()
+	now := time.Now()
+	address := []byte(`{"full_name":"Ada Lovelace","line1":"12 Analytical Row","city":"London","postal_code":"SW1A 1AA","country":"GB"}`)
+
+	mock.ExpectQuery("SELECT .* FROM orders").
+		WithArgs(withSnapshot).
+		WillReturnRows(pgxmock.NewRows(orderColumns).
+			AddRow(withSnapshot, "BN-1001", 42.5, userID, nil, nil, domain.OrderPending, address, address, now, now))
+
+	order, err := repo.GetOrderByID(context.Background(), withSnapshot)
+	require.NoError(t, err)
+	require.NotNil(t, order.ShippingAddress)
+	require.Equal(t, "Ada Lovelace", order.ShippingAddress.FullName)
+	require.Equal(t, "GB", order.BillingAddress.Country)
+
+	// Orders placed before the address book have NULL snapshots
+	mock.ExpectQuery("SELECT .* FROM orders").
+		WithArgs(legacy).
+		WillReturnRows(pgxmock.NewRows(orderColumns).
+			AddRow(legacy, "BN-0001", 10.0, userID, nil, nil, domain.OrderCompleted, nil, nil, now, now))
+
+	order, err = repo.GetOrderByID(context.Background(), legacy)
+	require.NoError(t, err)
+	require.Nil(t, order.ShippingAddress)
+	require.Nil(t, order.BillingAddress)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOrderRepo_ListOrdersByUserCursor(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &orderRepo{db: mock, sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)}
+	userID := uuid.New()
+	newest, older, oldest := uuid.New(), uuid.New(), uuid.New()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	// The first page reads one row more than it returns
+	mock.ExpectQuery(`SELECT .* FROM orders WHERE user_id = \$1 ORDER BY created_at DESC, id DESC LIMIT 3`).
+		WithArgs(userID.String()).
+		WillReturnRows(pgxmock.NewRows(orderColumns).
+			AddRow(newest, "BN-3", 30.0, userID, nil, nil, domain.OrderPending, nil, nil, now, now).
+			AddRow(older, "BN-2", 20.0, userID, nil, nil, domain.OrderPending, nil, nil, now.Add(-time.Hour), now).
+			AddRow(oldest, "BN-1", 10.0, userID, nil, nil, domain.OrderPending, nil, nil, now.Add(-2*time.Hour), now))
+	for _, id := range []uuid.UUID{newest, older} {
+		mock.ExpectQuery("FROM order_items").
+			WithArgs(id).
+			WillReturnRows(pgxmock.NewRows([]string{"book_id", "name", "image_url", "purchase_price", "purchase_count", "total_price"}))
+	}
+
+	page, err := repo.ListOrdersByUser(context.Background(), userID, domain.QueryOptions{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2)
+	require.Equal(t, older, page.Items[1].Order.ID)
+	require.NotEmpty(t, page.NextCursor)
+	require.Empty(t, page.PrevCursor)
+
+	// The next page continues after the last order of the first
+	mock.ExpectQuery(`SELECT .* FROM orders WHERE user_id = \$1 AND \(created_at, id\) < \(\$2, \$3\) ORDER BY created_at DESC, id DESC LIMIT 3`).
+		WithArgs(userID.String(), pgxmock.AnyArg(), older).
+		WillReturnRows(pgxmock.NewRows(orderColumns).
+			AddRow(oldest, "BN-1", 10.0, userID, nil, nil, domain.OrderPending, nil, nil, now.Add(-2*time.Hour), now))
+	mock.ExpectQuery("FROM order_items").
+		WithArgs(oldest).
+		WillReturnRows(pgxmock.NewRows([]string{"book_id", "name", "image_url", "purchase_price", "purchase_count", "total_price"}))
+
+	page, err = repo.ListOrdersByUser(context.Background(), userID, domain.QueryOptions{Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	require.Equal(t, oldest, page.Items[0].Order.ID)
+	require.Empty(t, page.NextCursor)
+	require.NotEmpty(t, page.PrevCursor)
+
+	// Cursors of another sort are refused before querying
+	_, err = repo.ListOrdersByUser(context.Background(), userID, domain.QueryOptions{Limit: 2, Cursor: "not-a-cursor"})
+	require.ErrorIs(t, err, domain.ErrInvalidCursor)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOrderRepo_AnonymizeAddresses(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &orderRepo{db: mock, sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)}
+	userID := uuid.New()
+
+	mock.ExpectExec(`UPDATE orders SET shipping_address = shipping_address - \$1::text\[\], billing_address = billing_address - \$2::text\[\], updated_at = NOW\(\) WHERE user_id = \$3 AND \(shipping_address IS NOT NULL OR billing_address IS NOT NULL\)`).
+		WithArgs("{full_name,line1,line2,phone}", "{full_name,line1,line2,phone}", userID.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+
+	require.NoError(t, repo.AnonymizeAddresses(context.Background(), userID))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+)
+
+type passwordHistoryRepo struct {
+	db   domain.DBExecer
+	gorm *gorm.DB
+	sb   squirrel.StatementBuilderType
+}
+
+func NewPasswordHistoryRepo(db *pgxpool.Pool, gormDB *gorm.DB) domain.PasswordHistoryRepository {
+	return &passwordHistoryRepo{
+		db:   db,
+		gorm: gormDB,
+		sb:   squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (r *passwordHistoryRepo) Add(
+	ctx context.Context,
+	userID uuid.UUID,
+	passwordHash string,
+) error {
+
+	query, args, err := r.sb.
+		Insert("previous_passwords").
+		Columns(
+			"user_id",
+			"password_hash",
+		).
+		Values(
+			userID,
+			passwordHash,
+		).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+func (r *passwordHistoryRepo) ListRecent(
+	ctx context.Context,
+	userID uuid.UUID,
+	limit int,
+) ([]string, error) {
+
+	var hashes []string
+
+	err := r.gorm.
+		WithContext(ctx).
+		Model(&domain.PreviousPassword{}).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Pluck("password_hash", &hashes).
+		Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
+func (r *passwordHistoryRepo) Prune(
+	ctx context.Context,
+	userID uuid.UUID,
+	keep int,
+) error {
+
+	query, args, err := r.sb.
+		Delete("previous_passwords").
+		Where(squirrel.Eq{"user_id": userID}).
+		Where("id NOT IN (SELECT id FROM previous_passwords WHERE user_id = ? ORDER BY created_at DESC LIMIT ?)", userID, keep).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+func (r *passwordHistoryRepo) DeleteByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) error {
+
+	query, args, err := r.sb.
+		Delete("previous_passwords").
+		Where(squirrel.Eq{"user_id": userID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/require"
+
+	"booknest/internal/domain"
+)
+
+func TestPasswordHistoryRepo_ListRecent(t *testing.T) {
+	db := setupTestDB(t, &domain.PreviousPassword{})
+
+	userID := uuid.New()
+	now := time.Now()
+	previous := []domain.PreviousPassword{
+		{ID: uuid.New(), UserID: userID, PasswordHash: "oldest", CreatedAt: now.Add(-3 * time.Hour)},
+		{ID: uuid.New(), UserID: userID, PasswordHash: "older", CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: uuid.New(), UserID: userID, PasswordHash: "newest", CreatedAt: now.Add(-time.Hour)},
+		{ID: uuid.New(), UserID: uuid.New(), PasswordHash: "other user", CreatedAt: now},
+	}
+	require.NoError(t, db.Create(&previous).Error)
+
+	repo := &passwordHistoryRepo{gorm: db}
+
+	hashes, err := repo.ListRecent(context.Background(), userID, 2)
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"newest", "older"}, hashes)
+}
+
+func TestPasswordHistoryRepo_Add(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &passwordHistoryRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	userID := uuid.New()
+	mock.ExpectExec("INSERT INTO previous_passwords").
+		WithArgs(userID, "hash").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	err = repo.Add(context.Background(), userID, "hash")
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPasswordHistoryRepo_Prune(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &passwordHistoryRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	userID := uuid.New()
+	mock.ExpectExec("DELETE FROM previous_passwords").
+		WithArgs(userID.String(), userID, 4).
+		WillReturnResult(pgxmock.NewResult("DELETE", 2))
+
+	err = repo.Prune(context.Background(), userID, 4)
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
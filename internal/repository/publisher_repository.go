@@ -44,16 +44,26 @@ func (r *publisherRepo) FindByID(
 
 func (r *publisherRepo) List(
 	ctx context.Context,
-	limit, offset int,
-) ([]domain.Publisher, error) {
-	var publishers []domain.Publisher
-	err := r.gorm.WithContext(ctx).
+	q domain.QueryOptions,
+) (domain.Page[domain.Publisher], error) {
+	keys := newKeyset(domain.SortByCreatedAt, "created_at", "id", keyTime, domain.Desc)
+	cursor, err := keys.decode(q.Cursor)
+	if err != nil {
+		return domain.Page[domain.Publisher]{}, err
+	}
+
+	publishers := make([]domain.Publisher, 0)
+	err = r.gorm.WithContext(ctx).
 		Where("deleted_at IS NULL").
-		Limit(limit).
-		Offset(offset).
-		Order("created_at DESC").
+		Scopes(keys.scope(cursor, q.Offset, q.Limit)).
 		Find(&publishers).Error
-	return publishers, err
+	if err != nil {
+		return domain.Page[domain.Publisher]{}, err
+	}
+
+	return keysetPage(keys, cursor, q.Offset, q.Limit, publishers, func(publisher domain.Publisher) (any, uuid.UUID) {
+		return publisher.CreatedAt, publisher.ID
+	}), nil
 }
 
 func (r *publisherRepo) Create(
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+)
+
+type refreshTokenRepo struct {
+	db   domain.DBExecer
+	gorm *gorm.DB
+	sb   squirrel.StatementBuilderType
+}
+
+func NewRefreshTokenRepo(db *pgxpool.Pool, gormDB *gorm.DB) domain.RefreshTokenRepository {
+	return &refreshTokenRepo{
+		db:   db,
+		gorm: gormDB,
+		sb:   squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (r *refreshTokenRepo) Create(
+	ctx context.Context,
+	token *domain.RefreshToken,
+) error {
+
+	query, args, err := r.sb.
+		Insert("refresh_tokens").
+		Columns(
+			"user_id",
+			"family_id",
+			"token_hash",
+			"expires_at",
+		).
+		Values(
+			token.UserID,
+			token.FamilyID,
+			token.TokenHash,
+			token.ExpiresAt,
+		).
+		Suffix("RETURNING id, created_at, updated_at").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	row := queryRowWithTx(ctx, r.db, query, args...)
+
+	return row.Scan(
+		&token.ID,
+		&token.CreatedAt,
+		&token.UpdatedAt,
+	)
+}
+
+// FindByHash returns the token regardless of its state so that
+// callers can detect reuse of already rotated tokens.
+func (r *refreshTokenRepo) FindByHash(
+	ctx context.Context,
+	tokenHash string,
+) (*domain.RefreshToken, error) {
+
+	var token domain.RefreshToken
+
+	err := r.gorm.
+		WithContext(ctx).
+		Where("token_hash = ?", tokenHash).
+		First(&token).
+		Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *refreshTokenRepo) Revoke(
+	ctx context.Context,
+	id uuid.UUID,
+	replacedBy *uuid.UUID,
+) (bool, error) {
+
+	query, args, err := r.sb.
+		Update("refresh_tokens").
+		Set("revoked_at", squirrel.Expr("NOW()")).
+		Set("replaced_by", replacedBy).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": id}).
+		Where("revoked_at IS NULL").
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := execWithTxTag(ctx, r.db, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *refreshTokenRepo) RevokeFamily(
+	ctx context.Context,
+	familyID uuid.UUID,
+) error {
+
+	query, args, err := r.sb.
+		Update("refresh_tokens").
+		Set("revoked_at", squirrel.Expr("NOW()")).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"family_id": familyID}).
+		Where("revoked_at IS NULL").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+func (r *refreshTokenRepo) RevokeAllByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) error {
+
+	query, args, err := r.sb.
+		Update("refresh_tokens").
+		Set("revoked_at", squirrel.Expr("NOW()")).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"user_id": userID}).
+		Where("revoked_at IS NULL").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
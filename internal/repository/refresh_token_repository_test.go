@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/require"
+
+	"booknest/internal/domain"
+)
+
+func TestRefreshTokenRepo_FindByHash(t *testing.T) {
+	db := setupTestDB(t, &domain.RefreshToken{})
+
+	now := time.Now()
+	rt := domain.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		FamilyID:  uuid.New(),
+		TokenHash: "token_hash",
+		ExpiresAt: now.Add(time.Hour),
+		RevokedAt: &now,
+	}
+
+	require.NoError(t, db.Create(&rt).Error)
+
+	repo := &refreshTokenRepo{gorm: db}
+
+	// Revoked tokens are still returned so reuse can be detected
+	found, err := repo.FindByHash(context.Background(), rt.TokenHash)
+
+	require.NoError(t, err)
+	require.Equal(t, rt.ID, found.ID)
+	require.Equal(t, rt.FamilyID, found.FamilyID)
+	require.NotNil(t, found.RevokedAt)
+}
+
+func TestRefreshTokenRepo_Create(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &refreshTokenRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	rt := &domain.RefreshToken{
+		UserID:    uuid.New(),
+		FamilyID:  uuid.New(),
+		TokenHash: "token_hash",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	id := uuid.New()
+	mock.ExpectQuery("INSERT INTO refresh_tokens").
+		WithArgs(
+			pgxmock.AnyArg(), pgxmock.AnyArg(),
+			pgxmock.AnyArg(), pgxmock.AnyArg(),
+		).
+		WillReturnRows(
+			pgxmock.NewRows([]string{"id", "created_at", "updated_at"}).
+				AddRow(id, time.Now(), time.Now()),
+		)
+
+	err = repo.Create(context.Background(), rt)
+
+	require.NoError(t, err)
+	require.Equal(t, id, rt.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshTokenRepo_Revoke(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &refreshTokenRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	id := uuid.New()
+	replacedBy := uuid.New()
+
+	mock.ExpectExec("UPDATE refresh_tokens").
+		WithArgs(&replacedBy, id.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("UPDATE refresh_tokens").
+		WithArgs(&replacedBy, id.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	revoked, err := repo.Revoke(context.Background(), id, &replacedBy)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = repo.Revoke(context.Background(), id, &replacedBy)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshTokenRepo_RevokeFamily(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &refreshTokenRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	familyID := uuid.New()
+
+	mock.ExpectExec("UPDATE refresh_tokens").
+		WithArgs(familyID.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 3))
+
+	err = repo.RevokeFamily(context.Background(), familyID)
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshTokenRepo_RevokeAllByUser(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &refreshTokenRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	userID := uuid.New()
+
+	mock.ExpectExec("UPDATE refresh_tokens").
+		WithArgs(userID.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+
+	err = repo.RevokeAllByUser(context.Background(), userID)
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
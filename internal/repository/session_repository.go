@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+)
+
+type sessionRepo struct {
+	db   domain.DBExecer
+	gorm *gorm.DB
+	sb   squirrel.StatementBuilderType
+}
+
+func NewSessionRepo(db *pgxpool.Pool, gormDB *gorm.DB) domain.SessionRepository {
+	return &sessionRepo{
+		db:   db,
+		gorm: gormDB,
+		sb:   squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (r *sessionRepo) Create(
+	ctx context.Context,
+	session *domain.Session,
+) error {
+
+	query, args, err := r.sb.
+		Insert("sessions").
+		Columns(
+			"id",
+			"user_id",
+			"user_agent",
+			"ip_address",
+		).
+		Values(
+			session.ID,
+			session.UserID,
+			session.UserAgent,
+			session.IPAddress,
+		).
+		Suffix("RETURNING last_seen_at, created_at, updated_at").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	row := queryRowWithTx(ctx, r.db, query, args...)
+
+	return row.Scan(
+		&session.LastSeenAt,
+		&session.CreatedAt,
+		&session.UpdatedAt,
+	)
+}
+
+func (r *sessionRepo) ListActiveByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]domain.Session, error) {
+
+	var sessions []domain.Session
+
+	err := r.gorm.
+		WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("last_seen_at DESC").
+		Find(&sessions).
+		Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (r *sessionRepo) IsRevoked(
+	ctx context.Context,
+	id uuid.UUID,
+) (bool, error) {
+
+	var count int64
+
+	err := r.gorm.
+		WithContext(ctx).
+		Model(&domain.Session{}).
+		Where("id = ? AND revoked_at IS NOT NULL", id).
+		Count(&count).
+		Error
+
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *sessionRepo) IsKnownDevice(
+	ctx context.Context,
+	userID uuid.UUID,
+	userAgent string,
+) (bool, error) {
+
+	var result struct {
+		Total   int64
+		Matches int64
+	}
+
+	err := r.gorm.
+		WithContext(ctx).
+		Model(&domain.Session{}).
+		Select("COUNT(*) AS total, COALESCE(SUM(CASE WHEN user_agent = ? THEN 1 ELSE 0 END), 0) AS matches", userAgent).
+		Where("user_id = ?", userID).
+		Scan(&result).
+		Error
+
+	if err != nil {
+		return false, err
+	}
+
+	return result.Total == 0 || result.Matches > 0, nil
+}
+
+func (r *sessionRepo) Touch(
+	ctx context.Context,
+	id uuid.UUID,
+) error {
+
+	query, args, err := r.sb.
+		Update("sessions").
+		Set("last_seen_at", squirrel.Expr("NOW()")).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": id}).
+		Where("revoked_at IS NULL").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+func (r *sessionRepo) Revoke(
+	ctx context.Context,
+	userID uuid.UUID,
+	id uuid.UUID,
+) (bool, error) {
+
+	query, args, err := r.sb.
+		Update("sessions").
+		Set("revoked_at", squirrel.Expr("NOW()")).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": id, "user_id": userID}).
+		Where("revoked_at IS NULL").
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := execWithTxTag(ctx, r.db, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *sessionRepo) RevokeAllByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) error {
+
+	query, args, err := r.sb.
+		Update("sessions").
+		Set("revoked_at", squirrel.Expr("NOW()")).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"user_id": userID}).
+		Where("revoked_at IS NULL").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
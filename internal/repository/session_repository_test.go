@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	pgxmock "github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/require"
+
+	"booknest/internal/domain"
+)
+
+func TestSessionRepo_ListActiveByUser(t *testing.T) {
+	db := setupTestDB(t, &domain.Session{})
+
+	userID := uuid.New()
+	now := time.Now()
+	sessions := []domain.Session{
+		{ID: uuid.New(), UserID: userID, UserAgent: "old", LastSeenAt: now.Add(-time.Hour)},
+		{ID: uuid.New(), UserID: userID, UserAgent: "recent", LastSeenAt: now},
+		{ID: uuid.New(), UserID: userID, UserAgent: "revoked", LastSeenAt: now, RevokedAt: &now},
+		{ID: uuid.New(), UserID: uuid.New(), UserAgent: "other user", LastSeenAt: now},
+	}
+	require.NoError(t, db.Create(&sessions).Error)
+
+	repo := &sessionRepo{gorm: db}
+
+	found, err := repo.ListActiveByUser(context.Background(), userID)
+
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	require.Equal(t, "recent", found[0].UserAgent)
+	require.Equal(t, "old", found[1].UserAgent)
+}
+
+func TestSessionRepo_IsRevoked(t *testing.T) {
+	db := setupTestDB(t, &domain.Session{})
+
+	now := time.Now()
+	active := domain.Session{ID: uuid.New(), UserID: uuid.New()}
+	revoked := domain.Session{ID: uuid.New(), UserID: uuid.New(), RevokedAt: &now}
+	require.NoError(t, db.Create(&active).Error)
+	require.NoError(t, db.Create(&revoked).Error)
+
+	repo := &sessionRepo{gorm: db}
+
+	ok, err := repo.IsRevoked(context.Background(), active.ID)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = repo.IsRevoked(context.Background(), revoked.ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Families from before sessions were recorded stay usable
+	ok, err = repo.IsRevoked(context.Background(), uuid.New())
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestSessionRepo_IsKnownDevice(t *testing.T) {
+	db := setupTestDB(t, &domain.Session{})
+	repo := &sessionRepo{gorm: db}
+
+	userID := uuid.New()
+
+	// The first login has nothing to compare against
+	known, err := repo.IsKnownDevice(context.Background(), userID, "laptop")
+	require.NoError(t, err)
+	require.True(t, known)
+
+	require.NoError(t, db.Create(&domain.Session{ID: uuid.New(), UserID: userID, UserAgent: "laptop"}).Error)
+
+	known, err = repo.IsKnownDevice(context.Background(), userID, "laptop")
+	require.NoError(t, err)
+	require.True(t, known)
+
+	known, err = repo.IsKnownDevice(context.Background(), userID, "phone")
+	require.NoError(t, err)
+	require.False(t, known)
+}
+
+func TestSessionRepo_Create(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &sessionRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	session := &domain.Session{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		UserAgent: "laptop",
+		IPAddress: "203.0.113.7",
+	}
+
+	now := time.Now()
+	mock.ExpectQuery("INSERT INTO sessions").
+		WithArgs(session.ID, session.UserID, "laptop", "203.0.113.7").
+		WillReturnRows(
+			pgxmock.NewRows([]string{"last_seen_at", "created_at", "updated_at"}).
+				AddRow(now, now, now),
+		)
+
+	err = repo.Create(context.Background(), session)
+
+	require.NoError(t, err)
+	require.Equal(t, now, session.LastSeenAt)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionRepo_Revoke(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &sessionRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	userID := uuid.New()
+	id := uuid.New()
+
+	mock.ExpectExec("UPDATE sessions").
+		WithArgs(id.String(), userID.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("UPDATE sessions").
+		WithArgs(id.String(), userID.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	revoked, err := repo.Revoke(context.Background(), userID, id)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// Sessions of other users or already revoked ones are left alone
+	revoked, err = repo.Revoke(context.Background(), userID, id)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionRepo_RevokeAllByUser(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &sessionRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	userID := uuid.New()
+
+	mock.ExpectExec("UPDATE sessions").
+		WithArgs(userID.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+
+	err = repo.RevokeAllByUser(context.Background(), userID)
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
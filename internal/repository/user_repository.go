@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/Masterminds/squirrel"
@@ -35,7 +36,7 @@ func (r *userRepo) FindByID(
 
 	err := r.gorm.
 		WithContext(ctx).
-		Where("id = ?", id).
+		Where("id = ? AND deleted_at IS NULL", id).
 		First(&user).
 		Error
 
@@ -51,7 +52,7 @@ func (r *userRepo) FindByEmail(
 
 	err := r.gorm.
 		WithContext(ctx).
-		Where("email = ?", email).
+		Where("email = ? AND deleted_at IS NULL", email).
 		First(&user).
 		Error
 
@@ -67,7 +68,7 @@ func (r *userRepo) FindByMobile(
 
 	err := r.gorm.
 		WithContext(ctx).
-		Where("mobile = ?", mobile).
+		Where("mobile = ? AND deleted_at IS NULL", mobile).
 		First(&user).
 		Error
 
@@ -123,7 +124,6 @@ func (r *userRepo) Update(ctx context.Context, user *domain.User) error {
 		Set("last_name", user.LastName).
 		Set("password", user.Password).
 		Set("last_login", user.LastLogin).
-		Set("is_active", user.IsActive).
 		Set("email_verified", user.EmailVerified).
 		Set("mobile_verified", user.MobileVerified).
 		Set("updated_at", squirrel.Expr("NOW()")).
@@ -138,11 +138,266 @@ func (r *userRepo) Update(ctx context.Context, user *domain.User) error {
 	return row.Scan(&user.UpdatedAt)
 }
 
+func (r *userRepo) UpdateRole(
+	ctx context.Context,
+	id uuid.UUID,
+	role domain.UserRole,
+) error {
+	query, args, err := r.sb.
+		Update("users").
+		Set("role", role).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+func (r *userRepo) SetActive(
+	ctx context.Context,
+	id uuid.UUID,
+	active bool,
+) error {
+	query, args, err := r.sb.
+		Update("users").
+		Set("is_active", active).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+func (r *userRepo) SetPasswordResetRequired(
+	ctx context.Context,
+	id uuid.UUID,
+	required bool,
+) error {
+	query, args, err := r.sb.
+		Update("users").
+		Set("password_reset_required", required).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+func (r *userRepo) SetPendingEmail(
+	ctx context.Context,
+	id uuid.UUID,
+	email *string,
+) error {
+	return r.setPending(ctx, id, "pending_email", email)
+}
+
+func (r *userRepo) SetPendingMobile(
+	ctx context.Context,
+	id uuid.UUID,
+	mobile *string,
+) error {
+	return r.setPending(ctx, id, "pending_mobile", mobile)
+}
+
+func (r *userRepo) setPending(
+	ctx context.Context,
+	id uuid.UUID,
+	column string,
+	value *string,
+) error {
+	query, args, err := r.sb.
+		Update("users").
+		Set(column, value).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+func (r *userRepo) ConfirmPendingEmail(ctx context.Context, id uuid.UUID) (bool, error) {
+	return r.confirmPending(ctx, id, "email", "pending_email", "email_verified")
+}
+
+func (r *userRepo) ConfirmPendingMobile(ctx context.Context, id uuid.UUID) (bool, error) {
+	return r.confirmPending(ctx, id, "mobile", "pending_mobile", "mobile_verified")
+}
+
+// confirmPending moves the pending address into place in a single statement,
+// so the unique index on the column rejects addresses taken in the meantime
+func (r *userRepo) confirmPending(
+	ctx context.Context,
+	id uuid.UUID,
+	column string,
+	pendingColumn string,
+	verifiedColumn string,
+) (bool, error) {
+	query, args, err := r.sb.
+		Update("users").
+		Set(column, squirrel.Expr(pendingColumn)).
+		Set(pendingColumn, nil).
+		Set(verifiedColumn, true).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": id}).
+		Where(squirrel.NotEq{pendingColumn: nil}).
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := execWithTxTag(ctx, r.db, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *userRepo) Search(
+	ctx context.Context,
+	filter domain.UserFilter,
+	pagination domain.QueryOptions,
+) (domain.Page[domain.User], int64, error) {
+
+	keys := newKeyset(domain.SortByCreatedAt, "created_at", "id", keyTime, domain.Desc)
+	cursor, err := keys.decode(pagination.Cursor)
+	if err != nil {
+		return domain.Page[domain.User]{}, 0, err
+	}
+
+	query := r.gorm.
+		WithContext(ctx).
+		Model(&domain.User{}).
+		Where("deleted_at IS NULL")
+
+	if filter.Email != nil {
+		query = query.Where("LOWER(email) LIKE ? ESCAPE '\\'", containsPattern(*filter.Email))
+	}
+	if filter.Mobile != nil {
+		query = query.Where("mobile LIKE ? ESCAPE '\\'", containsPattern(*filter.Mobile))
+	}
+	if filter.Name != nil {
+		query = query.Where("LOWER(first_name || ' ' || last_name) LIKE ? ESCAPE '\\'", containsPattern(*filter.Name))
+	}
+	if filter.Role != nil {
+		query = query.Where("role = ?", *filter.Role)
+	}
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+	if filter.EmailVerified != nil {
+		query = query.Where("email_verified = ?", *filter.EmailVerified)
+	}
+	if filter.MobileVerified != nil {
+		query = query.Where("mobile_verified = ?", *filter.MobileVerified)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return domain.Page[domain.User]{}, 0, err
+	}
+
+	users := make([]domain.User, 0)
+	err = query.
+		Scopes(keys.scope(cursor, pagination.Offset, pagination.Limit)).
+		Find(&users).
+		Error
+	if err != nil {
+		return domain.Page[domain.User]{}, 0, err
+	}
+
+	return keysetPage(keys, cursor, pagination.Offset, pagination.Limit, users, func(user domain.User) (any, uuid.UUID) {
+		return user.CreatedAt, user.ID
+	}), total, nil
+}
+
+// containsPattern builds a lower-case LIKE pattern, escaping the
+// wildcards a user may type.
+func containsPattern(s string) string {
+	s = strings.ToLower(s)
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "%", `\%`)
+	s = strings.ReplaceAll(s, "_", `\_`)
+	return "%" + s + "%"
+}
+
+func (r *userRepo) SetDeletionSchedule(
+	ctx context.Context,
+	id uuid.UUID,
+	at *time.Time,
+) error {
+	query, args, err := r.sb.
+		Update("users").
+		Set("deletion_scheduled_at", at).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": id}).
+		Where("deleted_at IS NULL").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return execWithTx(ctx, r.db, query, args...)
+}
+
+func (r *userRepo) FindDueForDeletion(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]domain.User, error) {
+
+	users := make([]domain.User, 0)
+
+	err := r.gorm.
+		WithContext(ctx).
+		Where("deletion_scheduled_at <= ? AND deleted_at IS NULL", before).
+		Order("deletion_scheduled_at").
+		Limit(limit).
+		Find(&users).
+		Error
+
+	return users, err
+}
+
+// Delete keeps the row for the order history but drops everything that
+// identifies the person. Email and mobile get placeholders derived from
+// the ID, since both columns are unique and not null.
 func (r *userRepo) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `
 		UPDATE users
-		SET deleted_at = NOW()
-		WHERE id = $1
+		SET first_name = 'Deleted',
+			last_name = 'User',
+			email = 'deleted-' || id || '@deleted.invalid',
+			mobile = 'deleted-' || id,
+			password = '',
+			pending_email = NULL,
+			pending_mobile = NULL,
+			is_active = false,
+			email_verified = false,
+			mobile_verified = false,
+			password_reset_required = false,
+			last_login = NULL,
+			updated_at = NOW(),
+			deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
 		RETURNING deleted_at;
 	`
 
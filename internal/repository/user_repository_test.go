@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	pgxmock "github.com/pashagolub/pgxmock/v3"
 	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 
 	"booknest/internal/domain"
 )
@@ -34,6 +35,25 @@ func TestUserRepo_FindByID(t *testing.T) {
 	require.Equal(t, user.Email, found.Email)
 }
 
+func TestUserRepo_FindByIDSkipsDeleted(t *testing.T) {
+	db := setupTestDB(t, &domain.User{})
+
+	deletedAt := time.Now()
+	user := domain.User{
+		ID:         uuid.New(),
+		Email:      "gone@booknest.com",
+		FirstName:  "Gone",
+		BaseEntity: domain.BaseEntity{DeletedAt: &deletedAt},
+	}
+
+	require.NoError(t, db.Create(&user).Error)
+
+	repo := &userRepo{gorm: db}
+
+	_, err := repo.FindByID(context.Background(), user.ID)
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
 func TestUserRepo_FindByEmail(t *testing.T) {
 	db := setupTestDB(t, &domain.User{})
 
@@ -145,7 +165,7 @@ func TestUserRepo_Update(t *testing.T) {
 		WithArgs(
 			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
 			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
-			pgxmock.AnyArg(), pgxmock.AnyArg(),
+			pgxmock.AnyArg(),
 		).
 		WillReturnRows(
 			pgxmock.NewRows([]string{"updated_at"}).
@@ -158,6 +178,211 @@ func TestUserRepo_Update(t *testing.T) {
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestUserRepo_UpdateRole(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &userRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	id := uuid.New()
+
+	mock.ExpectExec("UPDATE users").
+		WithArgs(domain.UserRoleAdmin, id.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	err = repo.UpdateRole(context.Background(), id, domain.UserRoleAdmin)
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_SetActiveAndPasswordResetRequired(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &userRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	id := uuid.New()
+
+	mock.ExpectExec("UPDATE users SET is_active").
+		WithArgs(false, id.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("UPDATE users SET password_reset_required").
+		WithArgs(true, id.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	require.NoError(t, repo.SetActive(context.Background(), id, false))
+	require.NoError(t, repo.SetPasswordResetRequired(context.Background(), id, true))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_PendingEmail(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &userRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	id := uuid.New()
+	email := "new@booknest.com"
+
+	mock.ExpectExec("UPDATE users SET pending_email").
+		WithArgs(&email, id.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`UPDATE users SET email = pending_email, pending_email = \$1, email_verified = \$2.* WHERE id = \$3 AND pending_email IS NOT NULL`).
+		WithArgs(nil, true, id.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("UPDATE users SET mobile = pending_mobile").
+		WithArgs(nil, true, id.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	require.NoError(t, repo.SetPendingEmail(context.Background(), id, &email))
+
+	confirmed, err := repo.ConfirmPendingEmail(context.Background(), id)
+	require.NoError(t, err)
+	require.True(t, confirmed)
+
+	confirmed, err = repo.ConfirmPendingMobile(context.Background(), id)
+	require.NoError(t, err)
+	require.False(t, confirmed)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_Search(t *testing.T) {
+	db := setupTestDB(t, &domain.User{})
+
+	now := time.Now()
+	users := []domain.User{
+		{ID: uuid.New(), FirstName: "Ada", LastName: "Lovelace", Email: "ada@booknest.com", Mobile: "+15550000001", Role: domain.UserRoleAdmin, IsActive: true, EmailVerified: true},
+		{ID: uuid.New(), FirstName: "Alan", LastName: "Turing", Email: "alan@example.com", Mobile: "+15550000002", Role: domain.UserRoleUser, IsActive: true},
+		{ID: uuid.New(), FirstName: "Grace", LastName: "Hopper", Email: "grace_h@example.com", Mobile: "+15550000003", Role: domain.UserRoleUser, IsActive: false},
+	}
+	for i := range users {
+		users[i].CreatedAt = now.Add(time.Duration(i-3) * time.Hour)
+		require.NoError(t, db.Create(&users[i]).Error)
+	}
+
+	repo := &userRepo{gorm: db}
+	ctx := context.Background()
+	page := domain.QueryOptions{Limit: 10}
+
+	email := "EXAMPLE.com"
+	found, total, err := repo.Search(ctx, domain.UserFilter{Email: &email}, page)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Len(t, found.Items, 2)
+
+	// Wildcards typed by the admin are matched literally
+	underscore := "_h@"
+	found, _, err = repo.Search(ctx, domain.UserFilter{Email: &underscore}, page)
+	require.NoError(t, err)
+	require.Len(t, found.Items, 1)
+	require.Equal(t, "Grace", found.Items[0].FirstName)
+
+	name := "ada love"
+	found, _, err = repo.Search(ctx, domain.UserFilter{Name: &name}, page)
+	require.NoError(t, err)
+	require.Len(t, found.Items, 1)
+
+	role := domain.UserRoleUser
+	active := true
+	found, _, err = repo.Search(ctx, domain.UserFilter{Role: &role, IsActive: &active}, page)
+	require.NoError(t, err)
+	require.Len(t, found.Items, 1)
+	require.Equal(t, "Alan", found.Items[0].FirstName)
+
+	verified := true
+	from := now.Add(-4 * time.Hour)
+	to := now.Add(-150 * time.Minute)
+	found, _, err = repo.Search(ctx, domain.UserFilter{EmailVerified: &verified, CreatedFrom: &from, CreatedTo: &to}, page)
+	require.NoError(t, err)
+	require.Len(t, found.Items, 1)
+	require.Equal(t, "Ada", found.Items[0].FirstName)
+
+	// Newest first, with the total of all matches
+	found, total, err = repo.Search(ctx, domain.UserFilter{}, domain.QueryOptions{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total)
+	require.Len(t, found.Items, 1)
+	require.Equal(t, "Alan", found.Items[0].FirstName)
+	require.NotEmpty(t, found.NextCursor)
+	require.NotEmpty(t, found.PrevCursor)
+
+	// The cursors page on from there, either way
+	next, _, err := repo.Search(ctx, domain.UserFilter{}, domain.QueryOptions{Limit: 1, Cursor: found.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, next.Items, 1)
+	require.Equal(t, "Ada", next.Items[0].FirstName)
+	require.Empty(t, next.NextCursor)
+
+	prev, _, err := repo.Search(ctx, domain.UserFilter{}, domain.QueryOptions{Limit: 1, Cursor: found.PrevCursor})
+	require.NoError(t, err)
+	require.Len(t, prev.Items, 1)
+	require.Equal(t, "Grace", prev.Items[0].FirstName)
+	require.Empty(t, prev.PrevCursor)
+
+	_, _, err = repo.Search(ctx, domain.UserFilter{}, domain.QueryOptions{Limit: 1, Cursor: "not-a-cursor"})
+	require.ErrorIs(t, err, domain.ErrInvalidCursor)
+}
+
+func TestUserRepo_SetDeletionSchedule(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &userRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	id := uuid.New()
+	at := time.Now().Add(time.Hour)
+
+	mock.ExpectExec("UPDATE users SET deletion_scheduled_at").
+		WithArgs(&at, id.String()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	require.NoError(t, repo.SetDeletionSchedule(context.Background(), id, &at))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_FindDueForDeletion(t *testing.T) {
+	db := setupTestDB(t, &domain.User{})
+
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	users := []domain.User{
+		{ID: uuid.New(), Email: "due@booknest.com", Mobile: "+15550000001", DeletionScheduledAt: &past},
+		{ID: uuid.New(), Email: "later@booknest.com", Mobile: "+15550000002", DeletionScheduledAt: &future},
+		{ID: uuid.New(), Email: "kept@booknest.com", Mobile: "+15550000003"},
+		{ID: uuid.New(), Email: "done@booknest.com", Mobile: "+15550000004", DeletionScheduledAt: &past, BaseEntity: domain.BaseEntity{DeletedAt: &past}},
+	}
+	for i := range users {
+		require.NoError(t, db.Create(&users[i]).Error)
+	}
+
+	repo := &userRepo{gorm: db}
+
+	due, err := repo.FindDueForDeletion(context.Background(), now, 10)
+
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	require.Equal(t, users[0].ID, due[0].ID)
+}
+
 func TestUserRepo_Delete(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
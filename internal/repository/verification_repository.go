@@ -2,10 +2,12 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"gorm.io/gorm"
 
@@ -92,6 +94,7 @@ func (r *verificationTokenRepo) Create(
 			"token_hash",
 			"expires_at",
 			"is_used",
+			"device_hash",
 		).
 		Values(
 			token.UserID,
@@ -99,6 +102,7 @@ func (r *verificationTokenRepo) Create(
 			token.TokenHash,
 			token.ExpiresAt,
 			token.IsUsed,
+			token.DeviceHash,
 		).
 		Suffix("RETURNING id, created_at, updated_at").
 		ToSql()
@@ -125,6 +129,7 @@ func (r *verificationTokenRepo) Update(
 		Set("is_used", token.IsUsed).
 		Set("used_at", token.UsedAt).
 		Set("expires_at", token.ExpiresAt).
+		Set("attempts", token.Attempts).
 		Set("updated_at", squirrel.Expr("NOW()")).
 		Where(squirrel.Eq{"id": token.ID}).
 		Suffix("RETURNING updated_at").
@@ -137,6 +142,55 @@ func (r *verificationTokenRepo) Update(
 	return row.Scan(&token.UpdatedAt)
 }
 
+func (r *verificationTokenRepo) Consume(
+	ctx context.Context,
+	id uuid.UUID,
+) (bool, error) {
+
+	query, args, err := r.sb.
+		Update("verification_tokens").
+		Set("is_used", true).
+		Set("used_at", squirrel.Expr("NOW()")).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": id, "is_used": false}).
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := execWithTxTag(ctx, r.db, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *verificationTokenRepo) RecordFailedAttempt(
+	ctx context.Context,
+	id uuid.UUID,
+	maxAttempts int,
+) (int, error) {
+
+	// SET expressions read the row as it was before the update
+	query := `
+		UPDATE verification_tokens
+		SET attempts = attempts + 1,
+			is_used = attempts + 1 >= $2,
+			used_at = CASE WHEN attempts + 1 >= $2 THEN NOW() END,
+			updated_at = NOW()
+		WHERE id = $1 AND is_used = false
+		RETURNING attempts
+	`
+
+	var attempts int
+	err := queryRowWithTx(ctx, r.db, query, id, maxAttempts).Scan(&attempts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return attempts, err
+}
+
 func (r *verificationTokenRepo) InvalidateByUserAndType(
 	ctx context.Context,
 	userID uuid.UUID,
@@ -175,3 +229,30 @@ func (r *verificationTokenRepo) Delete(ctx context.Context, id uuid.UUID) error
 	var deletedAt time.Time
 	return row.Scan(&deletedAt)
 }
+
+func (r *verificationTokenRepo) PurgeStale(
+	ctx context.Context,
+	cutoff time.Time,
+) (int64, error) {
+
+	query, args, err := r.sb.
+		Delete("verification_tokens").
+		Where(squirrel.Or{
+			squirrel.Lt{"expires_at": cutoff},
+			squirrel.And{
+				squirrel.Eq{"is_used": true},
+				squirrel.Expr("COALESCE(used_at, updated_at) < ?", cutoff),
+			},
+			squirrel.Lt{"deleted_at": cutoff},
+		}).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	tag, err := execWithTxTag(ctx, r.db, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	pgxmock "github.com/pashagolub/pgxmock/v3"
 	"github.com/stretchr/testify/require"
 
@@ -80,7 +81,7 @@ func TestVerificationRepo_Create(t *testing.T) {
 	mock.ExpectQuery("INSERT INTO verification_tokens").
 		WithArgs(
 			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
-			pgxmock.AnyArg(), pgxmock.AnyArg(),
+			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
 		).
 		WillReturnRows(
 			pgxmock.NewRows([]string{"id", "created_at", "updated_at"}).
@@ -114,7 +115,7 @@ func TestVerificationRepo_Update(t *testing.T) {
 	mock.ExpectQuery("UPDATE verification_tokens").
 		WithArgs(
 			pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
-			pgxmock.AnyArg(),
+			pgxmock.AnyArg(), pgxmock.AnyArg(),
 		).
 		WillReturnRows(
 			pgxmock.NewRows([]string{"updated_at"}).
@@ -184,3 +185,86 @@ func TestVerificationRepo_Delete(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestVerificationRepo_Consume(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &verificationTokenRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	id := uuid.New()
+
+	mock.ExpectExec("UPDATE verification_tokens").
+		WithArgs(true, id.String(), false).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("UPDATE verification_tokens").
+		WithArgs(true, id.String(), false).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	consumed, err := repo.Consume(context.Background(), id)
+	require.NoError(t, err)
+	require.True(t, consumed)
+
+	// A second redeem loses the race
+	consumed, err = repo.Consume(context.Background(), id)
+	require.NoError(t, err)
+	require.False(t, consumed)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVerificationRepo_RecordFailedAttempt(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &verificationTokenRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	id := uuid.New()
+
+	mock.ExpectQuery(`UPDATE verification_tokens SET attempts = attempts \+ 1`).
+		WithArgs(id, 5).
+		WillReturnRows(pgxmock.NewRows([]string{"attempts"}).AddRow(5))
+	mock.ExpectQuery(`UPDATE verification_tokens SET attempts = attempts \+ 1`).
+		WithArgs(id, 5).
+		WillReturnError(pgx.ErrNoRows)
+
+	attempts, err := repo.RecordFailedAttempt(context.Background(), id, 5)
+	require.NoError(t, err)
+	require.Equal(t, 5, attempts)
+
+	// The last guess burned the code, so later ones are not counted
+	attempts, err = repo.RecordFailedAttempt(context.Background(), id, 5)
+	require.NoError(t, err)
+	require.Zero(t, attempts)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVerificationRepo_PurgeStale(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := &verificationTokenRepo{
+		db: mock,
+		sb: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+
+	cutoff := time.Now().Add(-7 * 24 * time.Hour)
+
+	mock.ExpectExec(`DELETE FROM verification_tokens WHERE \(expires_at < \$1 OR \(is_used = \$2 AND COALESCE\(used_at, updated_at\) < \$3\) OR deleted_at < \$4\)`).
+		WithArgs(cutoff, true, cutoff, cutoff).
+		WillReturnResult(pgxmock.NewResult("DELETE", 42))
+
+	purged, err := repo.PurgeStale(context.Background(), cutoff)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(42), purged)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,204 @@
+package address_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/postalcode"
+	"booknest/internal/pkg/util"
+)
+
+// maxAddresses keeps address books to a size a checkout page can show
+const maxAddresses = 20
+
+var errTooManyAddresses = fmt.Errorf("an address book holds at most %d addresses", maxAddresses)
+
+type addressService struct {
+	db *pgxpool.Pool
+	r  domain.AddressRepository
+}
+
+func NewAddressService(db *pgxpool.Pool, r domain.AddressRepository) domain.AddressService {
+	return &addressService{
+		db: db,
+		r:  r,
+	}
+}
+
+func (s *addressService) List(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]domain.Address, error) {
+	addresses, err := s.r.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if addresses == nil {
+		addresses = make([]domain.Address, 0)
+	}
+	return addresses, nil
+}
+
+func (s *addressService) Get(
+	ctx context.Context,
+	userID uuid.UUID,
+	id uuid.UUID,
+) (domain.Address, error) {
+	address, err := s.r.FindByID(ctx, userID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.Address{}, domain.ErrAddressNotFound
+	}
+	return address, err
+}
+
+// Create adds an address. The first address of a user becomes the
+// default for shipping and billing.
+func (s *addressService) Create(
+	ctx context.Context,
+	userID uuid.UUID,
+	in domain.AddressInput,
+) (domain.Address, error) {
+	address, err := newAddress(userID, in)
+	if err != nil {
+		return domain.Address{}, err
+	}
+
+	existing, err := s.r.ListByUser(ctx, userID)
+	if err != nil {
+		return domain.Address{}, err
+	}
+	if len(existing) >= maxAddresses {
+		return domain.Address{}, errTooManyAddresses
+	}
+	if len(existing) == 0 {
+		address.IsDefaultShipping = true
+		address.IsDefaultBilling = true
+	}
+
+	err = util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		if err := s.clearDefaults(txCtx, address); err != nil {
+			return err
+		}
+		return s.r.Create(txCtx, &address)
+	})
+	if err != nil {
+		return domain.Address{}, err
+	}
+
+	return address, nil
+}
+
+// Update replaces the address. Orders placed with it keep their copy.
+func (s *addressService) Update(
+	ctx context.Context,
+	userID uuid.UUID,
+	id uuid.UUID,
+	in domain.AddressInput,
+) (domain.Address, error) {
+	current, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return domain.Address{}, err
+	}
+
+	address, err := newAddress(userID, in)
+	if err != nil {
+		return domain.Address{}, err
+	}
+	address.ID = current.ID
+	address.CreatedAt = current.CreatedAt
+
+	// A default is moved by marking another address, never just dropped
+	address.IsDefaultShipping = address.IsDefaultShipping || current.IsDefaultShipping
+	address.IsDefaultBilling = address.IsDefaultBilling || current.IsDefaultBilling
+
+	err = util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		if err := s.clearDefaults(txCtx, address); err != nil {
+			return err
+		}
+		return s.r.Update(txCtx, &address)
+	})
+	if err != nil {
+		return domain.Address{}, err
+	}
+
+	return address, nil
+}
+
+// Delete removes the address. A default it held moves to the oldest
+// remaining address, so checkout keeps a fallback while any are left.
+func (s *addressService) Delete(
+	ctx context.Context,
+	userID uuid.UUID,
+	id uuid.UUID,
+) error {
+	current, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	return util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		deleted, err := s.r.Delete(txCtx, userID, id)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			return domain.ErrAddressNotFound
+		}
+
+		if current.IsDefaultShipping {
+			if err := s.r.PromoteDefault(txCtx, userID, domain.AddressShipping); err != nil {
+				return err
+			}
+		}
+		if current.IsDefaultBilling {
+			if err := s.r.PromoteDefault(txCtx, userID, domain.AddressBilling); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// clearDefaults unsets the defaults address is about to take over
+func (s *addressService) clearDefaults(ctx context.Context, address domain.Address) error {
+	if address.IsDefaultShipping {
+		if err := s.r.ClearDefault(ctx, address.UserID, domain.AddressShipping); err != nil {
+			return err
+		}
+	}
+	if address.IsDefaultBilling {
+		if err := s.r.ClearDefault(ctx, address.UserID, domain.AddressBilling); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newAddress validates the input and normalises country and postal code
+func newAddress(userID uuid.UUID, in domain.AddressInput) (domain.Address, error) {
+	if err := postalcode.Validate(in.Country, in.PostalCode); err != nil {
+		return domain.Address{}, err
+	}
+
+	return domain.Address{
+		UserID:            userID,
+		Label:             in.Label,
+		FullName:          in.FullName,
+		Line1:             in.Line1,
+		Line2:             in.Line2,
+		City:              in.City,
+		Region:            in.Region,
+		PostalCode:        postalcode.Normalize(in.PostalCode),
+		Country:           strings.ToUpper(in.Country),
+		Phone:             in.Phone,
+		IsDefaultShipping: in.IsDefaultShipping,
+		IsDefaultBilling:  in.IsDefaultBilling,
+	}, nil
+}
@@ -0,0 +1,194 @@
+package address_service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/postalcode"
+)
+
+// MockAddressRepository is a mock implementation of domain.AddressRepository
+type MockAddressRepository struct {
+	ListByUserFunc     func(ctx context.Context, userID uuid.UUID) ([]domain.Address, error)
+	FindByIDFunc       func(ctx context.Context, userID, id uuid.UUID) (domain.Address, error)
+	FindDefaultFunc    func(ctx context.Context, userID uuid.UUID, kind domain.AddressKind) (domain.Address, error)
+	CreateFunc         func(ctx context.Context, address *domain.Address) error
+	UpdateFunc         func(ctx context.Context, address *domain.Address) error
+	ClearDefaultFunc   func(ctx context.Context, userID uuid.UUID, kind domain.AddressKind) error
+	PromoteDefaultFunc func(ctx context.Context, userID uuid.UUID, kind domain.AddressKind) error
+	DeleteFunc         func(ctx context.Context, userID, id uuid.UUID) (bool, error)
+}
+
+func (m *MockAddressRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Address, error) {
+	if m.ListByUserFunc != nil {
+		return m.ListByUserFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *MockAddressRepository) FindByID(ctx context.Context, userID, id uuid.UUID) (domain.Address, error) {
+	if m.FindByIDFunc != nil {
+		return m.FindByIDFunc(ctx, userID, id)
+	}
+	return domain.Address{}, gorm.ErrRecordNotFound
+}
+
+func (m *MockAddressRepository) FindDefault(ctx context.Context, userID uuid.UUID, kind domain.AddressKind) (domain.Address, error) {
+	if m.FindDefaultFunc != nil {
+		return m.FindDefaultFunc(ctx, userID, kind)
+	}
+	return domain.Address{}, gorm.ErrRecordNotFound
+}
+
+func (m *MockAddressRepository) Create(ctx context.Context, address *domain.Address) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, address)
+	}
+	return nil
+}
+
+func (m *MockAddressRepository) Update(ctx context.Context, address *domain.Address) error {
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(ctx, address)
+	}
+	return nil
+}
+
+func (m *MockAddressRepository) ClearDefault(ctx context.Context, userID uuid.UUID, kind domain.AddressKind) error {
+	if m.ClearDefaultFunc != nil {
+		return m.ClearDefaultFunc(ctx, userID, kind)
+	}
+	return nil
+}
+
+func (m *MockAddressRepository) PromoteDefault(ctx context.Context, userID uuid.UUID, kind domain.AddressKind) error {
+	if m.PromoteDefaultFunc != nil {
+		return m.PromoteDefaultFunc(ctx, userID, kind)
+	}
+	return nil
+}
+
+func (m *MockAddressRepository) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(ctx, userID, id)
+	}
+	return false, nil
+}
+
+func (m *MockAddressRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+
+func validInput() domain.AddressInput {
+	return domain.AddressInput{
+		FullName:   "Jane Doe",
+		Line1:      "10 Downing Street",
+		City:       "London",
+		PostalCode: " sw1a 2aa ",
+		Country:    "gb",
+	}
+}
+
+// TestNewAddress tests that postal codes are checked and normalised
+func TestNewAddress(t *testing.T) {
+	address, err := newAddress(uuid.New(), validInput())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if address.PostalCode != "SW1A 2AA" || address.Country != "GB" {
+		t.Fatalf("expected normalised values, got %q %q", address.PostalCode, address.Country)
+	}
+
+	in := validInput()
+	in.Country = "US"
+	if _, err := newAddress(uuid.New(), in); !errors.Is(err, postalcode.ErrInvalid) {
+		t.Fatalf("expected invalid postal code error, got %v", err)
+	}
+}
+
+// TestCreate_Invalid tests that nothing is stored for an invalid address
+func TestCreate_Invalid(t *testing.T) {
+	service := &addressService{r: &MockAddressRepository{
+		CreateFunc: func(ctx context.Context, address *domain.Address) error {
+			t.Fatalf("should not store the address")
+			return nil
+		},
+	}}
+
+	in := validInput()
+	in.PostalCode = ""
+	if _, err := service.Create(context.Background(), uuid.New(), in); !errors.Is(err, postalcode.ErrRequired) {
+		t.Fatalf("expected postal code required error, got %v", err)
+	}
+}
+
+// TestCreate_TooMany tests that address books are capped
+func TestCreate_TooMany(t *testing.T) {
+	service := &addressService{r: &MockAddressRepository{
+		ListByUserFunc: func(ctx context.Context, userID uuid.UUID) ([]domain.Address, error) {
+			return make([]domain.Address, maxAddresses), nil
+		},
+	}}
+
+	if _, err := service.Create(context.Background(), uuid.New(), validInput()); !errors.Is(err, errTooManyAddresses) {
+		t.Fatalf("expected too many addresses error, got %v", err)
+	}
+}
+
+// Note: storing an address and moving the defaults run in a transaction
+// and are covered by integration tests.
+
+// TestGet_NotFound tests that unknown and foreign addresses are not found
+func TestGet_NotFound(t *testing.T) {
+	service := &addressService{r: &MockAddressRepository{}}
+
+	if _, err := service.Get(context.Background(), uuid.New(), uuid.New()); !errors.Is(err, domain.ErrAddressNotFound) {
+		t.Fatalf("expected address not found, got %v", err)
+	}
+}
+
+// TestUpdate_NotFound tests that other users' addresses cannot be replaced
+func TestUpdate_NotFound(t *testing.T) {
+	service := &addressService{r: &MockAddressRepository{
+		UpdateFunc: func(ctx context.Context, address *domain.Address) error {
+			t.Fatalf("should not update the address")
+			return nil
+		},
+	}}
+
+	if _, err := service.Update(context.Background(), uuid.New(), uuid.New(), validInput()); !errors.Is(err, domain.ErrAddressNotFound) {
+		t.Fatalf("expected address not found, got %v", err)
+	}
+}
+
+// TestDelete tests that deleting a missing address is reported
+func TestDelete(t *testing.T) {
+	service := &addressService{r: &MockAddressRepository{
+		DeleteFunc: func(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+			t.Fatalf("should not delete the address")
+			return false, nil
+		},
+	}}
+
+	if err := service.Delete(context.Background(), uuid.New(), uuid.New()); !errors.Is(err, domain.ErrAddressNotFound) {
+		t.Fatalf("expected address not found, got %v", err)
+	}
+}
+
+// Note: deleting an address and promoting the next default run in a
+// transaction and are covered by integration tests.
+
+// TestList_Empty tests that an empty address book is an empty list
+func TestList_Empty(t *testing.T) {
+	service := &addressService{r: &MockAddressRepository{}}
+
+	addresses, err := service.List(context.Background(), uuid.New())
+	if err != nil || addresses == nil || len(addresses) != 0 {
+		t.Fatalf("expected an empty list, got %v %v", addresses, err)
+	}
+}
@@ -35,9 +35,9 @@ func (s *authorService) FindByID(
 
 func (s *authorService) List(
 	ctx context.Context,
-	limit, offset int,
-) ([]domain.Author, error) {
-	return s.r.List(ctx, limit, offset)
+	q domain.QueryOptions,
+) (domain.Page[domain.Author], error) {
+	return s.r.List(ctx, q)
 }
 
 func (s *authorService) Create(
@@ -14,7 +14,7 @@ import (
 type mockAuthorRepository struct {
 	findByIDFunc   func(ctx context.Context, id uuid.UUID) (domain.Author, error)
 	findByNameFunc func(ctx context.Context, name string) (domain.Author, error)
-	listFunc       func(ctx context.Context, limit, offset int) ([]domain.Author, error)
+	listFunc       func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Author], error)
 	createFunc     func(ctx context.Context, author *domain.Author) error
 	updateFunc     func(ctx context.Context, author *domain.Author) error
 	deleteFunc     func(ctx context.Context, id uuid.UUID) error
@@ -34,11 +34,11 @@ func (m *mockAuthorRepository) FindByName(ctx context.Context, name string) (dom
 	return domain.Author{}, gorm.ErrRecordNotFound
 }
 
-func (m *mockAuthorRepository) List(ctx context.Context, limit, offset int) ([]domain.Author, error) {
+func (m *mockAuthorRepository) List(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Author], error) {
 	if m.listFunc != nil {
-		return m.listFunc(ctx, limit, offset)
+		return m.listFunc(ctx, q)
 	}
-	return []domain.Author{}, nil
+	return domain.Page[domain.Author]{Items: []domain.Author{}}, nil
 }
 
 func (m *mockAuthorRepository) Create(ctx context.Context, author *domain.Author) error {
@@ -162,11 +162,11 @@ func TestAuthorReadAndDeletePassThrough(t *testing.T) {
 		findByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.Author, error) {
 			return expected, nil
 		},
-		listFunc: func(ctx context.Context, limit, offset int) ([]domain.Author, error) {
-			if limit != 5 || offset != 10 {
-				t.Fatalf("unexpected pagination: %d/%d", limit, offset)
+		listFunc: func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Author], error) {
+			if q.Limit != 5 || q.Offset != 10 {
+				t.Fatalf("unexpected pagination: %d/%d", q.Limit, q.Offset)
 			}
-			return []domain.Author{expected}, nil
+			return domain.Page[domain.Author]{Items: []domain.Author{expected}}, nil
 		},
 		deleteFunc: func(ctx context.Context, id uuid.UUID) error {
 			if id != authorID {
@@ -182,8 +182,8 @@ func TestAuthorReadAndDeletePassThrough(t *testing.T) {
 		t.Fatalf("unexpected find result: %+v, err=%v", author, err)
 	}
 
-	list, err := svc.List(context.Background(), 5, 10)
-	if err != nil || len(list) != 1 {
+	list, err := svc.List(context.Background(), domain.QueryOptions{Limit: 5, Offset: 10})
+	if err != nil || len(list.Items) != 1 {
 		t.Fatalf("unexpected list result: %+v, err=%v", list, err)
 	}
 
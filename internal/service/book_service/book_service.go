@@ -2,7 +2,11 @@ package book_service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -10,6 +14,18 @@ import (
 	"booknest/internal/domain"
 )
 
+const (
+	// suggestLimit is the number of suggestions per kind
+	suggestLimit = 5
+
+	// Queries outside these bounds, in characters, get no suggestions
+	minSuggestLength = 2
+	maxSuggestLength = 100
+
+	// suggestTimeout bounds a lookup, which runs on every keystroke
+	suggestTimeout = 300 * time.Millisecond
+)
+
 type bookService struct {
 	repo domain.BookRepository
 	db   *gorm.DB
@@ -102,8 +118,8 @@ func (s *bookService) GetBook(ctx context.Context, id uuid.UUID) (*domain.Book,
 	return s.repo.FindByID(ctx, id)
 }
 
-func (s *bookService) ListBooks(ctx context.Context, limit, offset int) ([]domain.Book, error) {
-	return s.repo.List(ctx, limit, offset)
+func (s *bookService) ListBooks(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Book], error) {
+	return s.repo.List(ctx, q)
 }
 
 func (s *bookService) FilterByCriteria(
@@ -112,19 +128,43 @@ func (s *bookService) FilterByCriteria(
 	q domain.QueryOptions,
 ) (*domain.BookSearchResult, error) {
 
-	books, total, err := s.repo.FilterByCriteria(ctx, filter, q)
+	page, total, facets, err := s.repo.FilterByCriteria(ctx, filter, q)
 	if err != nil {
 		return nil, err
 	}
 
 	return &domain.BookSearchResult{
-		Items:  books,
-		Total:  total,
-		Limit:  q.Limit,
-		Offset: q.Offset,
+		Items:       page.Items,
+		Total:       total,
+		Limit:       q.Limit,
+		Offset:      q.Offset,
+		Facets:      facets,
+		PageCursors: page.PageCursors,
 	}, nil
 }
 
+// Suggest returns the titles, authors, categories and ISBNs best matching
+// a partial query. A lookup that runs out of time suggests nothing rather
+// than failing, as the next keystroke will ask again.
+func (s *bookService) Suggest(ctx context.Context, query string) (*domain.BookSuggestions, error) {
+	query = strings.TrimSpace(query)
+	if n := utf8.RuneCountInString(query); n < minSuggestLength || n > maxSuggestLength {
+		return noSuggestions(), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, suggestTimeout)
+	defer cancel()
+
+	suggestions, err := s.repo.Suggest(ctx, query, suggestLimit)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return noSuggestions(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
 func (s *bookService) UpdateBook(
 	ctx context.Context,
 	id uuid.UUID,
@@ -213,6 +253,15 @@ func (s *bookService) DeleteBook(ctx context.Context, id uuid.UUID) error {
 	return s.repo.Delete(ctx, id)
 }
 
+func noSuggestions() *domain.BookSuggestions {
+	return &domain.BookSuggestions{
+		Titles:     []domain.Suggestion{},
+		Authors:    []domain.Suggestion{},
+		Categories: []domain.Suggestion{},
+		ISBNs:      []domain.Suggestion{},
+	}
+}
+
 func uniqueCategoryIDs(categoryIDs []uuid.UUID) []uuid.UUID {
 	if len(categoryIDs) == 0 {
 		return nil
@@ -14,8 +14,9 @@ import (
 
 type mockBookRepository struct {
 	findByIDFunc         func(ctx context.Context, id uuid.UUID) (*domain.Book, error)
-	listFunc             func(ctx context.Context, limit, offset int) ([]domain.Book, error)
-	filterByCriteriaFunc func(ctx context.Context, filter domain.BookFilter, pagination domain.QueryOptions) ([]domain.Book, int64, error)
+	listFunc             func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Book], error)
+	filterByCriteriaFunc func(ctx context.Context, filter domain.BookFilter, pagination domain.QueryOptions) (domain.Page[domain.BookHit], int64, *domain.BookFacets, error)
+	suggestFunc          func(ctx context.Context, query string, limit int) (*domain.BookSuggestions, error)
 	deleteFunc           func(ctx context.Context, id uuid.UUID) error
 }
 
@@ -30,18 +31,25 @@ func (m *mockBookRepository) FindByID(ctx context.Context, id uuid.UUID) (*domai
 	return nil, errors.New("not implemented")
 }
 
-func (m *mockBookRepository) List(ctx context.Context, limit, offset int) ([]domain.Book, error) {
+func (m *mockBookRepository) List(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Book], error) {
 	if m.listFunc != nil {
-		return m.listFunc(ctx, limit, offset)
+		return m.listFunc(ctx, q)
 	}
-	return []domain.Book{}, nil
+	return domain.Page[domain.Book]{Items: []domain.Book{}}, nil
 }
 
-func (m *mockBookRepository) FilterByCriteria(ctx context.Context, filter domain.BookFilter, pagination domain.QueryOptions) ([]domain.Book, int64, error) {
+func (m *mockBookRepository) FilterByCriteria(ctx context.Context, filter domain.BookFilter, pagination domain.QueryOptions) (domain.Page[domain.BookHit], int64, *domain.BookFacets, error) {
 	if m.filterByCriteriaFunc != nil {
 		return m.filterByCriteriaFunc(ctx, filter, pagination)
 	}
-	return []domain.Book{}, 0, nil
+	return domain.Page[domain.BookHit]{Items: []domain.BookHit{}}, 0, nil, nil
+}
+
+func (m *mockBookRepository) Suggest(ctx context.Context, query string, limit int) (*domain.BookSuggestions, error) {
+	if m.suggestFunc != nil {
+		return m.suggestFunc(ctx, query, limit)
+	}
+	return &domain.BookSuggestions{}, nil
 }
 
 func (m *mockBookRepository) Update(ctx context.Context, book *domain.Book) error {
@@ -114,17 +122,17 @@ func TestBookServiceReadAndFilterPassThrough(t *testing.T) {
 			}
 			return expected, nil
 		},
-		listFunc: func(ctx context.Context, limit, offset int) ([]domain.Book, error) {
-			if limit != 10 || offset != 20 {
-				t.Fatalf("unexpected pagination: %d/%d", limit, offset)
+		listFunc: func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Book], error) {
+			if q.Limit != 10 || q.Offset != 20 {
+				t.Fatalf("unexpected pagination: %d/%d", q.Limit, q.Offset)
 			}
-			return []domain.Book{*expected}, nil
+			return domain.Page[domain.Book]{Items: []domain.Book{*expected}}, nil
 		},
-		filterByCriteriaFunc: func(ctx context.Context, gotFilter domain.BookFilter, pagination domain.QueryOptions) ([]domain.Book, int64, error) {
+		filterByCriteriaFunc: func(ctx context.Context, gotFilter domain.BookFilter, pagination domain.QueryOptions) (domain.Page[domain.BookHit], int64, *domain.BookFacets, error) {
 			if pagination.Limit != query.Limit || pagination.Offset != query.Offset {
 				t.Fatalf("unexpected query options: %+v", pagination)
 			}
-			return []domain.Book{*expected}, 1, nil
+			return domain.Page[domain.BookHit]{Items: []domain.BookHit{{Book: *expected}}}, 1, &domain.BookFacets{InStock: 1}, nil
 		},
 		deleteFunc: func(ctx context.Context, id uuid.UUID) error {
 			if id != bookID {
@@ -141,8 +149,8 @@ func TestBookServiceReadAndFilterPassThrough(t *testing.T) {
 		t.Fatalf("unexpected GetBook result: %+v, err=%v", book, err)
 	}
 
-	books, err := svc.ListBooks(context.Background(), 10, 20)
-	if err != nil || len(books) != 1 {
+	books, err := svc.ListBooks(context.Background(), domain.QueryOptions{Limit: 10, Offset: 20})
+	if err != nil || len(books.Items) != 1 {
 		t.Fatalf("unexpected ListBooks result: %+v, err=%v", books, err)
 	}
 
@@ -153,8 +161,62 @@ func TestBookServiceReadAndFilterPassThrough(t *testing.T) {
 	if result.Total != 1 || result.Limit != query.Limit || result.Offset != query.Offset || len(result.Items) != 1 {
 		t.Fatalf("unexpected filter result: %+v", result)
 	}
+	if result.Facets == nil || result.Facets.InStock != 1 {
+		t.Fatalf("expected the facets, got %+v", result.Facets)
+	}
 
 	if err := svc.DeleteBook(context.Background(), bookID); err != nil {
 		t.Fatalf("unexpected DeleteBook error: %v", err)
 	}
 }
+
+func TestBookServiceSuggest(t *testing.T) {
+	var calls int
+	repo := &mockBookRepository{
+		suggestFunc: func(ctx context.Context, query string, limit int) (*domain.BookSuggestions, error) {
+			calls++
+			if query != "harry" || limit != suggestLimit {
+				t.Fatalf("unexpected lookup: %q/%d", query, limit)
+			}
+			if _, ok := ctx.Deadline(); !ok {
+				t.Fatalf("expected the lookup to have a deadline")
+			}
+			return &domain.BookSuggestions{Titles: []domain.Suggestion{{Text: "Harry Potter"}}}, nil
+		},
+	}
+	svc := NewBookService(repo, nil)
+
+	suggestions, err := svc.Suggest(context.Background(), "  harry ")
+	if err != nil || len(suggestions.Titles) != 1 {
+		t.Fatalf("unexpected suggestions: %+v, err=%v", suggestions, err)
+	}
+
+	// Too short to look up
+	suggestions, err = svc.Suggest(context.Background(), " h ")
+	if err != nil || suggestions.Titles == nil || len(suggestions.Titles) != 0 {
+		t.Fatalf("expected no suggestions, got %+v, err=%v", suggestions, err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 lookup, got %d", calls)
+	}
+}
+
+func TestBookServiceSuggestTimeout(t *testing.T) {
+	repo := &mockBookRepository{
+		suggestFunc: func(ctx context.Context, query string, limit int) (*domain.BookSuggestions, error) {
+			return nil, context.DeadlineExceeded
+		},
+	}
+
+	suggestions, err := NewBookService(repo, nil).Suggest(context.Background(), "harry")
+	if err != nil || len(suggestions.Titles) != 0 {
+		t.Fatalf("expected no suggestions on timeout, got %+v, err=%v", suggestions, err)
+	}
+
+	repo.suggestFunc = func(ctx context.Context, query string, limit int) (*domain.BookSuggestions, error) {
+		return nil, errors.New("db down")
+	}
+	if _, err := NewBookService(repo, nil).Suggest(context.Background(), "harry"); err == nil {
+		t.Fatalf("expected the error to be returned")
+	}
+}
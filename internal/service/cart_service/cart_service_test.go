@@ -61,16 +61,23 @@ func (m *mockCartRepository) ClearCart(ctx context.Context, cartID uuid.UUID) er
 	return nil
 }
 
+func (m *mockCartRepository) ClearUserCart(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+
 type mockBookRepository struct {
 	findByIDFunc func(ctx context.Context, id uuid.UUID) (*domain.Book, error)
 }
 
 func (m *mockBookRepository) Create(ctx context.Context, book *domain.Book) error { return nil }
-func (m *mockBookRepository) List(ctx context.Context, limit, offset int) ([]domain.Book, error) {
-	return nil, nil
+func (m *mockBookRepository) List(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Book], error) {
+	return domain.Page[domain.Book]{}, nil
+}
+func (m *mockBookRepository) FilterByCriteria(ctx context.Context, filter domain.BookFilter, pagination domain.QueryOptions) (domain.Page[domain.BookHit], int64, *domain.BookFacets, error) {
+	return domain.Page[domain.BookHit]{}, 0, nil, nil
 }
-func (m *mockBookRepository) FilterByCriteria(ctx context.Context, filter domain.BookFilter, pagination domain.QueryOptions) ([]domain.Book, int64, error) {
-	return nil, 0, nil
+func (m *mockBookRepository) Suggest(ctx context.Context, query string, limit int) (*domain.BookSuggestions, error) {
+	return nil, nil
 }
 func (m *mockBookRepository) Update(ctx context.Context, book *domain.Book) error { return nil }
 func (m *mockBookRepository) Delete(ctx context.Context, id uuid.UUID) error { return nil }
@@ -35,9 +35,9 @@ func (s *categoryService) FindByID(
 
 func (s *categoryService) List(
 	ctx context.Context,
-	limit, offset int,
-) ([]domain.Category, error) {
-	return s.r.List(ctx, limit, offset)
+	q domain.QueryOptions,
+) (domain.Page[domain.Category], error) {
+	return s.r.List(ctx, q)
 }
 
 func (s *categoryService) Create(
@@ -14,7 +14,7 @@ import (
 type mockCategoryRepository struct {
 	findByIDFunc   func(ctx context.Context, id uuid.UUID) (domain.Category, error)
 	findByNameFunc func(ctx context.Context, name string) (domain.Category, error)
-	listFunc       func(ctx context.Context, limit, offset int) ([]domain.Category, error)
+	listFunc       func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Category], error)
 	createFunc     func(ctx context.Context, category *domain.Category) error
 	updateFunc     func(ctx context.Context, category *domain.Category) error
 	deleteFunc     func(ctx context.Context, id uuid.UUID) error
@@ -34,11 +34,11 @@ func (m *mockCategoryRepository) FindByName(ctx context.Context, name string) (d
 	return domain.Category{}, gorm.ErrRecordNotFound
 }
 
-func (m *mockCategoryRepository) List(ctx context.Context, limit, offset int) ([]domain.Category, error) {
+func (m *mockCategoryRepository) List(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Category], error) {
 	if m.listFunc != nil {
-		return m.listFunc(ctx, limit, offset)
+		return m.listFunc(ctx, q)
 	}
-	return []domain.Category{}, nil
+	return domain.Page[domain.Category]{Items: []domain.Category{}}, nil
 }
 
 func (m *mockCategoryRepository) Create(ctx context.Context, category *domain.Category) error {
@@ -162,11 +162,11 @@ func TestCategoryReadAndDeletePassThrough(t *testing.T) {
 		findByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.Category, error) {
 			return expected, nil
 		},
-		listFunc: func(ctx context.Context, limit, offset int) ([]domain.Category, error) {
-			if limit != 5 || offset != 10 {
-				t.Fatalf("unexpected pagination: %d/%d", limit, offset)
+		listFunc: func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Category], error) {
+			if q.Limit != 5 || q.Offset != 10 {
+				t.Fatalf("unexpected pagination: %d/%d", q.Limit, q.Offset)
 			}
-			return []domain.Category{expected}, nil
+			return domain.Page[domain.Category]{Items: []domain.Category{expected}}, nil
 		},
 		deleteFunc: func(ctx context.Context, id uuid.UUID) error {
 			if id != categoryID {
@@ -182,8 +182,8 @@ func TestCategoryReadAndDeletePassThrough(t *testing.T) {
 		t.Fatalf("unexpected find result: %+v, err=%v", category, err)
 	}
 
-	list, err := svc.List(context.Background(), 5, 10)
-	if err != nil || len(list) != 1 {
+	list, err := svc.List(context.Background(), domain.QueryOptions{Limit: 5, Offset: 10})
+	if err != nil || len(list.Items) != 1 {
 		t.Fatalf("unexpected list result: %+v, err=%v", list, err)
 	}
 
@@ -0,0 +1,132 @@
+package maintenance_service
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/util"
+)
+
+// DefaultTokenRetention is how long expired and used verification tokens
+// are kept for support and audit questions
+const DefaultTokenRetention = 7 * 24 * time.Hour
+
+const (
+	verificationTokenPurgeLock = "maintenance:verification_tokens"
+	deletedAccountPurgeLock    = "maintenance:deleted_accounts"
+)
+
+// metrics is published on /debug/vars as "maintenance"
+var metrics = expvar.NewMap("maintenance")
+
+type maintenanceService struct {
+	db       *pgxpool.Pool
+	lr       domain.AdvisoryLockRepository
+	vtr      domain.VerificationTokenRepository
+	accounts domain.AccountPurger
+
+	tokenRetention time.Duration
+}
+
+func NewMaintenanceService(
+	db *pgxpool.Pool,
+	lr domain.AdvisoryLockRepository,
+	vtr domain.VerificationTokenRepository,
+	accounts domain.AccountPurger,
+	tokenRetention time.Duration,
+) domain.MaintenanceService {
+	return &maintenanceService{
+		db:       db,
+		lr:       lr,
+		vtr:      vtr,
+		accounts: accounts,
+
+		tokenRetention: tokenRetention,
+	}
+}
+
+// PurgeVerificationTokens deletes expired, used and deleted tokens older
+// than the retention window. The advisory lock lives as long as the
+// transaction, so only one replica purges at a time.
+func (s *maintenanceService) PurgeVerificationTokens(ctx context.Context) (int64, error) {
+	var purged int64
+
+	err := util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		var err error
+		purged, err = s.purgeVerificationTokens(txCtx)
+		return err
+	})
+	if err != nil {
+		metrics.Add("verification_tokens_purge_errors", 1)
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+// purgeVerificationTokens runs inside the transaction holding the lock
+func (s *maintenanceService) purgeVerificationTokens(ctx context.Context) (int64, error) {
+	locked, err := s.lr.TryXactLock(ctx, verificationTokenPurgeLock)
+	if err != nil {
+		return 0, err
+	}
+	if !locked {
+		// Another replica is on it
+		metrics.Add("verification_tokens_purge_skipped", 1)
+		return 0, nil
+	}
+
+	purged, err := s.vtr.PurgeStale(ctx, time.Now().Add(-s.tokenRetention))
+	if err != nil {
+		return 0, err
+	}
+
+	metrics.Add("verification_tokens_purge_runs", 1)
+	metrics.Add("verification_tokens_purged", purged)
+	return purged, nil
+}
+
+// PurgeDeletedAccounts anonymises the accounts whose grace period ended.
+// Each account is purged in its own transaction while this one only holds
+// the lock, so a failing account does not roll back the others.
+func (s *maintenanceService) PurgeDeletedAccounts(ctx context.Context) (int, error) {
+	var purged int
+
+	err := util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		var err error
+		purged, err = s.purgeDeletedAccounts(txCtx)
+		return err
+	})
+	if err != nil {
+		metrics.Add("deleted_accounts_purge_errors", 1)
+		return purged, err
+	}
+
+	return purged, nil
+}
+
+// purgeDeletedAccounts runs inside the transaction holding the lock
+func (s *maintenanceService) purgeDeletedAccounts(ctx context.Context) (int, error) {
+	locked, err := s.lr.TryXactLock(ctx, deletedAccountPurgeLock)
+	if err != nil {
+		return 0, err
+	}
+	if !locked {
+		// Another replica is on it
+		metrics.Add("deleted_accounts_purge_skipped", 1)
+		return 0, nil
+	}
+
+	purged, err := s.accounts.PurgeDeletedUsers(ctx)
+	metrics.Add("deleted_accounts_purged", int64(purged))
+	if err != nil {
+		return purged, err
+	}
+
+	metrics.Add("deleted_accounts_purge_runs", 1)
+	return purged, nil
+}
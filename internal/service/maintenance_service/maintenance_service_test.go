@@ -0,0 +1,208 @@
+package maintenance_service
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	"booknest/internal/domain"
+)
+
+// MockAdvisoryLockRepository is a mock implementation of domain.AdvisoryLockRepository
+type MockAdvisoryLockRepository struct {
+	TryXactLockFunc func(ctx context.Context, name string) (bool, error)
+}
+
+func (m *MockAdvisoryLockRepository) TryXactLock(ctx context.Context, name string) (bool, error) {
+	if m.TryXactLockFunc != nil {
+		return m.TryXactLockFunc(ctx, name)
+	}
+	return true, nil
+}
+
+// MockVerificationTokenRepository only implements purging; the other
+// methods are not used by this service
+type MockVerificationTokenRepository struct {
+	domain.VerificationTokenRepository
+	PurgeStaleFunc func(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+func (m *MockVerificationTokenRepository) PurgeStale(ctx context.Context, cutoff time.Time) (int64, error) {
+	if m.PurgeStaleFunc != nil {
+		return m.PurgeStaleFunc(ctx, cutoff)
+	}
+	return 0, nil
+}
+
+// MockAccountPurger is a mock implementation of domain.AccountPurger
+type MockAccountPurger struct {
+	PurgeDeletedUsersFunc func(ctx context.Context) (int, error)
+}
+
+func (m *MockAccountPurger) PurgeDeletedUsers(ctx context.Context) (int, error) {
+	if m.PurgeDeletedUsersFunc != nil {
+		return m.PurgeDeletedUsersFunc(ctx)
+	}
+	return 0, nil
+}
+
+func metricValue(name string) int64 {
+	if v, ok := metrics.Get(name).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+// TestNewMaintenanceService tests the constructor
+func TestNewMaintenanceService(t *testing.T) {
+	lr := &MockAdvisoryLockRepository{}
+	vtr := &MockVerificationTokenRepository{}
+	accounts := &MockAccountPurger{}
+
+	service, ok := NewMaintenanceService(nil, lr, vtr, accounts, time.Hour).(*maintenanceService)
+	if !ok {
+		t.Fatalf("expected *maintenanceService type")
+	}
+	if service.lr != lr || service.vtr != vtr || service.accounts != accounts || service.tokenRetention != time.Hour {
+		t.Fatalf("expected the dependencies to be set")
+	}
+}
+
+// TestPurgeVerificationTokens tests that tokens older than the retention window are purged
+func TestPurgeVerificationTokens(t *testing.T) {
+	var cutoff time.Time
+	service := &maintenanceService{
+		lr: &MockAdvisoryLockRepository{
+			TryXactLockFunc: func(ctx context.Context, name string) (bool, error) {
+				if name != verificationTokenPurgeLock {
+					t.Fatalf("unexpected lock %q", name)
+				}
+				return true, nil
+			},
+		},
+		vtr: &MockVerificationTokenRepository{
+			PurgeStaleFunc: func(ctx context.Context, before time.Time) (int64, error) {
+				cutoff = before
+				return 3, nil
+			},
+		},
+		tokenRetention: DefaultTokenRetention,
+	}
+	before := metricValue("verification_tokens_purged")
+
+	purged, err := service.purgeVerificationTokens(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if purged != 3 {
+		t.Fatalf("expected 3 purged tokens, got %d", purged)
+	}
+	if age := time.Since(cutoff); age < DefaultTokenRetention || age > DefaultTokenRetention+time.Minute {
+		t.Fatalf("expected the cutoff to be one retention window ago, got %s", age)
+	}
+	if got := metricValue("verification_tokens_purged") - before; got != 3 {
+		t.Fatalf("expected the metric to grow by 3, got %d", got)
+	}
+}
+
+// TestPurgeVerificationTokens_Locked tests that nothing is purged while another replica holds the lock
+func TestPurgeVerificationTokens_Locked(t *testing.T) {
+	service := &maintenanceService{
+		lr: &MockAdvisoryLockRepository{
+			TryXactLockFunc: func(ctx context.Context, name string) (bool, error) {
+				return false, nil
+			},
+		},
+		vtr: &MockVerificationTokenRepository{
+			PurgeStaleFunc: func(ctx context.Context, cutoff time.Time) (int64, error) {
+				t.Fatalf("should not purge without the lock")
+				return 0, nil
+			},
+		},
+	}
+	before := metricValue("verification_tokens_purge_skipped")
+
+	purged, err := service.purgeVerificationTokens(context.Background())
+	if err != nil || purged != 0 {
+		t.Fatalf("expected a skipped run, got %d %v", purged, err)
+	}
+	if metricValue("verification_tokens_purge_skipped") != before+1 {
+		t.Fatalf("expected the skip to be counted")
+	}
+}
+
+// TestPurgeVerificationTokens_LockError tests that lock failures are returned
+func TestPurgeVerificationTokens_LockError(t *testing.T) {
+	service := &maintenanceService{
+		lr: &MockAdvisoryLockRepository{
+			TryXactLockFunc: func(ctx context.Context, name string) (bool, error) {
+				return false, errors.New("connection reset")
+			},
+		},
+		vtr: &MockVerificationTokenRepository{},
+	}
+
+	if _, err := service.purgeVerificationTokens(context.Background()); err == nil {
+		t.Fatalf("expected the lock error")
+	}
+}
+
+// TestPurgeDeletedAccounts tests that accounts are purged under their own lock
+func TestPurgeDeletedAccounts(t *testing.T) {
+	service := &maintenanceService{
+		lr: &MockAdvisoryLockRepository{
+			TryXactLockFunc: func(ctx context.Context, name string) (bool, error) {
+				if name != deletedAccountPurgeLock {
+					t.Fatalf("unexpected lock %q", name)
+				}
+				return true, nil
+			},
+		},
+		accounts: &MockAccountPurger{
+			PurgeDeletedUsersFunc: func(ctx context.Context) (int, error) {
+				return 2, nil
+			},
+		},
+	}
+	before := metricValue("deleted_accounts_purged")
+
+	purged, err := service.purgeDeletedAccounts(context.Background())
+	if err != nil || purged != 2 {
+		t.Fatalf("expected 2 purged accounts, got %d %v", purged, err)
+	}
+	if got := metricValue("deleted_accounts_purged") - before; got != 2 {
+		t.Fatalf("expected the metric to grow by 2, got %d", got)
+	}
+}
+
+// TestPurgeDeletedAccounts_Locked tests that no account is purged while another replica holds the lock
+func TestPurgeDeletedAccounts_Locked(t *testing.T) {
+	service := &maintenanceService{
+		lr: &MockAdvisoryLockRepository{
+			TryXactLockFunc: func(ctx context.Context, name string) (bool, error) {
+				return false, nil
+			},
+		},
+		accounts: &MockAccountPurger{
+			PurgeDeletedUsersFunc: func(ctx context.Context) (int, error) {
+				t.Fatalf("should not purge without the lock")
+				return 0, nil
+			},
+		},
+	}
+	before := metricValue("deleted_accounts_purge_skipped")
+
+	purged, err := service.purgeDeletedAccounts(context.Background())
+	if err != nil || purged != 0 {
+		t.Fatalf("expected a skipped run, got %d %v", purged, err)
+	}
+	if metricValue("deleted_accounts_purge_skipped") != before+1 {
+		t.Fatalf("expected the skip to be counted")
+	}
+}
+
+// Note: PurgeVerificationTokens and PurgeDeletedAccounts hold the lock in
+// a transaction and are covered by integration tests.
@@ -4,30 +4,43 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
 
 	"booknest/internal/domain"
 	"booknest/internal/pkg/util"
 )
 
+var errShippingAddressRequired = errors.New("add a shipping address before checking out")
+
 type orderService struct {
-	db        *pgxpool.Pool
-	orderRepo domain.OrderRepository
-	cartRepo  domain.CartRepository
+	db          *pgxpool.Pool
+	orderRepo   domain.OrderRepository
+	cartRepo    domain.CartRepository
+	userRepo    domain.UserRepository
+	addressRepo domain.AddressRepository
+	notifier    domain.Notifier
 }
 
 func NewOrderService(
 	db *pgxpool.Pool,
 	orderRepo domain.OrderRepository,
 	cartRepo domain.CartRepository,
+	userRepo domain.UserRepository,
+	addressRepo domain.AddressRepository,
+	notifier domain.Notifier,
 ) domain.OrderService {
 	return &orderService{
-		db:        db,
-		orderRepo: orderRepo,
-		cartRepo:  cartRepo,
+		db:          db,
+		orderRepo:   orderRepo,
+		cartRepo:    cartRepo,
+		userRepo:    userRepo,
+		addressRepo: addressRepo,
+		notifier:    notifier,
 	}
 }
 
@@ -38,7 +51,12 @@ func (s *orderService) Checkout(
 ) (domain.OrderView, error) {
 	var orderView domain.OrderView
 
-	err := util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+	shipping, billing, err := s.orderAddresses(ctx, userID, input)
+	if err != nil {
+		return orderView, err
+	}
+
+	err = util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
 		cart, err := s.cartRepo.GetOrCreateCart(txCtx, userID)
 		if err != nil {
 			return err
@@ -78,6 +96,10 @@ func (s *orderService) Checkout(
 			PaymentMethod: &input.PaymentMethod,
 			PaymentStatus: ptrPaymentStatus(domain.PaymentPending),
 			Status:        domain.OrderPending,
+
+			// Copies, so editing the address book leaves the order alone
+			ShippingAddress: shipping.Snapshot(),
+			BillingAddress:  billing.Snapshot(),
 		}
 
 		if err := s.orderRepo.CreateOrder(txCtx, order); err != nil {
@@ -105,8 +127,12 @@ func (s *orderService) Checkout(
 		_ = cart
 		return nil
 	})
+	if err != nil {
+		return orderView, err
+	}
 
-	return orderView, err
+	go s.notifyOrder(userID, domain.TemplateOrderPlaced, orderView.Order)
+	return orderView, nil
 }
 
 func (s *orderService) ConfirmPayment(
@@ -190,8 +216,87 @@ func (s *orderService) ConfirmPayment(
 		}
 		return nil
 	})
+	if err != nil {
+		return orderView, err
+	}
+
+	template := domain.TemplateOrderCancelled
+	if input.Success {
+		template = domain.TemplateOrderPaid
+	}
+	go s.notifyOrder(userID, template, orderView.Order)
+
+	return orderView, nil
+}
+
+// orderAddresses resolves the addresses picked at checkout, falling back
+// to the defaults. Without a billing address the order is billed to the
+// shipping address.
+func (s *orderService) orderAddresses(
+	ctx context.Context,
+	userID uuid.UUID,
+	input domain.CheckoutInput,
+) (domain.Address, domain.Address, error) {
+	shipping, err := s.pickAddress(ctx, userID, input.ShippingAddressID, domain.AddressShipping)
+	if errors.Is(err, domain.ErrAddressNotFound) && input.ShippingAddressID == nil {
+		return domain.Address{}, domain.Address{}, errShippingAddressRequired
+	}
+	if err != nil {
+		return domain.Address{}, domain.Address{}, err
+	}
 
-	return orderView, err
+	billing, err := s.pickAddress(ctx, userID, input.BillingAddressID, domain.AddressBilling)
+	if errors.Is(err, domain.ErrAddressNotFound) && input.BillingAddressID == nil {
+		return shipping, shipping, nil
+	}
+	if err != nil {
+		return domain.Address{}, domain.Address{}, err
+	}
+
+	return shipping, billing, nil
+}
+
+// pickAddress loads the chosen address of the user, or the default of kind
+func (s *orderService) pickAddress(
+	ctx context.Context,
+	userID uuid.UUID,
+	id *uuid.UUID,
+	kind domain.AddressKind,
+) (domain.Address, error) {
+	var address domain.Address
+	var err error
+	if id != nil {
+		address, err = s.addressRepo.FindByID(ctx, userID, *id)
+	} else {
+		address, err = s.addressRepo.FindDefault(ctx, userID, kind)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.Address{}, domain.ErrAddressNotFound
+	}
+	return address, err
+}
+
+// notifyOrder emails the customer about the order. It runs after the
+// request, and the notifier logs failed deliveries.
+func (s *orderService) notifyOrder(
+	userID uuid.UUID,
+	template domain.NotificationTemplate,
+	order domain.Order,
+) {
+	ctx := context.Background()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		slog.Error("Cannot load the customer for an order notification", "order_id", order.ID, "error", err)
+		return
+	}
+
+	_ = s.notifier.Email(ctx, user.Email, template, map[string]any{
+		"Name":        user.FirstName,
+		"OrderNumber": order.OrderNumber,
+		"Total":       order.TotalPrice,
+	})
 }
 
 func validateOrderForPaymentConfirmation(order domain.Order) error {
@@ -209,18 +314,16 @@ func validateOrderForPaymentConfirmation(order domain.Order) error {
 func (s *orderService) ListUserOrders(
 	ctx context.Context,
 	userID uuid.UUID,
-	limit,
-	offset int,
-) ([]domain.OrderView, error) {
-	return s.orderRepo.ListOrdersByUser(ctx, userID, limit, offset)
+	q domain.QueryOptions,
+) (domain.Page[domain.OrderView], error) {
+	return s.orderRepo.ListOrdersByUser(ctx, userID, q)
 }
 
 func (s *orderService) ListAllOrders(
 	ctx context.Context,
-	limit,
-	offset int,
-) ([]domain.OrderView, error) {
-	return s.orderRepo.ListOrders(ctx, limit, offset)
+	q domain.QueryOptions,
+) (domain.Page[domain.OrderView], error) {
+	return s.orderRepo.ListOrders(ctx, q)
 }
 
 func ptrPaymentStatus(status domain.PaymentStatus) *domain.PaymentStatus {
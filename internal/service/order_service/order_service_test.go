@@ -6,13 +6,14 @@ import (
 	"testing"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 
 	"booknest/internal/domain"
 )
 
 type mockOrderRepository struct {
-	listOrdersByUserFunc func(ctx context.Context, userID uuid.UUID, limit, offset int) ([]domain.OrderView, error)
-	listOrdersFunc       func(ctx context.Context, limit, offset int) ([]domain.OrderView, error)
+	listOrdersByUserFunc func(ctx context.Context, userID uuid.UUID, q domain.QueryOptions) (domain.Page[domain.OrderView], error)
+	listOrdersFunc       func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.OrderView], error)
 }
 
 func (m *mockOrderRepository) CreateOrder(ctx context.Context, order *domain.Order) error { return nil }
@@ -34,17 +35,20 @@ func (m *mockOrderRepository) UpdateOrderStatus(ctx context.Context, orderID uui
 func (m *mockOrderRepository) DecrementStock(ctx context.Context, items []domain.OrderItem) error {
 	return nil
 }
-func (m *mockOrderRepository) ListOrdersByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]domain.OrderView, error) {
+func (m *mockOrderRepository) AnonymizeAddresses(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+func (m *mockOrderRepository) ListOrdersByUser(ctx context.Context, userID uuid.UUID, q domain.QueryOptions) (domain.Page[domain.OrderView], error) {
 	if m.listOrdersByUserFunc != nil {
-		return m.listOrdersByUserFunc(ctx, userID, limit, offset)
+		return m.listOrdersByUserFunc(ctx, userID, q)
 	}
-	return []domain.OrderView{}, nil
+	return domain.Page[domain.OrderView]{Items: []domain.OrderView{}}, nil
 }
-func (m *mockOrderRepository) ListOrders(ctx context.Context, limit, offset int) ([]domain.OrderView, error) {
+func (m *mockOrderRepository) ListOrders(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.OrderView], error) {
 	if m.listOrdersFunc != nil {
-		return m.listOrdersFunc(ctx, limit, offset)
+		return m.listOrdersFunc(ctx, q)
 	}
-	return []domain.OrderView{}, nil
+	return domain.Page[domain.OrderView]{Items: []domain.OrderView{}}, nil
 }
 
 type noopCartRepository struct{}
@@ -65,6 +69,9 @@ func (n *noopCartRepository) RemoveCartItem(ctx context.Context, cartID uuid.UUI
 	return nil
 }
 func (n *noopCartRepository) ClearCart(ctx context.Context, cartID uuid.UUID) error { return nil }
+func (n *noopCartRepository) ClearUserCart(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
 
 func TestPtrPaymentStatus(t *testing.T) {
 	status := ptrPaymentStatus(domain.PaymentPaid)
@@ -77,29 +84,29 @@ func TestOrderListPassThrough(t *testing.T) {
 	userID := uuid.New()
 	orders := []domain.OrderView{{Order: domain.Order{ID: uuid.New()}}}
 	repo := &mockOrderRepository{
-		listOrdersByUserFunc: func(ctx context.Context, gotUserID uuid.UUID, limit, offset int) ([]domain.OrderView, error) {
-			if gotUserID != userID || limit != 10 || offset != 5 {
+		listOrdersByUserFunc: func(ctx context.Context, gotUserID uuid.UUID, q domain.QueryOptions) (domain.Page[domain.OrderView], error) {
+			if gotUserID != userID || q.Limit != 10 || q.Offset != 5 {
 				t.Fatalf("unexpected user list params")
 			}
-			return orders, nil
+			return domain.Page[domain.OrderView]{Items: orders}, nil
 		},
-		listOrdersFunc: func(ctx context.Context, limit, offset int) ([]domain.OrderView, error) {
-			if limit != 20 || offset != 10 {
+		listOrdersFunc: func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.OrderView], error) {
+			if q.Limit != 20 || q.Offset != 10 {
 				t.Fatalf("unexpected list params")
 			}
-			return orders, nil
+			return domain.Page[domain.OrderView]{Items: orders}, nil
 		},
 	}
 
-	svc := NewOrderService(nil, repo, &noopCartRepository{})
+	svc := NewOrderService(nil, repo, &noopCartRepository{}, nil, nil, nil)
 
-	userOrders, err := svc.ListUserOrders(context.Background(), userID, 10, 5)
-	if err != nil || len(userOrders) != 1 {
+	userOrders, err := svc.ListUserOrders(context.Background(), userID, domain.QueryOptions{Limit: 10, Offset: 5})
+	if err != nil || len(userOrders.Items) != 1 {
 		t.Fatalf("unexpected ListUserOrders result: %+v, err=%v", userOrders, err)
 	}
 
-	allOrders, err := svc.ListAllOrders(context.Background(), 20, 10)
-	if err != nil || len(allOrders) != 1 {
+	allOrders, err := svc.ListAllOrders(context.Background(), domain.QueryOptions{Limit: 20, Offset: 10})
+	if err != nil || len(allOrders.Items) != 1 {
 		t.Fatalf("unexpected ListAllOrders result: %+v, err=%v", allOrders, err)
 	}
 }
@@ -167,3 +174,139 @@ func TestValidateOrderForPaymentConfirmation(t *testing.T) {
 		})
 	}
 }
+
+// stubUserRepository only implements the lookup used for notifications
+type stubUserRepository struct {
+	domain.UserRepository
+	user domain.User
+}
+
+func (m *stubUserRepository) FindByID(ctx context.Context, id uuid.UUID) (domain.User, error) {
+	return m.user, nil
+}
+
+type recordingNotifier struct {
+	to       string
+	template domain.NotificationTemplate
+	data     any
+}
+
+func (m *recordingNotifier) Email(ctx context.Context, to string, template domain.NotificationTemplate, data any) error {
+	m.to, m.template, m.data = to, template, data
+	return nil
+}
+
+func (m *recordingNotifier) SMS(ctx context.Context, to string, template domain.NotificationTemplate, data any) error {
+	return errors.New("not implemented")
+}
+
+func TestNotifyOrder_EmailsCustomer(t *testing.T) {
+	notifier := &recordingNotifier{}
+	svc := &orderService{
+		userRepo: &stubUserRepository{user: domain.User{Email: "reader@example.com", FirstName: "Ada"}},
+		notifier: notifier,
+	}
+
+	svc.notifyOrder(uuid.New(), domain.TemplateOrderPaid, domain.Order{OrderNumber: "BN-1", TotalPrice: 42.5})
+
+	if notifier.to != "reader@example.com" || notifier.template != domain.TemplateOrderPaid {
+		t.Fatalf("unexpected notification %s to %s", notifier.template, notifier.to)
+	}
+	data := notifier.data.(map[string]any)
+	if data["OrderNumber"] != "BN-1" || data["Total"] != 42.5 {
+		t.Fatalf("unexpected template data %v", data)
+	}
+}
+
+// Note: Checkout and ConfirmPayment send their notifications after a
+// database transaction and should be tested through integration tests.
+
+// stubAddressRepository only implements the lookups used at checkout
+type stubAddressRepository struct {
+	domain.AddressRepository
+	addresses map[uuid.UUID]domain.Address
+	defaults  map[domain.AddressKind]domain.Address
+}
+
+func (m *stubAddressRepository) FindByID(ctx context.Context, userID, id uuid.UUID) (domain.Address, error) {
+	address, ok := m.addresses[id]
+	if !ok || address.UserID != userID {
+		return domain.Address{}, gorm.ErrRecordNotFound
+	}
+	return address, nil
+}
+
+func (m *stubAddressRepository) FindDefault(ctx context.Context, userID uuid.UUID, kind domain.AddressKind) (domain.Address, error) {
+	address, ok := m.defaults[kind]
+	if !ok {
+		return domain.Address{}, gorm.ErrRecordNotFound
+	}
+	return address, nil
+}
+
+func TestOrderAddresses(t *testing.T) {
+	userID := uuid.New()
+	home := domain.Address{ID: uuid.New(), UserID: userID, City: "Berlin"}
+	office := domain.Address{ID: uuid.New(), UserID: userID, City: "Hamburg"}
+	stranger := domain.Address{ID: uuid.New(), UserID: uuid.New(), City: "Munich"}
+	addresses := map[uuid.UUID]domain.Address{home.ID: home, office.ID: office, stranger.ID: stranger}
+
+	tests := []struct {
+		name         string
+		defaults     map[domain.AddressKind]domain.Address
+		input        domain.CheckoutInput
+		wantShipping string
+		wantBilling  string
+		wantErr      error
+	}{
+		{
+			name:         "defaults",
+			defaults:     map[domain.AddressKind]domain.Address{domain.AddressShipping: home, domain.AddressBilling: office},
+			wantShipping: "Berlin",
+			wantBilling:  "Hamburg",
+		},
+		{
+			name:         "billed to shipping without billing default",
+			defaults:     map[domain.AddressKind]domain.Address{domain.AddressShipping: home},
+			wantShipping: "Berlin",
+			wantBilling:  "Berlin",
+		},
+		{
+			name:         "chosen addresses win",
+			defaults:     map[domain.AddressKind]domain.Address{domain.AddressShipping: home, domain.AddressBilling: home},
+			input:        domain.CheckoutInput{ShippingAddressID: &office.ID, BillingAddressID: &office.ID},
+			wantShipping: "Hamburg",
+			wantBilling:  "Hamburg",
+		},
+		{
+			name:    "no shipping address",
+			wantErr: errShippingAddressRequired,
+		},
+		{
+			name:     "address of another user",
+			defaults: map[domain.AddressKind]domain.Address{domain.AddressShipping: home},
+			input:    domain.CheckoutInput{BillingAddressID: &stranger.ID},
+			wantErr:  domain.ErrAddressNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &orderService{addressRepo: &stubAddressRepository{addresses: addresses, defaults: tc.defaults}}
+
+			shipping, billing, err := svc.orderAddresses(context.Background(), userID, tc.input)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if shipping.City != tc.wantShipping || billing.City != tc.wantBilling {
+				t.Fatalf("unexpected addresses %s / %s", shipping.City, billing.City)
+			}
+		})
+	}
+}
@@ -12,7 +12,7 @@ import (
 // MockPublisherRepository is a mock implementation of domain.PublisherRepository
 type MockPublisherRepository struct {
 	FindByIDFunc  func(ctx context.Context, id uuid.UUID) (domain.Publisher, error)
-	ListFunc      func(ctx context.Context, limit, offset int) ([]domain.Publisher, error)
+	ListFunc      func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Publisher], error)
 	CreateFunc    func(ctx context.Context, publisher *domain.Publisher) error
 	UpdateFunc    func(ctx context.Context, publisher *domain.Publisher) error
 	SetActiveFunc func(ctx context.Context, id uuid.UUID, active bool) error
@@ -33,11 +33,11 @@ func (m *MockPublisherRepository) Create(ctx context.Context, user *domain.Publi
 	return nil
 }
 
-func (m *MockPublisherRepository) List(ctx context.Context, limit, offset int) ([]domain.Publisher, error) {
+func (m *MockPublisherRepository) List(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.Publisher], error) {
 	if m.ListFunc != nil {
-		return m.ListFunc(ctx, limit, offset)
+		return m.ListFunc(ctx, q)
 	}
-	return []domain.Publisher{}, nil
+	return domain.Page[domain.Publisher]{Items: []domain.Publisher{}}, nil
 }
 
 func (m *MockPublisherRepository) Update(ctx context.Context, user *domain.Publisher) error {
@@ -36,9 +36,9 @@ func (s *publisherService) FindByID(
 
 func (s *publisherService) List(
 	ctx context.Context,
-	limit, offset int,
-) ([]domain.Publisher, error) {
-	return s.r.List(ctx, limit, offset)
+	q domain.QueryOptions,
+) (domain.Page[domain.Publisher], error) {
+	return s.r.List(ctx, q)
 }
 
 func (s *publisherService) Create(
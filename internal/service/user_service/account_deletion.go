@@ -0,0 +1,230 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/util"
+)
+
+const (
+	// Users can change their mind until the grace period ends
+	deletionGracePeriod = 30 * 24 * time.Hour
+
+	purgeBatchSize = 100
+	exportPageSize = 100
+)
+
+// withTransaction is swapped in tests
+var withTransaction = util.WithTransaction
+
+var errDeletionNotScheduled = errors.New("no deletion is scheduled")
+
+// ScheduleDeletion logs the user out everywhere and anonymises the account
+// once the grace period ended. Scheduling twice keeps the first date.
+func (s *userService) ScheduleDeletion(
+	ctx context.Context,
+	actorID uuid.UUID,
+	userID uuid.UUID,
+) (time.Time, error) {
+	user, err := s.r.FindByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if user.DeletionScheduledAt != nil {
+		return *user.DeletionScheduledAt, nil
+	}
+
+	at := time.Now().Add(deletionGracePeriod)
+
+	err = withTransaction(ctx, s.db, func(txCtx context.Context) error {
+		// 1. Schedule the deletion
+		if err := s.r.SetDeletionSchedule(txCtx, user.ID, &at); err != nil {
+			return err
+		}
+
+		// 2. End the sessions; logging in again allows cancelling
+		if err := s.endAllSessions(txCtx, user.ID); err != nil {
+			return err
+		}
+
+		// 3. Record who asked for it
+		return s.ar.Create(txCtx, &domain.AuditLog{
+			ActorID:      actorID,
+			Action:       domain.AuditDeletionScheduled,
+			TargetUserID: &user.ID,
+			Details:      map[string]string{"scheduled_at": at.UTC().Format(time.RFC3339)},
+		})
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return at, nil
+}
+
+func (s *userService) CancelDeletion(
+	ctx context.Context,
+	userID uuid.UUID,
+) error {
+	user, err := s.r.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.DeletionScheduledAt == nil {
+		return errDeletionNotScheduled
+	}
+
+	return withTransaction(ctx, s.db, func(txCtx context.Context) error {
+		if err := s.r.SetDeletionSchedule(txCtx, user.ID, nil); err != nil {
+			return err
+		}
+
+		return s.ar.Create(txCtx, &domain.AuditLog{
+			ActorID:      user.ID,
+			Action:       domain.AuditDeletionCancelled,
+			TargetUserID: &user.ID,
+		})
+	})
+}
+
+// ExportData collects the profile, every order, the cart and the address
+// book of the user
+func (s *userService) ExportData(
+	ctx context.Context,
+	userID uuid.UUID,
+) (domain.UserExport, error) {
+	user, err := s.r.FindByID(ctx, userID)
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+
+	orders := make([]domain.OrderView, 0)
+	q := domain.QueryOptions{Limit: exportPageSize}
+	for {
+		page, err := s.or.ListOrdersByUser(ctx, user.ID, q)
+		if err != nil {
+			return domain.UserExport{}, err
+		}
+		orders = append(orders, page.Items...)
+
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+
+	cart, err := s.cr.GetCartItems(ctx, user.ID)
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+	if cart == nil {
+		cart = make([]domain.CartItemDetail, 0)
+	}
+
+	addresses, err := s.adr.ListByUser(ctx, user.ID)
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+	if addresses == nil {
+		addresses = make([]domain.Address, 0)
+	}
+
+	return domain.UserExport{
+		ExportedAt: time.Now().UTC(),
+		Profile:    user,
+		Orders:     orders,
+		Cart:       cart,
+		Addresses:  addresses,
+	}, nil
+}
+
+// PurgeDeletedUsers anonymises the accounts whose grace period ended.
+// A failing account is logged and retried on the next run.
+func (s *userService) PurgeDeletedUsers(ctx context.Context) (int, error) {
+	purged := 0
+
+	for {
+		users, err := s.r.FindDueForDeletion(ctx, time.Now(), purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		batchPurged := 0
+		for _, user := range users {
+			if err := s.purgeUser(ctx, user.ID); err != nil {
+				slog.Error("Cannot delete account", "user_id", user.ID, "error", err)
+				continue
+			}
+			batchPurged++
+		}
+		purged += batchPurged
+
+		// Stop on the last page, or when every account in it failed
+		if len(users) < purgeBatchSize || batchPurged == 0 {
+			return purged, nil
+		}
+	}
+}
+
+func (s *userService) purgeUser(ctx context.Context, userID uuid.UUID) error {
+	return withTransaction(ctx, s.db, func(txCtx context.Context) error {
+		// 1. Replace the personal data, orders keep pointing at the row
+		if err := s.r.Delete(txCtx, userID); err != nil {
+			return err
+		}
+
+		// 2. Drop credentials, old passwords, provider links and pending codes
+		if err := s.endAllSessions(txCtx, userID); err != nil {
+			return err
+		}
+		if err := s.mfar.Delete(txCtx, userID); err != nil {
+			return err
+		}
+		if err := s.idr.DeleteByUser(txCtx, userID); err != nil {
+			return err
+		}
+		if err := s.phr.DeleteByUser(txCtx, userID); err != nil {
+			return err
+		}
+		for _, tokenType := range []domain.VerificationTokenType{
+			domain.VerificationEmail,
+			domain.VerificationMobile,
+			domain.PasswordReset,
+			domain.LoginOTP,
+			domain.LoginMagicLink,
+		} {
+			if err := s.vtr.InvalidateByUserAndType(txCtx, userID, tokenType); err != nil {
+				return err
+			}
+		}
+
+		// 3. Empty the cart and the address book, and strip the names,
+		// street lines and phone numbers from the addresses copied onto
+		// placed orders. The city, region, postal code and country stay
+		// for the invoices, which need them to account for tax.
+		if err := s.cr.ClearUserCart(txCtx, userID); err != nil {
+			return err
+		}
+		if err := s.adr.DeleteByUser(txCtx, userID); err != nil {
+			return err
+		}
+		if err := s.or.AnonymizeAddresses(txCtx, userID); err != nil {
+			return err
+		}
+
+		// 4. Keep a trace that the account existed and was deleted
+		return s.ar.Create(txCtx, &domain.AuditLog{
+			ActorID:      userID,
+			Action:       domain.AuditUserDeleted,
+			TargetUserID: &userID,
+		})
+	})
+}
@@ -0,0 +1,205 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/util"
+)
+
+// TestScheduleDeletion_AlreadyScheduled tests that asking twice keeps the first date
+func TestScheduleDeletion_AlreadyScheduled(t *testing.T) {
+	scheduled := time.Now().Add(24 * time.Hour)
+	service := &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id, DeletionScheduledAt: &scheduled}, nil
+			},
+			SetDeletionScheduleFunc: func(ctx context.Context, id uuid.UUID, at *time.Time) error {
+				t.Fatalf("should not reschedule")
+				return nil
+			},
+		},
+	}
+
+	userID := uuid.New()
+	at, err := service.ScheduleDeletion(context.Background(), userID, userID)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !at.Equal(scheduled) {
+		t.Fatalf("expected %v, got %v", scheduled, at)
+	}
+}
+
+// TestScheduleDeletion_NotFound tests that unknown or deleted users are rejected
+func TestScheduleDeletion_NotFound(t *testing.T) {
+	service := &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{}, errors.New("user not found")
+			},
+		},
+	}
+
+	_, err := service.ScheduleDeletion(context.Background(), uuid.New(), uuid.New())
+
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+// TestCancelDeletion_NotScheduled tests cancelling when nothing is pending
+func TestCancelDeletion_NotScheduled(t *testing.T) {
+	service := &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id}, nil
+			},
+		},
+	}
+
+	err := service.CancelDeletion(context.Background(), uuid.New())
+
+	if !errors.Is(err, errDeletionNotScheduled) {
+		t.Fatalf("expected not scheduled error, got %v", err)
+	}
+}
+
+// Note: scheduling and cancelling run in a transaction and are
+// covered by integration tests.
+
+// TestExportData_AllOrders tests that the export pages through every order
+func TestExportData_AllOrders(t *testing.T) {
+	userID := uuid.New()
+	var cursors []string
+	service := &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id, Email: "me@example.com"}, nil
+			},
+		},
+		or: &MockOrderRepository{
+			ListOrdersByUserFunc: func(ctx context.Context, id uuid.UUID, q domain.QueryOptions) (domain.Page[domain.OrderView], error) {
+				if id != userID {
+					t.Fatalf("expected orders of %s, got %s", userID, id)
+				}
+				cursors = append(cursors, q.Cursor)
+				if q.Cursor == "" {
+					page := domain.Page[domain.OrderView]{Items: make([]domain.OrderView, q.Limit)}
+					page.NextCursor = "next"
+					return page, nil
+				}
+				return domain.Page[domain.OrderView]{Items: make([]domain.OrderView, 3)}, nil
+			},
+		},
+		cr:  &MockCartRepository{},
+		adr: &MockAddressRepository{},
+	}
+
+	export, err := service.ExportData(context.Background(), userID)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(export.Orders) != exportPageSize+3 {
+		t.Fatalf("expected %d orders, got %d", exportPageSize+3, len(export.Orders))
+	}
+	if len(cursors) != 2 || cursors[1] != "next" {
+		t.Fatalf("unexpected pages %v", cursors)
+	}
+	if export.Cart == nil {
+		t.Fatalf("expected an empty cart rather than null")
+	}
+	if export.Addresses == nil {
+		t.Fatalf("expected an empty address book rather than null")
+	}
+	if export.Profile.Email != "me@example.com" {
+		t.Fatalf("expected the profile, got %+v", export.Profile)
+	}
+}
+
+// TestPurgeDeletedUsers_NothingDue tests a run without due accounts
+func TestPurgeDeletedUsers_NothingDue(t *testing.T) {
+	service := &userService{
+		r: &MockUserRepository{
+			FindDueForDeletionFunc: func(ctx context.Context, before time.Time, limit int) ([]domain.User, error) {
+				if limit != purgeBatchSize {
+					t.Fatalf("expected batches of %d, got %d", purgeBatchSize, limit)
+				}
+				return nil, nil
+			},
+		},
+	}
+
+	purged, err := service.PurgeDeletedUsers(context.Background())
+
+	if err != nil || purged != 0 {
+		t.Fatalf("expected nothing purged, got %d %v", purged, err)
+	}
+}
+
+// TestPurgeDeletedUsers_AnonymizesOrderAddresses tests that purging an
+// account scrubs the addresses copied onto its orders, in the same
+// transaction as the rest of the purge
+func TestPurgeDeletedUsers_AnonymizesOrderAddresses(t *testing.T) {
+	type txKey struct{}
+	withTransaction = func(ctx context.Context, _ *pgxpool.Pool, fn func(ctx context.Context) error) error {
+		return fn(context.WithValue(ctx, txKey{}, true))
+	}
+	t.Cleanup(func() { withTransaction = util.WithTransaction })
+
+	userID := uuid.New()
+	var anonymized []uuid.UUID
+	service := &userService{
+		r: &MockUserRepository{
+			FindDueForDeletionFunc: func(ctx context.Context, before time.Time, limit int) ([]domain.User, error) {
+				return []domain.User{{ID: userID}}, nil
+			},
+		},
+		or: &MockOrderRepository{
+			AnonymizeAddressesFunc: func(ctx context.Context, id uuid.UUID) error {
+				if ctx.Value(txKey{}) == nil {
+					t.Fatalf("expected the order addresses to be scrubbed in the purge transaction")
+				}
+				anonymized = append(anonymized, id)
+				return nil
+			},
+		},
+		vtr:  &MockVerificationTokenRepository{},
+		rtr:  &MockRefreshTokenRepository{},
+		sr:   &MockSessionRepository{},
+		idr:  &MockIdentityRepository{},
+		mfar: &MockMFARepository{},
+		phr:  &MockPasswordHistoryRepository{},
+		ar:   &MockAuditRepository{},
+		cr:   &MockCartRepository{},
+		adr:  &MockAddressRepository{},
+	}
+
+	purged, err := service.PurgeDeletedUsers(context.Background())
+
+	if err != nil || purged != 1 {
+		t.Fatalf("expected one account purged, got %d %v", purged, err)
+	}
+	if len(anonymized) != 1 || anonymized[0] != userID {
+		t.Fatalf("expected the orders of %s to be anonymized, got %v", userID, anonymized)
+	}
+
+	// A failing scrub keeps the account for the next run
+	service.or = &MockOrderRepository{
+		AnonymizeAddressesFunc: func(ctx context.Context, id uuid.UUID) error {
+			return errors.New("db down")
+		},
+	}
+	if purged, err := service.PurgeDeletedUsers(context.Background()); err != nil || purged != 0 {
+		t.Fatalf("expected nothing purged, got %d %v", purged, err)
+	}
+}
@@ -0,0 +1,171 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/util"
+)
+
+const (
+	// apiKeyPrefix makes keys easy to recognise, for people and secret scanners
+	apiKeyPrefix = "bnk_"
+	// apiKeyShownLength is how much of the key is kept to tell keys apart
+	apiKeyShownLength = len(apiKeyPrefix) + 8
+)
+
+var (
+	errInvalidScope    = errors.New("invalid api key scope")
+	errExpiryInThePast = errors.New("expires_at must be in the future")
+	errDuplicateScope  = errors.New("api key scopes must be unique")
+)
+
+// CreateAPIKey returns the raw key, which cannot be recovered later
+func (s *userService) CreateAPIKey(
+	ctx context.Context,
+	adminID uuid.UUID,
+	in domain.APIKeyInput,
+) (domain.CreatedAPIKey, error) {
+	seen := make(map[domain.APIKeyScope]bool, len(in.Scopes))
+	for _, scope := range in.Scopes {
+		if !scope.IsValid() {
+			return domain.CreatedAPIKey{}, errInvalidScope
+		}
+		if seen[scope] {
+			return domain.CreatedAPIKey{}, errDuplicateScope
+		}
+		seen[scope] = true
+	}
+	if in.ExpiresAt != nil && !in.ExpiresAt.After(time.Now()) {
+		return domain.CreatedAPIKey{}, errExpiryInThePast
+	}
+
+	rawToken, err := s.generateRawToken()
+	if err != nil {
+		return domain.CreatedAPIKey{}, err
+	}
+	rawKey := apiKeyPrefix + rawToken
+
+	key := domain.APIKey{
+		Name:      in.Name,
+		Prefix:    rawKey[:apiKeyShownLength],
+		KeyHash:   s.generateTokenHash(rawKey),
+		Scopes:    in.Scopes,
+		CreatedBy: adminID,
+		ExpiresAt: in.ExpiresAt,
+	}
+
+	err = util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		if err := s.akr.Create(txCtx, &key); err != nil {
+			return err
+		}
+
+		scopes := make([]string, 0, len(key.Scopes))
+		for _, scope := range key.Scopes {
+			scopes = append(scopes, string(scope))
+		}
+
+		return s.ar.Create(txCtx, &domain.AuditLog{
+			ActorID: adminID,
+			Action:  domain.AuditAPIKeyCreated,
+			Details: map[string]string{
+				"api_key_id": key.ID.String(),
+				"name":       key.Name,
+				"scopes":     strings.Join(scopes, ","),
+			},
+		})
+	})
+	if err != nil {
+		return domain.CreatedAPIKey{}, err
+	}
+
+	return domain.CreatedAPIKey{APIKey: key, Key: rawKey}, nil
+}
+
+func (s *userService) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	keys, err := s.akr.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if keys == nil {
+		keys = make([]domain.APIKey, 0)
+	}
+
+	return keys, nil
+}
+
+func (s *userService) RevokeAPIKey(
+	ctx context.Context,
+	adminID uuid.UUID,
+	id uuid.UUID,
+) error {
+	return util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		revoked, err := s.akr.Revoke(txCtx, id)
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return domain.ErrAPIKeyNotFound
+		}
+
+		return s.ar.Create(txCtx, &domain.AuditLog{
+			ActorID: adminID,
+			Action:  domain.AuditAPIKeyRevoked,
+			Details: map[string]string{"api_key_id": id.String()},
+		})
+	})
+}
+
+// AuthenticateAPIKey resolves a raw key to the admin it acts for. The
+// role is read from the account on every request, so demoting or
+// suspending the admin also limits their keys.
+func (s *userService) AuthenticateAPIKey(
+	ctx context.Context,
+	rawKey string,
+) (domain.APIKeyPrincipal, error) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return domain.APIKeyPrincipal{}, domain.ErrInvalidAPIKey
+	}
+
+	key, err := s.akr.FindByHash(ctx, s.generateTokenHash(rawKey))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.APIKeyPrincipal{}, domain.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return domain.APIKeyPrincipal{}, err
+	}
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt)) {
+		return domain.APIKeyPrincipal{}, domain.ErrInvalidAPIKey
+	}
+
+	owner, err := s.r.FindByID(ctx, key.CreatedBy)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.APIKeyPrincipal{}, domain.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return domain.APIKeyPrincipal{}, err
+	}
+	if !owner.IsActive || owner.DeletionScheduledAt != nil {
+		return domain.APIKeyPrincipal{}, domain.ErrInvalidAPIKey
+	}
+
+	// Last use is informational; a failed write must not block the request
+	if err := s.akr.Touch(ctx, key.ID); err != nil {
+		slog.Warn("Cannot record api key use", "api_key_id", key.ID, "error", err)
+	}
+
+	return domain.APIKeyPrincipal{
+		KeyID:  key.ID,
+		UserID: owner.ID,
+		Role:   owner.Role,
+		Scopes: key.Scopes,
+	}, nil
+}
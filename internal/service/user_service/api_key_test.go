@@ -0,0 +1,143 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+)
+
+// TestCreateAPIKey_Validation tests that bad scopes and expiries are rejected before storing
+func TestCreateAPIKey_Validation(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	service := &userService{
+		akr: &MockAPIKeyRepository{
+			CreateFunc: func(ctx context.Context, key *domain.APIKey) error {
+				t.Fatalf("should not store the key")
+				return nil
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		in   domain.APIKeyInput
+		want error
+	}{
+		{"unknown scope", domain.APIKeyInput{Name: "ci", Scopes: []domain.APIKeyScope{"books:delete"}}, errInvalidScope},
+		{"duplicate scope", domain.APIKeyInput{Name: "ci", Scopes: []domain.APIKeyScope{domain.ScopeOrdersRead, domain.ScopeOrdersRead}}, errDuplicateScope},
+		{"expired", domain.APIKeyInput{Name: "ci", Scopes: []domain.APIKeyScope{domain.ScopeOrdersRead}, ExpiresAt: &past}, errExpiryInThePast},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateAPIKey(context.Background(), uuid.New(), tt.in)
+
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// Note: creating and revoking keys run in a transaction and are covered
+// by integration tests.
+
+// TestAuthenticateAPIKey tests which keys may authenticate a request
+func TestAuthenticateAPIKey(t *testing.T) {
+	adminID := uuid.New()
+	past := time.Now().Add(-time.Minute)
+	scheduled := time.Now().Add(time.Hour)
+	rawKey := apiKeyPrefix + "secret"
+
+	tests := []struct {
+		name  string
+		key   domain.APIKey
+		owner domain.User
+		want  error
+	}{
+		{"valid", domain.APIKey{}, domain.User{IsActive: true, Role: domain.UserRoleAdmin}, nil},
+		{"revoked", domain.APIKey{RevokedAt: &past}, domain.User{IsActive: true}, domain.ErrInvalidAPIKey},
+		{"expired", domain.APIKey{ExpiresAt: &past}, domain.User{IsActive: true}, domain.ErrInvalidAPIKey},
+		{"suspended admin", domain.APIKey{}, domain.User{IsActive: false}, domain.ErrInvalidAPIKey},
+		{"admin leaving", domain.APIKey{}, domain.User{IsActive: true, DeletionScheduledAt: &scheduled}, domain.ErrInvalidAPIKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			touched := false
+			service := &userService{
+				akr: &MockAPIKeyRepository{
+					FindByHashFunc: func(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+						if keyHash != (userService{}).generateTokenHash(rawKey) {
+							t.Fatalf("expected lookup by the hash of the key")
+						}
+						key := tt.key
+						key.ID = uuid.New()
+						key.CreatedBy = adminID
+						key.Scopes = []domain.APIKeyScope{domain.ScopeBooksWrite}
+						return &key, nil
+					},
+					TouchFunc: func(ctx context.Context, id uuid.UUID) error {
+						touched = true
+						return nil
+					},
+				},
+				r: &MockUserRepository{
+					FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+						owner := tt.owner
+						owner.ID = id
+						return owner, nil
+					},
+				},
+			}
+
+			principal, err := service.AuthenticateAPIKey(context.Background(), rawKey)
+
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			if tt.want != nil {
+				if touched {
+					t.Fatalf("rejected keys should not be marked as used")
+				}
+				return
+			}
+			if principal.UserID != adminID || principal.Role != domain.UserRoleAdmin {
+				t.Fatalf("expected to act as the admin, got %+v", principal)
+			}
+			if !principal.HasScope(domain.ScopeBooksWrite) || principal.HasScope(domain.ScopeOrdersRead) {
+				t.Fatalf("unexpected scopes %v", principal.Scopes)
+			}
+			if !touched {
+				t.Fatalf("expected the use to be recorded")
+			}
+		})
+	}
+}
+
+// TestAuthenticateAPIKey_Unknown tests keys without the prefix or without a record
+func TestAuthenticateAPIKey_Unknown(t *testing.T) {
+	service := &userService{akr: &MockAPIKeyRepository{}}
+
+	for _, rawKey := range []string{"not-a-key", apiKeyPrefix + "unknown"} {
+		if _, err := service.AuthenticateAPIKey(context.Background(), rawKey); !errors.Is(err, domain.ErrInvalidAPIKey) {
+			t.Fatalf("expected invalid key error for %q, got %v", rawKey, err)
+		}
+	}
+}
+
+// TestListAPIKeys_Empty tests that no keys is an empty list rather than null
+func TestListAPIKeys_Empty(t *testing.T) {
+	service := &userService{akr: &MockAPIKeyRepository{}}
+
+	keys, err := service.ListAPIKeys(context.Background())
+
+	if err != nil || keys == nil {
+		t.Fatalf("expected an empty list, got %v %v", keys, err)
+	}
+}
@@ -0,0 +1,90 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+)
+
+// TestImpersonate_Success tests that the token acts as the user and names the admin
+func TestImpersonate_Success(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test_secret_key")
+	adminID := uuid.New()
+	userID := uuid.New()
+	var audited *domain.AuditLog
+	service := &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id, Email: "reader@example.com", Role: domain.UserRoleUser}, nil
+			},
+		},
+		ar: &MockAuditRepository{
+			CreateFunc: func(ctx context.Context, log *domain.AuditLog) error {
+				audited = log
+				return nil
+			},
+		},
+	}
+
+	token, err := service.Impersonate(context.Background(), adminID, userID, "ticket 4711")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if token.UserID != userID || token.ImpersonatorID != adminID || token.ExpiresIn != int64(impersonationTTL.Seconds()) {
+		t.Fatalf("unexpected token %+v", token)
+	}
+
+	parsed, err := jwt.Parse(token.AccessToken, func(token *jwt.Token) (interface{}, error) {
+		return []byte("test_secret_key"), nil
+	})
+	if err != nil || !parsed.Valid {
+		t.Fatalf("expected a valid JWT, got %v", err)
+	}
+
+	claims := parsed.Claims.(jwt.MapClaims)
+	if claims["user_id"] != userID.String() || claims["token_use"] != domain.TokenUseImpersonation {
+		t.Fatalf("unexpected claims %v", claims)
+	}
+	if act, _ := claims["act"].(map[string]interface{}); act["sub"] != adminID.String() {
+		t.Fatalf("expected the admin in the act claim, got %v", claims["act"])
+	}
+	if _, ok := claims["sid"]; ok {
+		t.Fatalf("expected no session")
+	}
+
+	if audited == nil || audited.Action != domain.AuditImpersonationStart || audited.ActorID != adminID || audited.Details["reason"] != "ticket 4711" {
+		t.Fatalf("expected the impersonation to be audited, got %+v", audited)
+	}
+}
+
+// TestImpersonate_Refused tests that admins cannot impersonate themselves or other admins
+func TestImpersonate_Refused(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test_secret_key")
+	adminID := uuid.New()
+	service := &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id, Role: domain.UserRoleAdmin}, nil
+			},
+		},
+		ar: &MockAuditRepository{
+			CreateFunc: func(ctx context.Context, log *domain.AuditLog) error {
+				t.Fatalf("should not audit a refused impersonation")
+				return nil
+			},
+		},
+	}
+
+	if _, err := service.Impersonate(context.Background(), adminID, adminID, "test"); !errors.Is(err, errCannotImpersonateSelf) {
+		t.Fatalf("expected self impersonation error, got %v", err)
+	}
+	if _, err := service.Impersonate(context.Background(), adminID, uuid.New(), "test"); !errors.Is(err, errCannotImpersonateAdmin) {
+		t.Fatalf("expected admin impersonation error, got %v", err)
+	}
+}
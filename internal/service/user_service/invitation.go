@@ -0,0 +1,212 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/util"
+)
+
+const invitationTTL = 72 * time.Hour
+
+var (
+	errInvalidRole         = errors.New("invalid role")
+	errUserAlreadyExists   = errors.New("a user with this email already exists")
+	errCannotChangeOwnRole = errors.New("admins cannot change their own role")
+)
+
+func (s *userService) InviteUser(
+	ctx context.Context,
+	adminID uuid.UUID,
+	in domain.InvitationInput,
+) (domain.Invitation, error) {
+	if !in.Role.IsValid() {
+		return domain.Invitation{}, errInvalidRole
+	}
+
+	_, err := s.r.FindByEmail(ctx, in.Email)
+	if err == nil {
+		return domain.Invitation{}, errUserAlreadyExists
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.Invitation{}, err
+	}
+
+	rawToken, err := s.generateRawToken()
+	if err != nil {
+		return domain.Invitation{}, err
+	}
+
+	invitation := domain.Invitation{
+		Email:     in.Email,
+		Role:      in.Role,
+		TokenHash: s.generateTokenHash(rawToken),
+		InvitedBy: adminID,
+		ExpiresAt: time.Now().Add(invitationTTL),
+	}
+
+	err = util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		if err := s.ir.Create(txCtx, &invitation); err != nil {
+			return err
+		}
+
+		return s.ar.Create(txCtx, &domain.AuditLog{
+			ActorID: adminID,
+			Action:  domain.AuditUserInvited,
+			Details: map[string]string{
+				"invitation_id": invitation.ID.String(),
+				"email":         invitation.Email,
+				"role":          string(invitation.Role),
+			},
+		})
+	})
+	if err != nil {
+		return domain.Invitation{}, err
+	}
+
+	go s.sendInvitation(invitation, rawToken)
+	return invitation, nil
+}
+
+func (s *userService) AcceptInvitation(
+	ctx context.Context,
+	in domain.AcceptInvitationInput,
+) error {
+	keys := s.attemptKeys(ctx, nil)
+	if err := s.checkAttempts(ctx, keys); err != nil {
+		return err
+	}
+
+	invitation, err := s.ir.FindByTokenHash(ctx, s.generateTokenHash(in.Token))
+	if err != nil || invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) {
+		if lockErr := s.recordFailedAttempt(ctx, keys); lockErr != nil {
+			return lockErr
+		}
+		return errInvalidOrExpiredToken
+	}
+
+	err = s.checkPasswordStrength(in.Password, domain.User{
+		FirstName: in.FirstName,
+		LastName:  in.LastName,
+		Email:     invitation.Email,
+		Mobile:    in.Mobile,
+	})
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := s.hashPassword(in.Password)
+	if err != nil {
+		return err
+	}
+
+	var user *domain.User
+	var mobileOTP string
+
+	err = util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		// 1. Claim the invitation; losing this race means it was used
+		accepted, err := s.ir.MarkAccepted(txCtx, invitation.ID)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return errInvalidOrExpiredToken
+		}
+
+		// 2. Create the user with the invited role. The token was
+		// emailed, so the address is already verified.
+		user = &domain.User{
+			ID:            uuid.New(),
+			FirstName:     in.FirstName,
+			LastName:      in.LastName,
+			Email:         invitation.Email,
+			Mobile:        in.Mobile,
+			Password:      hashedPassword,
+			Role:          invitation.Role,
+			IsActive:      true,
+			EmailVerified: true,
+		}
+		if err := s.r.Create(txCtx, user); err != nil {
+			return err
+		}
+
+		// 3. Create mobile OTP
+		mobileOTP = s.generateOTP(6)
+		if err := s.vtr.Create(txCtx, &domain.VerificationToken{
+			UserID:    user.ID,
+			Type:      domain.VerificationMobile,
+			TokenHash: s.generateTokenHash(mobileOTP),
+			ExpiresAt: time.Now().Add(5 * time.Minute),
+		}); err != nil {
+			return err
+		}
+
+		// 4. Record who granted the role
+		return s.ar.Create(txCtx, &domain.AuditLog{
+			ActorID:      invitation.InvitedBy,
+			Action:       domain.AuditInvitationAccepted,
+			TargetUserID: &user.ID,
+			Details: map[string]string{
+				"invitation_id": invitation.ID.String(),
+				"role":          string(invitation.Role),
+			},
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	go s.sendMobileVerification(*user, mobileOTP)
+	return nil
+}
+
+func (s *userService) ChangeUserRole(
+	ctx context.Context,
+	adminID uuid.UUID,
+	userID uuid.UUID,
+	role domain.UserRole,
+) error {
+	if !role.IsValid() {
+		return errInvalidRole
+	}
+
+	// Keeps the last admin from demoting themselves by accident
+	if adminID == userID {
+		return errCannotChangeOwnRole
+	}
+
+	user, err := s.r.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Role == role {
+		return nil
+	}
+
+	return util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		// 1. Update the role
+		if err := s.r.UpdateRole(txCtx, user.ID, role); err != nil {
+			return err
+		}
+
+		// 2. Record the change
+		if err := s.ar.Create(txCtx, &domain.AuditLog{
+			ActorID:      adminID,
+			Action:       domain.AuditRoleChanged,
+			TargetUserID: &user.ID,
+			Details: map[string]string{
+				"from": string(user.Role),
+				"to":   string(role),
+			},
+		}); err != nil {
+			return err
+		}
+
+		// 3. Tokens carry the role, so end the user's sessions
+		return s.endAllSessions(txCtx, user.ID)
+	})
+}
@@ -0,0 +1,126 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+)
+
+// TestInviteUser_InvalidRole tests that unknown roles are rejected
+func TestInviteUser_InvalidRole(t *testing.T) {
+	service := &userService{}
+
+	_, err := service.InviteUser(context.Background(), uuid.New(), domain.InvitationInput{
+		Email: "new@example.com",
+		Role:  "SUPERUSER",
+	})
+
+	if !errors.Is(err, errInvalidRole) {
+		t.Fatalf("expected invalid role error, got %v", err)
+	}
+}
+
+// TestInviteUser_ExistingUser tests that registered emails cannot be invited
+func TestInviteUser_ExistingUser(t *testing.T) {
+	service := &userService{
+		r: &MockUserRepository{
+			FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
+				return domain.User{ID: uuid.New(), Email: email}, nil
+			},
+		},
+		ir: &MockInvitationRepository{
+			CreateFunc: func(ctx context.Context, invitation *domain.Invitation) error {
+				t.Fatalf("should not create an invitation")
+				return nil
+			},
+		},
+	}
+
+	_, err := service.InviteUser(context.Background(), uuid.New(), domain.InvitationInput{
+		Email: "taken@example.com",
+		Role:  domain.UserRoleAdmin,
+	})
+
+	if !errors.Is(err, errUserAlreadyExists) {
+		t.Fatalf("expected user exists error, got %v", err)
+	}
+}
+
+// TestAcceptInvitation_Expired tests that expired invitations count as failed attempts
+func TestAcceptInvitation_Expired(t *testing.T) {
+	var recorded []string
+	service := &userService{
+		ir: &MockInvitationRepository{
+			FindByTokenHashFunc: func(ctx context.Context, tokenHash string) (*domain.Invitation, error) {
+				return &domain.Invitation{
+					ID:        uuid.New(),
+					Role:      domain.UserRoleAdmin,
+					ExpiresAt: time.Now().Add(-time.Minute),
+				}, nil
+			},
+			MarkAcceptedFunc: func(ctx context.Context, id uuid.UUID) (bool, error) {
+				t.Fatalf("expired invitations must not be accepted")
+				return false, nil
+			},
+		},
+		lar: &MockLoginAttemptRepository{
+			RecordFailureFunc: func(ctx context.Context, key string, window time.Duration) (*domain.LoginAttempt, error) {
+				recorded = append(recorded, key)
+				return &domain.LoginAttempt{Key: key, Failures: 1}, nil
+			},
+		},
+	}
+
+	ctx := domain.WithClientIP(context.Background(), "10.0.0.1")
+	err := service.AcceptInvitation(ctx, domain.AcceptInvitationInput{Token: "expired"})
+
+	if !errors.Is(err, errInvalidOrExpiredToken) {
+		t.Fatalf("expected invalid token error, got %v", err)
+	}
+	if len(recorded) != 1 || recorded[0] != "ip:10.0.0.1" {
+		t.Fatalf("expected the IP failure to be counted, got %v", recorded)
+	}
+}
+
+// TestChangeUserRole_Validation tests the checks done before any change
+func TestChangeUserRole_Validation(t *testing.T) {
+	adminID := uuid.New()
+	userID := uuid.New()
+	service := &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id, Role: domain.UserRoleAdmin}, nil
+			},
+			UpdateRoleFunc: func(ctx context.Context, id uuid.UUID, role domain.UserRole) error {
+				t.Fatalf("role must not be updated")
+				return nil
+			},
+		},
+		ar: &MockAuditRepository{
+			CreateFunc: func(ctx context.Context, log *domain.AuditLog) error {
+				t.Fatalf("nothing should be audited")
+				return nil
+			},
+		},
+	}
+
+	if err := service.ChangeUserRole(context.Background(), adminID, userID, "ROOT"); !errors.Is(err, errInvalidRole) {
+		t.Fatalf("expected invalid role error, got %v", err)
+	}
+	if err := service.ChangeUserRole(context.Background(), adminID, adminID, domain.UserRoleUser); !errors.Is(err, errCannotChangeOwnRole) {
+		t.Fatalf("expected own role error, got %v", err)
+	}
+
+	// The user already has the role, so there is nothing to change
+	if err := service.ChangeUserRole(context.Background(), adminID, userID, domain.UserRoleAdmin); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+// Note: InviteUser, AcceptInvitation and ChangeUserRole write inside a
+// database transaction and should be tested through integration tests.
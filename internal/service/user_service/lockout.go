@@ -0,0 +1,174 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+)
+
+// attemptPolicy defines when failures turn into a lockout. Once threshold
+// failures are reached inside window, every further failure locks the key
+// for baseDelay, doubling up to maxDelay.
+type attemptPolicy struct {
+	threshold int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	window    time.Duration
+}
+
+var (
+	accountAttemptPolicy = attemptPolicy{
+		threshold: 5,
+		baseDelay: 30 * time.Second,
+		maxDelay:  time.Hour,
+		window:    24 * time.Hour,
+	}
+
+	// Shared addresses (NAT, offices) get more room before locking
+	ipAttemptPolicy = attemptPolicy{
+		threshold: 20,
+		baseDelay: 30 * time.Second,
+		maxDelay:  time.Hour,
+		window:    24 * time.Hour,
+	}
+
+	// Every code or link sent starts a cooldown for its recipient that
+	// doubles with each resend, so nobody can run up SMS costs or keep
+	// cancelling a victim's code
+	recipientSendPolicy = attemptPolicy{
+		threshold: 1,
+		baseDelay: time.Minute,
+		maxDelay:  time.Hour,
+		window:    time.Hour,
+	}
+)
+
+func (p attemptPolicy) lockDuration(failures int) time.Duration {
+	if failures < p.threshold {
+		return 0
+	}
+
+	delay := p.baseDelay
+	for i := p.threshold; i < failures && delay < p.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.maxDelay {
+		delay = p.maxDelay
+	}
+	return delay
+}
+
+type attemptKey struct {
+	key    string
+	policy attemptPolicy
+}
+
+func accountAttemptKey(userID uuid.UUID) attemptKey {
+	return attemptKey{key: "user:" + userID.String(), policy: accountAttemptPolicy}
+}
+
+// attemptKeys returns the IP key and, when known, the account key
+func (s *userService) attemptKeys(ctx context.Context, userID *uuid.UUID) []attemptKey {
+	var keys []attemptKey
+
+	if ip := domain.ClientIPFromContext(ctx); ip != "" {
+		keys = append(keys, attemptKey{key: "ip:" + ip, policy: ipAttemptPolicy})
+	}
+	if userID != nil {
+		keys = append(keys, accountAttemptKey(*userID))
+	}
+
+	return keys
+}
+
+// checkAttempts rejects the request while any of the keys is locked
+func (s *userService) checkAttempts(ctx context.Context, keys []attemptKey) error {
+	now := time.Now()
+
+	for _, k := range keys {
+		attempt, err := s.lar.Find(ctx, k.key)
+		if err != nil {
+			return err
+		}
+		if attempt != nil && attempt.LockedUntil != nil && now.Before(*attempt.LockedUntil) {
+			return &domain.TooManyAttemptsError{RetryAfter: attempt.LockedUntil.Sub(now)}
+		}
+	}
+
+	return nil
+}
+
+// recordFailedAttempt counts a failure against every key and returns
+// a TooManyAttemptsError when that failure locked one of them.
+func (s *userService) recordFailedAttempt(ctx context.Context, keys []attemptKey) error {
+	var lockErr *domain.TooManyAttemptsError
+
+	for _, k := range keys {
+		attempt, err := s.lar.RecordFailure(ctx, k.key, k.policy.window)
+		if err != nil {
+			return err
+		}
+
+		delay := k.policy.lockDuration(attempt.Failures)
+		if delay == 0 {
+			continue
+		}
+
+		if err := s.lar.Lock(ctx, k.key, time.Now().Add(delay)); err != nil {
+			return err
+		}
+		if lockErr == nil || delay > lockErr.RetryAfter {
+			lockErr = &domain.TooManyAttemptsError{RetryAfter: delay}
+		}
+	}
+
+	if lockErr != nil {
+		return lockErr
+	}
+	return nil
+}
+
+// throttleSend rejects a request for a login code or link while its
+// recipient is cooling down or the caller's address is locked, then counts
+// the send against both. Recipients are keyed by the address the client
+// typed, so unknown ones are throttled alike and nothing is revealed.
+func (s *userService) throttleSend(ctx context.Context, channel, recipient string) error {
+	keys := append(s.attemptKeys(ctx, nil), attemptKey{
+		key:    "send:" + channel + ":" + strings.ToLower(strings.TrimSpace(recipient)),
+		policy: recipientSendPolicy,
+	})
+
+	if err := s.checkAttempts(ctx, keys); err != nil {
+		return err
+	}
+
+	// The lock this send starts only applies to the next request
+	err := s.recordFailedAttempt(ctx, keys)
+	var lockErr *domain.TooManyAttemptsError
+	if errors.As(err, &lockErr) {
+		return nil
+	}
+	return err
+}
+
+// resetAccountAttempts clears the account after a success. IP failures are
+// kept, otherwise one valid account would reset an attacker's address.
+func (s *userService) resetAccountAttempts(ctx context.Context, userID uuid.UUID) error {
+	return s.lar.Reset(ctx, accountAttemptKey(userID).key)
+}
+
+func (s *userService) UnlockUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) error {
+	if _, err := s.r.FindByID(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.resetAccountAttempts(ctx, userID)
+}
@@ -0,0 +1,201 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+)
+
+// TestAttemptPolicy_LockDuration tests the exponential back-off and its cap
+func TestAttemptPolicy_LockDuration(t *testing.T) {
+	policy := attemptPolicy{threshold: 5, baseDelay: 30 * time.Second, maxDelay: 2 * time.Minute}
+
+	tests := map[int]time.Duration{
+		4:  0,
+		5:  30 * time.Second,
+		6:  time.Minute,
+		7:  2 * time.Minute,
+		50: 2 * time.Minute,
+	}
+
+	for failures, want := range tests {
+		if got := policy.lockDuration(failures); got != want {
+			t.Fatalf("failures %d: expected %s, got %s", failures, want, got)
+		}
+	}
+}
+
+// TestLogin_LockedAccount tests that a locked account is rejected before checking the password
+func TestLogin_LockedAccount(t *testing.T) {
+	userID := uuid.New()
+	lockedUntil := time.Now().Add(time.Minute)
+
+	service := &userService{
+		r: &MockUserRepository{
+			FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
+				return domain.User{ID: userID, Email: email}, nil
+			},
+		},
+		lar: &MockLoginAttemptRepository{
+			FindFunc: func(ctx context.Context, key string) (*domain.LoginAttempt, error) {
+				if key == "user:"+userID.String() {
+					return &domain.LoginAttempt{Key: key, LockedUntil: &lockedUntil}, nil
+				}
+				return nil, nil
+			},
+			RecordFailureFunc: func(ctx context.Context, key string, window time.Duration) (*domain.LoginAttempt, error) {
+				t.Fatalf("locked requests must not be counted")
+				return nil, nil
+			},
+		},
+	}
+
+	_, err := service.Login(context.Background(), domain.LoginInput{Email: "test@example.com", Password: "x"})
+
+	var lockErr *domain.TooManyAttemptsError
+	if !errors.As(err, &lockErr) {
+		t.Fatalf("expected lockout error, got %v", err)
+	}
+	if lockErr.RetryAfter <= 0 || lockErr.RetryAfter > time.Minute {
+		t.Fatalf("unexpected retry after %s", lockErr.RetryAfter)
+	}
+}
+
+// TestLogin_FailureLocksAccountAndIP tests that the failure crossing the threshold locks both keys
+func TestLogin_FailureLocksAccountAndIP(t *testing.T) {
+	userID := uuid.New()
+	password := "password123"
+	hashed := mustHashPassword(t, password)
+
+	failures := map[string]int{"user:" + userID.String(): accountAttemptPolicy.threshold - 1}
+	locked := map[string]time.Time{}
+
+	service := &userService{
+		r: &MockUserRepository{
+			FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
+				return domain.User{ID: userID, Email: email, Password: hashed}, nil
+			},
+		},
+		lar: &MockLoginAttemptRepository{
+			RecordFailureFunc: func(ctx context.Context, key string, window time.Duration) (*domain.LoginAttempt, error) {
+				failures[key]++
+				return &domain.LoginAttempt{Key: key, Failures: failures[key]}, nil
+			},
+			LockFunc: func(ctx context.Context, key string, until time.Time) error {
+				locked[key] = until
+				return nil
+			},
+		},
+	}
+
+	ctx := domain.WithClientIP(context.Background(), "10.0.0.1")
+	_, err := service.Login(ctx, domain.LoginInput{Email: "test@example.com", Password: "wrong"})
+
+	var lockErr *domain.TooManyAttemptsError
+	if !errors.As(err, &lockErr) {
+		t.Fatalf("expected lockout error, got %v", err)
+	}
+	if failures["ip:10.0.0.1"] != 1 {
+		t.Fatalf("expected the IP failure to be counted")
+	}
+	if _, ok := locked["user:"+userID.String()]; !ok {
+		t.Fatalf("expected the account to be locked")
+	}
+	if _, ok := locked["ip:10.0.0.1"]; ok {
+		t.Fatalf("expected the IP to stay below its threshold")
+	}
+}
+
+// TestLogin_UnknownUserCountsIP tests that unknown accounts still count against the address
+func TestLogin_UnknownUserCountsIP(t *testing.T) {
+	var recorded []string
+	service := &userService{
+		r: &MockUserRepository{
+			FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
+				return domain.User{}, errors.New("user not found")
+			},
+		},
+		lar: &MockLoginAttemptRepository{
+			RecordFailureFunc: func(ctx context.Context, key string, window time.Duration) (*domain.LoginAttempt, error) {
+				recorded = append(recorded, key)
+				return &domain.LoginAttempt{Key: key, Failures: 1}, nil
+			},
+		},
+	}
+
+	ctx := domain.WithClientIP(context.Background(), "10.0.0.1")
+	_, err := service.Login(ctx, domain.LoginInput{Email: "nobody@example.com", Password: "x"})
+
+	if err == nil {
+		t.Fatalf("expected error for unknown user")
+	}
+	if len(recorded) != 1 || recorded[0] != "ip:10.0.0.1" {
+		t.Fatalf("expected only the IP to be counted, got %v", recorded)
+	}
+}
+
+// TestVerifyMobile_WrongOTPCountsAttempt tests that wrong mobile OTPs are counted
+func TestVerifyMobile_WrongOTPCountsAttempt(t *testing.T) {
+	userID := uuid.New()
+	service := &userService{}
+	token := &domain.VerificationToken{
+		UserID:    userID,
+		Type:      domain.VerificationMobile,
+		TokenHash: service.generateTokenHash("123456"),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	var recorded []string
+	service.vtr = &MockVerificationTokenRepository{
+		FindByUserIDAndTypeFunc: func(ctx context.Context, id uuid.UUID, tokenType domain.VerificationTokenType) (*domain.VerificationToken, error) {
+			return token, nil
+		},
+	}
+	service.lar = &MockLoginAttemptRepository{
+		RecordFailureFunc: func(ctx context.Context, key string, window time.Duration) (*domain.LoginAttempt, error) {
+			recorded = append(recorded, key)
+			return &domain.LoginAttempt{Key: key, Failures: 1}, nil
+		},
+	}
+
+	err := service.VerifyMobile(context.Background(), userID, "654321")
+
+	if !errors.Is(err, errInvalidOrExpiredToken) {
+		t.Fatalf("expected invalid token error, got %v", err)
+	}
+	if len(recorded) != 1 || recorded[0] != "user:"+userID.String() {
+		t.Fatalf("expected the account failure to be counted, got %v", recorded)
+	}
+}
+
+// TestUnlockUser tests that unlocking resets the account key
+func TestUnlockUser(t *testing.T) {
+	userID := uuid.New()
+	var reset string
+
+	service := &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id}, nil
+			},
+		},
+		lar: &MockLoginAttemptRepository{
+			ResetFunc: func(ctx context.Context, key string) error {
+				reset = key
+				return nil
+			},
+		},
+	}
+
+	if err := service.UnlockUser(context.Background(), userID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if reset != "user:"+userID.String() {
+		t.Fatalf("expected account key to be reset, got %q", reset)
+	}
+}
@@ -0,0 +1,139 @@
+package user_service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/util"
+)
+
+const (
+	loginOTPLength      = 6
+	loginOTPTTL         = 5 * time.Minute
+	loginOTPMaxAttempts = 5
+)
+
+var errInvalidLoginOTP = errors.New("invalid or expired code")
+
+func (s *userService) RequestLoginOTP(
+	ctx context.Context,
+	in domain.LoginOTPRequestInput,
+) error {
+	channel, recipient := "mobile", in.Mobile
+	if in.Email != "" {
+		channel, recipient = "email", in.Email
+	}
+	if err := s.throttleSend(ctx, channel, recipient); err != nil {
+		return err
+	}
+
+	var otp string
+	var user domain.User
+
+	err := util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		var err error
+
+		user, err = s.findByEmailOrMobile(txCtx, in.Email, in.Mobile)
+		if err != nil {
+			// Avoid user enumeration in passwordless flows.
+			return nil
+		}
+
+		// Only the most recent code can be used
+		if err := s.vtr.InvalidateByUserAndType(txCtx, user.ID, domain.LoginOTP); err != nil {
+			return err
+		}
+
+		otp = s.generateOTP(loginOTPLength)
+		if otp == "" {
+			return errors.New("cannot generate login code")
+		}
+
+		token := &domain.VerificationToken{
+			UserID:    user.ID,
+			Type:      domain.LoginOTP,
+			TokenHash: s.generateTokenHash(otp),
+			ExpiresAt: time.Now().Add(loginOTPTTL),
+		}
+
+		return s.vtr.Create(txCtx, token)
+	})
+	if err != nil || otp == "" {
+		return err
+	}
+
+	// Send the code to the channel the user asked for
+	go s.sendCode(user, in.Email != "", domain.TemplateLoginOTP, otp)
+
+	return nil
+}
+
+func (s *userService) LoginWithOTP(
+	ctx context.Context,
+	in domain.LoginOTPVerifyInput,
+) (domain.AuthTokens, error) {
+	if err := s.checkAttempts(ctx, s.attemptKeys(ctx, nil)); err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	user, err := s.findByEmailOrMobile(ctx, in.Email, in.Mobile)
+	if err != nil {
+		if lockErr := s.recordFailedAttempt(ctx, s.attemptKeys(ctx, nil)); lockErr != nil {
+			return domain.AuthTokens{}, lockErr
+		}
+		return domain.AuthTokens{}, errInvalidLoginOTP
+	}
+
+	keys := s.attemptKeys(ctx, &user.ID)
+	if err := s.checkAttempts(ctx, keys); err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	// Codes are looked up per user since short codes are not unique
+	token, err := s.vtr.FindByUserIDAndType(ctx, user.ID, domain.LoginOTP)
+	if err != nil {
+		return domain.AuthTokens{}, errInvalidLoginOTP
+	}
+
+	if time.Now().After(token.ExpiresAt) || token.Attempts >= loginOTPMaxAttempts {
+		return domain.AuthTokens{}, errInvalidLoginOTP
+	}
+
+	hash := s.generateTokenHash(in.OTP)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(token.TokenHash)) != 1 {
+		// Count the failure in the database, so parallel guesses cannot
+		// go past the limit; the code is burned once it is reached
+		if _, err := s.vtr.RecordFailedAttempt(ctx, token.ID, loginOTPMaxAttempts); err != nil {
+			return domain.AuthTokens{}, err
+		}
+		if lockErr := s.recordFailedAttempt(ctx, keys); lockErr != nil {
+			return domain.AuthTokens{}, lockErr
+		}
+		return domain.AuthTokens{}, errInvalidLoginOTP
+	}
+
+	if err := s.checkAccountStatus(user); err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	// Of concurrent logins with the same code only one wins
+	consumed, err := s.vtr.Consume(ctx, token.ID)
+	if err != nil {
+		return domain.AuthTokens{}, err
+	}
+	if !consumed {
+		return domain.AuthTokens{}, errInvalidLoginOTP
+	}
+
+	now := time.Now()
+	user.LastLogin = &now
+	if err := s.r.Update(ctx, &user); err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	// A login code does not replace the second factor
+	return s.completeLogin(ctx, user)
+}
@@ -0,0 +1,243 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+)
+
+// loginOTPCalls records what LoginWithOTP did to the stored code
+type loginOTPCalls struct {
+	failedAttempts []int
+	consumed       int
+}
+
+func newLoginOTPService(token *domain.VerificationToken, calls *loginOTPCalls) *userService {
+	userID := uuid.New()
+	token.ID = uuid.New()
+	token.UserID = userID
+
+	return &userService{
+		lar:  &MockLoginAttemptRepository{},
+		rtr:  &MockRefreshTokenRepository{},
+		sr:   &MockSessionRepository{},
+		mfar: &MockMFARepository{},
+		r: &MockUserRepository{
+			FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
+				return domain.User{ID: userID, Email: email, Role: domain.UserRoleUser, IsActive: true}, nil
+			},
+		},
+		vtr: &MockVerificationTokenRepository{
+			FindByUserIDAndTypeFunc: func(ctx context.Context, id uuid.UUID, tokenType domain.VerificationTokenType) (*domain.VerificationToken, error) {
+				if id != userID || tokenType != domain.LoginOTP {
+					return nil, errors.New("not found")
+				}
+				return token, nil
+			},
+			RecordFailedAttemptFunc: func(ctx context.Context, id uuid.UUID, maxAttempts int) (int, error) {
+				if id != token.ID {
+					return 0, errors.New("not found")
+				}
+				calls.failedAttempts = append(calls.failedAttempts, maxAttempts)
+				return len(calls.failedAttempts), nil
+			},
+			ConsumeFunc: func(ctx context.Context, id uuid.UUID) (bool, error) {
+				calls.consumed++
+				return calls.consumed == 1, nil
+			},
+			UpdateFunc: func(ctx context.Context, t *domain.VerificationToken) error {
+				return errors.New("codes must not be updated from a stale read")
+			},
+		},
+	}
+}
+
+// TestLoginWithOTP_SingleUse tests that a code logs in once, even when
+// concurrent requests both checked it before it was used
+func TestLoginWithOTP_SingleUse(t *testing.T) {
+	service := &userService{}
+	token := &domain.VerificationToken{
+		TokenHash: service.generateTokenHash("123456"),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	var calls loginOTPCalls
+	service = newLoginOTPService(token, &calls)
+
+	in := domain.LoginOTPVerifyInput{Email: "test@example.com", OTP: "123456"}
+
+	tokens, err := service.LoginWithOTP(context.Background(), in)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("expected tokens, got %+v", tokens)
+	}
+
+	_, err = service.LoginWithOTP(context.Background(), in)
+	if !errors.Is(err, errInvalidLoginOTP) {
+		t.Fatalf("expected invalid code error, got %v", err)
+	}
+}
+
+// TestLoginWithOTP_WrongCodeCountsAttempt tests that a wrong code is
+// counted in the database against the attempt limit
+func TestLoginWithOTP_WrongCodeCountsAttempt(t *testing.T) {
+	service := &userService{}
+	token := &domain.VerificationToken{
+		TokenHash: service.generateTokenHash("123456"),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	var calls loginOTPCalls
+	service = newLoginOTPService(token, &calls)
+
+	_, err := service.LoginWithOTP(context.Background(), domain.LoginOTPVerifyInput{
+		Email: "test@example.com",
+		OTP:   "654321",
+	})
+
+	if !errors.Is(err, errInvalidLoginOTP) {
+		t.Fatalf("expected invalid code error, got %v", err)
+	}
+	if len(calls.failedAttempts) != 1 || calls.failedAttempts[0] != loginOTPMaxAttempts {
+		t.Fatalf("expected one counted attempt against the limit, got %v", calls.failedAttempts)
+	}
+	if calls.consumed != 0 {
+		t.Fatalf("expected the code not to be redeemed")
+	}
+}
+
+// TestLoginWithOTP_TooManyAttempts tests that even the right code is rejected after the limit
+func TestLoginWithOTP_TooManyAttempts(t *testing.T) {
+	service := &userService{}
+	token := &domain.VerificationToken{
+		TokenHash: service.generateTokenHash("123456"),
+		ExpiresAt: time.Now().Add(time.Minute),
+		Attempts:  loginOTPMaxAttempts,
+	}
+	var calls loginOTPCalls
+	service = newLoginOTPService(token, &calls)
+
+	_, err := service.LoginWithOTP(context.Background(), domain.LoginOTPVerifyInput{
+		Email: "test@example.com",
+		OTP:   "123456",
+	})
+
+	if !errors.Is(err, errInvalidLoginOTP) {
+		t.Fatalf("expected invalid code error, got %v", err)
+	}
+	if len(calls.failedAttempts) != 0 || calls.consumed != 0 {
+		t.Fatalf("expected the code to be left alone, got %+v", calls)
+	}
+}
+
+// TestLoginWithOTP_Expired tests that an expired code is rejected
+func TestLoginWithOTP_Expired(t *testing.T) {
+	service := &userService{}
+	token := &domain.VerificationToken{
+		TokenHash: service.generateTokenHash("123456"),
+		ExpiresAt: time.Now().Add(-time.Second),
+	}
+	var calls loginOTPCalls
+	service = newLoginOTPService(token, &calls)
+
+	_, err := service.LoginWithOTP(context.Background(), domain.LoginOTPVerifyInput{
+		Email: "test@example.com",
+		OTP:   "123456",
+	})
+
+	if !errors.Is(err, errInvalidLoginOTP) {
+		t.Fatalf("expected invalid code error, got %v", err)
+	}
+}
+
+// TestLoginWithOTP_UnknownUser tests that unknown users get the same error as wrong codes
+func TestLoginWithOTP_UnknownUser(t *testing.T) {
+	service := &userService{
+		lar: &MockLoginAttemptRepository{},
+		r: &MockUserRepository{
+			FindByMobileFunc: func(ctx context.Context, mobile string) (domain.User, error) {
+				return domain.User{}, errors.New("record not found")
+			},
+		},
+	}
+
+	_, err := service.LoginWithOTP(context.Background(), domain.LoginOTPVerifyInput{
+		Mobile: "+911234567890",
+		OTP:    "123456",
+	})
+
+	if !errors.Is(err, errInvalidLoginOTP) {
+		t.Fatalf("expected invalid code error, got %v", err)
+	}
+}
+
+// TestRequestLoginOTP_Cooldown tests that a recipient cooling down gets no
+// new code, whatever the case of the address typed
+func TestRequestLoginOTP_Cooldown(t *testing.T) {
+	lockedUntil := time.Now().Add(time.Minute)
+
+	service := &userService{
+		lar: &MockLoginAttemptRepository{
+			FindFunc: func(ctx context.Context, key string) (*domain.LoginAttempt, error) {
+				if key == "send:email:test@example.com" {
+					return &domain.LoginAttempt{Key: key, LockedUntil: &lockedUntil}, nil
+				}
+				return nil, nil
+			},
+			RecordFailureFunc: func(ctx context.Context, key string, window time.Duration) (*domain.LoginAttempt, error) {
+				t.Fatalf("refused requests must not be counted")
+				return nil, nil
+			},
+		},
+	}
+
+	err := service.RequestLoginOTP(context.Background(), domain.LoginOTPRequestInput{Email: " Test@Example.com"})
+
+	var lockErr *domain.TooManyAttemptsError
+	if !errors.As(err, &lockErr) {
+		t.Fatalf("expected cooldown error, got %v", err)
+	}
+}
+
+// TestThrottleSend tests that a send counts against the caller's address
+// and starts the recipient's cooldown without failing the request
+func TestThrottleSend(t *testing.T) {
+	failures := map[string]int{}
+	locked := map[string]time.Time{}
+
+	service := &userService{
+		lar: &MockLoginAttemptRepository{
+			RecordFailureFunc: func(ctx context.Context, key string, window time.Duration) (*domain.LoginAttempt, error) {
+				failures[key]++
+				return &domain.LoginAttempt{Key: key, Failures: failures[key]}, nil
+			},
+			LockFunc: func(ctx context.Context, key string, until time.Time) error {
+				locked[key] = until
+				return nil
+			},
+		},
+	}
+
+	ctx := domain.WithClientIP(context.Background(), "10.0.0.1")
+	if err := service.throttleSend(ctx, "mobile", "+911234567890"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if failures["ip:10.0.0.1"] != 1 || failures["send:mobile:+911234567890"] != 1 {
+		t.Fatalf("expected the send to be counted against both keys, got %v", failures)
+	}
+	if _, ok := locked["send:mobile:+911234567890"]; !ok {
+		t.Fatalf("expected the recipient to cool down")
+	}
+	if _, ok := locked["ip:10.0.0.1"]; ok {
+		t.Fatalf("expected the IP to stay below its threshold")
+	}
+}
+
+// Note: the rest of RequestLoginOTP runs inside a database transaction and
+// should be tested through integration tests.
@@ -0,0 +1,159 @@
+package user_service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/url"
+	"time"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/util"
+)
+
+const magicLinkTTL = 15 * time.Minute
+
+// defaultMagicLinkURL is the frontend page that redeems login links
+const defaultMagicLinkURL = "http://localhost:5173/login/magic"
+
+var errInvalidMagicLink = errors.New("invalid or expired link")
+
+// RequestMagicLink emails a single-use login link. When in.BindDevice is
+// set the returned device code must accompany the link, so a forwarded or
+// intercepted email cannot be used elsewhere.
+func (s *userService) RequestMagicLink(
+	ctx context.Context,
+	in domain.MagicLinkRequestInput,
+) (string, error) {
+	if err := s.throttleSend(ctx, "email", in.Email); err != nil {
+		return "", err
+	}
+
+	var deviceCode string
+	var deviceHash *string
+	if in.BindDevice {
+		// Unknown emails get a code too, so the answer reveals nothing
+		var err error
+		deviceCode, err = s.generateRawToken()
+		if err != nil {
+			return "", err
+		}
+		hash := s.generateTokenHash(deviceCode)
+		deviceHash = &hash
+	}
+
+	var rawToken string
+	var user domain.User
+
+	err := util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		var err error
+
+		user, err = s.r.FindByEmail(txCtx, in.Email)
+		if err != nil {
+			// Avoid user enumeration in passwordless flows.
+			return nil
+		}
+
+		// Only the most recent link can be used
+		if err := s.vtr.InvalidateByUserAndType(txCtx, user.ID, domain.LoginMagicLink); err != nil {
+			return err
+		}
+
+		rawToken, err = s.generateRawToken()
+		if err != nil {
+			return err
+		}
+
+		token := &domain.VerificationToken{
+			UserID:     user.ID,
+			Type:       domain.LoginMagicLink,
+			TokenHash:  s.generateTokenHash(rawToken),
+			ExpiresAt:  time.Now().Add(magicLinkTTL),
+			DeviceHash: deviceHash,
+		}
+
+		return s.vtr.Create(txCtx, token)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if rawToken != "" {
+		go s.sendMagicLink(user, s.magicLink(rawToken))
+	}
+
+	return deviceCode, nil
+}
+
+func (s *userService) LoginWithMagicLink(
+	ctx context.Context,
+	in domain.MagicLinkLoginInput,
+) (domain.AuthTokens, error) {
+	// Links are only guessable per address
+	keys := s.attemptKeys(ctx, nil)
+	if err := s.checkAttempts(ctx, keys); err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	token, err := s.vtr.FindByHashAndType(ctx, s.generateTokenHash(in.Token), domain.LoginMagicLink)
+	if err != nil || token.IsUsed || time.Now().After(token.ExpiresAt) || !s.sameDevice(token, in.DeviceCode) {
+		if lockErr := s.recordFailedAttempt(ctx, keys); lockErr != nil {
+			return domain.AuthTokens{}, lockErr
+		}
+		return domain.AuthTokens{}, errInvalidMagicLink
+	}
+
+	user, err := s.r.FindByID(ctx, token.UserID)
+	if err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	if err := s.checkAccountStatus(user); err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	// Of concurrent redeems of the same link only one wins
+	consumed, err := s.vtr.Consume(ctx, token.ID)
+	if err != nil {
+		return domain.AuthTokens{}, err
+	}
+	if !consumed {
+		return domain.AuthTokens{}, errInvalidMagicLink
+	}
+
+	now := time.Now()
+	user.LastLogin = &now
+	if err := s.r.Update(ctx, &user); err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	// A login link does not replace the second factor
+	return s.completeLogin(ctx, user)
+}
+
+// sameDevice reports whether deviceCode matches the device the link is
+// bound to. Unbound links work on any device.
+func (s *userService) sameDevice(token *domain.VerificationToken, deviceCode string) bool {
+	if token.DeviceHash == nil {
+		return true
+	}
+	hash := s.generateTokenHash(deviceCode)
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(*token.DeviceHash)) == 1
+}
+
+// magicLink builds the frontend URL that redeems rawToken
+func (s *userService) magicLink(rawToken string) string {
+	base := s.magicLinkURL
+	if base == "" {
+		base = defaultMagicLinkURL
+	}
+
+	link, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(rawToken)
+	}
+	query := link.Query()
+	query.Set("token", rawToken)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
@@ -0,0 +1,152 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+)
+
+func newMagicLinkService(token *domain.VerificationToken, consumed *int) *userService {
+	return &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id, Email: "test@example.com", Role: domain.UserRoleUser, IsActive: true}, nil
+			},
+		},
+		vtr: &MockVerificationTokenRepository{
+			FindByHashAndTypeFunc: func(ctx context.Context, tokenHash string, tokenType domain.VerificationTokenType) (*domain.VerificationToken, error) {
+				if tokenHash != token.TokenHash || tokenType != domain.LoginMagicLink {
+					return nil, errors.New("not found")
+				}
+				return token, nil
+			},
+			ConsumeFunc: func(ctx context.Context, id uuid.UUID) (bool, error) {
+				*consumed++
+				return *consumed == 1, nil
+			},
+		},
+		rtr:  &MockRefreshTokenRepository{},
+		sr:   &MockSessionRepository{},
+		mfar: &MockMFARepository{},
+		lar:  &MockLoginAttemptRepository{},
+	}
+}
+
+// TestLoginWithMagicLink_SingleUse tests that a link logs in once
+func TestLoginWithMagicLink_SingleUse(t *testing.T) {
+	service := &userService{}
+	token := &domain.VerificationToken{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		TokenHash: service.generateTokenHash("raw-token"),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	consumed := 0
+	service = newMagicLinkService(token, &consumed)
+
+	tokens, err := service.LoginWithMagicLink(context.Background(), domain.MagicLinkLoginInput{Token: "raw-token"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("expected tokens, got %+v", tokens)
+	}
+
+	// A concurrent redeem that loaded the token before it was used
+	_, err = service.LoginWithMagicLink(context.Background(), domain.MagicLinkLoginInput{Token: "raw-token"})
+	if !errors.Is(err, errInvalidMagicLink) {
+		t.Fatalf("expected invalid link error, got %v", err)
+	}
+}
+
+// TestLoginWithMagicLink_BoundDevice tests that a bound link needs its device code
+func TestLoginWithMagicLink_BoundDevice(t *testing.T) {
+	service := &userService{}
+	deviceHash := service.generateTokenHash("device-code")
+	token := &domain.VerificationToken{
+		ID:         uuid.New(),
+		UserID:     uuid.New(),
+		TokenHash:  service.generateTokenHash("raw-token"),
+		ExpiresAt:  time.Now().Add(time.Minute),
+		DeviceHash: &deviceHash,
+	}
+	consumed := 0
+	service = newMagicLinkService(token, &consumed)
+
+	for _, deviceCode := range []string{"", "other-device"} {
+		_, err := service.LoginWithMagicLink(context.Background(), domain.MagicLinkLoginInput{Token: "raw-token", DeviceCode: deviceCode})
+		if !errors.Is(err, errInvalidMagicLink) {
+			t.Fatalf("expected invalid link error for %q, got %v", deviceCode, err)
+		}
+	}
+	if consumed != 0 {
+		t.Fatalf("expected the link to stay usable on its device")
+	}
+
+	if _, err := service.LoginWithMagicLink(context.Background(), domain.MagicLinkLoginInput{Token: "raw-token", DeviceCode: "device-code"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+// TestLoginWithMagicLink_Expired tests that an expired link is rejected
+func TestLoginWithMagicLink_Expired(t *testing.T) {
+	service := &userService{}
+	token := &domain.VerificationToken{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		TokenHash: service.generateTokenHash("raw-token"),
+		ExpiresAt: time.Now().Add(-time.Second),
+	}
+	consumed := 0
+	service = newMagicLinkService(token, &consumed)
+
+	_, err := service.LoginWithMagicLink(context.Background(), domain.MagicLinkLoginInput{Token: "raw-token"})
+	if !errors.Is(err, errInvalidMagicLink) {
+		t.Fatalf("expected invalid link error, got %v", err)
+	}
+}
+
+// TestRequestMagicLink_Cooldown tests that a recipient cooling down gets no new link
+func TestRequestMagicLink_Cooldown(t *testing.T) {
+	lockedUntil := time.Now().Add(time.Minute)
+
+	service := &userService{
+		lar: &MockLoginAttemptRepository{
+			FindFunc: func(ctx context.Context, key string) (*domain.LoginAttempt, error) {
+				if key == "send:email:test@example.com" {
+					return &domain.LoginAttempt{Key: key, LockedUntil: &lockedUntil}, nil
+				}
+				return nil, nil
+			},
+		},
+	}
+
+	_, err := service.RequestMagicLink(context.Background(), domain.MagicLinkRequestInput{Email: "test@example.com", BindDevice: true})
+
+	var lockErr *domain.TooManyAttemptsError
+	if !errors.As(err, &lockErr) {
+		t.Fatalf("expected cooldown error, got %v", err)
+	}
+}
+
+// Note: the rest of RequestMagicLink creates the token in a transaction
+// and is covered by integration tests.
+
+// TestMagicLink tests that the token is added to the configured page
+func TestMagicLink(t *testing.T) {
+	service := &userService{magicLinkURL: "https://booknest.example/login?from=email"}
+
+	got := service.magicLink("abc123")
+	if got != "https://booknest.example/login?from=email&token=abc123" {
+		t.Fatalf("unexpected link %q", got)
+	}
+
+	if got := (&userService{}).magicLink("abc123"); got != defaultMagicLinkURL+"?token=abc123" {
+		t.Fatalf("unexpected default link %q", got)
+	}
+}
@@ -0,0 +1,365 @@
+package user_service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/totp"
+	"booknest/internal/pkg/util"
+)
+
+const (
+	mfaIssuer = "BookNest"
+	// mfaPendingTTL bounds how long the second login step may take
+	mfaPendingTTL = 5 * time.Minute
+	// mfaSkew accepts codes from one step before or after the current one
+	mfaSkew = 1
+
+	recoveryCodeCount = 10
+)
+
+var (
+	errMFARequired        = errors.New("two-factor authentication required")
+	errMFAMandatory       = errors.New("two-factor authentication is mandatory for admins")
+	errMFAAlreadyEnabled  = errors.New("two-factor authentication already enabled")
+	errMFANotEnabled      = errors.New("two-factor authentication is not enabled")
+	errMFASetupNotStarted = errors.New("two-factor setup has not been started")
+	errInvalidMFACode     = errors.New("invalid two-factor code")
+	errInvalidMFAToken    = errors.New("invalid or expired two-factor token")
+)
+
+func (s *userService) LoginWithMFA(
+	ctx context.Context,
+	in domain.MFALoginInput,
+) (domain.AuthTokens, error) {
+	userID, err := s.parseMFAPendingJWT(in.MFAToken)
+	if err != nil {
+		return domain.AuthTokens{}, errInvalidMFAToken
+	}
+
+	user, err := s.r.FindByID(ctx, userID)
+	if err != nil {
+		return domain.AuthTokens{}, errInvalidMFAToken
+	}
+
+	if err := s.checkAccountStatus(user); err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	keys := s.attemptKeys(ctx, &user.ID)
+	if err := s.checkAttempts(ctx, keys); err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	credential, err := s.findMFA(ctx, user.ID)
+	if err != nil {
+		return domain.AuthTokens{}, err
+	}
+	if credential == nil || credential.EnabledAt == nil {
+		return domain.AuthTokens{}, errMFARequired
+	}
+
+	var tokens domain.AuthTokens
+	err = util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		// 1. Consume the TOTP step or recovery code
+		if err := s.verifyMFACode(txCtx, credential, in.Code); err != nil {
+			return err
+		}
+
+		// 2. Every login starts a new session
+		tokens, err = s.issueTokens(txCtx, user)
+		return err
+	})
+	if errors.Is(err, errInvalidMFACode) {
+		if lockErr := s.recordFailedAttempt(ctx, keys); lockErr != nil {
+			return domain.AuthTokens{}, lockErr
+		}
+	}
+	if err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	if err := s.resetAccountAttempts(ctx, user.ID); err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	return tokens, nil
+}
+
+func (s *userService) SetupMFA(
+	ctx context.Context,
+	userID uuid.UUID,
+) (domain.MFASetup, error) {
+	user, err := s.r.FindByID(ctx, userID)
+	if err != nil {
+		return domain.MFASetup{}, err
+	}
+
+	credential, err := s.findMFA(ctx, user.ID)
+	if err != nil {
+		return domain.MFASetup{}, err
+	}
+	if credential != nil && credential.EnabledAt != nil {
+		return domain.MFASetup{}, errMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return domain.MFASetup{}, err
+	}
+
+	// Starting over replaces any secret that was never confirmed
+	if err := s.mfar.Upsert(ctx, &domain.MFACredential{
+		UserID: user.ID,
+		Secret: secret,
+	}); err != nil {
+		return domain.MFASetup{}, err
+	}
+
+	return domain.MFASetup{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(mfaIssuer, user.Email, secret),
+	}, nil
+}
+
+func (s *userService) EnableMFA(
+	ctx context.Context,
+	userID uuid.UUID,
+	code string,
+) ([]string, error) {
+	credential, err := s.findMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil {
+		return nil, errMFASetupNotStarted
+	}
+	if credential.EnabledAt != nil {
+		return nil, errMFAAlreadyEnabled
+	}
+
+	// Only a code from the authenticator proves the secret was stored
+	step, ok := totp.Validate(credential.Secret, strings.TrimSpace(code), time.Now(), mfaSkew)
+	if !ok {
+		return nil, errInvalidMFACode
+	}
+
+	codes, hashes, err := s.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		// 1. Remember the step so the same code cannot log in
+		used, err := s.mfar.UseStep(txCtx, userID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return errInvalidMFACode
+		}
+
+		// 2. Enable the authenticator
+		if err := s.mfar.Enable(txCtx, userID); err != nil {
+			return err
+		}
+
+		// 3. Store the recovery code hashes
+		return s.mfar.ReplaceRecoveryCodes(txCtx, userID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *userService) DisableMFA(
+	ctx context.Context,
+	userID uuid.UUID,
+	code string,
+) error {
+	user, err := s.r.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if s.mfaRequired(user) {
+		return errMFAMandatory
+	}
+
+	credential, err := s.findMFA(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if credential == nil || credential.EnabledAt == nil {
+		return errMFANotEnabled
+	}
+
+	return util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		if err := s.verifyMFACode(txCtx, credential, code); err != nil {
+			return err
+		}
+
+		return s.mfar.Delete(txCtx, user.ID)
+	})
+}
+
+func (s *userService) RegenerateRecoveryCodes(
+	ctx context.Context,
+	userID uuid.UUID,
+	code string,
+) ([]string, error) {
+	credential, err := s.findMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil || credential.EnabledAt == nil {
+		return nil, errMFANotEnabled
+	}
+
+	codes, hashes, err := s.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		if err := s.verifyMFACode(txCtx, credential, code); err != nil {
+			return err
+		}
+
+		// Old codes stop working once the new ones are stored
+		return s.mfar.ReplaceRecoveryCodes(txCtx, userID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// mfaRequired reports whether the user may not log in with a single factor
+func (s *userService) mfaRequired(user domain.User) bool {
+	return user.Role == domain.UserRoleAdmin
+}
+
+// findMFA returns nil when the user never started enrollment
+func (s *userService) findMFA(
+	ctx context.Context,
+	userID uuid.UUID,
+) (*domain.MFACredential, error) {
+	credential, err := s.mfar.FindByUserID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+// completeLogin issues tokens after the first factor, or a limited
+// mfa_pending token when a second factor is needed.
+func (s *userService) completeLogin(
+	ctx context.Context,
+	user domain.User,
+) (domain.AuthTokens, error) {
+	credential, err := s.findMFA(ctx, user.ID)
+	if err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	enabled := credential != nil && credential.EnabledAt != nil
+	if !enabled && !s.mfaRequired(user) {
+		// Failures are only forgiven once every factor has passed
+		if err := s.resetAccountAttempts(ctx, user.ID); err != nil {
+			return domain.AuthTokens{}, err
+		}
+
+		// Every login starts a new session
+		return s.issueTokens(ctx, user)
+	}
+
+	mfaToken, err := s.generateMFAPendingJWT(user)
+	if err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	return domain.AuthTokens{
+		TokenType:             "Bearer",
+		ExpiresIn:             int64(mfaPendingTTL.Seconds()),
+		MFARequired:           true,
+		MFAEnrollmentRequired: !enabled,
+		MFAToken:              mfaToken,
+	}, nil
+}
+
+// verifyMFACode accepts a TOTP code or an unused recovery code
+func (s *userService) verifyMFACode(
+	ctx context.Context,
+	credential *domain.MFACredential,
+	code string,
+) error {
+	code = strings.TrimSpace(code)
+
+	if step, ok := totp.Validate(credential.Secret, code, time.Now(), mfaSkew); ok {
+		// Each step is accepted once, so an observed code cannot be replayed
+		used, err := s.mfar.UseStep(ctx, credential.UserID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return errInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := s.mfar.UseRecoveryCode(
+		ctx,
+		credential.UserID,
+		s.generateTokenHash(normalizeRecoveryCode(code)),
+	)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errInvalidMFACode
+	}
+	return nil
+}
+
+// generateRecoveryCodes returns the codes to show once and their hashes to store
+func (s *userService) generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		// 50 random bits per code
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := base32.StdEncoding.EncodeToString(b)[:10]
+
+		// example: "K7Q2M-XJ4PA"
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, s.generateTokenHash(raw))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode ignores case and the dash separator
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
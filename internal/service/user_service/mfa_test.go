@@ -0,0 +1,255 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/totp"
+)
+
+func enabledCredential(userID uuid.UUID, secret string) *domain.MFACredential {
+	now := time.Now()
+	return &domain.MFACredential{UserID: userID, Secret: secret, EnabledAt: &now}
+}
+
+// TestCompleteLogin_AdminWithoutMFA tests that admins get only an enrollment token
+func TestCompleteLogin_AdminWithoutMFA(t *testing.T) {
+	service := &userService{
+		mfar: &MockMFARepository{},
+		lar: &MockLoginAttemptRepository{
+			ResetFunc: func(ctx context.Context, key string) error {
+				t.Fatalf("attempts must not be reset before the second factor")
+				return nil
+			},
+		},
+	}
+	user := domain.User{ID: uuid.New(), Role: domain.UserRoleAdmin}
+
+	tokens, err := service.completeLogin(context.Background(), user)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !tokens.MFARequired || !tokens.MFAEnrollmentRequired {
+		t.Fatalf("expected enrollment to be required, got %+v", tokens)
+	}
+	if tokens.AccessToken != "" || tokens.RefreshToken != "" {
+		t.Fatalf("expected no session tokens before the second factor")
+	}
+
+	userID, err := service.parseMFAPendingJWT(tokens.MFAToken)
+	if err != nil || userID != user.ID {
+		t.Fatalf("expected pending token for the user, got %v %v", userID, err)
+	}
+}
+
+// TestCompleteLogin_EnrolledUser tests that users who opted in must pass the second factor
+func TestCompleteLogin_EnrolledUser(t *testing.T) {
+	user := domain.User{ID: uuid.New(), Role: domain.UserRoleUser}
+	service := &userService{mfar: &MockMFARepository{
+		FindByUserIDFunc: func(ctx context.Context, userID uuid.UUID) (*domain.MFACredential, error) {
+			return enabledCredential(userID, "SECRET"), nil
+		},
+	}}
+
+	tokens, err := service.completeLogin(context.Background(), user)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !tokens.MFARequired || tokens.MFAEnrollmentRequired {
+		t.Fatalf("expected second factor to be required, got %+v", tokens)
+	}
+}
+
+// TestParseMFAPendingJWT_RejectsAccessToken tests that a full access token is not an mfa token
+func TestParseMFAPendingJWT_RejectsAccessToken(t *testing.T) {
+	service := &userService{}
+	accessToken, err := service.generateJWT(domain.User{ID: uuid.New()}, uuid.New())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := service.parseMFAPendingJWT(accessToken); err == nil {
+		t.Fatalf("expected access token to be rejected")
+	}
+}
+
+// TestVerifyMFACode_TOTP tests that a code is accepted once per time step
+func TestVerifyMFACode_TOTP(t *testing.T) {
+	secret, _ := totp.GenerateSecret()
+	credential := enabledCredential(uuid.New(), secret)
+
+	var lastStep int64
+	service := &userService{mfar: &MockMFARepository{
+		UseStepFunc: func(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+			if step <= lastStep {
+				return false, nil
+			}
+			lastStep = step
+			return true, nil
+		},
+	}}
+
+	code, _ := totp.Code(secret, totp.Step(time.Now()))
+
+	if err := service.verifyMFACode(context.Background(), credential, code); err != nil {
+		t.Fatalf("expected code to be accepted, got %v", err)
+	}
+	if err := service.verifyMFACode(context.Background(), credential, code); !errors.Is(err, errInvalidMFACode) {
+		t.Fatalf("expected replayed code to be rejected, got %v", err)
+	}
+}
+
+// TestVerifyMFACode_RecoveryCode tests that recovery codes are normalized before lookup
+func TestVerifyMFACode_RecoveryCode(t *testing.T) {
+	service := &userService{}
+	codes, hashes, err := service.generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("expected %d recovery codes", recoveryCodeCount)
+	}
+
+	service.mfar = &MockMFARepository{
+		UseRecoveryCodeFunc: func(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+			return codeHash == hashes[0], nil
+		},
+	}
+	credential := enabledCredential(uuid.New(), "SECRET")
+
+	if err := service.verifyMFACode(context.Background(), credential, " "+codes[0]+" "); err != nil {
+		t.Fatalf("expected recovery code to be accepted, got %v", err)
+	}
+	if err := service.verifyMFACode(context.Background(), credential, codes[1]); !errors.Is(err, errInvalidMFACode) {
+		t.Fatalf("expected unknown recovery code to be rejected, got %v", err)
+	}
+}
+
+// TestEnableMFA_InvalidCode tests that enrollment requires a valid authenticator code
+func TestEnableMFA_InvalidCode(t *testing.T) {
+	secret, _ := totp.GenerateSecret()
+	service := &userService{mfar: &MockMFARepository{
+		FindByUserIDFunc: func(ctx context.Context, userID uuid.UUID) (*domain.MFACredential, error) {
+			return &domain.MFACredential{UserID: userID, Secret: secret}, nil
+		},
+	}}
+
+	_, err := service.EnableMFA(context.Background(), uuid.New(), "000000x")
+
+	if !errors.Is(err, errInvalidMFACode) {
+		t.Fatalf("expected invalid code error, got %v", err)
+	}
+}
+
+// TestSetupMFA_AlreadyEnabled tests that an enabled secret is never replaced
+func TestSetupMFA_AlreadyEnabled(t *testing.T) {
+	service := &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id, Email: "test@example.com"}, nil
+			},
+		},
+		mfar: &MockMFARepository{
+			FindByUserIDFunc: func(ctx context.Context, userID uuid.UUID) (*domain.MFACredential, error) {
+				return enabledCredential(userID, "SECRET"), nil
+			},
+			UpsertFunc: func(ctx context.Context, credential *domain.MFACredential) error {
+				t.Fatalf("should not replace an enabled secret")
+				return nil
+			},
+		},
+	}
+
+	_, err := service.SetupMFA(context.Background(), uuid.New())
+
+	if !errors.Is(err, errMFAAlreadyEnabled) {
+		t.Fatalf("expected already enabled error, got %v", err)
+	}
+}
+
+// TestSetupMFA_ReturnsProvisioningURI tests starting an enrollment
+func TestSetupMFA_ReturnsProvisioningURI(t *testing.T) {
+	var stored *domain.MFACredential
+	service := &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id, Email: "admin@example.com"}, nil
+			},
+		},
+		mfar: &MockMFARepository{
+			UpsertFunc: func(ctx context.Context, credential *domain.MFACredential) error {
+				stored = credential
+				return nil
+			},
+		},
+	}
+
+	setup, err := service.SetupMFA(context.Background(), uuid.New())
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if stored == nil || stored.Secret != setup.Secret {
+		t.Fatalf("expected the returned secret to be stored")
+	}
+	if setup.ProvisioningURI != totp.ProvisioningURI(mfaIssuer, "admin@example.com", setup.Secret) {
+		t.Fatalf("unexpected provisioning uri %q", setup.ProvisioningURI)
+	}
+}
+
+// TestDisableMFA_AdminNotAllowed tests that admins cannot turn off 2FA
+func TestDisableMFA_AdminNotAllowed(t *testing.T) {
+	service := &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id, Role: domain.UserRoleAdmin}, nil
+			},
+		},
+		mfar: &MockMFARepository{},
+	}
+
+	err := service.DisableMFA(context.Background(), uuid.New(), "123456")
+
+	if !errors.Is(err, errMFAMandatory) {
+		t.Fatalf("expected mandatory error, got %v", err)
+	}
+}
+
+// TestRefreshToken_AdminWithoutMFA tests that old admin sessions must enroll before refreshing
+func TestRefreshToken_AdminWithoutMFA(t *testing.T) {
+	userID := uuid.New()
+	service := &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id, Role: domain.UserRoleAdmin, IsActive: true}, nil
+			},
+		},
+		rtr: &MockRefreshTokenRepository{
+			FindByHashFunc: func(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+				return &domain.RefreshToken{
+					ID:        uuid.New(),
+					UserID:    userID,
+					FamilyID:  uuid.New(),
+					ExpiresAt: time.Now().Add(time.Hour),
+				}, nil
+			},
+		},
+		mfar: &MockMFARepository{},
+	}
+
+	_, err := service.RefreshToken(context.Background(), "raw")
+
+	if !errors.Is(err, errMFARequired) {
+		t.Fatalf("expected mfa required error, got %v", err)
+	}
+}
+
+// Note: LoginWithMFA, EnableMFA success and RegenerateRecoveryCodes run inside
+// a database transaction and should be tested through integration tests.
@@ -0,0 +1,158 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/oidc"
+)
+
+// The user has this long to finish logging in at the provider
+const oidcLoginStateTTL = 10 * time.Minute
+
+var (
+	errOIDCLoginExpired       = errors.New("login request expired or was already used")
+	errOIDCEmailNotVerified   = errors.New("the provider has not verified this email")
+	errNoAccountForIdentity   = errors.New("no account uses this email, please register first")
+	errAccountEmailUnverified = errors.New("verify your email before signing in with a provider")
+)
+
+// StartOIDCLogin returns the provider URL to redirect the user to
+func (s *userService) StartOIDCLogin(
+	ctx context.Context,
+	providerName string,
+) (string, error) {
+	provider, ok := s.oidc[providerName]
+	if !ok {
+		return "", domain.ErrUnknownOIDCProvider
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+
+	// The verifier never leaves BookNest, only its challenge does
+	if err := s.idr.CreateLoginState(ctx, &domain.OIDCLoginState{
+		StateHash:    s.generateTokenHash(state),
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(oidcLoginStateTTL),
+	}); err != nil {
+		return "", err
+	}
+
+	return provider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallenge(verifier))
+}
+
+// CompleteOIDCLogin handles the provider's callback and logs the linked user in
+func (s *userService) CompleteOIDCLogin(
+	ctx context.Context,
+	providerName string,
+	code string,
+	state string,
+) (domain.AuthTokens, error) {
+	provider, ok := s.oidc[providerName]
+	if !ok {
+		return domain.AuthTokens{}, domain.ErrUnknownOIDCProvider
+	}
+
+	// 1. The state proves the login was started here, and only once
+	loginState, err := s.idr.ConsumeLoginState(ctx, s.generateTokenHash(state))
+	if err != nil || loginState.Provider != providerName {
+		return domain.AuthTokens{}, errOIDCLoginExpired
+	}
+
+	// 2. Redeem the code with the PKCE verifier
+	identity, err := provider.Exchange(ctx, code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	// 3. Find or link the BookNest account
+	user, err := s.userForIdentity(ctx, providerName, identity)
+	if err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	if err := s.checkAccountStatus(user); err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	now := time.Now()
+	user.LastLogin = &now
+	if err := s.r.Update(ctx, &user); err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	// Enrolled users and admins still pass the second factor
+	return s.completeLogin(ctx, user)
+}
+
+// userForIdentity returns the user linked to the external account, linking
+// it first by email when both sides verified the address. Accounts are not
+// created here since they need a mobile number.
+func (s *userService) userForIdentity(
+	ctx context.Context,
+	providerName string,
+	identity domain.ExternalIdentity,
+) (domain.User, error) {
+	linked, err := s.idr.FindByProviderSubject(ctx, providerName, identity.Subject)
+	if err == nil {
+		return s.r.FindByID(ctx, linked.UserID)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.User{}, err
+	}
+
+	if !identity.EmailVerified || identity.Email == "" {
+		return domain.User{}, errOIDCEmailNotVerified
+	}
+
+	user, err := s.r.FindByEmail(ctx, identity.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.User{}, errNoAccountForIdentity
+	}
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	// Otherwise whoever registered someone else's address first would
+	// receive their provider logins
+	if !user.EmailVerified {
+		return domain.User{}, errAccountEmailUnverified
+	}
+
+	if err := s.idr.Create(ctx, &domain.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}); err != nil {
+		return domain.User{}, err
+	}
+
+	if err := s.ar.Create(ctx, &domain.AuditLog{
+		ActorID:      user.ID,
+		Action:       domain.AuditIdentityLinked,
+		TargetUserID: &user.ID,
+		Details:      map[string]string{"provider": providerName},
+	}); err != nil {
+		return domain.User{}, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,216 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/oidc"
+	"booknest/internal/pkg/oidc/oidctest"
+)
+
+// newOIDCTestService wires the service to a local stub provider named "stub".
+// Login states are kept in memory and can be used once.
+func newOIDCTestService(t *testing.T, users *MockUserRepository, identities *MockIdentityRepository) (*userService, *oidctest.Server) {
+	server := oidctest.NewServer("booknest")
+	t.Cleanup(server.Close)
+
+	provider, err := oidc.NewProvider(oidc.Config{
+		Name:        "stub",
+		Issuer:      server.URL,
+		ClientID:    "booknest",
+		RedirectURL: "http://booknest.test/auth/oidc/stub/callback",
+	}, server.Client())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	states := map[string]*domain.OIDCLoginState{}
+	identities.CreateLoginStateFunc = func(ctx context.Context, state *domain.OIDCLoginState) error {
+		states[state.StateHash] = state
+		return nil
+	}
+	identities.ConsumeLoginStateFunc = func(ctx context.Context, stateHash string) (*domain.OIDCLoginState, error) {
+		state, ok := states[stateHash]
+		if !ok {
+			return nil, errors.New("no rows in result set")
+		}
+		delete(states, stateHash)
+		return state, nil
+	}
+
+	service := &userService{
+		r:    users,
+		idr:  identities,
+		rtr:  &MockRefreshTokenRepository{},
+		sr:   &MockSessionRepository{},
+		mfar: &MockMFARepository{},
+		lar:  &MockLoginAttemptRepository{},
+		ar:   &MockAuditRepository{},
+		oidc: map[string]domain.OIDCProvider{"stub": provider},
+	}
+
+	return service, server
+}
+
+// loginAtStub follows the stub's redirect and returns the callback's code and state
+func loginAtStub(t *testing.T, service *userService) (string, string) {
+	authURL, err := service.StartOIDCLogin(context.Background(), "stub")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("expected a redirect, got %v", err)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+// TestOIDCLogin_LinksVerifiedEmail tests that the first provider login links the account
+func TestOIDCLogin_LinksVerifiedEmail(t *testing.T) {
+	user := domain.User{ID: uuid.New(), Email: "reader@example.com", EmailVerified: true, IsActive: true}
+	var linked *domain.UserIdentity
+
+	service, _ := newOIDCTestService(t,
+		&MockUserRepository{
+			FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
+				if email != user.Email {
+					t.Fatalf("expected lookup of %s, got %s", user.Email, email)
+				}
+				return user, nil
+			},
+			UpdateFunc: func(ctx context.Context, u *domain.User) error { return nil },
+		},
+		&MockIdentityRepository{
+			CreateFunc: func(ctx context.Context, identity *domain.UserIdentity) error {
+				linked = identity
+				return nil
+			},
+		},
+	)
+
+	code, state := loginAtStub(t, service)
+	tokens, err := service.CompleteOIDCLogin(context.Background(), "stub", code, state)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("expected BookNest tokens, got %+v", tokens)
+	}
+	if linked == nil || linked.UserID != user.ID || linked.Provider != "stub" || linked.Subject != "stub-user" {
+		t.Fatalf("expected the identity to be linked, got %+v", linked)
+	}
+}
+
+// TestOIDCLogin_LinkedIdentity tests that linked identities skip the email lookup
+func TestOIDCLogin_LinkedIdentity(t *testing.T) {
+	userID := uuid.New()
+
+	service, _ := newOIDCTestService(t,
+		&MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id, IsActive: true}, nil
+			},
+			FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
+				t.Fatalf("should not look up the email of a linked identity")
+				return domain.User{}, nil
+			},
+			UpdateFunc: func(ctx context.Context, u *domain.User) error { return nil },
+		},
+		&MockIdentityRepository{
+			FindByProviderSubjectFunc: func(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+				return &domain.UserIdentity{UserID: userID, Provider: provider, Subject: subject}, nil
+			},
+		},
+	)
+
+	code, state := loginAtStub(t, service)
+	if _, err := service.CompleteOIDCLogin(context.Background(), "stub", code, state); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+// TestOIDCLogin_UnverifiedAccountEmail tests that unverified BookNest emails are not linked
+func TestOIDCLogin_UnverifiedAccountEmail(t *testing.T) {
+	service, _ := newOIDCTestService(t,
+		&MockUserRepository{
+			FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
+				return domain.User{ID: uuid.New(), Email: email, IsActive: true}, nil
+			},
+		},
+		&MockIdentityRepository{
+			CreateFunc: func(ctx context.Context, identity *domain.UserIdentity) error {
+				t.Fatalf("should not link an unverified account")
+				return nil
+			},
+		},
+	)
+
+	code, state := loginAtStub(t, service)
+	_, err := service.CompleteOIDCLogin(context.Background(), "stub", code, state)
+
+	if !errors.Is(err, errAccountEmailUnverified) {
+		t.Fatalf("expected unverified email error, got %v", err)
+	}
+}
+
+// TestOIDCLogin_ProviderEmailUnverified tests that the provider must vouch for the email
+func TestOIDCLogin_ProviderEmailUnverified(t *testing.T) {
+	service, server := newOIDCTestService(t, &MockUserRepository{}, &MockIdentityRepository{})
+	server.SetIdentity(oidctest.Identity{Subject: "1", Email: "reader@example.com"})
+
+	code, state := loginAtStub(t, service)
+	_, err := service.CompleteOIDCLogin(context.Background(), "stub", code, state)
+
+	if !errors.Is(err, errOIDCEmailNotVerified) {
+		t.Fatalf("expected provider email error, got %v", err)
+	}
+}
+
+// TestOIDCLogin_ReplayedState tests that a callback cannot be used twice
+func TestOIDCLogin_ReplayedState(t *testing.T) {
+	service, _ := newOIDCTestService(t,
+		&MockUserRepository{
+			FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
+				return domain.User{}, errors.New("lookup failed")
+			},
+		},
+		&MockIdentityRepository{},
+	)
+
+	code, state := loginAtStub(t, service)
+	_, _ = service.CompleteOIDCLogin(context.Background(), "stub", code, state)
+	_, err := service.CompleteOIDCLogin(context.Background(), "stub", code, state)
+
+	if !errors.Is(err, errOIDCLoginExpired) {
+		t.Fatalf("expected expired login error, got %v", err)
+	}
+}
+
+// TestOIDCLogin_UnknownProvider tests that only configured providers are used
+func TestOIDCLogin_UnknownProvider(t *testing.T) {
+	service := &userService{}
+
+	if _, err := service.StartOIDCLogin(context.Background(), "myspace"); !errors.Is(err, domain.ErrUnknownOIDCProvider) {
+		t.Fatalf("expected unknown provider error, got %v", err)
+	}
+	if _, err := service.CompleteOIDCLogin(context.Background(), "myspace", "code", "state"); !errors.Is(err, domain.ErrUnknownOIDCProvider) {
+		t.Fatalf("expected unknown provider error, got %v", err)
+	}
+}
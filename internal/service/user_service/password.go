@@ -0,0 +1,96 @@
+package user_service
+
+import (
+	"context"
+	"log/slog"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/password"
+)
+
+var defaultPasswordPolicy = password.Default()
+
+func (s userService) passwordPolicy() *password.Policy {
+	if s.passwords == nil {
+		return defaultPasswordPolicy
+	}
+	return s.passwords
+}
+
+func (s userService) hashPassword(raw string) (string, error) {
+	return s.passwordPolicy().Hash(raw)
+}
+
+func (s userService) comparePassword(hash, raw string) bool {
+	// Hashes of every supported algorithm verify, whatever is configured
+	return s.passwordPolicy().Verify(hash, raw)
+}
+
+// checkPasswordStrength applies the policy, refusing passwords built
+// from the account's own details
+func (s userService) checkPasswordStrength(raw string, user domain.User) error {
+	return s.passwordPolicy().Check(raw, user.Email, user.FirstName, user.LastName, user.Mobile)
+}
+
+// rehashPassword upgrades a hash made with an older algorithm or cost
+// while the raw password is at hand. The caller stores the user.
+func (s userService) rehashPassword(user *domain.User, raw string) {
+	if !s.passwordPolicy().NeedsRehash(user.Password) {
+		return
+	}
+
+	hashed, err := s.hashPassword(raw)
+	if err != nil {
+		// The old hash still works, so the login goes on
+		slog.Error("Cannot rehash the password", "user_id", user.ID, "error", err)
+		return
+	}
+	user.Password = hashed
+}
+
+// changePassword checks newPassword against the policy and the recent
+// passwords, then replaces the hash and keeps the old one in the history.
+// It must run in a transaction; the caller stores the user.
+func (s *userService) changePassword(
+	ctx context.Context,
+	user *domain.User,
+	newPassword string,
+) error {
+	if err := s.checkPasswordStrength(newPassword, *user); err != nil {
+		return err
+	}
+
+	historySize := s.passwordPolicy().HistorySize
+	if historySize > 0 {
+		// The current password counts as the most recent one
+		recent := []string{user.Password}
+		previous, err := s.phr.ListRecent(ctx, user.ID, historySize-1)
+		if err != nil {
+			return err
+		}
+		recent = append(recent, previous...)
+
+		for _, hash := range recent {
+			if hash != "" && s.comparePassword(hash, newPassword) {
+				return domain.ErrPasswordReused
+			}
+		}
+	}
+
+	hashed, err := s.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	if historySize > 1 && user.Password != "" {
+		if err := s.phr.Add(ctx, user.ID, user.Password); err != nil {
+			return err
+		}
+		if err := s.phr.Prune(ctx, user.ID, historySize-1); err != nil {
+			return err
+		}
+	}
+
+	user.Password = hashed
+	return nil
+}
@@ -0,0 +1,133 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"booknest/internal/domain"
+)
+
+// TestChangePassword_Reused tests that the current and recent passwords are refused
+func TestChangePassword_Reused(t *testing.T) {
+	current := mustHashPassword(t, "Current-secret-1")
+	previous := mustHashPassword(t, "Previous-secret-2")
+
+	for _, reused := range []string{"Current-secret-1", "Previous-secret-2"} {
+		t.Run(reused, func(t *testing.T) {
+			service := &userService{
+				phr: &MockPasswordHistoryRepository{
+					ListRecentFunc: func(ctx context.Context, userID uuid.UUID, limit int) ([]string, error) {
+						if limit != defaultPasswordPolicy.HistorySize-1 {
+							t.Fatalf("expected %d previous passwords, got %d", defaultPasswordPolicy.HistorySize-1, limit)
+						}
+						return []string{previous}, nil
+					},
+					AddFunc: func(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+						t.Fatalf("should not record a refused password")
+						return nil
+					},
+				},
+			}
+
+			user := domain.User{ID: uuid.New(), Password: current}
+			err := service.changePassword(context.Background(), &user, reused)
+
+			if !errors.Is(err, domain.ErrPasswordReused) {
+				t.Fatalf("expected reused password error, got %v", err)
+			}
+			if user.Password != current {
+				t.Fatalf("expected the password to stay unchanged")
+			}
+		})
+	}
+}
+
+// TestChangePassword_Weak tests that the strength policy is applied
+func TestChangePassword_Weak(t *testing.T) {
+	service := &userService{phr: &MockPasswordHistoryRepository{}}
+
+	user := domain.User{ID: uuid.New(), Email: "reader@example.com"}
+	for _, weak := range []string{"short", "onlyletters", "Reader-2024"} {
+		if err := service.changePassword(context.Background(), &user, weak); !errors.Is(err, domain.ErrWeakPassword) {
+			t.Fatalf("expected %q to be too weak, got %v", weak, err)
+		}
+	}
+}
+
+// TestChangePassword_KeepsPrevious tests that the replaced hash goes into the history
+func TestChangePassword_KeepsPrevious(t *testing.T) {
+	current := mustHashPassword(t, "Current-secret-1")
+	var added string
+	pruned := -1
+	service := &userService{
+		phr: &MockPasswordHistoryRepository{
+			AddFunc: func(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+				added = passwordHash
+				return nil
+			},
+			PruneFunc: func(ctx context.Context, userID uuid.UUID, keep int) error {
+				pruned = keep
+				return nil
+			},
+		},
+	}
+
+	user := domain.User{ID: uuid.New(), Password: current}
+	if err := service.changePassword(context.Background(), &user, "Brand-new-secret-3"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if added != current {
+		t.Fatalf("expected the old hash to be kept")
+	}
+	if pruned != defaultPasswordPolicy.HistorySize-1 {
+		t.Fatalf("expected the history to be pruned to %d, got %d", defaultPasswordPolicy.HistorySize-1, pruned)
+	}
+	if !service.comparePassword(user.Password, "Brand-new-secret-3") {
+		t.Fatalf("expected the new password to be set")
+	}
+}
+
+// Note: ResetPassword and ResetPasswordWithToken run changePassword in a
+// transaction and are covered by integration tests.
+
+// TestLogin_RehashesLegacyHash tests that bcrypt hashes are upgraded on login
+func TestLogin_RehashesLegacyHash(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+
+	var stored string
+	service := &userService{
+		r: &MockUserRepository{
+			FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
+				return domain.User{ID: uuid.New(), Email: email, Password: string(legacy), Role: domain.UserRoleUser, IsActive: true}, nil
+			},
+			UpdateFunc: func(ctx context.Context, user *domain.User) error {
+				stored = user.Password
+				return nil
+			},
+		},
+		rtr:  &MockRefreshTokenRepository{},
+		sr:   &MockSessionRepository{},
+		mfar: &MockMFARepository{},
+		lar:  &MockLoginAttemptRepository{},
+	}
+
+	if _, err := service.Login(context.Background(), domain.LoginInput{Email: "test@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !strings.HasPrefix(stored, "$argon2id$") {
+		t.Fatalf("expected an argon2id hash to be stored, got %q", stored)
+	}
+	if !service.comparePassword(stored, "password123") {
+		t.Fatalf("expected the new hash to verify")
+	}
+}
@@ -0,0 +1,203 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/util"
+)
+
+var (
+	errEmailInUse  = errors.New("email already in use")
+	errMobileInUse = errors.New("mobile already in use")
+)
+
+// UpdateProfile changes the names right away. A new email or mobile is
+// only stored as pending and replaces the current one once the code sent
+// to the new address is verified, so a typo cannot lock the user out.
+// Submitting the current address again cancels a pending change.
+func (s *userService) UpdateProfile(
+	ctx context.Context,
+	userID uuid.UUID,
+	in domain.ProfileInput,
+) (domain.User, error) {
+	user, err := s.r.FindByID(ctx, userID)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	newEmail := in.Email != nil && *in.Email != user.Email
+	newMobile := in.Mobile != nil && *in.Mobile != user.Mobile
+
+	if newEmail {
+		if err := s.verificationPolicy().Check(domain.ActionChangeEmail, user); err != nil {
+			return domain.User{}, err
+		}
+
+		taken, err := addressTaken(s.r.FindByEmail(ctx, *in.Email))
+		if err != nil {
+			return domain.User{}, err
+		}
+		if taken {
+			return domain.User{}, errEmailInUse
+		}
+	}
+	if newMobile {
+		taken, err := addressTaken(s.r.FindByMobile(ctx, *in.Mobile))
+		if err != nil {
+			return domain.User{}, err
+		}
+		if taken {
+			return domain.User{}, errMobileInUse
+		}
+	}
+
+	var emailCode, mobileOTP string
+
+	err = util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		// 1. Names need no verification
+		if in.FirstName != nil || in.LastName != nil {
+			if in.FirstName != nil {
+				user.FirstName = *in.FirstName
+			}
+			if in.LastName != nil {
+				user.LastName = *in.LastName
+			}
+			if err := s.r.Update(txCtx, &user); err != nil {
+				return err
+			}
+		}
+
+		// 2. Replace or cancel the pending email
+		if in.Email != nil && (newEmail || user.PendingEmail != nil) {
+			var pending *string
+			if newEmail {
+				pending = in.Email
+			}
+			if err := s.r.SetPendingEmail(txCtx, user.ID, pending); err != nil {
+				return err
+			}
+			if err := s.vtr.InvalidateByUserAndType(txCtx, user.ID, domain.VerificationEmail); err != nil {
+				return err
+			}
+			user.PendingEmail = pending
+
+			if newEmail {
+				code, err := s.generateRawToken()
+				if err != nil {
+					return err
+				}
+				emailCode = code
+
+				if err := s.vtr.Create(txCtx, &domain.VerificationToken{
+					UserID:    user.ID,
+					Type:      domain.VerificationEmail,
+					TokenHash: s.generateTokenHash(emailCode),
+					ExpiresAt: time.Now().Add(24 * time.Hour),
+				}); err != nil {
+					return err
+				}
+			}
+		}
+
+		// 3. Replace or cancel the pending mobile
+		if in.Mobile != nil && (newMobile || user.PendingMobile != nil) {
+			var pending *string
+			if newMobile {
+				pending = in.Mobile
+			}
+			if err := s.r.SetPendingMobile(txCtx, user.ID, pending); err != nil {
+				return err
+			}
+			if err := s.vtr.InvalidateByUserAndType(txCtx, user.ID, domain.VerificationMobile); err != nil {
+				return err
+			}
+			user.PendingMobile = pending
+
+			if newMobile {
+				mobileOTP = s.generateOTP(6)
+				return s.vtr.Create(txCtx, &domain.VerificationToken{
+					UserID:    user.ID,
+					Type:      domain.VerificationMobile,
+					TokenHash: s.generateTokenHash(mobileOTP),
+					ExpiresAt: time.Now().Add(5 * time.Minute),
+				})
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	// The codes go to the new addresses
+	if emailCode != "" {
+		go s.sendEmailVerification(pendingRecipient(user), emailCode)
+	}
+	if mobileOTP != "" {
+		go s.sendMobileVerification(pendingRecipient(user), mobileOTP)
+	}
+
+	return user, nil
+}
+
+// addressTaken takes the result of a lookup by email or mobile
+func addressTaken(_ domain.User, err error) (bool, error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// markVerified sets the verification flag, or moves the pending address
+// into place when the token was sent for an address change
+func (s *userService) markVerified(
+	ctx context.Context,
+	user *domain.User,
+	tokenType domain.VerificationTokenType,
+) error {
+	var confirmed bool
+	var err error
+
+	switch {
+	case tokenType == domain.VerificationEmail && user.PendingEmail != nil:
+		confirmed, err = s.r.ConfirmPendingEmail(ctx, user.ID)
+	case tokenType == domain.VerificationMobile && user.PendingMobile != nil:
+		confirmed, err = s.r.ConfirmPendingMobile(ctx, user.ID)
+	case tokenType == domain.VerificationEmail:
+		user.EmailVerified = true
+		return s.r.Update(ctx, user)
+	case tokenType == domain.VerificationMobile:
+		user.MobileVerified = true
+		return s.r.Update(ctx, user)
+	default:
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		// The change was cancelled in the meantime
+		return errInvalidOrExpiredToken
+	}
+	return nil
+}
+
+// pendingRecipient returns user with the pending addresses in place, for
+// sending verification codes to them
+func pendingRecipient(user domain.User) domain.User {
+	if user.PendingEmail != nil {
+		user.Email = *user.PendingEmail
+	}
+	if user.PendingMobile != nil {
+		user.Mobile = *user.PendingMobile
+	}
+	return user
+}
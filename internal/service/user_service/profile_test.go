@@ -0,0 +1,191 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/verification"
+)
+
+// TestUpdateProfile_EmailInUse tests that another account's email is rejected
+func TestUpdateProfile_EmailInUse(t *testing.T) {
+	userID := uuid.New()
+	taken := "taken@example.com"
+	service := &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id, Email: "me@example.com", MobileVerified: true}, nil
+			},
+			FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
+				return domain.User{ID: uuid.New(), Email: email}, nil
+			},
+			SetPendingEmailFunc: func(ctx context.Context, id uuid.UUID, email *string) error {
+				t.Fatalf("should not store the email")
+				return nil
+			},
+		},
+	}
+
+	_, err := service.UpdateProfile(context.Background(), userID, domain.ProfileInput{Email: &taken})
+
+	if !errors.Is(err, errEmailInUse) {
+		t.Fatalf("expected email in use error, got %v", err)
+	}
+}
+
+// TestUpdateProfile_EmailChangeNeedsVerifiedMobile tests that the email
+// cannot be changed before the mobile is verified
+func TestUpdateProfile_EmailChangeNeedsVerifiedMobile(t *testing.T) {
+	email := "new@example.com"
+	service := &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id, Email: "me@example.com", EmailVerified: true}, nil
+			},
+			FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
+				t.Fatalf("should not look up the new email")
+				return domain.User{}, nil
+			},
+		},
+	}
+
+	_, err := service.UpdateProfile(context.Background(), uuid.New(), domain.ProfileInput{Email: &email})
+
+	var verifyErr *domain.VerificationRequiredError
+	if !errors.As(err, &verifyErr) {
+		t.Fatalf("expected verification required error, got %v", err)
+	}
+	if len(verifyErr.Missing) != 1 || verifyErr.Missing[0] != domain.ChannelMobile {
+		t.Fatalf("expected the mobile to be missing, got %v", verifyErr.Missing)
+	}
+}
+
+// TestUpdateProfile_LookupError tests that lookup failures are not mistaken for a free address
+func TestUpdateProfile_LookupError(t *testing.T) {
+	mobile := "+15550001234"
+	service := &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id, Mobile: "+15559999999"}, nil
+			},
+			FindByMobileFunc: func(ctx context.Context, mobile string) (domain.User, error) {
+				return domain.User{}, errors.New("connection reset")
+			},
+		},
+	}
+
+	_, err := service.UpdateProfile(context.Background(), uuid.New(), domain.ProfileInput{Mobile: &mobile})
+
+	if err == nil || errors.Is(err, errMobileInUse) {
+		t.Fatalf("expected the lookup error, got %v", err)
+	}
+}
+
+// Note: the profile update itself runs in a transaction and is covered
+// by integration tests.
+
+// TestMarkVerified_PendingEmail tests that verifying a changed email moves it into place
+func TestMarkVerified_PendingEmail(t *testing.T) {
+	pending := "new@example.com"
+	confirmed := false
+	service := &userService{
+		r: &MockUserRepository{
+			ConfirmPendingEmailFunc: func(ctx context.Context, id uuid.UUID) (bool, error) {
+				confirmed = true
+				return true, nil
+			},
+			UpdateFunc: func(ctx context.Context, user *domain.User) error {
+				t.Fatalf("should not update the current email's flag")
+				return nil
+			},
+		},
+	}
+
+	user := domain.User{ID: uuid.New(), Email: "old@example.com", EmailVerified: true, PendingEmail: &pending}
+	if err := service.markVerified(context.Background(), &user, domain.VerificationEmail); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !confirmed {
+		t.Fatalf("expected the pending email to be confirmed")
+	}
+}
+
+// TestMarkVerified_ChangeCancelled tests that a token for a cancelled change is rejected
+func TestMarkVerified_ChangeCancelled(t *testing.T) {
+	pending := "+15550001234"
+	service := &userService{
+		r: &MockUserRepository{
+			ConfirmPendingMobileFunc: func(ctx context.Context, id uuid.UUID) (bool, error) {
+				return false, nil
+			},
+		},
+	}
+
+	user := domain.User{ID: uuid.New(), PendingMobile: &pending}
+	err := service.markVerified(context.Background(), &user, domain.VerificationMobile)
+
+	if !errors.Is(err, errInvalidOrExpiredToken) {
+		t.Fatalf("expected invalid token error, got %v", err)
+	}
+}
+
+// TestMarkVerified_NoPendingChange tests that plain verification sets the flag
+func TestMarkVerified_NoPendingChange(t *testing.T) {
+	var updated domain.User
+	service := &userService{
+		r: &MockUserRepository{
+			UpdateFunc: func(ctx context.Context, user *domain.User) error {
+				updated = *user
+				return nil
+			},
+		},
+	}
+
+	user := domain.User{ID: uuid.New()}
+	if err := service.markVerified(context.Background(), &user, domain.VerificationMobile); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !updated.MobileVerified || updated.EmailVerified {
+		t.Fatalf("expected only the mobile to be verified, got %+v", updated)
+	}
+}
+
+// TestAddressTaken tests how lookups by address are interpreted
+func TestAddressTaken(t *testing.T) {
+	if taken, err := addressTaken(domain.User{}, gorm.ErrRecordNotFound); taken || err != nil {
+		t.Fatalf("expected a free address, got %v %v", taken, err)
+	}
+	if taken, err := addressTaken(domain.User{ID: uuid.New()}, nil); !taken || err != nil {
+		t.Fatalf("expected a taken address, got %v %v", taken, err)
+	}
+}
+
+// TestCheckVerified tests that the configured policy is applied to the stored user
+func TestCheckVerified(t *testing.T) {
+	service := &userService{
+		r: &MockUserRepository{
+			FindByIDFunc: func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+				return domain.User{ID: id, EmailVerified: true}, nil
+			},
+		},
+		verification: verification.Policy{domain.ActionCheckout: {domain.ChannelEmail}},
+	}
+
+	if err := service.CheckVerified(context.Background(), uuid.New(), domain.ActionCheckout); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	service.verification = nil
+	err := service.CheckVerified(context.Background(), uuid.New(), domain.ActionCheckout)
+	var verifyErr *domain.VerificationRequiredError
+	if !errors.As(err, &verifyErr) || verifyErr.Missing[0] != domain.ChannelMobile {
+		t.Fatalf("expected the default policy to need the mobile, got %v", err)
+	}
+}
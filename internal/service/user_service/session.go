@@ -0,0 +1,104 @@
+package user_service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/util"
+)
+
+func (s *userService) ListSessions(
+	ctx context.Context,
+	userID uuid.UUID,
+	currentID uuid.UUID,
+) ([]domain.Session, error) {
+	sessions, err := s.sr.ListActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if sessions == nil {
+		sessions = make([]domain.Session, 0)
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession logs one device out; its access tokens stop working at once
+func (s *userService) RevokeSession(
+	ctx context.Context,
+	userID uuid.UUID,
+	sessionID uuid.UUID,
+) error {
+	return util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		revoked, err := s.sr.Revoke(txCtx, userID, sessionID)
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return domain.ErrSessionNotFound
+		}
+
+		return s.rtr.RevokeFamily(txCtx, sessionID)
+	})
+}
+
+// startSession records a completed login and warns the user about
+// logins from a device they never used before
+func (s *userService) startSession(
+	ctx context.Context,
+	user domain.User,
+) (*domain.Session, error) {
+	session := &domain.Session{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		UserAgent: domain.UserAgentFromContext(ctx),
+		IPAddress: domain.ClientIPFromContext(ctx),
+	}
+
+	known, err := s.sr.IsKnownDevice(ctx, user.ID, session.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sr.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	if !known {
+		go s.sendNewDeviceAlert(user, *session)
+	}
+
+	return session, nil
+}
+
+// endSession revokes a session together with its refresh tokens
+func (s *userService) endSession(
+	ctx context.Context,
+	userID uuid.UUID,
+	sessionID uuid.UUID,
+) error {
+	if err := s.rtr.RevokeFamily(ctx, sessionID); err != nil {
+		return err
+	}
+
+	_, err := s.sr.Revoke(ctx, userID, sessionID)
+	return err
+}
+
+// endAllSessions logs the user out on every device
+func (s *userService) endAllSessions(
+	ctx context.Context,
+	userID uuid.UUID,
+) error {
+	if err := s.rtr.RevokeAllByUser(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.sr.RevokeAllByUser(ctx, userID)
+}
@@ -0,0 +1,103 @@
+package user_service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+)
+
+// TestStartSession_NewDeviceAlert tests that a login from an unknown device is reported by email
+func TestStartSession_NewDeviceAlert(t *testing.T) {
+	sent := make(chan map[string]any, 1)
+	service := &userService{
+		sr: &MockSessionRepository{
+			IsKnownDeviceFunc: func(ctx context.Context, userID uuid.UUID, userAgent string) (bool, error) {
+				return false, nil
+			},
+		},
+		notifier: &MockNotifier{
+			EmailFunc: func(ctx context.Context, to string, template domain.NotificationTemplate, data any) error {
+				if template != domain.TemplateNewDevice {
+					t.Errorf("unexpected template %s", template)
+				}
+				sent <- data.(map[string]any)
+				return nil
+			},
+		},
+	}
+
+	ctx := domain.WithUserAgent(domain.WithClientIP(context.Background(), "203.0.113.7"), "phone")
+	session, err := service.startSession(ctx, domain.User{ID: uuid.New(), Email: "me@example.com"})
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if session.UserAgent != "phone" || session.IPAddress != "203.0.113.7" {
+		t.Fatalf("expected the client info to be stored, got %+v", session)
+	}
+
+	select {
+	case data := <-sent:
+		if data["UserAgent"] != "phone" || data["IPAddress"] != "203.0.113.7" {
+			t.Fatalf("expected the device in the email, got %v", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected a new device email")
+	}
+}
+
+// TestStartSession_KnownDevice tests that known devices log in quietly
+func TestStartSession_KnownDevice(t *testing.T) {
+	service := &userService{
+		sr: &MockSessionRepository{},
+		notifier: &MockNotifier{
+			EmailFunc: func(ctx context.Context, to string, template domain.NotificationTemplate, data any) error {
+				t.Errorf("should not send an email for a known device")
+				return nil
+			},
+		},
+	}
+
+	if _, err := service.startSession(context.Background(), domain.User{ID: uuid.New()}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+// TestListSessions_MarksCurrent tests that the caller's own session is flagged
+func TestListSessions_MarksCurrent(t *testing.T) {
+	current := uuid.New()
+	service := &userService{
+		sr: &MockSessionRepository{
+			ListActiveByUserFunc: func(ctx context.Context, userID uuid.UUID) ([]domain.Session, error) {
+				return []domain.Session{{ID: uuid.New()}, {ID: current}}, nil
+			},
+		},
+	}
+
+	sessions, err := service.ListSessions(context.Background(), uuid.New(), current)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if sessions[0].Current || !sessions[1].Current {
+		t.Fatalf("expected only the second session to be current, got %+v", sessions)
+	}
+}
+
+// TestListSessions_Empty tests that no sessions are returned as an empty list
+func TestListSessions_Empty(t *testing.T) {
+	service := &userService{sr: &MockSessionRepository{}}
+
+	sessions, err := service.ListSessions(context.Background(), uuid.New(), uuid.Nil)
+
+	if err != nil || sessions == nil {
+		t.Fatalf("expected an empty list, got %v %v", sessions, err)
+	}
+}
+
+// Note: revoking a single session runs in a transaction and is covered
+// by integration tests.
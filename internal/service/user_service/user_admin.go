@@ -0,0 +1,171 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/util"
+)
+
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+)
+
+var errCannotSuspendSelf = errors.New("admins cannot suspend themselves")
+
+func (s *userService) SearchUsers(
+	ctx context.Context,
+	filter domain.UserFilter,
+	pagination domain.QueryOptions,
+) (*domain.UserSearchResult, error) {
+	if pagination.Limit == 0 {
+		pagination.Limit = defaultUserPageSize
+	}
+	if pagination.Limit > maxUserPageSize {
+		pagination.Limit = maxUserPageSize
+	}
+
+	page, total, err := s.r.Search(ctx, filter, pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.UserSearchResult{
+		Items:       page.Items,
+		Total:       total,
+		Limit:       pagination.Limit,
+		Offset:      pagination.Offset,
+		PageCursors: page.PageCursors,
+	}, nil
+}
+
+func (s *userService) SuspendUser(
+	ctx context.Context,
+	adminID uuid.UUID,
+	userID uuid.UUID,
+	reason string,
+) error {
+	if adminID == userID {
+		return errCannotSuspendSelf
+	}
+
+	user, err := s.r.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		// 1. Block new logins
+		if err := s.r.SetActive(txCtx, user.ID, false); err != nil {
+			return err
+		}
+
+		// 2. End the sessions; access tokens run out on their own
+		if err := s.endAllSessions(txCtx, user.ID); err != nil {
+			return err
+		}
+
+		// 3. Record the suspension
+		return s.ar.Create(txCtx, &domain.AuditLog{
+			ActorID:      adminID,
+			Action:       domain.AuditUserSuspended,
+			TargetUserID: &user.ID,
+			Details:      map[string]string{"reason": reason},
+		})
+	})
+}
+
+func (s *userService) ReactivateUser(
+	ctx context.Context,
+	adminID uuid.UUID,
+	userID uuid.UUID,
+) error {
+	user, err := s.r.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		if err := s.r.SetActive(txCtx, user.ID, true); err != nil {
+			return err
+		}
+
+		return s.ar.Create(txCtx, &domain.AuditLog{
+			ActorID:      adminID,
+			Action:       domain.AuditUserReactivated,
+			TargetUserID: &user.ID,
+		})
+	})
+}
+
+func (s *userService) ForcePasswordReset(
+	ctx context.Context,
+	adminID uuid.UUID,
+	userID uuid.UUID,
+) error {
+	user, err := s.r.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	rawToken, err := s.generateRawToken()
+	if err != nil {
+		return err
+	}
+
+	err = util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		// 1. Block logins with the current password
+		if err := s.r.SetPasswordResetRequired(txCtx, user.ID, true); err != nil {
+			return err
+		}
+
+		// 2. End the sessions
+		if err := s.endAllSessions(txCtx, user.ID); err != nil {
+			return err
+		}
+
+		// 3. Replace any pending reset token
+		if err := s.vtr.InvalidateByUserAndType(txCtx, user.ID, domain.PasswordReset); err != nil {
+			return err
+		}
+		if err := s.vtr.Create(txCtx, &domain.VerificationToken{
+			UserID:    user.ID,
+			Type:      domain.PasswordReset,
+			TokenHash: s.generateTokenHash(rawToken),
+			ExpiresAt: time.Now().Add(30 * time.Minute),
+		}); err != nil {
+			return err
+		}
+
+		// 4. Record who forced the reset
+		return s.ar.Create(txCtx, &domain.AuditLog{
+			ActorID:      adminID,
+			Action:       domain.AuditPasswordResetForced,
+			TargetUserID: &user.ID,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	go s.sendCode(user, true, domain.TemplatePasswordReset, rawToken)
+	return nil
+}
+
+// checkAccountStatus rejects suspended users and users who must reset
+// their password. It runs after the credentials were checked, so the
+// status is not revealed to someone guessing passwords.
+func (s *userService) checkAccountStatus(user domain.User) error {
+	if !user.IsActive {
+		return domain.ErrAccountSuspended
+	}
+	if user.PasswordResetRequired {
+		return domain.ErrPasswordResetRequired
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package user_service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+)
+
+// TestSearchUsers_ClampsLimit tests the default and maximum page sizes
+func TestSearchUsers_ClampsLimit(t *testing.T) {
+	var limits []uint64
+	service := &userService{
+		r: &MockUserRepository{
+			SearchFunc: func(ctx context.Context, filter domain.UserFilter, pagination domain.QueryOptions) (domain.Page[domain.User], int64, error) {
+				limits = append(limits, pagination.Limit)
+				return domain.Page[domain.User]{}, 0, nil
+			},
+		},
+	}
+
+	for _, limit := range []uint64{0, 500, 10} {
+		result, err := service.SearchUsers(context.Background(), domain.UserFilter{}, domain.QueryOptions{Limit: limit})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if result.Limit != limits[len(limits)-1] {
+			t.Fatalf("expected result limit %d, got %d", limits[len(limits)-1], result.Limit)
+		}
+	}
+
+	if limits[0] != defaultUserPageSize || limits[1] != maxUserPageSize || limits[2] != 10 {
+		t.Fatalf("unexpected limits %v", limits)
+	}
+}
+
+// TestSuspendUser_Self tests that admins cannot lock themselves out
+func TestSuspendUser_Self(t *testing.T) {
+	adminID := uuid.New()
+	service := &userService{
+		r: &MockUserRepository{
+			SetActiveFunc: func(ctx context.Context, id uuid.UUID, active bool) error {
+				t.Fatalf("should not change the account")
+				return nil
+			},
+		},
+	}
+
+	err := service.SuspendUser(context.Background(), adminID, adminID, "")
+
+	if !errors.Is(err, errCannotSuspendSelf) {
+		t.Fatalf("expected self suspension error, got %v", err)
+	}
+}
+
+// Note: the suspend, reactivate and forced reset paths run in a
+// transaction and are covered by integration tests.
+
+// TestLogin_Suspended tests that suspended users cannot log in
+func TestLogin_Suspended(t *testing.T) {
+	password := "password123"
+	service := &userService{
+		r: &MockUserRepository{
+			FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
+				return domain.User{
+					ID:       uuid.New(),
+					Email:    email,
+					Password: mustHashPassword(t, password),
+					IsActive: false,
+				}, nil
+			},
+			UpdateFunc: func(ctx context.Context, user *domain.User) error {
+				t.Fatalf("should not record the login")
+				return nil
+			},
+		},
+		lar: &MockLoginAttemptRepository{},
+	}
+
+	_, err := service.Login(context.Background(), domain.LoginInput{
+		Email:    "test@example.com",
+		Password: password,
+	})
+
+	if !errors.Is(err, domain.ErrAccountSuspended) {
+		t.Fatalf("expected suspended error, got %v", err)
+	}
+}
+
+// TestLogin_PasswordResetRequired tests that a forced reset blocks the old password
+func TestLogin_PasswordResetRequired(t *testing.T) {
+	password := "password123"
+	service := &userService{
+		r: &MockUserRepository{
+			FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
+				return domain.User{
+					ID:                    uuid.New(),
+					Email:                 email,
+					Password:              mustHashPassword(t, password),
+					IsActive:              true,
+					PasswordResetRequired: true,
+				}, nil
+			},
+		},
+		lar: &MockLoginAttemptRepository{},
+	}
+
+	_, err := service.Login(context.Background(), domain.LoginInput{
+		Email:    "test@example.com",
+		Password: password,
+	})
+
+	if !errors.Is(err, domain.ErrPasswordResetRequired) {
+		t.Fatalf("expected password reset error, got %v", err)
+	}
+}
@@ -7,33 +7,26 @@ import (
 	"encoding/hex"
 	"errors"
 	"io"
-	"log/slog"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 
 	"booknest/internal/domain"
+	"booknest/internal/pkg/jwtkeys"
 	"booknest/internal/pkg/util"
 )
 
-func (s userService) hashPassword(p string) string {
-	// Generate bcrypt hash
-	hashed, err := bcrypt.GenerateFromPassword([]byte(p), bcrypt.MinCost)
-	if err != nil {
-		slog.Error("Cannot hash the password", "error", err)
-		return ""
-	}
-
-	// Return the hashed password as a string
-	return string(hashed)
-}
+const (
+	// Access tokens are short-lived; revoking their session rejects them early
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
 
-func (s userService) comparePassword(hash, raw string) bool {
-	// Compare the hashed password with the raw password
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(raw)) == nil
-}
+var (
+	errRefreshTokenReused    = errors.New("refresh token reuse detected")
+	errInvalidOrExpiredToken = errors.New("invalid or expired token")
+)
 
 func (s userService) generateRawToken() (string, error) {
 	// Generate a secure random token
@@ -79,15 +72,11 @@ func (s userService) generateTokenHash(rawToken string) string {
 	return hex.EncodeToString(hash[:])
 }
 
-func (s userService) generateJWT(user domain.User) (string, error) {
-	// Generate JWT token for the user
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		// Backward-compatible env key used in older local setups.
-		secret = os.Getenv("JWT_AUTH_SECRET")
-	}
-	if secret == "" {
-		secret = "booknest_secret" // fallback for local dev
+func (s userService) generateJWT(user domain.User, sessionID uuid.UUID) (string, error) {
+	// Load the signing keys (RS256/EdDSA, or the legacy HS256 secret)
+	keys, err := jwtkeys.FromEnv()
+	if err != nil {
+		return "", err
 	}
 
 	// Create JWT claims
@@ -95,14 +84,112 @@ func (s userService) generateJWT(user domain.User) (string, error) {
 		"user_id":   user.ID.String(),
 		"user_role": user.Role,
 		"email":     user.Email,
-		"exp":       time.Now().Add(24 * time.Hour).Unix(),
+		"sid":       sessionID.String(),
+		"exp":       time.Now().Add(accessTokenTTL).Unix(),
 		"iat":       time.Now().Unix(),
 	}
 
-	// Create the token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	// Sign the token with the secret
-	return token.SignedString([]byte(secret))
+	// Sign the token with the current signing key
+	return keys.Sign(claims)
+}
+
+func (s userService) generateMFAPendingJWT(user domain.User) (string, error) {
+	keys, err := jwtkeys.FromEnv()
+	if err != nil {
+		return "", err
+	}
+
+	// The token_use claim keeps this token out of regular routes
+	claims := jwt.MapClaims{
+		"user_id":   user.ID.String(),
+		"user_role": user.Role,
+		"email":     user.Email,
+		"token_use": domain.TokenUseMFAPending,
+		"exp":       time.Now().Add(mfaPendingTTL).Unix(),
+		"iat":       time.Now().Unix(),
+	}
+
+	return keys.Sign(claims)
+}
+
+func (s userService) parseMFAPendingJWT(raw string) (uuid.UUID, error) {
+	keys, err := jwtkeys.FromEnv()
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	token, err := keys.Parse(raw)
+	if err != nil || !token.Valid {
+		return uuid.Nil, errInvalidMFAToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["token_use"] != domain.TokenUseMFAPending {
+		return uuid.Nil, errInvalidMFAToken
+	}
+
+	userID, _ := claims["user_id"].(string)
+	return uuid.Parse(userID)
+}
+
+func (s *userService) createRefreshToken(
+	ctx context.Context,
+	userID uuid.UUID,
+	familyID uuid.UUID,
+) (string, *domain.RefreshToken, error) {
+	// Refresh tokens are opaque and only their hash is stored
+	rawToken, err := s.generateRawToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	token := &domain.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: s.generateTokenHash(rawToken),
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	}
+
+	if err := s.rtr.Create(ctx, token); err != nil {
+		return "", nil, err
+	}
+
+	return rawToken, token, nil
+}
+
+func (s *userService) newAuthTokens(accessToken, refreshToken string) domain.AuthTokens {
+	return domain.AuthTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(accessTokenTTL.Seconds()),
+	}
+}
+
+// issueTokens starts a session for a completed login. The session ID is
+// also the family of the refresh tokens.
+func (s *userService) issueTokens(
+	ctx context.Context,
+	user domain.User,
+) (domain.AuthTokens, error) {
+	session, err := s.startSession(ctx, user)
+	if err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	// Generate the access token
+	accessToken, err := s.generateJWT(user, session.ID)
+	if err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	// Store a refresh token for the family
+	rawRefresh, _, err := s.createRefreshToken(ctx, user.ID, session.ID)
+	if err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	return s.newAuthTokens(accessToken, rawRefresh), nil
 }
 
 func (s *userService) verifyToken(
@@ -137,15 +224,8 @@ func (s *userService) verifyToken(
 			return err
 		}
 
-		// 4. Update verification flags
-		switch tokenType {
-		case domain.VerificationEmail:
-			user.EmailVerified = true
-		case domain.VerificationMobile:
-			user.MobileVerified = true
-		}
-
-		if err := s.r.Update(txCtx, &user); err != nil {
+		// 4. Update verification flags, or apply a pending address change
+		if err := s.markVerified(txCtx, &user, tokenType); err != nil {
 			return err
 		}
 
@@ -158,10 +238,76 @@ func (s *userService) verifyToken(
 	})
 }
 
-func (s *userService) sendEmailVerification(email string, token domain.VerificationToken) {
-	slog.Debug("Sending Email...", "email", email, "token:", token.TokenHash)
+func (s *userService) findByEmailOrMobile(
+	ctx context.Context,
+	email string,
+	mobile string,
+) (domain.User, error) {
+	// Email takes precedence when both are provided
+	if email != "" {
+		return s.r.FindByEmail(ctx, email)
+	}
+	if mobile != "" {
+		return s.r.FindByMobile(ctx, mobile)
+	}
+	return domain.User{}, errors.New("email or mobile is required")
+}
+
+// Notifications are sent once the request is done, so they use their own
+// context. The notifier retries and logs failed deliveries.
+
+func (s *userService) sendEmailVerification(user domain.User, code string) {
+	_ = s.notifier.Email(context.Background(), user.Email, domain.TemplateEmailVerification, map[string]any{
+		"Name": user.FirstName,
+		"Code": code,
+	})
+}
+
+func (s *userService) sendMobileVerification(user domain.User, otp string) {
+	_ = s.notifier.SMS(context.Background(), user.Mobile, domain.TemplateMobileVerification, map[string]any{
+		"Name": user.FirstName,
+		"Code": otp,
+	})
+}
+
+// sendCode sends a login or reset code by email, or by SMS when viaEmail is false
+func (s *userService) sendCode(
+	user domain.User,
+	viaEmail bool,
+	template domain.NotificationTemplate,
+	code string,
+) {
+	data := map[string]any{
+		"Name": user.FirstName,
+		"Code": code,
+	}
+
+	if viaEmail {
+		_ = s.notifier.Email(context.Background(), user.Email, template, data)
+		return
+	}
+	_ = s.notifier.SMS(context.Background(), user.Mobile, template, data)
+}
+
+func (s *userService) sendMagicLink(user domain.User, link string) {
+	_ = s.notifier.Email(context.Background(), user.Email, domain.TemplateMagicLink, map[string]any{
+		"Name": user.FirstName,
+		"Link": link,
+	})
 }
 
-func (s *userService) sendMobileVerification(mobile, otp string) {
-	slog.Debug("Sending OTP...", "mobile:", mobile, "otp:", otp)
+func (s *userService) sendInvitation(invitation domain.Invitation, token string) {
+	_ = s.notifier.Email(context.Background(), invitation.Email, domain.TemplateInvitation, map[string]any{
+		"Role": string(invitation.Role),
+		"Code": token,
+	})
+}
+
+func (s *userService) sendNewDeviceAlert(user domain.User, session domain.Session) {
+	_ = s.notifier.Email(context.Background(), user.Email, domain.TemplateNewDevice, map[string]any{
+		"Name":      user.FirstName,
+		"UserAgent": session.UserAgent,
+		"IPAddress": session.IPAddress,
+		"Time":      session.CreatedAt.UTC().Format(time.RFC1123),
+	})
 }
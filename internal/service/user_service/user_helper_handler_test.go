@@ -4,14 +4,17 @@ import (
 	"context"
 	"errors"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 
 	"booknest/internal/domain"
+	"booknest/internal/pkg/password"
 )
 
 // MockUserRepository is a mock implementation of domain.UserRepository
@@ -21,7 +24,18 @@ type MockUserRepository struct {
 	FindByEmailFunc  func(ctx context.Context, email string) (domain.User, error)
 	FindByMobileFunc func(ctx context.Context, mobile string) (domain.User, error)
 	UpdateFunc       func(ctx context.Context, user *domain.User) error
+	UpdateRoleFunc   func(ctx context.Context, id uuid.UUID, role domain.UserRole) error
+	SetActiveFunc    func(ctx context.Context, id uuid.UUID, active bool) error
+	SearchFunc       func(ctx context.Context, filter domain.UserFilter, pagination domain.QueryOptions) (domain.Page[domain.User], int64, error)
 	DeleteFunc       func(ctx context.Context, id uuid.UUID) error
+
+	SetPasswordResetRequiredFunc func(ctx context.Context, id uuid.UUID, required bool) error
+	SetPendingEmailFunc          func(ctx context.Context, id uuid.UUID, email *string) error
+	SetPendingMobileFunc         func(ctx context.Context, id uuid.UUID, mobile *string) error
+	ConfirmPendingEmailFunc      func(ctx context.Context, id uuid.UUID) (bool, error)
+	ConfirmPendingMobileFunc     func(ctx context.Context, id uuid.UUID) (bool, error)
+	SetDeletionScheduleFunc      func(ctx context.Context, id uuid.UUID, at *time.Time) error
+	FindDueForDeletionFunc       func(ctx context.Context, before time.Time, limit int) ([]domain.User, error)
 }
 
 func (m *MockUserRepository) Create(ctx context.Context, user *domain.User) error {
@@ -59,6 +73,76 @@ func (m *MockUserRepository) Update(ctx context.Context, user *domain.User) erro
 	return nil
 }
 
+func (m *MockUserRepository) UpdateRole(ctx context.Context, id uuid.UUID, role domain.UserRole) error {
+	if m.UpdateRoleFunc != nil {
+		return m.UpdateRoleFunc(ctx, id, role)
+	}
+	return nil
+}
+
+func (m *MockUserRepository) SetActive(ctx context.Context, id uuid.UUID, active bool) error {
+	if m.SetActiveFunc != nil {
+		return m.SetActiveFunc(ctx, id, active)
+	}
+	return nil
+}
+
+func (m *MockUserRepository) SetPasswordResetRequired(ctx context.Context, id uuid.UUID, required bool) error {
+	if m.SetPasswordResetRequiredFunc != nil {
+		return m.SetPasswordResetRequiredFunc(ctx, id, required)
+	}
+	return nil
+}
+
+func (m *MockUserRepository) SetPendingEmail(ctx context.Context, id uuid.UUID, email *string) error {
+	if m.SetPendingEmailFunc != nil {
+		return m.SetPendingEmailFunc(ctx, id, email)
+	}
+	return nil
+}
+
+func (m *MockUserRepository) SetPendingMobile(ctx context.Context, id uuid.UUID, mobile *string) error {
+	if m.SetPendingMobileFunc != nil {
+		return m.SetPendingMobileFunc(ctx, id, mobile)
+	}
+	return nil
+}
+
+func (m *MockUserRepository) ConfirmPendingEmail(ctx context.Context, id uuid.UUID) (bool, error) {
+	if m.ConfirmPendingEmailFunc != nil {
+		return m.ConfirmPendingEmailFunc(ctx, id)
+	}
+	return true, nil
+}
+
+func (m *MockUserRepository) ConfirmPendingMobile(ctx context.Context, id uuid.UUID) (bool, error) {
+	if m.ConfirmPendingMobileFunc != nil {
+		return m.ConfirmPendingMobileFunc(ctx, id)
+	}
+	return true, nil
+}
+
+func (m *MockUserRepository) SetDeletionSchedule(ctx context.Context, id uuid.UUID, at *time.Time) error {
+	if m.SetDeletionScheduleFunc != nil {
+		return m.SetDeletionScheduleFunc(ctx, id, at)
+	}
+	return nil
+}
+
+func (m *MockUserRepository) FindDueForDeletion(ctx context.Context, before time.Time, limit int) ([]domain.User, error) {
+	if m.FindDueForDeletionFunc != nil {
+		return m.FindDueForDeletionFunc(ctx, before, limit)
+	}
+	return nil, nil
+}
+
+func (m *MockUserRepository) Search(ctx context.Context, filter domain.UserFilter, pagination domain.QueryOptions) (domain.Page[domain.User], int64, error) {
+	if m.SearchFunc != nil {
+		return m.SearchFunc(ctx, filter, pagination)
+	}
+	return domain.Page[domain.User]{}, 0, errors.New("not implemented")
+}
+
 func (m *MockUserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	if m.DeleteFunc != nil {
 		return m.DeleteFunc(ctx, id)
@@ -74,6 +158,9 @@ type MockVerificationTokenRepository struct {
 	UpdateFunc                  func(ctx context.Context, token *domain.VerificationToken) error
 	InvalidateByUserAndTypeFunc func(ctx context.Context, userID uuid.UUID, tokenType domain.VerificationTokenType) error
 	DeleteFunc                  func(ctx context.Context, id uuid.UUID) error
+	PurgeStaleFunc              func(ctx context.Context, cutoff time.Time) (int64, error)
+	ConsumeFunc                 func(ctx context.Context, id uuid.UUID) (bool, error)
+	RecordFailedAttemptFunc     func(ctx context.Context, id uuid.UUID, maxAttempts int) (int, error)
 }
 
 func (m *MockVerificationTokenRepository) Create(ctx context.Context, token *domain.VerificationToken) error {
@@ -118,12 +205,622 @@ func (m *MockVerificationTokenRepository) Delete(ctx context.Context, id uuid.UU
 	return nil
 }
 
+func (m *MockVerificationTokenRepository) Consume(ctx context.Context, id uuid.UUID) (bool, error) {
+	if m.ConsumeFunc != nil {
+		return m.ConsumeFunc(ctx, id)
+	}
+	return true, nil
+}
+
+func (m *MockVerificationTokenRepository) RecordFailedAttempt(ctx context.Context, id uuid.UUID, maxAttempts int) (int, error) {
+	if m.RecordFailedAttemptFunc != nil {
+		return m.RecordFailedAttemptFunc(ctx, id, maxAttempts)
+	}
+	return 1, nil
+}
+
+func (m *MockVerificationTokenRepository) PurgeStale(ctx context.Context, cutoff time.Time) (int64, error) {
+	if m.PurgeStaleFunc != nil {
+		return m.PurgeStaleFunc(ctx, cutoff)
+	}
+	return 0, nil
+}
+
+// MockRefreshTokenRepository is a mock implementation of domain.RefreshTokenRepository
+type MockRefreshTokenRepository struct {
+	CreateFunc          func(ctx context.Context, token *domain.RefreshToken) error
+	FindByHashFunc      func(ctx context.Context, tokenHash string) (*domain.RefreshToken, error)
+	RevokeFunc          func(ctx context.Context, id uuid.UUID, replacedBy *uuid.UUID) (bool, error)
+	RevokeFamilyFunc    func(ctx context.Context, familyID uuid.UUID) error
+	RevokeAllByUserFunc func(ctx context.Context, userID uuid.UUID) error
+}
+
+func (m *MockRefreshTokenRepository) Create(ctx context.Context, token *domain.RefreshToken) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, token)
+	}
+	return nil
+}
+
+func (m *MockRefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	if m.FindByHashFunc != nil {
+		return m.FindByHashFunc(ctx, tokenHash)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockRefreshTokenRepository) Revoke(ctx context.Context, id uuid.UUID, replacedBy *uuid.UUID) (bool, error) {
+	if m.RevokeFunc != nil {
+		return m.RevokeFunc(ctx, id, replacedBy)
+	}
+	return true, nil
+}
+
+func (m *MockRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	if m.RevokeFamilyFunc != nil {
+		return m.RevokeFamilyFunc(ctx, familyID)
+	}
+	return nil
+}
+
+func (m *MockRefreshTokenRepository) RevokeAllByUser(ctx context.Context, userID uuid.UUID) error {
+	if m.RevokeAllByUserFunc != nil {
+		return m.RevokeAllByUserFunc(ctx, userID)
+	}
+	return nil
+}
+
+// MockSessionRepository is a mock implementation of domain.SessionRepository
+type MockSessionRepository struct {
+	CreateFunc           func(ctx context.Context, session *domain.Session) error
+	ListActiveByUserFunc func(ctx context.Context, userID uuid.UUID) ([]domain.Session, error)
+	IsRevokedFunc        func(ctx context.Context, id uuid.UUID) (bool, error)
+	IsKnownDeviceFunc    func(ctx context.Context, userID uuid.UUID, userAgent string) (bool, error)
+	TouchFunc            func(ctx context.Context, id uuid.UUID) error
+	RevokeFunc           func(ctx context.Context, userID, id uuid.UUID) (bool, error)
+	RevokeAllByUserFunc  func(ctx context.Context, userID uuid.UUID) error
+}
+
+func (m *MockSessionRepository) Create(ctx context.Context, session *domain.Session) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, session)
+	}
+	return nil
+}
+
+func (m *MockSessionRepository) ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]domain.Session, error) {
+	if m.ListActiveByUserFunc != nil {
+		return m.ListActiveByUserFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *MockSessionRepository) IsRevoked(ctx context.Context, id uuid.UUID) (bool, error) {
+	if m.IsRevokedFunc != nil {
+		return m.IsRevokedFunc(ctx, id)
+	}
+	return false, nil
+}
+
+func (m *MockSessionRepository) IsKnownDevice(ctx context.Context, userID uuid.UUID, userAgent string) (bool, error) {
+	if m.IsKnownDeviceFunc != nil {
+		return m.IsKnownDeviceFunc(ctx, userID, userAgent)
+	}
+	return true, nil
+}
+
+func (m *MockSessionRepository) Touch(ctx context.Context, id uuid.UUID) error {
+	if m.TouchFunc != nil {
+		return m.TouchFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockSessionRepository) Revoke(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	if m.RevokeFunc != nil {
+		return m.RevokeFunc(ctx, userID, id)
+	}
+	return true, nil
+}
+
+func (m *MockSessionRepository) RevokeAllByUser(ctx context.Context, userID uuid.UUID) error {
+	if m.RevokeAllByUserFunc != nil {
+		return m.RevokeAllByUserFunc(ctx, userID)
+	}
+	return nil
+}
+
+// MockMFARepository is a mock implementation of domain.MFARepository
+type MockMFARepository struct {
+	UpsertFunc               func(ctx context.Context, credential *domain.MFACredential) error
+	FindByUserIDFunc         func(ctx context.Context, userID uuid.UUID) (*domain.MFACredential, error)
+	EnableFunc               func(ctx context.Context, userID uuid.UUID) error
+	UseStepFunc              func(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	DeleteFunc               func(ctx context.Context, userID uuid.UUID) error
+	ReplaceRecoveryCodesFunc func(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+	UseRecoveryCodeFunc      func(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+}
+
+func (m *MockMFARepository) Upsert(ctx context.Context, credential *domain.MFACredential) error {
+	if m.UpsertFunc != nil {
+		return m.UpsertFunc(ctx, credential)
+	}
+	return nil
+}
+
+func (m *MockMFARepository) FindByUserID(ctx context.Context, userID uuid.UUID) (*domain.MFACredential, error) {
+	if m.FindByUserIDFunc != nil {
+		return m.FindByUserIDFunc(ctx, userID)
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockMFARepository) Enable(ctx context.Context, userID uuid.UUID) error {
+	if m.EnableFunc != nil {
+		return m.EnableFunc(ctx, userID)
+	}
+	return nil
+}
+
+func (m *MockMFARepository) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	if m.UseStepFunc != nil {
+		return m.UseStepFunc(ctx, userID, step)
+	}
+	return true, nil
+}
+
+func (m *MockMFARepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(ctx, userID)
+	}
+	return nil
+}
+
+func (m *MockMFARepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	if m.ReplaceRecoveryCodesFunc != nil {
+		return m.ReplaceRecoveryCodesFunc(ctx, userID, codeHashes)
+	}
+	return nil
+}
+
+func (m *MockMFARepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	if m.UseRecoveryCodeFunc != nil {
+		return m.UseRecoveryCodeFunc(ctx, userID, codeHash)
+	}
+	return false, nil
+}
+
+// MockPasswordHistoryRepository is a mock implementation of domain.PasswordHistoryRepository
+type MockPasswordHistoryRepository struct {
+	AddFunc          func(ctx context.Context, userID uuid.UUID, passwordHash string) error
+	ListRecentFunc   func(ctx context.Context, userID uuid.UUID, limit int) ([]string, error)
+	PruneFunc        func(ctx context.Context, userID uuid.UUID, keep int) error
+	DeleteByUserFunc func(ctx context.Context, userID uuid.UUID) error
+}
+
+func (m *MockPasswordHistoryRepository) Add(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	if m.AddFunc != nil {
+		return m.AddFunc(ctx, userID, passwordHash)
+	}
+	return nil
+}
+
+func (m *MockPasswordHistoryRepository) ListRecent(ctx context.Context, userID uuid.UUID, limit int) ([]string, error) {
+	if m.ListRecentFunc != nil {
+		return m.ListRecentFunc(ctx, userID, limit)
+	}
+	return nil, nil
+}
+
+func (m *MockPasswordHistoryRepository) Prune(ctx context.Context, userID uuid.UUID, keep int) error {
+	if m.PruneFunc != nil {
+		return m.PruneFunc(ctx, userID, keep)
+	}
+	return nil
+}
+
+func (m *MockPasswordHistoryRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	if m.DeleteByUserFunc != nil {
+		return m.DeleteByUserFunc(ctx, userID)
+	}
+	return nil
+}
+
+// MockLoginAttemptRepository is a mock implementation of domain.LoginAttemptRepository
+type MockLoginAttemptRepository struct {
+	FindFunc          func(ctx context.Context, key string) (*domain.LoginAttempt, error)
+	RecordFailureFunc func(ctx context.Context, key string, window time.Duration) (*domain.LoginAttempt, error)
+	LockFunc          func(ctx context.Context, key string, until time.Time) error
+	ResetFunc         func(ctx context.Context, key string) error
+}
+
+func (m *MockLoginAttemptRepository) Find(ctx context.Context, key string) (*domain.LoginAttempt, error) {
+	if m.FindFunc != nil {
+		return m.FindFunc(ctx, key)
+	}
+	return nil, nil
+}
+
+func (m *MockLoginAttemptRepository) RecordFailure(ctx context.Context, key string, window time.Duration) (*domain.LoginAttempt, error) {
+	if m.RecordFailureFunc != nil {
+		return m.RecordFailureFunc(ctx, key, window)
+	}
+	return &domain.LoginAttempt{Key: key, Failures: 1}, nil
+}
+
+func (m *MockLoginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	if m.LockFunc != nil {
+		return m.LockFunc(ctx, key, until)
+	}
+	return nil
+}
+
+func (m *MockLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	if m.ResetFunc != nil {
+		return m.ResetFunc(ctx, key)
+	}
+	return nil
+}
+
+// MockInvitationRepository is a mock implementation of domain.InvitationRepository
+type MockInvitationRepository struct {
+	CreateFunc          func(ctx context.Context, invitation *domain.Invitation) error
+	FindByTokenHashFunc func(ctx context.Context, tokenHash string) (*domain.Invitation, error)
+	MarkAcceptedFunc    func(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+func (m *MockInvitationRepository) Create(ctx context.Context, invitation *domain.Invitation) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, invitation)
+	}
+	return nil
+}
+
+func (m *MockInvitationRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*domain.Invitation, error) {
+	if m.FindByTokenHashFunc != nil {
+		return m.FindByTokenHashFunc(ctx, tokenHash)
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockInvitationRepository) MarkAccepted(ctx context.Context, id uuid.UUID) (bool, error) {
+	if m.MarkAcceptedFunc != nil {
+		return m.MarkAcceptedFunc(ctx, id)
+	}
+	return true, nil
+}
+
+// MockAPIKeyRepository is a mock implementation of domain.APIKeyRepository
+type MockAPIKeyRepository struct {
+	CreateFunc     func(ctx context.Context, key *domain.APIKey) error
+	FindByHashFunc func(ctx context.Context, keyHash string) (*domain.APIKey, error)
+	ListFunc       func(ctx context.Context) ([]domain.APIKey, error)
+	TouchFunc      func(ctx context.Context, id uuid.UUID) error
+	RevokeFunc     func(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+func (m *MockAPIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, key)
+	}
+	return nil
+}
+
+func (m *MockAPIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	if m.FindByHashFunc != nil {
+		return m.FindByHashFunc(ctx, keyHash)
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockAPIKeyRepository) List(ctx context.Context) ([]domain.APIKey, error) {
+	if m.ListFunc != nil {
+		return m.ListFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockAPIKeyRepository) Touch(ctx context.Context, id uuid.UUID) error {
+	if m.TouchFunc != nil {
+		return m.TouchFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockAPIKeyRepository) Revoke(ctx context.Context, id uuid.UUID) (bool, error) {
+	if m.RevokeFunc != nil {
+		return m.RevokeFunc(ctx, id)
+	}
+	return true, nil
+}
+
+// MockAuditRepository is a mock implementation of domain.AuditRepository
+type MockAuditRepository struct {
+	CreateFunc func(ctx context.Context, log *domain.AuditLog) error
+}
+
+func (m *MockAuditRepository) Create(ctx context.Context, log *domain.AuditLog) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, log)
+	}
+	return nil
+}
+
+// MockIdentityRepository is a mock implementation of domain.IdentityRepository
+type MockIdentityRepository struct {
+	FindByProviderSubjectFunc func(ctx context.Context, provider, subject string) (*domain.UserIdentity, error)
+	CreateFunc                func(ctx context.Context, identity *domain.UserIdentity) error
+	DeleteByUserFunc          func(ctx context.Context, userID uuid.UUID) error
+	CreateLoginStateFunc      func(ctx context.Context, state *domain.OIDCLoginState) error
+	ConsumeLoginStateFunc     func(ctx context.Context, stateHash string) (*domain.OIDCLoginState, error)
+}
+
+func (m *MockIdentityRepository) FindByProviderSubject(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	if m.FindByProviderSubjectFunc != nil {
+		return m.FindByProviderSubjectFunc(ctx, provider, subject)
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockIdentityRepository) Create(ctx context.Context, identity *domain.UserIdentity) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, identity)
+	}
+	return nil
+}
+
+func (m *MockIdentityRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	if m.DeleteByUserFunc != nil {
+		return m.DeleteByUserFunc(ctx, userID)
+	}
+	return nil
+}
+
+func (m *MockIdentityRepository) CreateLoginState(ctx context.Context, state *domain.OIDCLoginState) error {
+	if m.CreateLoginStateFunc != nil {
+		return m.CreateLoginStateFunc(ctx, state)
+	}
+	return nil
+}
+
+func (m *MockIdentityRepository) ConsumeLoginState(ctx context.Context, stateHash string) (*domain.OIDCLoginState, error) {
+	if m.ConsumeLoginStateFunc != nil {
+		return m.ConsumeLoginStateFunc(ctx, stateHash)
+	}
+	return nil, errors.New("not found")
+}
+
+// MockNotifier is a mock implementation of domain.Notifier
+type MockNotifier struct {
+	EmailFunc func(ctx context.Context, to string, template domain.NotificationTemplate, data any) error
+	SMSFunc   func(ctx context.Context, to string, template domain.NotificationTemplate, data any) error
+}
+
+func (m *MockNotifier) Email(ctx context.Context, to string, template domain.NotificationTemplate, data any) error {
+	if m.EmailFunc != nil {
+		return m.EmailFunc(ctx, to, template, data)
+	}
+	return nil
+}
+
+func (m *MockNotifier) SMS(ctx context.Context, to string, template domain.NotificationTemplate, data any) error {
+	if m.SMSFunc != nil {
+		return m.SMSFunc(ctx, to, template, data)
+	}
+	return nil
+}
+
+// MockOrderRepository is a mock implementation of domain.OrderRepository
+type MockOrderRepository struct {
+	CreateOrderFunc        func(ctx context.Context, order *domain.Order) error
+	CreateOrderItemsFunc   func(ctx context.Context, items []domain.OrderItem) error
+	ListOrdersByUserFunc   func(ctx context.Context, userID uuid.UUID, q domain.QueryOptions) (domain.Page[domain.OrderView], error)
+	ListOrdersFunc         func(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.OrderView], error)
+	GetOrderByIDFunc       func(ctx context.Context, orderID uuid.UUID) (domain.Order, error)
+	GetOrderItemsFunc      func(ctx context.Context, orderID uuid.UUID) ([]domain.OrderItemDetail, error)
+	UpdateOrderPaymentFunc func(ctx context.Context, orderID uuid.UUID, status domain.PaymentStatus, method domain.PaymentMethod) error
+	UpdateOrderStatusFunc  func(ctx context.Context, orderID uuid.UUID, status domain.OrderStatus) error
+	DecrementStockFunc     func(ctx context.Context, items []domain.OrderItem) error
+	AnonymizeAddressesFunc func(ctx context.Context, userID uuid.UUID) error
+}
+
+func (m *MockOrderRepository) CreateOrder(ctx context.Context, order *domain.Order) error {
+	if m.CreateOrderFunc != nil {
+		return m.CreateOrderFunc(ctx, order)
+	}
+	return nil
+}
+
+func (m *MockOrderRepository) CreateOrderItems(ctx context.Context, items []domain.OrderItem) error {
+	if m.CreateOrderItemsFunc != nil {
+		return m.CreateOrderItemsFunc(ctx, items)
+	}
+	return nil
+}
+
+func (m *MockOrderRepository) ListOrdersByUser(ctx context.Context, userID uuid.UUID, q domain.QueryOptions) (domain.Page[domain.OrderView], error) {
+	if m.ListOrdersByUserFunc != nil {
+		return m.ListOrdersByUserFunc(ctx, userID, q)
+	}
+	return domain.Page[domain.OrderView]{}, nil
+}
+
+func (m *MockOrderRepository) ListOrders(ctx context.Context, q domain.QueryOptions) (domain.Page[domain.OrderView], error) {
+	if m.ListOrdersFunc != nil {
+		return m.ListOrdersFunc(ctx, q)
+	}
+	return domain.Page[domain.OrderView]{}, nil
+}
+
+func (m *MockOrderRepository) GetOrderByID(ctx context.Context, orderID uuid.UUID) (domain.Order, error) {
+	if m.GetOrderByIDFunc != nil {
+		return m.GetOrderByIDFunc(ctx, orderID)
+	}
+	return domain.Order{}, nil
+}
+
+func (m *MockOrderRepository) GetOrderItems(ctx context.Context, orderID uuid.UUID) ([]domain.OrderItemDetail, error) {
+	if m.GetOrderItemsFunc != nil {
+		return m.GetOrderItemsFunc(ctx, orderID)
+	}
+	return nil, nil
+}
+
+func (m *MockOrderRepository) UpdateOrderPayment(ctx context.Context, orderID uuid.UUID, status domain.PaymentStatus, method domain.PaymentMethod) error {
+	if m.UpdateOrderPaymentFunc != nil {
+		return m.UpdateOrderPaymentFunc(ctx, orderID, status, method)
+	}
+	return nil
+}
+
+func (m *MockOrderRepository) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, status domain.OrderStatus) error {
+	if m.UpdateOrderStatusFunc != nil {
+		return m.UpdateOrderStatusFunc(ctx, orderID, status)
+	}
+	return nil
+}
+
+func (m *MockOrderRepository) AnonymizeAddresses(ctx context.Context, userID uuid.UUID) error {
+	if m.AnonymizeAddressesFunc != nil {
+		return m.AnonymizeAddressesFunc(ctx, userID)
+	}
+	return nil
+}
+
+func (m *MockOrderRepository) DecrementStock(ctx context.Context, items []domain.OrderItem) error {
+	if m.DecrementStockFunc != nil {
+		return m.DecrementStockFunc(ctx, items)
+	}
+	return nil
+}
+
+// MockCartRepository is a mock implementation of domain.CartRepository
+type MockCartRepository struct {
+	GetOrCreateCartFunc    func(ctx context.Context, userID uuid.UUID) (domain.Cart, error)
+	GetCartItemsFunc       func(ctx context.Context, userID uuid.UUID) ([]domain.CartItemDetail, error)
+	GetCartItemRecordsFunc func(ctx context.Context, userID uuid.UUID) ([]domain.CartItemRecord, error)
+	UpsertCartItemFunc     func(ctx context.Context, cartID uuid.UUID, bookID uuid.UUID, count int, unitPrice float64) error
+	RemoveCartItemFunc     func(ctx context.Context, cartID uuid.UUID, bookID uuid.UUID) error
+	ClearCartFunc          func(ctx context.Context, cartID uuid.UUID) error
+	ClearUserCartFunc      func(ctx context.Context, userID uuid.UUID) error
+}
+
+func (m *MockCartRepository) GetOrCreateCart(ctx context.Context, userID uuid.UUID) (domain.Cart, error) {
+	if m.GetOrCreateCartFunc != nil {
+		return m.GetOrCreateCartFunc(ctx, userID)
+	}
+	return domain.Cart{}, nil
+}
+
+func (m *MockCartRepository) GetCartItems(ctx context.Context, userID uuid.UUID) ([]domain.CartItemDetail, error) {
+	if m.GetCartItemsFunc != nil {
+		return m.GetCartItemsFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *MockCartRepository) GetCartItemRecords(ctx context.Context, userID uuid.UUID) ([]domain.CartItemRecord, error) {
+	if m.GetCartItemRecordsFunc != nil {
+		return m.GetCartItemRecordsFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *MockCartRepository) UpsertCartItem(ctx context.Context, cartID uuid.UUID, bookID uuid.UUID, count int, unitPrice float64) error {
+	if m.UpsertCartItemFunc != nil {
+		return m.UpsertCartItemFunc(ctx, cartID, bookID, count, unitPrice)
+	}
+	return nil
+}
+
+func (m *MockCartRepository) RemoveCartItem(ctx context.Context, cartID uuid.UUID, bookID uuid.UUID) error {
+	if m.RemoveCartItemFunc != nil {
+		return m.RemoveCartItemFunc(ctx, cartID, bookID)
+	}
+	return nil
+}
+
+func (m *MockCartRepository) ClearCart(ctx context.Context, cartID uuid.UUID) error {
+	if m.ClearCartFunc != nil {
+		return m.ClearCartFunc(ctx, cartID)
+	}
+	return nil
+}
+
+func (m *MockCartRepository) ClearUserCart(ctx context.Context, userID uuid.UUID) error {
+	if m.ClearUserCartFunc != nil {
+		return m.ClearUserCartFunc(ctx, userID)
+	}
+	return nil
+}
+
+type MockAddressRepository struct {
+	ListByUserFunc   func(ctx context.Context, userID uuid.UUID) ([]domain.Address, error)
+	DeleteByUserFunc func(ctx context.Context, userID uuid.UUID) error
+}
+
+func (m *MockAddressRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Address, error) {
+	if m.ListByUserFunc != nil {
+		return m.ListByUserFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *MockAddressRepository) FindByID(ctx context.Context, userID, id uuid.UUID) (domain.Address, error) {
+	return domain.Address{}, errors.New("not implemented")
+}
+
+func (m *MockAddressRepository) FindDefault(ctx context.Context, userID uuid.UUID, kind domain.AddressKind) (domain.Address, error) {
+	return domain.Address{}, errors.New("not implemented")
+}
+
+func (m *MockAddressRepository) Create(ctx context.Context, address *domain.Address) error {
+	return nil
+}
+
+func (m *MockAddressRepository) Update(ctx context.Context, address *domain.Address) error {
+	return nil
+}
+
+func (m *MockAddressRepository) ClearDefault(ctx context.Context, userID uuid.UUID, kind domain.AddressKind) error {
+	return nil
+}
+
+func (m *MockAddressRepository) PromoteDefault(ctx context.Context, userID uuid.UUID, kind domain.AddressKind) error {
+	return nil
+}
+
+func (m *MockAddressRepository) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+func (m *MockAddressRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	if m.DeleteByUserFunc != nil {
+		return m.DeleteByUserFunc(ctx, userID)
+	}
+	return nil
+}
+
+// mustHashPassword hashes raw with the default policy
+func mustHashPassword(t *testing.T, raw string) string {
+	t.Helper()
+
+	hashed, err := (&userService{}).hashPassword(raw)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	return hashed
+}
+
 // TestHashPassword_Success tests successful password hashing
 func TestHashPassword_Success(t *testing.T) {
 	service := &userService{}
 	password := "securepassword123"
 
-	hashed := service.hashPassword(password)
+	hashed, err := service.hashPassword(password)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
 
 	if hashed == "" {
 		t.Fatalf("expected non-empty hashed password, got empty string")
@@ -133,9 +830,22 @@ func TestHashPassword_Success(t *testing.T) {
 		t.Fatalf("hashed password should not equal raw password")
 	}
 
-	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
-	if err != nil {
-		t.Fatalf("bcrypt comparison failed: %v", err)
+	if !service.comparePassword(hashed, password) {
+		t.Fatalf("expected the hash to verify")
+	}
+}
+
+// TestHashPassword_Error tests that hashing failures are returned instead of an empty hash
+func TestHashPassword_Error(t *testing.T) {
+	service := &userService{passwords: &password.Policy{
+		Hasher: password.Hasher{Algorithm: password.Bcrypt, BcryptCost: bcrypt.MinCost},
+	}}
+
+	// bcrypt cannot hash more than 72 bytes
+	hashed, err := service.hashPassword(strings.Repeat("a", 73))
+
+	if err == nil || hashed != "" {
+		t.Fatalf("expected an error, got %q %v", hashed, err)
 	}
 }
 
@@ -143,7 +853,7 @@ func TestHashPassword_Success(t *testing.T) {
 func TestComparePassword_Success(t *testing.T) {
 	service := &userService{}
 	password := "securepassword123"
-	hashed := service.hashPassword(password)
+	hashed := mustHashPassword(t, password)
 
 	result := service.comparePassword(hashed, password)
 
@@ -156,7 +866,7 @@ func TestComparePassword_Success(t *testing.T) {
 func TestComparePassword_Failure(t *testing.T) {
 	service := &userService{}
 	password := "securepassword123"
-	hashed := service.hashPassword(password)
+	hashed := mustHashPassword(t, password)
 
 	result := service.comparePassword(hashed, "wrongpassword")
 
@@ -283,7 +993,8 @@ func TestGenerateJWT_Success(t *testing.T) {
 		Role:  domain.UserRoleUser,
 	}
 
-	token, err := service.generateJWT(user)
+	sessionID := uuid.New()
+	token, err := service.generateJWT(user, sessionID)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -311,6 +1022,9 @@ func TestGenerateJWT_Success(t *testing.T) {
 	if claims["email"] != "test@example.com" {
 		t.Fatalf("expected email in claims")
 	}
+	if claims["sid"] != sessionID.String() {
+		t.Fatalf("expected sid in claims")
+	}
 }
 
 // TestGenerateJWT_DefaultSecret tests JWT generation uses default secret when env var is missing
@@ -325,7 +1039,7 @@ func TestGenerateJWT_DefaultSecret(t *testing.T) {
 		Role:  domain.UserRoleUser,
 	}
 
-	token, err := service.generateJWT(user)
+	token, err := service.generateJWT(user, uuid.New())
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -357,7 +1071,7 @@ func TestGenerateJWT_ExpirationTime(t *testing.T) {
 		Role:  domain.UserRoleUser,
 	}
 
-	token, err := service.generateJWT(user)
+	token, err := service.generateJWT(user, uuid.New())
 	afterGeneration := time.Now()
 
 	if err != nil {
@@ -372,14 +1086,103 @@ func TestGenerateJWT_ExpirationTime(t *testing.T) {
 	exp := int64(claims["exp"].(float64))
 	expTime := time.Unix(exp, 0)
 
-	expectedExpiration := afterGeneration.Add(24 * time.Hour)
+	expectedExpiration := afterGeneration.Add(accessTokenTTL)
 	diffSeconds := expTime.Sub(expectedExpiration).Seconds()
 
 	if diffSeconds < -1 || diffSeconds > 1 {
-		t.Fatalf("expected expiration in ~24 hours, got difference of %v seconds", diffSeconds)
+		t.Fatalf("expected expiration in ~%v, got difference of %v seconds", accessTokenTTL, diffSeconds)
+	}
+}
+
+// TestIssueTokens_StoresHashedRefreshToken tests that only the refresh token hash is stored
+func TestIssueTokens_StoresHashedRefreshToken(t *testing.T) {
+	var stored *domain.RefreshToken
+	var session *domain.Session
+
+	service := &userService{
+		rtr: &MockRefreshTokenRepository{
+			CreateFunc: func(ctx context.Context, token *domain.RefreshToken) error {
+				stored = token
+				return nil
+			},
+		},
+		sr: &MockSessionRepository{
+			CreateFunc: func(ctx context.Context, s *domain.Session) error {
+				session = s
+				return nil
+			},
+		},
+	}
+
+	user := domain.User{ID: uuid.New(), Email: "test@example.com", Role: domain.UserRoleUser}
+	tokens, err := service.issueTokens(context.Background(), user)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if stored == nil || session == nil {
+		t.Fatalf("expected refresh token and session to be stored")
+	}
+	if stored.TokenHash == tokens.RefreshToken {
+		t.Fatalf("expected refresh token to be stored hashed")
+	}
+	if stored.TokenHash != service.generateTokenHash(tokens.RefreshToken) {
+		t.Fatalf("expected stored hash to match the issued refresh token")
+	}
+	if stored.FamilyID != session.ID || stored.UserID != user.ID {
+		t.Fatalf("expected refresh token to belong to the user and session")
+	}
+	if tokens.ExpiresIn != int64(accessTokenTTL.Seconds()) {
+		t.Fatalf("expected expires_in of %v, got %d", accessTokenTTL.Seconds(), tokens.ExpiresIn)
 	}
 }
 
 // Note: TestVerifyToken tests are not included here as they require
 // a database pool and transaction context which cannot be easily mocked.
 // These should be tested through integration tests with a test database.
+
+// TestSendEmailVerification_SendsRawCode tests that the user gets the code, not its hash
+func TestSendEmailVerification_SendsRawCode(t *testing.T) {
+	var sent map[string]any
+	service := &userService{notifier: &MockNotifier{
+		EmailFunc: func(ctx context.Context, to string, template domain.NotificationTemplate, data any) error {
+			if to != "test@example.com" || template != domain.TemplateEmailVerification {
+				t.Fatalf("unexpected email %s to %s", template, to)
+			}
+			sent = data.(map[string]any)
+			return nil
+		},
+	}}
+
+	service.sendEmailVerification(domain.User{Email: "test@example.com", FirstName: "Ada"}, "raw-code")
+
+	if sent["Code"] != "raw-code" || sent["Name"] != "Ada" {
+		t.Fatalf("unexpected template data %v", sent)
+	}
+}
+
+// TestSendCode_Channel tests that codes go to the channel the user asked for
+func TestSendCode_Channel(t *testing.T) {
+	var channel, recipient string
+	service := &userService{notifier: &MockNotifier{
+		EmailFunc: func(ctx context.Context, to string, template domain.NotificationTemplate, data any) error {
+			channel, recipient = "email", to
+			return nil
+		},
+		SMSFunc: func(ctx context.Context, to string, template domain.NotificationTemplate, data any) error {
+			channel, recipient = "sms", to
+			return nil
+		},
+	}}
+	user := domain.User{Email: "test@example.com", Mobile: "+15550001234"}
+
+	service.sendCode(user, true, domain.TemplatePasswordReset, "token")
+	if channel != "email" || recipient != user.Email {
+		t.Fatalf("expected email to %s, got %s to %s", user.Email, channel, recipient)
+	}
+
+	service.sendCode(user, false, domain.TemplateLoginOTP, "123456")
+	if channel != "sms" || recipient != user.Mobile {
+		t.Fatalf("expected sms to %s, got %s to %s", user.Mobile, channel, recipient)
+	}
+}
@@ -2,6 +2,7 @@ package user_service
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
 	"time"
 
@@ -9,20 +10,83 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"booknest/internal/domain"
+	"booknest/internal/pkg/password"
 	"booknest/internal/pkg/util"
+	"booknest/internal/pkg/verification"
 )
 
 type userService struct {
-	db  *pgxpool.Pool
-	r   domain.UserRepository
-	vtr domain.VerificationTokenRepository
+	db   *pgxpool.Pool
+	r    domain.UserRepository
+	vtr  domain.VerificationTokenRepository
+	rtr  domain.RefreshTokenRepository
+	sr   domain.SessionRepository
+	idr  domain.IdentityRepository
+	mfar domain.MFARepository
+	phr  domain.PasswordHistoryRepository
+	lar  domain.LoginAttemptRepository
+	ir   domain.InvitationRepository
+	akr  domain.APIKeyRepository
+	ar   domain.AuditRepository
+	or   domain.OrderRepository
+	cr   domain.CartRepository
+	adr  domain.AddressRepository
+
+	// oidc holds the configured login providers by name
+	oidc map[string]domain.OIDCProvider
+	// passwords is the hashing and strength policy, nil uses the default
+	passwords *password.Policy
+	// verification lists the channels actions need, nil uses the default
+	verification verification.Policy
+	// magicLinkURL is the page login links point to, empty uses the default
+	magicLinkURL string
+	notifier     domain.Notifier
 }
 
-func NewUserService(db *pgxpool.Pool, r domain.UserRepository, vtr domain.VerificationTokenRepository) domain.UserService {
+func NewUserService(
+	db *pgxpool.Pool,
+	r domain.UserRepository,
+	vtr domain.VerificationTokenRepository,
+	rtr domain.RefreshTokenRepository,
+	sr domain.SessionRepository,
+	idr domain.IdentityRepository,
+	mfar domain.MFARepository,
+	phr domain.PasswordHistoryRepository,
+	lar domain.LoginAttemptRepository,
+	ir domain.InvitationRepository,
+	akr domain.APIKeyRepository,
+	ar domain.AuditRepository,
+	or domain.OrderRepository,
+	cr domain.CartRepository,
+	adr domain.AddressRepository,
+	oidcProviders map[string]domain.OIDCProvider,
+	passwords *password.Policy,
+	verificationPolicy verification.Policy,
+	magicLinkURL string,
+	notifier domain.Notifier,
+) domain.UserService {
 	return &userService{
-		db:  db,
-		r:   r,
-		vtr: vtr,
+		db:   db,
+		r:    r,
+		vtr:  vtr,
+		rtr:  rtr,
+		sr:   sr,
+		idr:  idr,
+		mfar: mfar,
+		phr:  phr,
+		lar:  lar,
+		ir:   ir,
+		akr:  akr,
+		ar:   ar,
+		or:   or,
+		cr:   cr,
+		adr:  adr,
+
+		oidc:         oidcProviders,
+		passwords:    passwords,
+		verification: verificationPolicy,
+		magicLinkURL: magicLinkURL,
+		notifier:     notifier,
 	}
 }
 
@@ -37,21 +101,38 @@ func (s *userService) Register(
 	ctx context.Context,
 	in domain.UserInput,
 ) error {
-	var emailToken *domain.VerificationToken
+	var user *domain.User
+	var emailCode string
 	var mobileOTP string
 
+	// Reject weak passwords before anything is stored
+	err := s.checkPasswordStrength(in.Password, domain.User{
+		FirstName: in.FirstName,
+		LastName:  in.LastName,
+		Email:     in.Email,
+		Mobile:    in.Mobile,
+	})
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := s.hashPassword(in.Password)
+	if err != nil {
+		return err
+	}
+
 	// Use transaction for user registration
-	err := util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+	err = util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
 		// Create an user domain
-		user := &domain.User{
+		user = &domain.User{
 			ID:        uuid.New(),
 			FirstName: in.FirstName,
 			LastName:  in.LastName,
 			Email:     in.Email,
 			Mobile:    in.Mobile,
-			Password:  s.hashPassword(in.Password),
-			Role:      in.Role,
-			IsActive:  true,
+			Password:  hashedPassword,
+			// Other roles are only granted through invitations
+			Role:     domain.UserRoleUser,
+			IsActive: true,
 		}
 
 		// Create the user
@@ -78,7 +159,7 @@ func (s *userService) Register(
 			return err
 		}
 		// Assign to outer variable for sending email after transaction
-		emailToken = token
+		emailCode = verificationCode
 
 		// Create mobile OTP
 		mobileOTP = s.generateOTP(6)
@@ -98,11 +179,11 @@ func (s *userService) Register(
 	}
 
 	go func() {
-		s.sendEmailVerification(in.Email, *emailToken)
+		s.sendEmailVerification(*user, emailCode)
 	}()
 
 	go func() {
-		s.sendMobileVerification(in.Mobile, mobileOTP)
+		s.sendMobileVerification(*user, mobileOTP)
 	}()
 
 	return err
@@ -111,11 +192,16 @@ func (s *userService) Register(
 func (s *userService) Login(
 	ctx context.Context,
 	in domain.LoginInput,
-) (string, error) {
+) (domain.AuthTokens, error) {
 
 	var user domain.User
 	var err error
 
+	// Reject locked out addresses before touching the account
+	if err := s.checkAttempts(ctx, s.attemptKeys(ctx, nil)); err != nil {
+		return domain.AuthTokens{}, err
+	}
+
 	// Get user by email or mobile
 	if in.Email != "" {
 		user, err = s.r.FindByEmail(ctx, in.Email)
@@ -123,35 +209,163 @@ func (s *userService) Login(
 		user, err = s.r.FindByMobile(ctx, in.Mobile)
 	}
 	if err != nil {
-		return "", err
+		// Unknown accounts still count against the address
+		if lockErr := s.recordFailedAttempt(ctx, s.attemptKeys(ctx, nil)); lockErr != nil {
+			return domain.AuthTokens{}, lockErr
+		}
+		return domain.AuthTokens{}, err
+	}
+
+	keys := s.attemptKeys(ctx, &user.ID)
+	if err := s.checkAttempts(ctx, keys); err != nil {
+		return domain.AuthTokens{}, err
 	}
 
 	// Validate the password
 	if !s.comparePassword(user.Password, in.Password) {
-		return "", errors.New("invalid credentials")
+		if lockErr := s.recordFailedAttempt(ctx, keys); lockErr != nil {
+			return domain.AuthTokens{}, lockErr
+		}
+		return domain.AuthTokens{}, errors.New("invalid credentials")
 	}
 
+	if err := s.checkAccountStatus(user); err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	// Upgrade the hash to the configured policy, saved with the last login
+	s.rehashPassword(&user, in.Password)
+
 	// Update last login
 	now := time.Now()
 	user.LastLogin = &now
 
 	// Update user
 	if err := s.r.Update(ctx, &user); err != nil {
-		return "", err
+		return domain.AuthTokens{}, err
+	}
+
+	// Admins and enrolled users must pass the second factor first
+	return s.completeLogin(ctx, user)
+}
+
+func (s *userService) RefreshToken(
+	ctx context.Context,
+	rawToken string,
+) (domain.AuthTokens, error) {
+	current, err := s.rtr.FindByHash(ctx, s.generateTokenHash(rawToken))
+	if err != nil {
+		return domain.AuthTokens{}, errors.New("invalid refresh token")
+	}
+
+	if current.RevokedAt != nil {
+		// A token that was already rotated is being replayed, so assume it
+		// leaked and revoke every token issued from the same login.
+		if current.ReplacedBy != nil {
+			if err := s.endSession(ctx, current.UserID, current.FamilyID); err != nil {
+				return domain.AuthTokens{}, err
+			}
+			return domain.AuthTokens{}, errRefreshTokenReused
+		}
+		return domain.AuthTokens{}, errors.New("invalid refresh token")
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return domain.AuthTokens{}, errors.New("refresh token expired")
+	}
+
+	user, err := s.r.FindByID(ctx, current.UserID)
+	if err != nil {
+		return domain.AuthTokens{}, errors.New("invalid refresh token")
+	}
+
+	if err := s.checkAccountStatus(user); err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	// Sessions started before 2FA became mandatory must enroll first
+	if s.mfaRequired(user) {
+		credential, err := s.findMFA(ctx, user.ID)
+		if err != nil {
+			return domain.AuthTokens{}, err
+		}
+		if credential == nil || credential.EnabledAt == nil {
+			return domain.AuthTokens{}, errMFARequired
+		}
 	}
 
-	// Generate JWT token
-	return s.generateJWT(user)
+	var tokens domain.AuthTokens
+	err = util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		// 1. Create the next token in the family
+		rawRefresh, next, err := s.createRefreshToken(txCtx, user.ID, current.FamilyID)
+		if err != nil {
+			return err
+		}
+
+		// 2. Retire the presented token; losing this race means it was reused
+		revoked, err := s.rtr.Revoke(txCtx, current.ID, &next.ID)
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return errRefreshTokenReused
+		}
+
+		// 3. Generate a new access token for the same session
+		accessToken, err := s.generateJWT(user, current.FamilyID)
+		if err != nil {
+			return err
+		}
+
+		// 4. Keep the session's last activity current
+		if err := s.sr.Touch(txCtx, current.FamilyID); err != nil {
+			return err
+		}
+
+		tokens = s.newAuthTokens(accessToken, rawRefresh)
+		return nil
+	})
+
+	if errors.Is(err, errRefreshTokenReused) {
+		if err := s.endSession(ctx, current.UserID, current.FamilyID); err != nil {
+			return domain.AuthTokens{}, err
+		}
+	}
+	if err != nil {
+		return domain.AuthTokens{}, err
+	}
+
+	return tokens, nil
+}
+
+func (s *userService) Logout(
+	ctx context.Context,
+	rawToken string,
+) error {
+	token, err := s.rtr.FindByHash(ctx, s.generateTokenHash(rawToken))
+	if err != nil {
+		// Unknown tokens are already logged out
+		return nil
+	}
+
+	return s.endSession(ctx, token.UserID, token.FamilyID)
+}
+
+func (s *userService) LogoutAll(
+	ctx context.Context,
+	userID uuid.UUID,
+) error {
+	return s.endAllSessions(ctx, userID)
 }
 
 func (s *userService) ForgotPassword(
 	ctx context.Context,
 	in domain.ForgotPasswordInput,
-) (string, error) {
+) error {
 	var rawToken string
+	var user domain.User
 
 	err := util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
-		var user domain.User
 		var err error
 
 		if in.Email != "" {
@@ -182,11 +396,13 @@ func (s *userService) ForgotPassword(
 
 		return s.vtr.Create(txCtx, token)
 	})
-	if err != nil {
-		return "", err
+	if err != nil || rawToken == "" {
+		return err
 	}
 
-	return rawToken, nil
+	// Send the token to the channel the user asked for
+	go s.sendCode(user, in.Email != "", domain.TemplatePasswordReset, rawToken)
+	return nil
 }
 
 func (s *userService) ResetPassword(
@@ -202,11 +418,21 @@ func (s *userService) ResetPassword(
 			return err
 		}
 
-		// Add hashed password to the user
-		user.Password = s.hashPassword(newPassword)
+		// Check the policy and the recent passwords, then hash
+		if err := s.changePassword(txCtx, &user, newPassword); err != nil {
+			return err
+		}
 
 		// Update the user
-		return s.r.Update(txCtx, &user)
+		if err := s.r.Update(txCtx, &user); err != nil {
+			return err
+		}
+
+		// A new password satisfies a reset forced by an admin
+		if user.PasswordResetRequired {
+			return s.r.SetPasswordResetRequired(txCtx, user.ID, false)
+		}
+		return nil
 	})
 }
 
@@ -217,13 +443,19 @@ func (s *userService) ResetPasswordWithToken(
 ) error {
 	tokenHash := s.generateTokenHash(rawToken)
 
-	return util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+	// Reset tokens are only guessable per address
+	keys := s.attemptKeys(ctx, nil)
+	if err := s.checkAttempts(ctx, keys); err != nil {
+		return err
+	}
+
+	err := util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
 		token, err := s.vtr.FindByHashAndType(txCtx, tokenHash, domain.PasswordReset)
 		if err != nil {
-			return errors.New("invalid or expired token")
+			return errInvalidOrExpiredToken
 		}
 		if token.IsUsed || time.Now().After(token.ExpiresAt) {
-			return errors.New("invalid or expired token")
+			return errInvalidOrExpiredToken
 		}
 
 		user, err := s.r.FindByID(txCtx, token.UserID)
@@ -231,10 +463,17 @@ func (s *userService) ResetPasswordWithToken(
 			return err
 		}
 
-		user.Password = s.hashPassword(newPassword)
+		if err := s.changePassword(txCtx, &user, newPassword); err != nil {
+			return err
+		}
 		if err := s.r.Update(txCtx, &user); err != nil {
 			return err
 		}
+		if user.PasswordResetRequired {
+			if err := s.r.SetPasswordResetRequired(txCtx, user.ID, false); err != nil {
+				return err
+			}
+		}
 
 		now := time.Now()
 		token.IsUsed = true
@@ -245,6 +484,12 @@ func (s *userService) ResetPasswordWithToken(
 
 		return s.vtr.InvalidateByUserAndType(txCtx, user.ID, domain.PasswordReset)
 	})
+	if errors.Is(err, errInvalidOrExpiredToken) {
+		if lockErr := s.recordFailedAttempt(ctx, keys); lockErr != nil {
+			return lockErr
+		}
+	}
+	return err
 }
 
 func (s *userService) VerifyEmail(
@@ -256,9 +501,51 @@ func (s *userService) VerifyEmail(
 
 func (s *userService) VerifyMobile(
 	ctx context.Context,
+	userID uuid.UUID,
 	otp string,
 ) error {
-	return s.verifyToken(ctx, otp, domain.VerificationMobile)
+	keys := s.attemptKeys(ctx, &userID)
+	if err := s.checkAttempts(ctx, keys); err != nil {
+		return err
+	}
+
+	// OTPs are short, so only the user's own latest code is compared
+	token, err := s.vtr.FindByUserIDAndType(ctx, userID, domain.VerificationMobile)
+	if err != nil || time.Now().After(token.ExpiresAt) {
+		return errInvalidOrExpiredToken
+	}
+
+	hash := s.generateTokenHash(otp)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(token.TokenHash)) != 1 {
+		if lockErr := s.recordFailedAttempt(ctx, keys); lockErr != nil {
+			return lockErr
+		}
+		return errInvalidOrExpiredToken
+	}
+
+	err = util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		// 1. Fetch user
+		user, err := s.r.FindByID(txCtx, userID)
+		if err != nil {
+			return err
+		}
+
+		// 2. Update verification flag, or apply a pending mobile change
+		if err := s.markVerified(txCtx, &user, domain.VerificationMobile); err != nil {
+			return err
+		}
+
+		// 3. Mark token as used
+		now := time.Now()
+		token.IsUsed = true
+		token.UsedAt = &now
+		return s.vtr.Update(txCtx, token)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.resetAccountAttempts(ctx, userID)
 }
 
 func (s *userService) ResendEmailVerification(
@@ -266,18 +553,20 @@ func (s *userService) ResendEmailVerification(
 	userID uuid.UUID,
 ) error {
 
-	var emailToken *domain.VerificationToken
-	var email string
+	var user domain.User
+	var rawToken string
 
 	err := util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		var err error
+
 		// Fetch user
-		user, err := s.r.FindByID(txCtx, userID)
+		user, err = s.r.FindByID(txCtx, userID)
 		if err != nil {
 			return err
 		}
 
-		// Check if already verified
-		if user.EmailVerified {
+		// Check if already verified, unless the email is being changed
+		if user.EmailVerified && user.PendingEmail == nil {
 			return errors.New("email already verified")
 		}
 
@@ -291,7 +580,7 @@ func (s *userService) ResendEmailVerification(
 		}
 
 		// Create new verification token
-		rawToken, err := s.generateRawToken()
+		rawToken, err = s.generateRawToken()
 		if err != nil {
 			return err
 		}
@@ -309,9 +598,6 @@ func (s *userService) ResendEmailVerification(
 			return err
 		}
 
-		// Assign to outer variables for sending email after transaction
-		emailToken = token
-		email = user.Email
 		return nil
 	})
 
@@ -319,8 +605,8 @@ func (s *userService) ResendEmailVerification(
 		return err
 	}
 
-	// Send verification email
-	go s.sendEmailVerification(email, *emailToken)
+	// Send verification email, to the new address during a change
+	go s.sendEmailVerification(pendingRecipient(user), rawToken)
 	return nil
 }
 
@@ -330,17 +616,19 @@ func (s *userService) ResendMobileOTP(
 ) error {
 
 	var otp string
-	var mobile string
+	var user domain.User
 
 	err := util.WithTransaction(ctx, s.db, func(txCtx context.Context) error {
+		var err error
+
 		// Fetch user
-		user, err := s.r.FindByID(txCtx, userID)
+		user, err = s.r.FindByID(txCtx, userID)
 		if err != nil {
 			return err
 		}
 
-		// Check if already verified
-		if user.MobileVerified {
+		// Check if already verified, unless the mobile is being changed
+		if user.MobileVerified && user.PendingMobile == nil {
 			return errors.New("mobile already verified")
 		}
 
@@ -365,27 +653,14 @@ func (s *userService) ResendMobileOTP(
 		}
 
 		// Add token to the database
-		if err := s.vtr.Create(txCtx, token); err != nil {
-			return err
-		}
-
-		// Assign to outer variable for sending SMS after transaction
-		mobile = user.Mobile
-		return nil
+		return s.vtr.Create(txCtx, token)
 	})
 
 	if err != nil {
 		return err
 	}
 
-	// Send mobile OTP
-	go s.sendMobileVerification(mobile, otp)
+	// Send mobile OTP, to the new number during a change
+	go s.sendMobileVerification(pendingRecipient(user), otp)
 	return nil
 }
-
-func (s *userService) DeleteUser(
-	ctx context.Context,
-	id uuid.UUID,
-) error {
-	return s.r.Delete(ctx, id)
-}
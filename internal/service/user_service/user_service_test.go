@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -65,13 +66,13 @@ func TestLogin_SuccessByEmail(t *testing.T) {
 
 	mockUserRepo := &MockUserRepository{
 		FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
-			service := &userService{}
-			hashedPassword := service.hashPassword(password)
+			hashedPassword := mustHashPassword(t, password)
 			return domain.User{
 				ID:       userID,
 				Email:    email,
 				Password: hashedPassword,
 				Role:     domain.UserRoleUser,
+				IsActive: true,
 			}, nil
 		},
 		UpdateFunc: func(ctx context.Context, user *domain.User) error {
@@ -79,7 +80,7 @@ func TestLogin_SuccessByEmail(t *testing.T) {
 		},
 	}
 
-	service := &userService{r: mockUserRepo}
+	service := &userService{r: mockUserRepo, rtr: &MockRefreshTokenRepository{}, sr: &MockSessionRepository{}, mfar: &MockMFARepository{}, lar: &MockLoginAttemptRepository{}}
 	input := domain.LoginInput{
 		Email:    "test@example.com",
 		Password: password,
@@ -91,8 +92,8 @@ func TestLogin_SuccessByEmail(t *testing.T) {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	if token == "" {
-		t.Fatalf("expected non-empty token")
+	if token.AccessToken == "" || token.RefreshToken == "" {
+		t.Fatalf("expected non-empty tokens")
 	}
 }
 
@@ -103,13 +104,13 @@ func TestLogin_SuccessByMobile(t *testing.T) {
 
 	mockUserRepo := &MockUserRepository{
 		FindByMobileFunc: func(ctx context.Context, mobile string) (domain.User, error) {
-			service := &userService{}
-			hashedPassword := service.hashPassword(password)
+			hashedPassword := mustHashPassword(t, password)
 			return domain.User{
 				ID:       userID,
 				Mobile:   mobile,
 				Password: hashedPassword,
 				Role:     domain.UserRoleUser,
+				IsActive: true,
 			}, nil
 		},
 		UpdateFunc: func(ctx context.Context, user *domain.User) error {
@@ -117,7 +118,7 @@ func TestLogin_SuccessByMobile(t *testing.T) {
 		},
 	}
 
-	service := &userService{r: mockUserRepo}
+	service := &userService{r: mockUserRepo, rtr: &MockRefreshTokenRepository{}, sr: &MockSessionRepository{}, mfar: &MockMFARepository{}, lar: &MockLoginAttemptRepository{}}
 	input := domain.LoginInput{
 		Mobile:   "1234567890",
 		Password: password,
@@ -129,8 +130,8 @@ func TestLogin_SuccessByMobile(t *testing.T) {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	if token == "" {
-		t.Fatalf("expected non-empty token")
+	if token.AccessToken == "" || token.RefreshToken == "" {
+		t.Fatalf("expected non-empty tokens")
 	}
 }
 
@@ -140,18 +141,18 @@ func TestLogin_InvalidPassword(t *testing.T) {
 
 	mockUserRepo := &MockUserRepository{
 		FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
-			service := &userService{}
-			hashedPassword := service.hashPassword(password)
+			hashedPassword := mustHashPassword(t, password)
 			return domain.User{
 				ID:       uuid.New(),
 				Email:    email,
 				Password: hashedPassword,
 				Role:     domain.UserRoleUser,
+				IsActive: true,
 			}, nil
 		},
 	}
 
-	service := &userService{r: mockUserRepo}
+	service := &userService{r: mockUserRepo, rtr: &MockRefreshTokenRepository{}, sr: &MockSessionRepository{}, mfar: &MockMFARepository{}, lar: &MockLoginAttemptRepository{}}
 	input := domain.LoginInput{
 		Email:    "test@example.com",
 		Password: "wrongpassword",
@@ -176,7 +177,7 @@ func TestLogin_UserNotFound(t *testing.T) {
 		},
 	}
 
-	service := &userService{r: mockUserRepo}
+	service := &userService{r: mockUserRepo, rtr: &MockRefreshTokenRepository{}, sr: &MockSessionRepository{}, mfar: &MockMFARepository{}, lar: &MockLoginAttemptRepository{}}
 	input := domain.LoginInput{
 		Email:    "nonexistent@example.com",
 		Password: "password123",
@@ -198,13 +199,13 @@ func TestLogin_UpdatesLastLogin(t *testing.T) {
 
 	mockUserRepo := &MockUserRepository{
 		FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
-			service := &userService{}
-			hashedPassword := service.hashPassword(password)
+			hashedPassword := mustHashPassword(t, password)
 			return domain.User{
 				ID:       userID,
 				Email:    email,
 				Password: hashedPassword,
 				Role:     domain.UserRoleUser,
+				IsActive: true,
 			}, nil
 		},
 		UpdateFunc: func(ctx context.Context, user *domain.User) error {
@@ -214,7 +215,7 @@ func TestLogin_UpdatesLastLogin(t *testing.T) {
 		},
 	}
 
-	service := &userService{r: mockUserRepo}
+	service := &userService{r: mockUserRepo, rtr: &MockRefreshTokenRepository{}, sr: &MockSessionRepository{}, mfar: &MockMFARepository{}, lar: &MockLoginAttemptRepository{}}
 	input := domain.LoginInput{
 		Email:    "test@example.com",
 		Password: password,
@@ -225,8 +226,8 @@ func TestLogin_UpdatesLastLogin(t *testing.T) {
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
-	if token == "" {
-		t.Fatalf("expected non-empty token")
+	if token.AccessToken == "" || token.RefreshToken == "" {
+		t.Fatalf("expected non-empty tokens")
 	}
 
 	if !updateCalled {
@@ -245,49 +246,6 @@ func TestResetPassword_Success(t *testing.T) {
 	t.Skip("ResetPassword requires database transaction, tested through integration tests")
 }
 
-// TestDeleteUser_Success tests successful user deletion
-func TestDeleteUser_Success(t *testing.T) {
-	userID := uuid.New()
-	deleteCalled := false
-
-	mockUserRepo := &MockUserRepository{
-		DeleteFunc: func(ctx context.Context, id uuid.UUID) error {
-			if id == userID {
-				deleteCalled = true
-				return nil
-			}
-			return errors.New("user not found")
-		},
-	}
-
-	service := &userService{r: mockUserRepo}
-	err := service.DeleteUser(context.Background(), userID)
-
-	if err != nil {
-		t.Fatalf("expected no error, got %v", err)
-	}
-
-	if !deleteCalled {
-		t.Fatalf("expected Delete to be called")
-	}
-}
-
-// TestDeleteUser_NotFound tests deletion of non-existent user
-func TestDeleteUser_NotFound(t *testing.T) {
-	mockUserRepo := &MockUserRepository{
-		DeleteFunc: func(ctx context.Context, id uuid.UUID) error {
-			return errors.New("user not found")
-		},
-	}
-
-	service := &userService{r: mockUserRepo}
-	err := service.DeleteUser(context.Background(), uuid.New())
-
-	if err == nil {
-		t.Fatalf("expected error, got nil")
-	}
-}
-
 // TestVerifyEmail_CallsVerifyToken tests that VerifyEmail delegates to verifyToken
 func TestVerifyEmail_CallsVerifyToken(t *testing.T) {
 	// This test would require mocking the verifyToken method
@@ -316,12 +274,180 @@ func TestResendMobileOTP_MobileAlreadyVerified(t *testing.T) {
 	t.Skip("ResendMobileOTP requires database transaction, tested through integration tests")
 }
 
+// TestRefreshToken_ReuseRevokesFamily tests that replaying a rotated token revokes its family
+func TestRefreshToken_ReuseRevokesFamily(t *testing.T) {
+	familyID := uuid.New()
+	replacedBy := uuid.New()
+	now := time.Now()
+	var revokedFamily uuid.UUID
+
+	mockRefreshRepo := &MockRefreshTokenRepository{
+		FindByHashFunc: func(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+			return &domain.RefreshToken{
+				ID:         uuid.New(),
+				FamilyID:   familyID,
+				ExpiresAt:  now.Add(time.Hour),
+				RevokedAt:  &now,
+				ReplacedBy: &replacedBy,
+			}, nil
+		},
+		RevokeFamilyFunc: func(ctx context.Context, id uuid.UUID) error {
+			revokedFamily = id
+			return nil
+		},
+	}
+
+	service := &userService{rtr: mockRefreshRepo, sr: &MockSessionRepository{}}
+	_, err := service.RefreshToken(context.Background(), "rotated-token")
+
+	if !errors.Is(err, errRefreshTokenReused) {
+		t.Fatalf("expected reuse error, got %v", err)
+	}
+	if revokedFamily != familyID {
+		t.Fatalf("expected token family to be revoked")
+	}
+}
+
+// TestRefreshToken_LoggedOut tests that a revoked but never rotated token is rejected
+func TestRefreshToken_LoggedOut(t *testing.T) {
+	now := time.Now()
+
+	mockRefreshRepo := &MockRefreshTokenRepository{
+		FindByHashFunc: func(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+			return &domain.RefreshToken{ID: uuid.New(), ExpiresAt: now.Add(time.Hour), RevokedAt: &now}, nil
+		},
+		RevokeFamilyFunc: func(ctx context.Context, id uuid.UUID) error {
+			t.Fatalf("should not revoke the family for a logged out token")
+			return nil
+		},
+	}
+
+	service := &userService{rtr: mockRefreshRepo, sr: &MockSessionRepository{}}
+	_, err := service.RefreshToken(context.Background(), "logged-out-token")
+
+	if err == nil || errors.Is(err, errRefreshTokenReused) {
+		t.Fatalf("expected invalid refresh token error, got %v", err)
+	}
+}
+
+// TestRefreshToken_Expired tests that an expired refresh token is rejected
+func TestRefreshToken_Expired(t *testing.T) {
+	mockRefreshRepo := &MockRefreshTokenRepository{
+		FindByHashFunc: func(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+			return &domain.RefreshToken{ID: uuid.New(), ExpiresAt: time.Now().Add(-time.Minute)}, nil
+		},
+	}
+
+	service := &userService{rtr: mockRefreshRepo, sr: &MockSessionRepository{}}
+	_, err := service.RefreshToken(context.Background(), "expired-token")
+
+	if err == nil {
+		t.Fatalf("expected error for expired token")
+	}
+}
+
+// TestLogout_RevokesFamily tests that logout revokes the token family
+func TestLogout_RevokesFamily(t *testing.T) {
+	familyID := uuid.New()
+	var revokedFamily uuid.UUID
+
+	service := &userService{sr: &MockSessionRepository{}}
+	mockRefreshRepo := &MockRefreshTokenRepository{
+		FindByHashFunc: func(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+			if tokenHash != service.generateTokenHash("raw-token") {
+				t.Fatalf("expected lookup by token hash")
+			}
+			return &domain.RefreshToken{ID: uuid.New(), FamilyID: familyID}, nil
+		},
+		RevokeFamilyFunc: func(ctx context.Context, id uuid.UUID) error {
+			revokedFamily = id
+			return nil
+		},
+	}
+	service.rtr = mockRefreshRepo
+
+	if err := service.Logout(context.Background(), "raw-token"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if revokedFamily != familyID {
+		t.Fatalf("expected token family to be revoked")
+	}
+}
+
+// TestLogoutAll_RevokesUserTokens tests that logout-all revokes every token of the user
+func TestLogoutAll_RevokesUserTokens(t *testing.T) {
+	userID := uuid.New()
+	called := false
+
+	sessionsEnded := false
+
+	service := &userService{
+		rtr: &MockRefreshTokenRepository{
+			RevokeAllByUserFunc: func(ctx context.Context, id uuid.UUID) error {
+				called = id == userID
+				return nil
+			},
+		},
+		sr: &MockSessionRepository{
+			RevokeAllByUserFunc: func(ctx context.Context, id uuid.UUID) error {
+				sessionsEnded = id == userID
+				return nil
+			},
+		},
+	}
+
+	if err := service.LogoutAll(context.Background(), userID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !called {
+		t.Fatalf("expected RevokeAllByUser to be called for the user")
+	}
+	if !sessionsEnded {
+		t.Fatalf("expected the sessions to be revoked")
+	}
+}
+
 // TestNewUserService tests service initialization
 func TestNewUserService(t *testing.T) {
 	mockUserRepo := &MockUserRepository{}
 	mockVerificationRepo := &MockVerificationTokenRepository{}
 
-	service := NewUserService(nil, mockUserRepo, mockVerificationRepo)
+	mockRefreshRepo := &MockRefreshTokenRepository{}
+	mockSessionRepo := &MockSessionRepository{}
+	mockIdentityRepo := &MockIdentityRepository{}
+	mockMFARepo := &MockMFARepository{}
+	mockPasswordHistoryRepo := &MockPasswordHistoryRepository{}
+	mockAttemptRepo := &MockLoginAttemptRepository{}
+	mockInvitationRepo := &MockInvitationRepository{}
+	mockAPIKeyRepo := &MockAPIKeyRepository{}
+	mockAuditRepo := &MockAuditRepository{}
+	mockOrderRepo := &MockOrderRepository{}
+	mockCartRepo := &MockCartRepository{}
+	mockAddressRepo := &MockAddressRepository{}
+	mockNotifier := &MockNotifier{}
+
+	service := NewUserService(
+		nil,
+		mockUserRepo,
+		mockVerificationRepo,
+		mockRefreshRepo,
+		mockSessionRepo,
+		mockIdentityRepo,
+		mockMFARepo,
+		mockPasswordHistoryRepo,
+		mockAttemptRepo,
+		mockInvitationRepo,
+		mockAPIKeyRepo,
+		mockAuditRepo,
+		mockOrderRepo,
+		mockCartRepo,
+		mockAddressRepo,
+		nil,
+		nil,
+		nil,
+		"",
+		mockNotifier,
+	)
 
 	if service == nil {
 		t.Fatalf("expected non-nil service")
@@ -339,6 +465,38 @@ func TestNewUserService(t *testing.T) {
 	if userService.vtr != mockVerificationRepo {
 		t.Fatalf("expected verification token repository to be set")
 	}
+
+	if userService.rtr != mockRefreshRepo {
+		t.Fatalf("expected refresh token repository to be set")
+	}
+
+	if userService.mfar != mockMFARepo {
+		t.Fatalf("expected mfa repository to be set")
+	}
+
+	if userService.phr != mockPasswordHistoryRepo {
+		t.Fatalf("expected password history repository to be set")
+	}
+
+	if userService.lar != mockAttemptRepo {
+		t.Fatalf("expected login attempt repository to be set")
+	}
+
+	if userService.ir != mockInvitationRepo {
+		t.Fatalf("expected invitation repository to be set")
+	}
+
+	if userService.ar != mockAuditRepo {
+		t.Fatalf("expected audit repository to be set")
+	}
+
+	if userService.adr != mockAddressRepo {
+		t.Fatalf("expected address repository to be set")
+	}
+
+	if userService.notifier != mockNotifier {
+		t.Fatalf("expected notifier to be set")
+	}
 }
 
 // TestLogin_TrimsContextualEmailAndMobile tests login prefers email when both provided
@@ -350,13 +508,13 @@ func TestLogin_PrefersEmailOverMobile(t *testing.T) {
 	mockUserRepo := &MockUserRepository{
 		FindByEmailFunc: func(ctx context.Context, email string) (domain.User, error) {
 			emailCalled = true
-			service := &userService{}
-			hashedPassword := service.hashPassword(password)
+			hashedPassword := mustHashPassword(t, password)
 			return domain.User{
 				ID:       userID,
 				Email:    email,
 				Password: hashedPassword,
 				Role:     domain.UserRoleUser,
+				IsActive: true,
 			}, nil
 		},
 		FindByMobileFunc: func(ctx context.Context, mobile string) (domain.User, error) {
@@ -368,7 +526,7 @@ func TestLogin_PrefersEmailOverMobile(t *testing.T) {
 		},
 	}
 
-	service := &userService{r: mockUserRepo}
+	service := &userService{r: mockUserRepo, rtr: &MockRefreshTokenRepository{}, sr: &MockSessionRepository{}, mfar: &MockMFARepository{}, lar: &MockLoginAttemptRepository{}}
 	input := domain.LoginInput{
 		Email:    "test@example.com",
 		Mobile:   "1234567890",
@@ -415,28 +573,3 @@ func TestFindUser_PassesContext(t *testing.T) {
 	}
 }
 
-// TestDeleteUser_PassesContext tests that DeleteUser passes context properly
-func TestDeleteUser_PassesContext(t *testing.T) {
-	userID := uuid.New()
-	contextPassed := false
-
-	mockUserRepo := &MockUserRepository{
-		DeleteFunc: func(ctx context.Context, id uuid.UUID) error {
-			if ctx == nil {
-				t.Fatalf("expected non-nil context")
-			}
-			contextPassed = true
-			return nil
-		},
-	}
-
-	service := &userService{r: mockUserRepo}
-	err := service.DeleteUser(context.Background(), userID)
-	if err != nil {
-		t.Fatalf("expected no error, got %v", err)
-	}
-
-	if !contextPassed {
-		t.Fatalf("expected context to be passed to repository")
-	}
-}
@@ -0,0 +1,31 @@
+package user_service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"booknest/internal/domain"
+	"booknest/internal/pkg/verification"
+)
+
+func (s userService) verificationPolicy() verification.Policy {
+	if s.verification == nil {
+		return verification.Default()
+	}
+	return s.verification
+}
+
+// CheckVerified returns a *domain.VerificationRequiredError when the user
+// has not verified the channels the action needs
+func (s *userService) CheckVerified(
+	ctx context.Context,
+	userID uuid.UUID,
+	action domain.ProtectedAction,
+) error {
+	user, err := s.r.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.verificationPolicy().Check(action, user)
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
 
 	"github.com/joho/godotenv"
 
@@ -24,6 +27,10 @@ import (
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
 	// Load the .env file
 	err := godotenv.Load()
@@ -39,11 +46,15 @@ func main() {
 
 	defer dbpool.Close()
 
+	// Cancelled on Ctrl + C, which stops the server and the background jobs
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	// Set up server
-	r, err := SetupServer(dbpool)
+	r, err := SetupServer(ctx, dbpool)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	StartHTTPServer(r)
+	StartHTTPServer(ctx, r)
 }
@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -16,12 +18,21 @@ import (
 
 	"booknest/internal/http/controller"
 	"booknest/internal/http/database"
+	"booknest/internal/http/routes"
 	"booknest/internal/middleware"
+	"booknest/internal/pkg/jwtkeys"
+	"booknest/internal/pkg/notification"
+	"booknest/internal/pkg/oidc"
+	"booknest/internal/pkg/password"
+	"booknest/internal/pkg/util"
+	"booknest/internal/pkg/verification"
 	"booknest/internal/repository"
+	"booknest/internal/service/address_service"
 	"booknest/internal/service/author_service"
 	"booknest/internal/service/book_service"
 	"booknest/internal/service/cart_service"
 	"booknest/internal/service/category_service"
+	"booknest/internal/service/maintenance_service"
 	"booknest/internal/service/order_service"
 	"booknest/internal/service/publisher_service"
 	"booknest/internal/service/user_service"
@@ -29,6 +40,11 @@ import (
 
 var connectGORM = database.ConnectGORM
 
+const (
+	accountPurgeInterval = time.Hour
+	tokenPurgeInterval   = time.Hour
+)
+
 func useCORSMiddleware(allowedOrigins map[string]bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		origin := c.GetHeader("Origin")
@@ -43,8 +59,9 @@ func useCORSMiddleware(allowedOrigins map[string]bool) gin.HandlerFunc {
 			)
 			c.Writer.Header().Set(
 				"Access-Control-Allow-Methods",
-				"GET, POST, PUT, DELETE, OPTIONS",
+				"GET, POST, PUT, PATCH, DELETE, OPTIONS",
 			)
+			c.Writer.Header().Set("Access-Control-Expose-Headers", "Retry-After")
 			c.Writer.Header().Set("Access-Control-Max-Age", "86400")
 		}
 
@@ -57,7 +74,21 @@ func useCORSMiddleware(allowedOrigins map[string]bool) gin.HandlerFunc {
 	}
 }
 
-func SetupServer(dbpool *pgxpool.Pool) (*gin.Engine, error) {
+// trustedProxies reads the comma separated IPs and CIDRs of the reverse
+// proxies in front of the server. Unset, no proxy is trusted.
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// SetupServer wires the routes. The background jobs it starts stop when
+// ctx is cancelled.
+func SetupServer(ctx context.Context, dbpool *pgxpool.Pool) (*gin.Engine, error) {
 	gormdb, err := connectGORM()
 	if err != nil {
 		return nil, fmt.Errorf("connect gorm: %w", err)
@@ -68,36 +99,151 @@ func SetupServer(dbpool *pgxpool.Pool) (*gin.Engine, error) {
 		return nil, fmt.Errorf("gorm db handle: %w", err)
 	}
 
+	// Fail fast on a broken signing key configuration
+	jwtKeys, err := jwtkeys.FromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("load jwt keys: %w", err)
+	}
+
+	notifier, err := notification.FromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("setup notifications: %w", err)
+	}
+
+	oidcProviders, err := oidc.FromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("setup login providers: %w", err)
+	}
+
+	passwordPolicy, err := password.FromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("setup password policy: %w", err)
+	}
+
+	verificationPolicy, err := verification.FromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("setup verification policy: %w", err)
+	}
+
 	userRepo := repository.NewUserRepo(dbpool, gormdb)
 	vtRepo := repository.NewVerificationRepo(dbpool, gormdb)
-	userService := user_service.NewUserService(dbpool, userRepo, vtRepo)
-	userController := controller.NewUserController(userService)
+	refreshTokenRepo := repository.NewRefreshTokenRepo(dbpool, gormdb)
+	sessionRepo := repository.NewSessionRepo(dbpool, gormdb)
+	identityRepo := repository.NewIdentityRepo(dbpool, gormdb)
+	mfaRepo := repository.NewMFARepo(dbpool, gormdb)
+	passwordHistoryRepo := repository.NewPasswordHistoryRepo(dbpool, gormdb)
+	invitationRepo := repository.NewInvitationRepo(dbpool, gormdb)
+	apiKeyRepo := repository.NewAPIKeyRepo(dbpool, gormdb)
+	auditRepo := repository.NewAuditRepo(dbpool, gormdb)
+
+	// Postgres keeps lockouts consistent across replicas
+	loginAttemptRepo := repository.NewLoginAttemptRepo(dbpool, gormdb)
+	if os.Getenv("LOGIN_ATTEMPT_STORE") == "memory" {
+		loginAttemptRepo = repository.NewMemoryLoginAttemptRepo()
+	}
+
+	cartRepo := repository.NewCartRepo(dbpool)
+	orderRepo := repository.NewOrderRepo(dbpool)
+	addressRepo := repository.NewAddressRepo(dbpool, gormdb)
+
+	userService := user_service.NewUserService(
+		dbpool,
+		userRepo,
+		vtRepo,
+		refreshTokenRepo,
+		sessionRepo,
+		identityRepo,
+		mfaRepo,
+		passwordHistoryRepo,
+		loginAttemptRepo,
+		invitationRepo,
+		apiKeyRepo,
+		auditRepo,
+		orderRepo,
+		cartRepo,
+		addressRepo,
+		oidcProviders,
+		passwordPolicy,
+		verificationPolicy,
+		os.Getenv("MAGIC_LINK_URL"),
+		notifier,
+	)
+
+	// Tokens of revoked sessions are rejected, impersonated requests
+	// audited and API keys accepted on catalog and reporting routes
+	auth, err := middleware.NewAuth(sessionRepo, userService, userService, auditRepo)
+	if err != nil {
+		return nil, fmt.Errorf("setup auth middleware: %w", err)
+	}
+	userController := controller.NewUserController(userService, auth)
+
+	tokenRetention := maintenance_service.DefaultTokenRetention
+	if raw := os.Getenv("VERIFICATION_TOKEN_RETENTION"); raw != "" {
+		if tokenRetention, err = time.ParseDuration(raw); err != nil {
+			return nil, fmt.Errorf("parse VERIFICATION_TOKEN_RETENTION: %w", err)
+		}
+		// A cutoff of now or later would delete tokens still in use
+		if tokenRetention <= 0 {
+			return nil, fmt.Errorf("VERIFICATION_TOKEN_RETENTION must be positive, got %s", raw)
+		}
+	}
+	maintenanceService := maintenance_service.NewMaintenanceService(
+		dbpool,
+		repository.NewAdvisoryLockRepo(dbpool),
+		vtRepo,
+		userService,
+		tokenRetention,
+	)
+
+	// Anonymise accounts whose deletion grace period ended
+	go util.RunEvery(ctx, "account purge", accountPurgeInterval, func(ctx context.Context) error {
+		purged, err := maintenanceService.PurgeDeletedAccounts(ctx)
+		if purged > 0 {
+			slog.Info("Deleted accounts anonymised", "count", purged)
+		}
+		return err
+	})
+
+	// Drop verification tokens nobody can use anymore
+	go util.RunEvery(ctx, "verification token purge", tokenPurgeInterval, func(ctx context.Context) error {
+		purged, err := maintenanceService.PurgeVerificationTokens(ctx)
+		if purged > 0 {
+			slog.Info("Stale verification tokens purged", "count", purged)
+		}
+		return err
+	})
 
 	bookRepo := repository.NewBookRepository(gormdb, sqlDB)
 	bookService := book_service.NewBookService(bookRepo, gormdb)
-	bookController := controller.NewBookController(bookService)
+	bookController := controller.NewBookController(bookService, auth)
 
 	authorRepo := repository.NewAuthorRepo(gormdb)
 	authorService := author_service.NewAuthorService(authorRepo)
-	authorController := controller.NewAuthorController(authorService)
+	authorController := controller.NewAuthorController(authorService, auth)
 
 	categoryRepo := repository.NewCategoryRepo(gormdb)
 	categoryService := category_service.NewCategoryService(categoryRepo)
-	categoryController := controller.NewCategoryController(categoryService)
+	categoryController := controller.NewCategoryController(categoryService, auth)
 
 	publisherRepo := repository.NewPublisherRepo(dbpool, gormdb)
 	publisherService := publisher_service.NewPublisherService(dbpool, publisherRepo)
-	publisherController := controller.NewPublisherController(publisherService)
+	publisherController := controller.NewPublisherController(publisherService, auth)
 
-	cartRepo := repository.NewCartRepo(dbpool)
 	cartService := cart_service.NewCartService(dbpool, cartRepo, bookRepo)
-	cartController := controller.NewCartController(cartService)
+	cartController := controller.NewCartController(cartService, auth)
 
-	orderRepo := repository.NewOrderRepo(dbpool)
-	orderService := order_service.NewOrderService(dbpool, orderRepo, cartRepo)
-	orderController := controller.NewOrderController(orderService)
+	orderService := order_service.NewOrderService(dbpool, orderRepo, cartRepo, userRepo, addressRepo, notifier)
+	orderController := controller.NewOrderController(orderService, auth)
+
+	addressService := address_service.NewAddressService(dbpool, addressRepo)
+	addressController := controller.NewAddressController(addressService, auth)
 
 	r := gin.Default()
+	// The client IP feeds the per-IP lockout, so X-Forwarded-For is only
+	// believed when the request came through one of our own proxies
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		return nil, fmt.Errorf("parse TRUSTED_PROXIES: %w", err)
+	}
 	r.Use(useCORSMiddleware(map[string]bool{
 		"http://localhost:3000": true,
 		"http://localhost:5173": true,
@@ -111,10 +257,25 @@ func SetupServer(dbpool *pgxpool.Pool) (*gin.Engine, error) {
 		ginSwagger.WrapHandler(swaggerFiles.Handler),
 	)
 
+	// Counters of the background jobs, for the monitoring scraper
+	if user := os.Getenv("METRICS_USER"); user != "" {
+		r.GET(
+			"/debug/vars",
+			gin.BasicAuth(gin.Accounts{user: os.Getenv("METRICS_PASSWORD")}),
+			gin.WrapH(expvar.Handler()),
+		)
+	}
+
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	// Public keys for services that verify BookNest tokens
+	r.GET(routes.JWKSRoute, func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, jwtKeys.JWKS())
+	})
+
 	userController.RegisterRoutes(r)
 	bookController.RegisterRoutes(r)
 	authorController.RegisterRoutes(r)
@@ -122,12 +283,14 @@ func SetupServer(dbpool *pgxpool.Pool) (*gin.Engine, error) {
 	publisherController.RegisterRoutes(r)
 	cartController.RegisterRoutes(r)
 	orderController.RegisterRoutes(r)
+	addressController.RegisterRoutes(r)
 
 	return r, nil
 }
 
-// StartHTTPServer starts the HTTP server — only used by main.go
-func StartHTTPServer(r *gin.Engine) {
+// StartHTTPServer starts the HTTP server and shuts it down once ctx is
+// cancelled — only used by main.go
+func StartHTTPServer(ctx context.Context, r *gin.Engine) {
 	srv := &http.Server{
 		Addr:    ":8080",
 		Handler: r,
@@ -144,20 +307,18 @@ func StartHTTPServer(r *gin.Engine) {
 
 	// graceful shutdown
 	/*
-		* Creates a channel to receive OS signals and Listens for:
+		* main cancels ctx on an OS signal:
 			- Ctrl + C
 			- Docker stop
 			- Pod termination
-			<-quit blocks until signal arrives
+			<-ctx.Done() blocks until signal arrives
 	*/
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
-	<-quit
+	<-ctx.Done()
 
 	log.Println("Shutting down server...")
 
 	// Gives active requests 5 seconds to finish
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	/*
@@ -167,7 +328,7 @@ func StartHTTPServer(r *gin.Engine) {
 		3. Closes idle connections
 		4. Respects the timeout context
 	*/
-	if err := srv.Shutdown(ctx); err != nil {
+	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 }
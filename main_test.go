@@ -4,8 +4,10 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -14,6 +16,7 @@ import (
 func TestSetupServer_Success(t *testing.T) {
 	t.Setenv("SWAGGER_USER", "swagger")
 	t.Setenv("SWAGGER_PASSWORD", "swagger-pass")
+	t.Setenv("NOTIFY_OUTBOX_DIR", t.TempDir())
 
 	originalConnectGORM := connectGORM
 	t.Cleanup(func() {
@@ -24,7 +27,7 @@ func TestSetupServer_Success(t *testing.T) {
 		return gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	}
 
-	router, err := SetupServer(&pgxpool.Pool{})
+	router, err := SetupServer(t.Context(), &pgxpool.Pool{})
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -37,6 +40,15 @@ func TestSetupServer_Success(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Fatalf("expected 200 OK, got %d", w.Code)
 	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 OK from jwks, got %d", w.Code)
+	}
 }
 
 func TestSetupServer_ConnectGORMError(t *testing.T) {
@@ -49,8 +61,106 @@ func TestSetupServer_ConnectGORMError(t *testing.T) {
 		return nil, errors.New("db unavailable")
 	}
 
-	_, err := SetupServer(&pgxpool.Pool{})
+	_, err := SetupServer(t.Context(), &pgxpool.Pool{})
 	if err == nil {
 		t.Fatalf("expected error, got nil")
 	}
 }
+
+func TestSetupServer_ClientIP(t *testing.T) {
+	t.Setenv("SWAGGER_USER", "swagger")
+	t.Setenv("SWAGGER_PASSWORD", "swagger-pass")
+	t.Setenv("NOTIFY_OUTBOX_DIR", t.TempDir())
+
+	originalConnectGORM := connectGORM
+	t.Cleanup(func() {
+		connectGORM = originalConnectGORM
+	})
+
+	connectGORM = func() (*gorm.DB, error) {
+		return gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	}
+
+	tests := []struct {
+		name    string
+		proxies string
+		want    string
+	}{
+		{"no trusted proxy", "", "10.0.0.1"},
+		{"trusted proxy", "10.0.0.0/8", "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXIES", tt.proxies)
+
+			router, err := SetupServer(t.Context(), &pgxpool.Pool{})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			var got string
+			router.GET("/ip", func(c *gin.Context) { got = c.ClientIP() })
+
+			req, _ := http.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = "10.0.0.1:4242"
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Fatalf("expected client IP %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "not-an-ip")
+	if _, err := SetupServer(t.Context(), &pgxpool.Pool{}); err == nil {
+		t.Fatalf("expected error for an invalid proxy")
+	}
+}
+
+func TestUseCORSMiddleware_Preflight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(useCORSMiddleware(map[string]bool{"http://localhost:5173": true}))
+
+	req, _ := http.NewRequest(http.MethodOptions, "/me", nil)
+	req.Header.Set("Origin", "http://localhost:5173")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if methods := w.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(methods, http.MethodPatch) {
+		t.Fatalf("expected PATCH to be allowed, got %q", methods)
+	}
+}
+
+func TestSetupServer_TokenRetention(t *testing.T) {
+	t.Setenv("SWAGGER_USER", "swagger")
+	t.Setenv("SWAGGER_PASSWORD", "swagger-pass")
+	t.Setenv("NOTIFY_OUTBOX_DIR", t.TempDir())
+
+	originalConnectGORM := connectGORM
+	t.Cleanup(func() {
+		connectGORM = originalConnectGORM
+	})
+
+	connectGORM = func() (*gorm.DB, error) {
+		return gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	}
+
+	for _, retention := range []string{"0s", "-1h", "a week"} {
+		t.Setenv("VERIFICATION_TOKEN_RETENTION", retention)
+		if _, err := SetupServer(t.Context(), &pgxpool.Pool{}); err == nil {
+			t.Fatalf("expected error for retention %q", retention)
+		}
+	}
+
+	t.Setenv("VERIFICATION_TOKEN_RETENTION", "72h")
+	if _, err := SetupServer(t.Context(), &pgxpool.Pool{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}